@@ -0,0 +1,161 @@
+package v1
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"bookadmin/model/common/response"
+	"bookadmin/service"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type AuditApi struct{}
+
+// auditExportLimit CSV导出的最大行数
+const auditExportLimit = 50000
+
+// getAuditActor 从请求上下文中获取审计操作人
+func getAuditActor(c *gin.Context) model.AuditActor {
+	actor := model.AuditActor{IP: c.ClientIP()}
+	if userID, ok := c.Get("user_id"); ok {
+		actor.UserID, _ = userID.(uint)
+	}
+	if username, ok := c.Get("username"); ok {
+		actor.Username, _ = username.(string)
+	}
+	return actor
+}
+
+// parseAuditQuery 解析审计日志筛选参数
+// 支持 actor_id、action、target_type、target_id、start_date、end_date（格式：2024-01-01，含当天）
+func parseAuditQuery(c *gin.Context) (service.AuditQuery, error) {
+	var q service.AuditQuery
+
+	if v := c.Query("actor_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return q, err
+		}
+		q.ActorID = uint(id)
+	}
+	if v := c.Query("target_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return q, err
+		}
+		q.TargetID = uint(id)
+	}
+	q.Action = c.Query("action")
+	q.TargetType = c.Query("target_type")
+
+	if v := c.Query("start_date"); v != "" {
+		start, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return q, err
+		}
+		q.StartTime = &start
+	}
+	if v := c.Query("end_date"); v != "" {
+		end, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return q, err
+		}
+		end = end.AddDate(0, 0, 1)
+		q.EndTime = &end
+	}
+	return q, nil
+}
+
+// GetAuditLogList 分页查询审计日志
+func (a *AuditApi) GetAuditLogList(c *gin.Context) {
+	q, err := parseAuditQuery(c)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	var logs []model.AuditLog
+	var total int64
+	db := service.GlobalAuditService.Query(q)
+
+	if err := db.Count(&total).Error; err != nil {
+		global.GVA_LOG.Error("获取审计日志失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取数据失败"))
+		return
+	}
+
+	offset := (page - 1) * pageSize
+	if err := db.Order("id DESC").Limit(pageSize).Offset(offset).Find(&logs).Error; err != nil {
+		global.GVA_LOG.Error("获取审计日志失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取数据失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(response.PageResult{
+		List:     logs,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, "获取成功"))
+}
+
+// ExportAuditLogs 按筛选条件导出审计日志CSV
+func (a *AuditApi) ExportAuditLogs(c *gin.Context) {
+	q, err := parseAuditQuery(c)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	rows, err := service.GlobalAuditService.Query(q).Order("id ASC").Limit(auditExportLimit).Rows()
+	if err != nil {
+		global.GVA_LOG.Error("导出审计日志失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("导出失败"))
+		return
+	}
+	defer rows.Close()
+
+	filename := fmt.Sprintf("audit_logs_%s.csv", time.Now().Format("20060102150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+
+	// 写入BOM，保证Excel正确识别UTF-8
+	_, _ = c.Writer.Write([]byte("\xEF\xBB\xBF"))
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"ID", "时间", "操作人ID", "操作人", "动作", "目标类型", "目标ID", "变更前", "变更后", "IP"})
+
+	for rows.Next() {
+		var log model.AuditLog
+		if err := global.GVA_DB.ScanRows(rows, &log); err != nil {
+			global.GVA_LOG.Error("读取审计日志失败", zap.Error(err))
+			break
+		}
+		_ = w.Write([]string{
+			strconv.FormatUint(uint64(log.ID), 10),
+			log.CreatedAt.Format("2006-01-02 15:04:05"),
+			strconv.FormatUint(uint64(log.ActorID), 10),
+			log.ActorName,
+			string(log.Action),
+			log.TargetType,
+			strconv.FormatUint(uint64(log.TargetID), 10),
+			log.Before,
+			log.After,
+			log.IP,
+		})
+	}
+	w.Flush()
+}
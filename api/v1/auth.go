@@ -4,6 +4,7 @@ import (
 	"bookadmin/global"
 	"bookadmin/model"
 	"bookadmin/model/common/response"
+	"bookadmin/service"
 	"bookadmin/utils"
 	"strconv"
 
@@ -13,6 +14,8 @@ import (
 
 type AuthApi struct{}
 
+var tokenService = service.NewTokenService()
+
 // Login 用户登录
 func (a *AuthApi) Login(c *gin.Context) {
 	var req struct {
@@ -34,20 +37,31 @@ func (a *AuthApi) Login(c *gin.Context) {
 		return
 	}
 
-	// 验证密码（简化处理，实际应使用bcrypt）
-	if user.Password != req.Password {
+	// 验证密码
+	if !utils.CheckPassword(user.Password, req.Password) {
 		c.JSON(200, response.FailWithMessage("用户名或密码错误"))
 		return
 	}
 
+	// 旧版明文密码在登录成功后升级为bcrypt哈希
+	if !utils.IsPasswordHashed(user.Password) {
+		if hashed, err := utils.HashPassword(req.Password); err != nil {
+			global.GVA_LOG.Error("密码加密失败", zap.Error(err))
+		} else if err := global.GVA_DB.Model(&user).Update("password", hashed).Error; err != nil {
+			global.GVA_LOG.Error("升级密码存储失败", zap.Uint("user_id", user.ID), zap.Error(err))
+		} else {
+			global.GVA_LOG.Info("明文密码已升级为bcrypt", zap.Uint("user_id", user.ID))
+		}
+	}
+
 	// 检查用户状态
 	if user.Status != "active" {
 		c.JSON(200, response.FailWithMessage("用户已被禁用"))
 		return
 	}
 
-	// 生成token
-	token, err := utils.GenerateToken(user.ID, user.Username, user.Role)
+	// 签发令牌
+	tokens, err := tokenService.IssueTokens(c.Request.Context(), &user)
 	if err != nil {
 		global.GVA_LOG.Error("生成token失败", zap.Error(err))
 		c.JSON(200, response.FailWithMessage("登录失败"))
@@ -55,14 +69,60 @@ func (a *AuthApi) Login(c *gin.Context) {
 	}
 
 	c.JSON(200, response.OkWithData(gin.H{
-		"token":    token,
-		"user_id":  user.ID,
-		"username": user.Username,
-		"role":     user.Role,
-		"real_name": user.RealName,
+		"token":              tokens.AccessToken,
+		"expires_at":         tokens.AccessExpiresAt,
+		"refresh_token":      tokens.RefreshToken,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+		"user_id":            user.ID,
+		"username":           user.Username,
+		"role":               user.Role,
+		"real_name":          user.RealName,
+		"permissions":        service.GlobalPermissionService.GetRolePermissions(user.Role),
 	}))
 }
 
+// RefreshToken 使用刷新令牌换取新的令牌对
+func (a *AuthApi) RefreshToken(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	tokens, err := tokenService.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(tokens))
+}
+
+// Logout 注销登录，吊销当前访问令牌和刷新令牌
+func (a *AuthApi) Logout(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	_ = c.ShouldBindJSON(&req)
+
+	value, _ := c.Get("claims")
+	claims, ok := value.(*utils.Claims)
+	if !ok {
+		c.JSON(200, response.FailWithMessage("未登录"))
+		return
+	}
+
+	if err := tokenService.Logout(c.Request.Context(), claims, req.RefreshToken); err != nil {
+		c.JSON(200, response.FailWithMessage("注销失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithMessage("已退出登录"))
+}
+
 // Register 用户注册（读者注册）
 func (a *AuthApi) Register(c *gin.Context) {
 	var req struct {
@@ -88,10 +148,17 @@ func (a *AuthApi) Register(c *gin.Context) {
 		return
 	}
 
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		global.GVA_LOG.Error("密码加密失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("注册失败"))
+		return
+	}
+
 	// 创建用户
 	user := model.User{
 		Username: req.Username,
-		Password: req.Password, // 简化处理，实际应加密
+		Password: hashedPassword,
 		Email:    req.Email,
 		Phone:    req.Phone,
 		Role:     model.RoleReader,
@@ -110,13 +177,12 @@ func (a *AuthApi) Register(c *gin.Context) {
 
 	// 创建读者信息
 	reader := model.Reader{
-		UserID:     user.ID,
-		ReaderNo:   readerNo,
-		IDCard:     req.IDCard,
-		Address:    req.Address,
-		Status:     model.ReaderStatusPending, // 待审核
-		MaxBorrow:  5,
-		BorrowDays: 30,
+		UserID:         user.ID,
+		ReaderNo:       readerNo,
+		IDCard:         req.IDCard,
+		Address:        req.Address,
+		Status:         model.ReaderStatusPending, // 待审核
+		PatronCategory: model.DefaultPatronCategory,
 	}
 
 	if err := global.GVA_DB.Create(&reader).Error; err != nil {
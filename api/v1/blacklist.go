@@ -86,13 +86,6 @@ func (b *BlacklistApi) AddToBlacklist(c *gin.Context) {
 		return
 	}
 
-	// 获取操作员ID
-	operatorID, _ := c.Get("user_id")
-	var opID uint
-	if operatorID != nil {
-		opID = operatorID.(uint)
-	}
-
 	// 解析结束日期
 	var endDate *time.Time
 	if req.EndDate != "" {
@@ -106,7 +99,7 @@ func (b *BlacklistApi) AddToBlacklist(c *gin.Context) {
 
 	// 添加到黑名单
 	reason := model.BlacklistReason(req.Reason)
-	if err := blacklistService.AddToBlacklist(req.ReaderID, reason, req.Description, endDate, opID); err != nil {
+	if err := blacklistService.AddToBlacklist(req.ReaderID, reason, req.Description, endDate, getAuditActor(c)); err != nil {
 		global.GVA_LOG.Error("添加黑名单失败", zap.Error(err))
 		c.JSON(200, response.FailWithMessage(err.Error()))
 		return
@@ -129,15 +122,8 @@ func (b *BlacklistApi) RemoveFromBlacklist(c *gin.Context) {
 	}
 	_ = c.ShouldBindJSON(&req)
 
-	// 获取操作员ID
-	operatorID, _ := c.Get("user_id")
-	var opID uint
-	if operatorID != nil {
-		opID = operatorID.(uint)
-	}
-
 	// 解除黑名单
-	if err := blacklistService.RemoveFromBlacklist(uint(blacklistID), opID, req.Remark); err != nil {
+	if err := blacklistService.RemoveFromBlacklist(uint(blacklistID), getAuditActor(c), req.Remark); err != nil {
 		global.GVA_LOG.Error("解除黑名单失败", zap.Error(err))
 		c.JSON(200, response.FailWithMessage(err.Error()))
 		return
@@ -6,20 +6,25 @@ import (
 	"bookadmin/model/common/request"
 	"bookadmin/model/common/response"
 	"bookadmin/service"
+	"errors"
 	"strconv"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type BookApi struct{}
 
+var bookCopyService = service.NewBookCopyService()
+
 // CreateBook 创建图书
 func (b *BookApi) CreateBook(c *gin.Context) {
 	var req struct {
 		model.Book
-		CategoryIDs []uint `json:"category_ids"` // 分类ID列表
+		CategoryIDs   []uint `json:"category_ids"`   // 分类ID列表
+		ShelfLocation string `json:"shelf_location"` // 副本默认架位
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		global.GVA_LOG.Error("参数绑定失败", zap.Error(err))
@@ -60,12 +65,27 @@ func (b *BookApi) CreateBook(c *gin.Context) {
 		}
 	}
 
+	// 按总库存生成馆藏副本，库存由副本状态推导
+	if err := bookCopyService.GenerateCopies(tx, req.Book.ID, req.Book.TotalStock, req.ShelfLocation); err != nil {
+		tx.Rollback()
+		global.GVA_LOG.Error("生成馆藏副本失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("生成馆藏副本失败: "+err.Error()))
+		return
+	}
+
+	if err := service.GlobalAuditService.Record(tx, getAuditActor(c), model.AuditBookCreate, "book", req.Book.ID, nil, req); err != nil {
+		tx.Rollback()
+		c.JSON(200, response.FailWithMessage("创建失败: "+err.Error()))
+		return
+	}
+
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		global.GVA_LOG.Error("提交事务失败", zap.Error(err))
 		c.JSON(200, response.FailWithMessage("创建失败: "+err.Error()))
 		return
 	}
+	service.GlobalSearchService.IndexBooks(req.Book.ID)
 
 	c.JSON(200, response.OkWithMessage("创建成功"))
 }
@@ -79,7 +99,7 @@ func (b *BookApi) DeleteBook(c *gin.Context) {
 		return
 	}
 
-	if err := global.GVA_DB.Delete(&model.Book{}, req.ID).Error; err != nil {
+	if err := deleteBookWithAudit(c, req.ID); err != nil {
 		global.GVA_LOG.Error("删除失败", zap.Error(err))
 		c.JSON(200, response.FailWithMessage("删除失败: "+err.Error()))
 		return
@@ -91,19 +111,17 @@ func (b *BookApi) DeleteBook(c *gin.Context) {
 // UpdateBook 更新图书
 func (b *BookApi) UpdateBook(c *gin.Context) {
 	var req struct {
-		ID             uint    `json:"id"`
-		Title          string  `json:"title"`
-		Author         string  `json:"author"`
-		Publisher      string  `json:"publisher"`
-		PublishDate    string  `json:"publish_date"`
-		ISBN           string  `json:"isbn"`
-		Price          float64 `json:"price"`
-		Description    string  `json:"description"`
-		Category       string  `json:"category"`
-		CategoryIDs    []uint  `json:"category_ids"` // 分类ID列表
-		CoverImage     string  `json:"cover_image"`
-		TotalStock     int     `json:"total_stock"`
-		AvailableStock int     `json:"available_stock"`
+		ID          uint    `json:"id"`
+		Title       string  `json:"title"`
+		Author      string  `json:"author"`
+		Publisher   string  `json:"publisher"`
+		PublishDate string  `json:"publish_date"`
+		ISBN        string  `json:"isbn"`
+		Price       float64 `json:"price"`
+		Description string  `json:"description"`
+		Category    string  `json:"category"`
+		CategoryIDs []uint  `json:"category_ids"` // 分类ID列表
+		CoverImage  string  `json:"cover_image"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -121,17 +139,6 @@ func (b *BookApi) UpdateBook(c *gin.Context) {
 
 	bookID := req.ID
 
-	// 检查图书是否存在（排除软删除的记录）
-	var existBook model.Book
-	if err := global.GVA_DB.Where("id = ?", bookID).First(&existBook).Error; err != nil {
-		global.GVA_LOG.Error("图书不存在", zap.Uint("id", bookID), zap.Error(err))
-		c.JSON(200, response.FailWithMessage("图书不存在"))
-		return
-	}
-
-	// 记录更新前的库存，用于检测库存变化
-	oldAvailableStock := existBook.AvailableStock
-
 	// 开始事务
 	tx := global.GVA_DB.Begin()
 	defer func() {
@@ -140,19 +147,30 @@ func (b *BookApi) UpdateBook(c *gin.Context) {
 		}
 	}()
 
+	// 检查图书是否存在（排除软删除的记录）
+	// 锁定图书行，与借还书、同时进行的修改依次执行，审计日志记录的修改前数据是最新的
+	var existBook model.Book
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", bookID).First(&existBook).Error; err != nil {
+		tx.Rollback()
+		global.GVA_LOG.Error("图书不存在", zap.Uint("id", bookID), zap.Error(err))
+		c.JSON(200, response.FailWithMessage("图书不存在"))
+		return
+	}
+
+	before := existBook
+
 	// 使用map明确指定要更新的字段，包括cover_image（即使为空字符串也要更新）
+	// 库存由馆藏副本推导，不在此处修改，请使用副本管理接口
 	updateData := map[string]interface{}{
-		"title":           req.Title,
-		"author":          req.Author,
-		"publisher":       req.Publisher,
-		"publish_date":    req.PublishDate,
-		"isbn":            req.ISBN,
-		"price":           req.Price,
-		"description":     req.Description,
-		"category":        req.Category,
-		"cover_image":     req.CoverImage,
-		"total_stock":     req.TotalStock,
-		"available_stock": req.AvailableStock,
+		"title":        req.Title,
+		"author":       req.Author,
+		"publisher":    req.Publisher,
+		"publish_date": req.PublishDate,
+		"isbn":         req.ISBN,
+		"price":        req.Price,
+		"description":  req.Description,
+		"category":     req.Category,
+		"cover_image":  req.CoverImage,
 	}
 
 	if err := tx.Model(&existBook).Updates(updateData).Error; err != nil {
@@ -179,6 +197,13 @@ func (b *BookApi) UpdateBook(c *gin.Context) {
 			c.JSON(200, response.FailWithMessage("更新分类关联失败: "+err.Error()))
 			return
 		}
+		updateData["category_ids"] = req.CategoryIDs
+	}
+
+	if err := service.GlobalAuditService.Record(tx, getAuditActor(c), model.AuditBookUpdate, "book", bookID, before, updateData); err != nil {
+		tx.Rollback()
+		c.JSON(200, response.FailWithMessage("更新失败: "+err.Error()))
+		return
 	}
 
 	// 提交事务
@@ -187,27 +212,7 @@ func (b *BookApi) UpdateBook(c *gin.Context) {
 		c.JSON(200, response.FailWithMessage("更新失败: "+err.Error()))
 		return
 	}
-
-	// 检测库存变化：如果从0变为>0，检查并通知预约者
-	// 重新查询更新后的实际库存值，确保准确性
-	var updatedBook model.Book
-	if err := global.GVA_DB.First(&updatedBook, bookID).Error; err == nil {
-		// 如果原来库存是0，现在库存>0，说明有库存补充
-		if oldAvailableStock == 0 && updatedBook.AvailableStock > 0 {
-			// 异步处理预约通知，不影响更新响应
-			go func() {
-				reservationService := service.NewReservationService()
-				if err := reservationService.CheckAndNotifyAvailableReservations(bookID); err != nil {
-					global.GVA_LOG.Error("通知预约者失败", zap.Uint("book_id", bookID), zap.Error(err))
-				} else {
-					global.GVA_LOG.Info("库存补充，已通知预约者",
-						zap.Uint("book_id", bookID),
-						zap.Int("old_stock", oldAvailableStock),
-						zap.Int("new_stock", updatedBook.AvailableStock))
-				}
-			}()
-		}
-	}
+	service.GlobalSearchService.IndexBooks(bookID)
 
 	c.JSON(200, response.OkWithMessage("更新成功"))
 }
@@ -231,51 +236,66 @@ func (b *BookApi) GetBook(c *gin.Context) {
 	c.JSON(200, response.OkWithData(book))
 }
 
-// GetBookList 获取图书列表，支持搜索和分页
+// GetBookList 获取图书列表，支持关键词搜索和分页，有关键词时按相关度排序
 func (b *BookApi) GetBookList(c *gin.Context) {
 	var pageInfo request.PageInfo
 	_ = c.ShouldBindQuery(&pageInfo)
 
-	// 设置默认值
-	if pageInfo.Page <= 0 {
-		pageInfo.Page = 1
+	result, err := service.GlobalSearchService.Search(model.SearchQuery{
+		Keyword:  pageInfo.Keyword,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	})
+	if err != nil {
+		global.GVA_LOG.Error("获取数据失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取数据失败: "+err.Error()))
+		return
 	}
-	if pageInfo.PageSize <= 0 {
-		pageInfo.PageSize = 10
+
+	books := make([]model.Book, 0, len(result.List))
+	for _, hit := range result.List {
+		books = append(books, hit.Book)
 	}
 
-	var books []model.Book
-	var total int64
-	db := global.GVA_DB.Model(&model.Book{})
+	c.JSON(200, response.OkWithDetailed(response.PageResult{
+		List:     books,
+		Total:    result.Total,
+		Page:     result.Page,
+		PageSize: result.PageSize,
+	}, "获取成功"))
+}
 
-	// 搜索功能
-	if pageInfo.Keyword != "" {
-		keyword := "%" + strings.TrimSpace(pageInfo.Keyword) + "%"
-		db = db.Where("title LIKE ? OR author LIKE ? OR publisher LIKE ? OR isbn LIKE ?",
-			keyword, keyword, keyword, keyword)
+// SearchBooks 搜索图书：相关度排序、分类/出版社/年份/可借/价格筛选、分面统计和高亮
+func (b *BookApi) SearchBooks(c *gin.Context) {
+	var q model.SearchQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
 	}
 
-	// 获取总数
-	if err := db.Count(&total).Error; err != nil {
-		global.GVA_LOG.Error("获取数据失败", zap.Error(err))
-		c.JSON(200, response.FailWithMessage("获取数据失败: "+err.Error()))
+	result, err := service.GlobalSearchService.Search(q)
+	if err != nil {
+		if errors.Is(err, service.ErrSearchQuery) {
+			c.JSON(200, response.FailWithMessage(err.Error()))
+			return
+		}
+		global.GVA_LOG.Error("搜索图书失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("搜索失败"))
 		return
 	}
 
-	// 分页查询，预加载分类
-	offset := (pageInfo.Page - 1) * pageInfo.PageSize
-	if err := db.Preload("Categories").Order("created_at DESC").Limit(pageInfo.PageSize).Offset(offset).Find(&books).Error; err != nil {
-		global.GVA_LOG.Error("获取数据失败", zap.Error(err))
-		c.JSON(200, response.FailWithMessage("获取数据失败: "+err.Error()))
+	c.JSON(200, response.OkWithDetailed(result, "获取成功"))
+}
+
+// RebuildSearchIndex 重建图书搜索索引
+func (b *BookApi) RebuildSearchIndex(c *gin.Context) {
+	if err := service.GlobalSearchService.Rebuild(); err != nil {
+		global.GVA_LOG.Error("重建搜索索引失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("重建失败: "+err.Error()))
 		return
 	}
 
-	c.JSON(200, response.OkWithDetailed(response.PageResult{
-		List:     books,
-		Total:    total,
-		Page:     pageInfo.Page,
-		PageSize: pageInfo.PageSize,
-	}, "获取成功"))
+	c.JSON(200, response.OkWithMessage("重建成功"))
 }
 
 // DeleteBookById 通过ID删除图书（URL参数方式）
@@ -288,7 +308,7 @@ func (b *BookApi) DeleteBookById(c *gin.Context) {
 		return
 	}
 
-	if err := global.GVA_DB.Delete(&model.Book{}, id).Error; err != nil {
+	if err := deleteBookWithAudit(c, uint(id)); err != nil {
 		global.GVA_LOG.Error("删除失败", zap.Error(err))
 		c.JSON(200, response.FailWithMessage("删除失败: "+err.Error()))
 		return
@@ -296,3 +316,23 @@ func (b *BookApi) DeleteBookById(c *gin.Context) {
 
 	c.JSON(200, response.OkWithMessage("删除成功"))
 }
+
+// deleteBookWithAudit 删除图书并记录审计日志
+func deleteBookWithAudit(c *gin.Context, id uint) error {
+	var book model.Book
+	if err := global.GVA_DB.First(&book, id).Error; err != nil {
+		return errors.New("图书不存在")
+	}
+
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&book).Error; err != nil {
+			return err
+		}
+		return service.GlobalAuditService.Record(tx, getAuditActor(c), model.AuditBookDelete, "book", book.ID, book, nil)
+	})
+	if err != nil {
+		return err
+	}
+	service.GlobalSearchService.RemoveBooks(book.ID)
+	return nil
+}
@@ -0,0 +1,190 @@
+package v1
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"bookadmin/model/common/response"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type BookCopyApi struct{}
+
+// GetCopyList 获取图书的馆藏副本列表
+func (b *BookCopyApi) GetCopyList(c *gin.Context) {
+	bookID, err := strconv.ParseUint(c.Query("book_id"), 10, 32)
+	if err != nil || bookID == 0 {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	var copies []model.BookCopy
+	db := global.GVA_DB.Model(&model.BookCopy{}).Where("book_id = ?", bookID)
+
+	// 状态筛选
+	status := c.Query("status")
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+
+	if err := db.Order("id ASC").Find(&copies).Error; err != nil {
+		global.GVA_LOG.Error("获取副本列表失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取副本列表失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(gin.H{
+		"list":  copies,
+		"total": len(copies),
+	}))
+}
+
+// GetCopyByBarcode 根据条码查询副本
+func (b *BookCopyApi) GetCopyByBarcode(c *gin.Context) {
+	barcode := c.Query("barcode")
+	if barcode == "" {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	bookCopy, err := bookCopyService.GetCopyByBarcode(barcode)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	var book model.Book
+	if err := global.GVA_DB.First(&book, bookCopy.BookID).Error; err == nil {
+		bookCopy.Book = &book
+	}
+
+	c.JSON(200, response.OkWithData(bookCopy))
+}
+
+// AddCopies 新增馆藏副本
+func (b *BookCopyApi) AddCopies(c *gin.Context) {
+	var req struct {
+		BookID          uint     `json:"book_id" binding:"required"`
+		Count           int      `json:"count"`    // 自动生成条码的副本数量
+		Barcodes        []string `json:"barcodes"` // 指定条码（与count二选一）
+		ShelfLocation   string   `json:"shelf_location"`
+		Condition       string   `json:"condition"`
+		AcquisitionDate string   `json:"acquisition_date"` // 格式：2024-01-01，为空表示当天
+		Remark          string   `json:"remark"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	var acquisitionDate *time.Time
+	if req.AcquisitionDate != "" {
+		parsedDate, err := time.Parse("2006-01-02", req.AcquisitionDate)
+		if err != nil {
+			c.JSON(200, response.FailWithMessage("日期格式错误"))
+			return
+		}
+		acquisitionDate = &parsedDate
+	}
+
+	count := req.Count
+	if len(req.Barcodes) > 0 {
+		count = len(req.Barcodes)
+	}
+	if count <= 0 || count > 500 {
+		c.JSON(200, response.FailWithMessage("副本数量应在1-500之间"))
+		return
+	}
+
+	copies := make([]model.BookCopy, count)
+	for i := range copies {
+		if i < len(req.Barcodes) {
+			copies[i].Barcode = req.Barcodes[i]
+		}
+		copies[i].ShelfLocation = req.ShelfLocation
+		copies[i].Condition = model.BookCopyCondition(req.Condition)
+		copies[i].AcquisitionDate = acquisitionDate
+		copies[i].Remark = req.Remark
+	}
+
+	created, err := bookCopyService.AddCopies(req.BookID, copies, getAuditActor(c))
+	if err != nil {
+		global.GVA_LOG.Error("新增副本失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(gin.H{
+		"list":  created,
+		"total": len(created),
+	}, "新增成功"))
+}
+
+// RetireCopy 剔旧副本
+func (b *BookCopyApi) RetireCopy(c *gin.Context) {
+	copyID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	var req struct {
+		Remark string `json:"remark"`
+	}
+	_ = c.ShouldBindJSON(&req)
+
+	if err := bookCopyService.RetireCopy(uint(copyID), req.Remark, getAuditActor(c)); err != nil {
+		global.GVA_LOG.Error("剔旧副本失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithMessage("剔旧成功"))
+}
+
+// RepairCopy 修补完成的副本重新上架
+func (b *BookCopyApi) RepairCopy(c *gin.Context) {
+	copyID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	var req struct {
+		Remark string `json:"remark"`
+	}
+	_ = c.ShouldBindJSON(&req)
+
+	if err := bookCopyService.RepairCopy(uint(copyID), req.Remark, getAuditActor(c)); err != nil {
+		global.GVA_LOG.Error("副本上架失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithMessage("上架成功"))
+}
+
+// RelocateCopy 调整副本架位
+func (b *BookCopyApi) RelocateCopy(c *gin.Context) {
+	var req struct {
+		ID            uint   `json:"id" binding:"required"`
+		ShelfLocation string `json:"shelf_location" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	if err := bookCopyService.RelocateCopy(req.ID, req.ShelfLocation, getAuditActor(c)); err != nil {
+		global.GVA_LOG.Error("调整架位失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithMessage("调整成功"))
+}
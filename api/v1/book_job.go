@@ -0,0 +1,199 @@
+package v1
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"bookadmin/model/common/response"
+	"bookadmin/service"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type BookJobApi struct{}
+
+// ImportBooks 上传文件并创建后台导入任务
+// 表单字段：file（必填）、format（可选，默认按扩展名判断）、update_existing（默认true）、create_categories（默认false）、shelf_location
+func (a *BookJobApi) ImportBooks(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("请上传文件"))
+		return
+	}
+
+	format := model.BookFileFormat(c.PostForm("format"))
+	if format == "" {
+		if format, err = service.DetectBookFileFormat(file.Filename); err != nil {
+			c.JSON(200, response.FailWithMessage(err.Error()))
+			return
+		}
+	}
+	if !isValidBookFileFormat(format) {
+		c.JSON(200, response.FailWithMessage("不支持的文件格式"))
+		return
+	}
+
+	updateExisting, _ := strconv.ParseBool(c.DefaultPostForm("update_existing", "true"))
+	createCategories, _ := strconv.ParseBool(c.DefaultPostForm("create_categories", "false"))
+	opts := service.BookImportOptions{
+		UpdateExisting:   updateExisting,
+		CreateCategories: createCategories,
+		ShelfLocation:    c.PostForm("shelf_location"),
+	}
+
+	job, err := service.GlobalBookJobService.CreateImportJob(file, format, opts, getAuditActor(c))
+	if err != nil {
+		global.GVA_LOG.Error("创建导入任务失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(job, "导入任务已提交"))
+}
+
+// ExportBooks 创建后台导出任务
+func (a *BookJobApi) ExportBooks(c *gin.Context) {
+	var req struct {
+		Format     string `json:"format" binding:"required"` // csv/xlsx/marc/marcxml
+		CategoryID uint   `json:"category_id"`
+		Keyword    string `json:"keyword"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	format := model.BookFileFormat(req.Format)
+	if !isValidBookFileFormat(format) {
+		c.JSON(200, response.FailWithMessage("不支持的文件格式"))
+		return
+	}
+
+	job, err := service.GlobalBookJobService.CreateExportJob(format, service.BookExportOptions{
+		CategoryID: req.CategoryID,
+		Keyword:    req.Keyword,
+	}, getAuditActor(c))
+	if err != nil {
+		global.GVA_LOG.Error("创建导出任务失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(job, "导出任务已提交"))
+}
+
+// GetBookJobList 分页获取导入导出任务
+func (a *BookJobApi) GetBookJobList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	jobs, total, err := service.GlobalBookJobService.GetJobList(model.BookJobType(c.Query("job_type")), page, pageSize)
+	if err != nil {
+		global.GVA_LOG.Error("获取任务列表失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取数据失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(response.PageResult{
+		List:     jobs,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, "获取成功"))
+}
+
+// GetBookJob 获取任务详情（含逐行错误）
+func (a *BookJobApi) GetBookJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Query("id"), 10, 32)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	job, err := service.GlobalBookJobService.GetJob(uint(id))
+	if err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	rowErrors := service.GlobalBookJobService.GetJobRowErrors(job)
+	job.ErrorReport = ""
+	c.JSON(200, response.OkWithData(gin.H{
+		"job":    job,
+		"errors": rowErrors,
+	}))
+}
+
+// DownloadErrorReport 下载导入任务的逐行错误报告CSV
+func (a *BookJobApi) DownloadErrorReport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Query("id"), 10, 32)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	job, err := service.GlobalBookJobService.GetJob(uint(id))
+	if err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=import_%d_errors.csv", job.ID))
+
+	// 写入BOM，保证Excel正确识别UTF-8
+	_, _ = c.Writer.Write([]byte("\xEF\xBB\xBF"))
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"行号", "ISBN", "错误"})
+	for _, e := range service.GlobalBookJobService.GetJobRowErrors(job) {
+		_ = w.Write([]string{strconv.Itoa(e.Row), e.ISBN, e.Message})
+	}
+	w.Flush()
+}
+
+// DownloadExport 下载导出文件
+func (a *BookJobApi) DownloadExport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Query("id"), 10, 32)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	job, err := service.GlobalBookJobService.GetExportFile(uint(id))
+	if err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.FileAttachment(job.FilePath, job.FileName)
+}
+
+// GetImportTemplate 下载CSV导入模板
+func (a *BookJobApi) GetImportTemplate(c *gin.Context) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename=book_import_template.csv")
+
+	_, _ = c.Writer.Write([]byte("\xEF\xBB\xBF"))
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"ISBN", "书名", "作者", "出版社", "出版日期", "价格", "分类", "描述", "封面图片", "副本数", "架位"})
+	_ = w.Write([]string{"978-7-111-54742-6", "Go程序设计语言", "Alan A. A. Donovan", "机械工业出版社", "2016-01-01", "79.00", "计算机;编程语言", "", "", "3", "A-01-02"})
+	w.Flush()
+}
+
+// isValidBookFileFormat 判断文件格式是否支持
+func isValidBookFileFormat(format model.BookFileFormat) bool {
+	switch format {
+	case model.BookFormatCSV, model.BookFormatXLSX, model.BookFormatMARC, model.BookFormatMARCXML:
+		return true
+	}
+	return false
+}
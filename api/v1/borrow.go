@@ -40,8 +40,14 @@ func (b *BorrowApi) BorrowBook(c *gin.Context) {
 	// 确定用户ID：如果req.ReaderID为空，使用当前用户ID
 	// 注意：这里传递的是UserID，服务层会根据UserID查找或创建Reader记录
 	var targetUserID uint
-	if req.ReaderID != nil && *req.ReaderID > 0 {
-		// 管理员为其他读者借书（这里的ReaderID实际上是UserID）
+	if req.ReaderID != nil && *req.ReaderID > 0 && *req.ReaderID != userID {
+		// 为其他读者借书需要代借权限（这里的ReaderID实际上是UserID）
+		role, _ := c.Get("role")
+		userRole, _ := role.(model.UserRole)
+		if !service.GlobalPermissionService.HasPermission(userRole, model.PermBorrowOnBehalf) {
+			c.JSON(200, response.FailWithMessage("权限不足"))
+			return
+		}
 		targetUserID = *req.ReaderID
 	} else {
 		// 普通用户为自己借书
@@ -363,12 +369,15 @@ func (b *BorrowApi) GetBorrowStatistics(c *gin.Context) {
 	var reader model.Reader
 	if err := global.GVA_DB.Where("user_id = ?", userID).First(&reader).Error; err != nil {
 		// 如果读者不存在，返回默认统计数据（新用户）
+		defaultPolicy := service.GlobalPolicyService.Resolve(model.DefaultPatronCategory, nil)
 		c.JSON(200, response.OkWithData(gin.H{
 			"borrowing_count":    0,
 			"overdue_count":      0,
 			"total_borrow_count": 0,
 			"reservation_count":  0,
-			"max_borrow":         5,
+			"patron_category":    model.DefaultPatronCategory,
+			"max_borrow":         defaultPolicy.MaxBorrow,
+			"max_reservations":   defaultPolicy.MaxReservations,
 			"unpaid_fine":        0,
 			"total_fine":         0,
 			"is_blacklisted":     false,
@@ -406,12 +415,16 @@ func (b *BorrowApi) GetBorrowStatistics(c *gin.Context) {
 		}).
 		Count(&reservationCount)
 
+	policy := service.GlobalPolicyService.ResolveForReader(&reader)
+
 	c.JSON(200, response.OkWithData(gin.H{
 		"borrowing_count":    borrowingCount,
 		"overdue_count":      overdueCount,
 		"total_borrow_count": totalBorrowCount,
 		"reservation_count":  reservationCount,
-		"max_borrow":         reader.MaxBorrow,
+		"patron_category":    reader.PatronCategory,
+		"max_borrow":         policy.MaxBorrow,
+		"max_reservations":   policy.MaxReservations,
 		"unpaid_fine":        reader.UnpaidFine,
 		"total_fine":         reader.TotalFine,
 		"is_blacklisted":     reader.IsBlacklisted,
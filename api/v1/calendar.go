@@ -0,0 +1,171 @@
+package v1
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"bookadmin/model/common/response"
+	"bookadmin/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type CalendarApi struct{}
+
+// GetCalendar 获取日期区间内的开馆情况（默认从今天起30天）
+func (a *CalendarApi) GetCalendar(c *gin.Context) {
+	start := time.Now()
+	if v := c.Query("start_date"); v != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.JSON(200, response.FailWithMessage("日期格式错误"))
+			return
+		}
+		start = parsed
+	}
+
+	end := start.AddDate(0, 0, 29)
+	if v := c.Query("end_date"); v != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.JSON(200, response.FailWithMessage("日期格式错误"))
+			return
+		}
+		end = parsed
+	}
+
+	if end.Before(start) {
+		c.JSON(200, response.FailWithMessage("结束日期不能早于开始日期"))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(service.GlobalCalendarService.GetCalendar(start, end)))
+}
+
+// GetOpeningHours 获取每周开放时间表
+func (a *CalendarApi) GetOpeningHours(c *gin.Context) {
+	hours, err := service.GlobalCalendarService.GetOpeningHours()
+	if err != nil {
+		global.GVA_LOG.Error("获取开放时间失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取数据失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(hours))
+}
+
+// UpdateOpeningHours 更新每周开放时间表
+func (a *CalendarApi) UpdateOpeningHours(c *gin.Context) {
+	var req struct {
+		Hours []struct {
+			Weekday   int    `json:"weekday"`
+			IsOpen    bool   `json:"is_open"`
+			OpenTime  string `json:"open_time"`
+			CloseTime string `json:"close_time"`
+		} `json:"hours" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	hours := make([]model.OpeningHours, 0, len(req.Hours))
+	for _, h := range req.Hours {
+		hours = append(hours, model.OpeningHours{
+			Weekday:   h.Weekday,
+			IsOpen:    h.IsOpen,
+			OpenTime:  h.OpenTime,
+			CloseTime: h.CloseTime,
+		})
+	}
+
+	if err := service.GlobalCalendarService.UpdateOpeningHours(hours, getAuditActor(c)); err != nil {
+		global.GVA_LOG.Error("更新开放时间失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithMessage("更新成功"))
+}
+
+// GetExceptions 获取节假日闭馆/调休开馆列表
+func (a *CalendarApi) GetExceptions(c *gin.Context) {
+	year, _ := strconv.Atoi(c.Query("year"))
+
+	exceptions, err := service.GlobalCalendarService.GetExceptions(year)
+	if err != nil {
+		global.GVA_LOG.Error("获取日历例外失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取数据失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(exceptions))
+}
+
+// CreateException 新增节假日闭馆或调休开馆
+func (a *CalendarApi) CreateException(c *gin.Context) {
+	var req struct {
+		StartDate string `json:"start_date" binding:"required"` // 格式：2024-01-01
+		EndDate   string `json:"end_date"`                      // 为空表示与开始日期相同
+		IsOpen    bool   `json:"is_open"`
+		OpenTime  string `json:"open_time"`
+		CloseTime string `json:"close_time"`
+		Reason    string `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	startDate, err := time.ParseInLocation("2006-01-02", req.StartDate, time.Local)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("日期格式错误"))
+		return
+	}
+	endDate := startDate
+	if req.EndDate != "" {
+		endDate, err = time.ParseInLocation("2006-01-02", req.EndDate, time.Local)
+		if err != nil {
+			c.JSON(200, response.FailWithMessage("日期格式错误"))
+			return
+		}
+	}
+
+	exception := model.CalendarException{
+		StartDate: startDate,
+		EndDate:   endDate,
+		IsOpen:    req.IsOpen,
+		OpenTime:  req.OpenTime,
+		CloseTime: req.CloseTime,
+		Reason:    req.Reason,
+	}
+
+	if err := service.GlobalCalendarService.CreateException(&exception, getAuditActor(c)); err != nil {
+		global.GVA_LOG.Error("新增日历例外失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(exception, "新增成功"))
+}
+
+// DeleteException 删除日历例外
+func (a *CalendarApi) DeleteException(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	if err := service.GlobalCalendarService.DeleteException(uint(id), getAuditActor(c)); err != nil {
+		global.GVA_LOG.Error("删除日历例外失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithMessage("删除成功"))
+}
@@ -0,0 +1,79 @@
+package v1
+
+import (
+	"bookadmin/global"
+	"bookadmin/model/common/response"
+	"bookadmin/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type DeskApi struct{}
+
+var deskService = service.NewDeskService()
+
+// Checkout 流通台扫码借书
+func (d *DeskApi) Checkout(c *gin.Context) {
+	var req struct {
+		ReaderNo string `json:"reader_no" binding:"required"` // 读者证号
+		Barcode  string `json:"barcode" binding:"required"`   // 副本条码
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	// 获取操作员ID
+	operatorID, _ := c.Get("user_id")
+	var opID uint
+	if operatorID != nil {
+		opID = operatorID.(uint)
+	}
+
+	receipt, err := deskService.Checkout(req.ReaderNo, req.Barcode, opID)
+	if err != nil {
+		global.GVA_LOG.Error("流通台借书失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(receipt, "借书成功"))
+}
+
+// Checkin 流通台扫码还书
+func (d *DeskApi) Checkin(c *gin.Context) {
+	var req struct {
+		Barcode string `json:"barcode" binding:"required"` // 副本条码
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	// 获取操作员ID
+	operatorID, _ := c.Get("user_id")
+	var opID uint
+	if operatorID != nil {
+		opID = operatorID.(uint)
+	}
+
+	receipt, err := deskService.Checkin(req.Barcode, opID)
+	if err != nil {
+		global.GVA_LOG.Error("流通台还书失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	msg := "还书成功"
+	if receipt.FineAmount > 0 {
+		msg = "还书成功，产生逾期费用"
+	}
+	if len(receipt.PendingHolds) > 0 {
+		msg += "，该书有预约，请放入预约架"
+	}
+
+	c.JSON(200, response.OkWithDetailed(receipt, msg))
+}
@@ -6,6 +6,7 @@ import (
 	"bookadmin/model/common/request"
 	"bookadmin/model/common/response"
 	"bookadmin/service"
+	"fmt"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -42,11 +43,61 @@ func (f *FineApi) GetMyFines(c *gin.Context) {
 	}
 
 	c.JSON(200, response.OkWithData(gin.H{
-		"list":         fines,
-		"total_unpaid": reader.UnpaidFine,
+		"list":          fines,
+		"total_unpaid":  reader.UnpaidFine,
+		"credit_amount": reader.FineCredit,
 	}))
 }
 
+// CreatePayment 在线支付我的罚款，fine_ids 为空时支付全部未结清的罚款
+func (f *FineApi) CreatePayment(c *gin.Context) {
+	var req struct {
+		FineIDs []uint `json:"fine_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	order, err := service.GlobalPaymentService.CreateOrder(c.GetUint("user_id"), req.FineIDs)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(order, "下单成功，请在有效期内完成支付"))
+}
+
+// GetMyPayments 我的支付订单
+func (f *FineApi) GetMyPayments(c *gin.Context) {
+	var reader model.Reader
+	if err := global.GVA_DB.Where("user_id = ?", c.GetUint("user_id")).First(&reader).Error; err != nil {
+		c.JSON(200, response.FailWithMessage("读者信息不存在"))
+		return
+	}
+
+	q := parsePaymentQuery(c)
+	q.ReaderID = reader.ID
+	paymentPage(c, q)
+}
+
+// GetPayment 查询我的支付订单，待支付的订单会向支付渠道确认最新状态
+func (f *FineApi) GetPayment(c *gin.Context) {
+	orderNo := c.Query("order_no")
+	if orderNo == "" {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	order, err := service.GlobalPaymentService.GetReaderOrder(c.GetUint("user_id"), orderNo)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(order))
+}
+
 // GetFineList 获取罚款列表（管理员）
 func (f *FineApi) GetFineList(c *gin.Context) {
 	var pageInfo request.PageInfo
@@ -104,11 +155,12 @@ func (f *FineApi) GetFineList(c *gin.Context) {
 	}, "获取成功"))
 }
 
-// PayFine 支付罚款
+// PayFine 现场收取罚款（图书管理员录入读者线下支付的金额）
 func (f *FineApi) PayFine(c *gin.Context) {
 	var req struct {
 		FineID     uint    `json:"fine_id" binding:"required"`
 		PaidAmount float64 `json:"paid_amount" binding:"required"`
+		RequestID  string  `json:"request_id" binding:"max=40"` // 收款单号，重复提交时只收取一次
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -124,13 +176,18 @@ func (f *FineApi) PayFine(c *gin.Context) {
 	}
 
 	// 支付罚款
-	if err := fineService.PayFine(req.FineID, req.PaidAmount, opID); err != nil {
+	credit, err := fineService.PayFine(req.FineID, req.PaidAmount, req.RequestID, opID)
+	if err != nil {
 		global.GVA_LOG.Error("支付罚款失败", zap.Error(err))
 		c.JSON(200, response.FailWithMessage(err.Error()))
 		return
 	}
 
-	c.JSON(200, response.OkWithMessage("支付成功"))
+	msg := "支付成功"
+	if credit > 0 {
+		msg = fmt.Sprintf("支付成功，读者预存余额 %.2f 元，将自动冲抵以后的罚款", credit)
+	}
+	c.JSON(200, response.OkWithDetailed(gin.H{"credit_amount": credit}, msg))
 }
 
 // WaiveFine 豁免罚款（管理员）
@@ -147,15 +204,8 @@ func (f *FineApi) WaiveFine(c *gin.Context) {
 	}
 	_ = c.ShouldBindJSON(&req)
 
-	// 获取操作员ID
-	operatorID, _ := c.Get("user_id")
-	var opID uint
-	if operatorID != nil {
-		opID = operatorID.(uint)
-	}
-
 	// 豁免罚款
-	if err := fineService.WaiveFine(uint(fineID), opID, req.Remark); err != nil {
+	if err := fineService.WaiveFine(uint(fineID), getAuditActor(c), req.Remark); err != nil {
 		global.GVA_LOG.Error("豁免罚款失败", zap.Error(err))
 		c.JSON(200, response.FailWithMessage(err.Error()))
 		return
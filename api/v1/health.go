@@ -0,0 +1,32 @@
+package v1
+
+import (
+	"bookadmin/global"
+	"bookadmin/model/common/response"
+	"bookadmin/service"
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthApi struct{}
+
+// GetHealth 服务健康状态，包含MySQL连接状态和点赞/收藏当前运行模式
+func (a *HealthApi) GetHealth(c *gin.Context) {
+	mysqlStatus := "up"
+	if sqlDB, err := global.GVA_DB.DB(); err != nil {
+		mysqlStatus = "down"
+	} else {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+		if err := sqlDB.PingContext(ctx); err != nil {
+			mysqlStatus = "down"
+		}
+		cancel()
+	}
+
+	c.JSON(200, response.OkWithData(gin.H{
+		"mysql": mysqlStatus,
+		"redis": service.GlobalRedisHealth.Status(),
+	}))
+}
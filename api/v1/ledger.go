@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"bookadmin/model/common/response"
+	"bookadmin/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type LedgerApi struct{}
+
+// GetMyLedger 我的罚款账务：余额和账务明细
+func (a *LedgerApi) GetMyLedger(c *gin.Context) {
+	var reader model.Reader
+	if err := global.GVA_DB.Where("user_id = ?", c.GetUint("user_id")).First(&reader).Error; err != nil {
+		c.JSON(200, response.FailWithMessage("读者信息不存在"))
+		return
+	}
+
+	q := parseLedgerQuery(c)
+	q.ReaderID = reader.ID
+	ledgerPage(c, q)
+}
+
+// GetReaderLedger 读者的罚款账务（管理员），不指定读者时查询全部账务
+func (a *LedgerApi) GetReaderLedger(c *gin.Context) {
+	q := parseLedgerQuery(c)
+	readerID, _ := strconv.ParseUint(c.Query("reader_id"), 10, 32)
+	q.ReaderID = uint(readerID)
+	ledgerPage(c, q)
+}
+
+// GetReconcileReport 对账报告（只检查不修正）
+func (a *LedgerApi) GetReconcileReport(c *gin.Context) {
+	report, err := service.GlobalLedgerService.Reconcile(false, getAuditActor(c))
+	if err != nil {
+		global.GVA_LOG.Error("罚款对账失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("对账失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(report))
+}
+
+// FixReconcile 对账并按账务修正读者的缓存余额
+func (a *LedgerApi) FixReconcile(c *gin.Context) {
+	report, err := service.GlobalLedgerService.Reconcile(true, getAuditActor(c))
+	if err != nil {
+		global.GVA_LOG.Error("罚款对账修正失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("对账修正失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(report, "修正完成"))
+}
+
+// Adjust 人工调整读者的罚款或预存余额
+func (a *LedgerApi) Adjust(c *gin.Context) {
+	var req struct {
+		ReaderID  uint    `json:"reader_id" binding:"required"`
+		FineID    *uint   `json:"fine_id"`
+		Amount    float64 `json:"amount" binding:"required"`
+		Memo      string  `json:"memo" binding:"required,max=255"`
+		RequestID string  `json:"request_id" binding:"max=40"` // 请求编号，重复提交时只调整一次
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	txn, err := service.GlobalLedgerService.Adjust(req.ReaderID, req.FineID, req.Amount, req.Memo, req.RequestID, getAuditActor(c))
+	if err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(txn, "调整成功"))
+}
+
+// parseLedgerQuery 解析账务明细的分页和筛选参数
+func parseLedgerQuery(c *gin.Context) service.LedgerQuery {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+	fineID, _ := strconv.ParseUint(c.Query("fine_id"), 10, 32)
+
+	return service.LedgerQuery{
+		FineID:   uint(fineID),
+		Type:     model.LedgerType(c.Query("type")),
+		Page:     page,
+		PageSize: pageSize,
+	}
+}
+
+// ledgerPage 查询并返回读者余额和账务明细分页结果，未指定读者时只返回明细
+func ledgerPage(c *gin.Context, q service.LedgerQuery) {
+	list, total, err := service.GlobalLedgerService.ListTransactions(q)
+	if err != nil {
+		global.GVA_LOG.Error("获取罚款账务失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取数据失败"))
+		return
+	}
+
+	result := gin.H{
+		"list":     list,
+		"total":    total,
+		"page":     q.Page,
+		"pageSize": q.PageSize,
+	}
+	if q.ReaderID > 0 {
+		balance, err := service.GlobalLedgerService.Balance(global.GVA_DB, q.ReaderID)
+		if err != nil {
+			global.GVA_LOG.Error("获取罚款余额失败", zap.Error(err))
+			c.JSON(200, response.FailWithMessage("获取数据失败"))
+			return
+		}
+		result["balance"] = balance
+	}
+
+	c.JSON(200, response.OkWithDetailed(result, "获取成功"))
+}
@@ -0,0 +1,137 @@
+package v1
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"bookadmin/model/common/response"
+	"bookadmin/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type LoanPolicyApi struct{}
+
+// GetPatronCategories 获取读者类型列表
+func (a *LoanPolicyApi) GetPatronCategories(c *gin.Context) {
+	categories := make([]gin.H, 0, len(model.PatronCategoryNames))
+	for _, code := range []model.PatronCategory{model.PatronStudent, model.PatronStaff, model.PatronFaculty, model.PatronGuest} {
+		categories = append(categories, gin.H{
+			"code":       code,
+			"name":       model.PatronCategoryNames[code],
+			"is_default": code == model.DefaultPatronCategory,
+		})
+	}
+
+	c.JSON(200, response.OkWithData(categories))
+}
+
+// GetPolicyList 获取借阅规则列表（可按读者类型筛选）
+func (a *LoanPolicyApi) GetPolicyList(c *gin.Context) {
+	policies, err := service.GlobalPolicyService.GetPolicies(model.PatronCategory(c.Query("patron_category")))
+	if err != nil {
+		global.GVA_LOG.Error("获取借阅规则失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取数据失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(policies))
+}
+
+// SavePolicy 保存借阅规则
+// category_id 为0时修改读者类型的基础规则，否则新增或修改该分类的覆盖规则，留空(null)的字段沿用基础规则
+func (a *LoanPolicyApi) SavePolicy(c *gin.Context) {
+	var req struct {
+		PatronCategory  string   `json:"patron_category" binding:"required"`
+		CategoryID      uint     `json:"category_id"`
+		MaxBorrow       *int     `json:"max_borrow"`
+		BorrowDays      *int     `json:"borrow_days"`
+		MaxRenew        *int     `json:"max_renew"`
+		RenewDays       *int     `json:"renew_days"`
+		MaxReservations *int     `json:"max_reservations"`
+		PickupDays      *int     `json:"pickup_days"`
+		FinePerDay      *float64 `json:"fine_per_day"`
+		MaxFineRate     *float64 `json:"max_fine_rate"`
+		Remark          string   `json:"remark"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	policy := model.LoanPolicy{
+		PatronCategory:  model.PatronCategory(req.PatronCategory),
+		CategoryID:      req.CategoryID,
+		MaxBorrow:       req.MaxBorrow,
+		BorrowDays:      req.BorrowDays,
+		MaxRenew:        req.MaxRenew,
+		RenewDays:       req.RenewDays,
+		MaxReservations: req.MaxReservations,
+		PickupDays:      req.PickupDays,
+		FinePerDay:      req.FinePerDay,
+		MaxFineRate:     req.MaxFineRate,
+		Remark:          req.Remark,
+	}
+
+	if err := service.GlobalPolicyService.SavePolicy(&policy, getAuditActor(c)); err != nil {
+		global.GVA_LOG.Error("保存借阅规则失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(policy, "保存成功"))
+}
+
+// DeletePolicy 删除图书分类覆盖规则
+func (a *LoanPolicyApi) DeletePolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	if err := service.GlobalPolicyService.DeletePolicy(uint(id), getAuditActor(c)); err != nil {
+		global.GVA_LOG.Error("删除借阅规则失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithMessage("删除成功"))
+}
+
+// ResolvePolicy 查看读者借阅某本书时最终生效的规则（book_id 为空时只返回基础规则）
+func (a *LoanPolicyApi) ResolvePolicy(c *gin.Context) {
+	readerID, err := strconv.ParseUint(c.Query("reader_id"), 10, 32)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	var reader model.Reader
+	if err := global.GVA_DB.First(&reader, readerID).Error; err != nil {
+		c.JSON(200, response.FailWithMessage("读者不存在"))
+		return
+	}
+
+	if c.Query("book_id") == "" {
+		c.JSON(200, response.OkWithData(service.GlobalPolicyService.ResolveForReader(&reader)))
+		return
+	}
+
+	bookID, err := strconv.ParseUint(c.Query("book_id"), 10, 32)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	policy, err := service.GlobalPolicyService.ResolveForBook(global.GVA_DB, &reader, uint(bookID))
+	if err != nil {
+		global.GVA_LOG.Error("获取借阅规则失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取借阅规则失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(policy))
+}
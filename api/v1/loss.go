@@ -0,0 +1,135 @@
+package v1
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"bookadmin/model/common/response"
+	"bookadmin/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type LossApi struct{}
+
+// Assess 预览赔偿金额
+func (a *LossApi) Assess(c *gin.Context) {
+	recordID, err := strconv.ParseUint(c.Query("record_id"), 10, 32)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	assessment, err := service.GlobalLossService.Assess(uint(recordID), model.LossType(c.Query("type")), model.DamageLevel(c.Query("level")))
+	if err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(assessment))
+}
+
+// DeclareLost 登记图书丢失
+func (a *LossApi) DeclareLost(c *gin.Context) {
+	var req struct {
+		RecordID uint   `json:"record_id" binding:"required"`
+		Remark   string `json:"remark"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	report, err := service.GlobalLossService.DeclareLost(req.RecordID, req.Remark, getAuditActor(c))
+	if err != nil {
+		global.GVA_LOG.Error("登记图书丢失失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(report, "登记成功"))
+}
+
+// ReturnDamaged 损坏还书
+func (a *LossApi) ReturnDamaged(c *gin.Context) {
+	var req struct {
+		RecordID uint              `json:"record_id" binding:"required"`
+		Level    model.DamageLevel `json:"level" binding:"required"`
+		Remark   string            `json:"remark"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	report, err := service.GlobalLossService.ReturnDamaged(req.RecordID, req.Level, req.Remark, getAuditActor(c))
+	if err != nil {
+		global.GVA_LOG.Error("损坏还书失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(report, "还书成功"))
+}
+
+// Found 丢失的图书找回
+func (a *LossApi) Found(c *gin.Context) {
+	reportID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	// refund_to 为 cash 时已付的赔偿现场退还，默认存入读者预存余额
+	var req struct {
+		RefundTo string `json:"refund_to"`
+	}
+	_ = c.ShouldBindJSON(&req)
+	if req.RefundTo != "" && req.RefundTo != "credit" && req.RefundTo != "cash" {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	report, err := service.GlobalLossService.FoundLost(uint(reportID), req.RefundTo == "cash", getAuditActor(c))
+	if err != nil {
+		global.GVA_LOG.Error("登记图书找回失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(report, "登记成功"))
+}
+
+// GetLossList 赔偿记录列表
+func (a *LossApi) GetLossList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+	readerID, _ := strconv.ParseUint(c.Query("reader_id"), 10, 32)
+
+	list, total, err := service.GlobalLossService.ListReports(service.LossQuery{
+		ReaderID: uint(readerID),
+		Type:     model.LossType(c.Query("type")),
+		Status:   model.LossStatus(c.Query("status")),
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		global.GVA_LOG.Error("获取赔偿记录失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取数据失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, "获取成功"))
+}
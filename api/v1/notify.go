@@ -0,0 +1,224 @@
+package v1
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"bookadmin/model/common/response"
+	"bookadmin/service"
+	"bookadmin/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// notifyHeartbeat 推送连接的心跳间隔，防止代理因空闲断开连接
+const notifyHeartbeat = 25 * time.Second
+
+type NotifyApi struct{}
+
+// Stream 实时通知推送（Server-Sent Events）
+// 连接建立后先推送当前未读消息数；访问令牌过期时服务端断开，客户端刷新令牌后重连
+func (a *NotifyApi) Stream(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	client, unsubscribe := service.GlobalNotifyService.Subscribe(userID)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭Nginx缓冲
+	c.Status(http.StatusOK)
+
+	// 断线后浏览器3秒后自动重连
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+
+	count, err := messageService.GetUnreadCount(userID)
+	if err != nil {
+		global.GVA_LOG.Error("获取未读消息数量失败", zap.Error(err))
+	}
+	if !writeNotification(c, model.Notification{
+		UserID:    userID,
+		Event:     model.NotifyUnreadCount,
+		Data:      gin.H{"count": count},
+		CreatedAt: time.Now(),
+	}) {
+		return
+	}
+
+	expire := time.NewTimer(time.Hour)
+	defer expire.Stop()
+	if value, ok := c.Get("claims"); ok {
+		if claims, ok := value.(*utils.Claims); ok && claims.ExpiresAt != nil {
+			expire.Reset(time.Until(claims.ExpiresAt.Time))
+		}
+	}
+
+	heartbeat := time.NewTicker(notifyHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-expire.C:
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case n := <-client.Events:
+			if !writeNotification(c, n) {
+				return
+			}
+		}
+	}
+}
+
+// writeNotification 以SSE格式写出一条事件，连接已断开时返回false
+func writeNotification(c *gin.Context, n model.Notification) bool {
+	data, err := json.Marshal(n.Data)
+	if err != nil {
+		global.GVA_LOG.Error("序列化通知失败", zap.String("event", string(n.Event)), zap.Error(err))
+		return true
+	}
+
+	if n.ID != "" {
+		if _, err := fmt.Fprintf(c.Writer, "id: %s\n", n.ID); err != nil {
+			return false
+		}
+	}
+	if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", n.Event, data); err != nil {
+		return false
+	}
+	c.Writer.Flush()
+	return true
+}
+
+// GetPreferences 我的通知偏好
+func (a *NotifyApi) GetPreferences(c *gin.Context) {
+	prefs, err := service.GlobalNotifyDispatchService.GetPreferences(c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(prefs))
+}
+
+// UpdatePreferences 修改我的通知偏好（各消息类型的邮件、短信开关和免打扰时段）
+func (a *NotifyApi) UpdatePreferences(c *gin.Context) {
+	var req struct {
+		Items      []model.NotifyPreferenceItem `json:"items"`
+		QuietStart string                       `json:"quiet_start"`
+		QuietEnd   string                       `json:"quiet_end"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if err := service.GlobalNotifyDispatchService.UpdatePreferences(userID, req.Items, req.QuietStart, req.QuietEnd); err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	prefs, err := service.GlobalNotifyDispatchService.GetPreferences(userID)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+	c.JSON(200, response.OkWithDetailed(prefs, "保存成功"))
+}
+
+// GetTemplates 消息模板列表
+func (a *NotifyApi) GetTemplates(c *gin.Context) {
+	templates, err := service.GlobalNotifyDispatchService.ListTemplates()
+	if err != nil {
+		global.GVA_LOG.Error("获取消息模板失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取数据失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(templates))
+}
+
+// UpdateTemplate 修改消息模板
+func (a *NotifyApi) UpdateTemplate(c *gin.Context) {
+	var req struct {
+		ID      uint   `json:"id" binding:"required"`
+		Subject string `json:"subject" binding:"max=255"`
+		Body    string `json:"body" binding:"required"`
+		Enabled bool   `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	tpl, err := service.GlobalNotifyDispatchService.UpdateTemplate(req.ID, req.Subject, req.Body, req.Enabled, getAuditActor(c))
+	if err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(tpl, "保存成功"))
+}
+
+// GetDeliveries 邮件、短信投递记录
+func (a *NotifyApi) GetDeliveries(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+	userID, _ := strconv.ParseUint(c.Query("user_id"), 10, 32)
+	messageID, _ := strconv.ParseUint(c.Query("message_id"), 10, 32)
+
+	q := service.DeliveryQuery{
+		Status:    model.DeliveryStatus(c.Query("status")),
+		Channel:   model.NotifyChannel(c.Query("channel")),
+		UserID:    uint(userID),
+		MessageID: uint(messageID),
+		Page:      page,
+		PageSize:  pageSize,
+	}
+	list, total, err := service.GlobalNotifyDispatchService.ListDeliveries(q)
+	if err != nil {
+		global.GVA_LOG.Error("获取投递记录失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取数据失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, "获取成功"))
+}
+
+// RetryDelivery 重新发送失败的投递
+func (a *NotifyApi) RetryDelivery(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	if err := service.GlobalNotifyDispatchService.RetryDelivery(uint(id)); err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithMessage("已加入发送队列"))
+}
@@ -0,0 +1,75 @@
+package v1
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"bookadmin/model/common/response"
+	"bookadmin/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type OutboxApi struct{}
+
+// GetStats 各状态的待执行事件数
+func (a *OutboxApi) GetStats(c *gin.Context) {
+	stats, err := service.GlobalOutboxService.Stats()
+	if err != nil {
+		global.GVA_LOG.Error("获取待执行事件统计失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取数据失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(stats))
+}
+
+// GetEventList 待执行事件列表
+func (a *OutboxApi) GetEventList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	q := service.OutboxQuery{
+		Status:   model.OutboxStatus(c.Query("status")),
+		Topic:    model.OutboxTopic(c.Query("topic")),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	list, total, err := service.GlobalOutboxService.ListEvents(q)
+	if err != nil {
+		global.GVA_LOG.Error("获取待执行事件失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取数据失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, "获取成功"))
+}
+
+// RetryEvent 重新执行失败的事件
+func (a *OutboxApi) RetryEvent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	if err := service.GlobalOutboxService.RetryEvent(uint(id), getAuditActor(c)); err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+	service.GlobalOutboxService.Wake()
+
+	c.JSON(200, response.OkWithMessage("已重新加入执行队列"))
+}
@@ -0,0 +1,113 @@
+package v1
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"bookadmin/model/common/response"
+	"bookadmin/service"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// paymentCallbackMaxBody 支付回调请求体大小上限
+const paymentCallbackMaxBody = 64 << 10
+
+type PaymentApi struct{}
+
+// Callback 支付渠道的支付结果回调，处理成功时返回 success，渠道收到其他应答会重复推送
+func (a *PaymentApi) Callback(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, paymentCallbackMaxBody))
+	if err != nil {
+		c.String(http.StatusBadRequest, "fail")
+		return
+	}
+
+	if err := service.GlobalPaymentService.HandleCallback(c.Param("provider"), c.Request.Header, body); err != nil {
+		c.String(http.StatusBadRequest, "fail")
+		return
+	}
+	c.String(http.StatusOK, service.PaymentCallbackAck)
+}
+
+// MockPay 模拟支付页面：直接完成支付（仅 mock 渠道）
+func (a *PaymentApi) MockPay(c *gin.Context) {
+	orderNo := c.Query("order_no")
+	if orderNo == "" {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	if err := service.GlobalPaymentService.MockPay(orderNo); err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithMessage("支付成功"))
+}
+
+// GetOrderList 支付订单列表
+func (a *PaymentApi) GetOrderList(c *gin.Context) {
+	q := parsePaymentQuery(c)
+	readerID, _ := strconv.ParseUint(c.Query("reader_id"), 10, 32)
+	q.ReaderID = uint(readerID)
+	paymentPage(c, q)
+}
+
+// Refund 支付订单全额退款，订单结清的罚款恢复为未支付
+func (a *PaymentApi) Refund(c *gin.Context) {
+	var req struct {
+		OrderNo string `json:"order_no" binding:"required"`
+		Reason  string `json:"reason" binding:"max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	if err := service.GlobalPaymentService.RefundOrder(req.OrderNo, req.Reason, getAuditActor(c)); err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithMessage("退款成功"))
+}
+
+// parsePaymentQuery 解析支付订单列表的分页和筛选参数
+func parsePaymentQuery(c *gin.Context) service.PaymentQuery {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	return service.PaymentQuery{
+		Status:   model.PaymentStatus(c.Query("status")),
+		OrderNo:  c.Query("order_no"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+}
+
+// paymentPage 查询并返回支付订单分页结果
+func paymentPage(c *gin.Context, q service.PaymentQuery) {
+	orders, total, err := service.GlobalPaymentService.ListOrders(q)
+	if err != nil {
+		global.GVA_LOG.Error("获取支付订单失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取数据失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(response.PageResult{
+		List:     orders,
+		Total:    total,
+		Page:     q.Page,
+		PageSize: q.PageSize,
+	}, "获取成功"))
+}
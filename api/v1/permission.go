@@ -0,0 +1,53 @@
+package v1
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"bookadmin/model/common/response"
+	"bookadmin/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type PermissionApi struct{}
+
+// GetPermissionList 获取系统定义的全部权限
+func (p *PermissionApi) GetPermissionList(c *gin.Context) {
+	c.JSON(200, response.OkWithData(model.AllPermissions))
+}
+
+// GetRolePermissions 获取角色的权限列表
+func (p *PermissionApi) GetRolePermissions(c *gin.Context) {
+	role := model.UserRole(c.Query("role"))
+	if role == "" {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(gin.H{
+		"role":        role,
+		"permissions": service.GlobalPermissionService.GetRolePermissions(role),
+	}))
+}
+
+// UpdateRolePermissions 覆盖设置角色的权限
+func (p *PermissionApi) UpdateRolePermissions(c *gin.Context) {
+	var req struct {
+		Role        model.UserRole     `json:"role" binding:"required"`
+		Permissions []model.Permission `json:"permissions"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	if err := service.GlobalPermissionService.SetRolePermissions(req.Role, req.Permissions, getAuditActor(c)); err != nil {
+		global.GVA_LOG.Error("更新角色权限失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithMessage("更新成功"))
+}
@@ -3,6 +3,7 @@ package v1
 import (
 	"bookadmin/model"
 	"bookadmin/service"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -37,9 +38,9 @@ func (api *RankingAPI) GetRanking(c *gin.Context) {
 	}
 
 	// 查询榜单
-	ranking, err := api.service.GetRanking(c.Request.Context(), req.Type, req.Period, req.Limit)
+	ranking, err := api.service.GetRanking(c.Request.Context(), req.Type, req.Period, req.PeriodKey, req.CategoryID, req.Limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "msg": "查询失败"})
+		rankingError(c, err)
 		return
 	}
 
@@ -58,11 +59,13 @@ func (api *RankingAPI) GetLikeWeekRanking(c *gin.Context) {
 		c.Request.Context(),
 		model.RankingTypeLike,
 		model.RankingPeriodWeek,
+		c.Query("period_key"),
+		0,
 		limit,
 	)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "msg": "查询失败"})
+		rankingError(c, err)
 		return
 	}
 
@@ -81,11 +84,13 @@ func (api *RankingAPI) GetLikeMonthRanking(c *gin.Context) {
 		c.Request.Context(),
 		model.RankingTypeLike,
 		model.RankingPeriodMonth,
+		c.Query("period_key"),
+		0,
 		limit,
 	)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "msg": "查询失败"})
+		rankingError(c, err)
 		return
 	}
 
@@ -104,11 +109,13 @@ func (api *RankingAPI) GetFavoriteWeekRanking(c *gin.Context) {
 		c.Request.Context(),
 		model.RankingTypeFavorite,
 		model.RankingPeriodWeek,
+		c.Query("period_key"),
+		0,
 		limit,
 	)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "msg": "查询失败"})
+		rankingError(c, err)
 		return
 	}
 
@@ -127,11 +134,13 @@ func (api *RankingAPI) GetFavoriteMonthRanking(c *gin.Context) {
 		c.Request.Context(),
 		model.RankingTypeFavorite,
 		model.RankingPeriodMonth,
+		c.Query("period_key"),
+		0,
 		limit,
 	)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "msg": "查询失败"})
+		rankingError(c, err)
 		return
 	}
 
@@ -142,15 +151,42 @@ func (api *RankingAPI) GetFavoriteMonthRanking(c *gin.Context) {
 	})
 }
 
-// RebuildRankings 重建所有榜单（管理员接口）
-func (api *RankingAPI) RebuildRankings(c *gin.Context) {
-	// 检查管理员权限
-	role, exists := c.Get("role")
-	if !exists || (role != "admin" && role != "系统管理员") {
-		c.JSON(http.StatusForbidden, gin.H{"code": 403, "msg": "权限不足"})
+// GetSnapshotPeriods 获取有历史快照的周期列表
+func (api *RankingAPI) GetSnapshotPeriods(c *gin.Context) {
+	var req struct {
+		Type   model.RankingType   `form:"type" binding:"required,oneof=like favorite borrow reserve trending rating"`
+		Period model.RankingPeriod `form:"period" binding:"required,oneof=week month year"`
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "参数错误: " + err.Error()})
 		return
 	}
 
+	periodKeys, err := api.service.GetSnapshotPeriods(req.Type, req.Period)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "msg": "查询失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"msg":  "查询成功",
+		"data": periodKeys,
+	})
+}
+
+// rankingError 周期标识无效时返回参数错误，其他错误返回查询失败
+func rankingError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrRankingPeriodKey) {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "msg": "查询失败"})
+}
+
+// RebuildRankings 重建所有榜单（管理员接口）
+func (api *RankingAPI) RebuildRankings(c *gin.Context) {
+	// 权限由路由上的 RequirePermission(ranking:rebuild) 校验
 	err := api.service.RebuildAllRankings(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "msg": "重建失败"})
@@ -5,10 +5,12 @@ import (
 	"bookadmin/model"
 	"bookadmin/model/common/request"
 	"bookadmin/model/common/response"
+	"bookadmin/service"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type ReaderApi struct{}
@@ -110,12 +112,19 @@ func (r *ReaderApi) UpdateReaderStatus(c *gin.Context) {
 		return
 	}
 
+	before := map[string]interface{}{"status": reader.Status, "remark": reader.Remark}
 	reader.Status = model.ReaderStatus(req.Status)
 	if req.Remark != "" {
 		reader.Remark = req.Remark
 	}
 
-	if err := global.GVA_DB.Save(&reader).Error; err != nil {
+	if err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&reader).Error; err != nil {
+			return err
+		}
+		return service.GlobalAuditService.Record(tx, getAuditActor(c), model.AuditReaderStatus, "reader", reader.ID, before,
+			map[string]interface{}{"status": reader.Status, "remark": reader.Remark})
+	}); err != nil {
 		global.GVA_LOG.Error("更新读者状态失败", zap.Error(err))
 		c.JSON(200, response.FailWithMessage("更新失败"))
 		return
@@ -124,14 +133,13 @@ func (r *ReaderApi) UpdateReaderStatus(c *gin.Context) {
 	c.JSON(200, response.OkWithMessage("更新成功"))
 }
 
-// UpdateReader 更新读者信息
+// UpdateReader 更新读者信息（读者类型决定借阅规则）
 func (r *ReaderApi) UpdateReader(c *gin.Context) {
 	var req struct {
-		ID        uint   `json:"id" binding:"required"`
-		MaxBorrow   int    `json:"max_borrow"`
-		BorrowDays  int    `json:"borrow_days"`
-		Address     string `json:"address"`
-		Remark      string `json:"remark"`
+		ID             uint   `json:"id" binding:"required"`
+		PatronCategory string `json:"patron_category"`
+		Address        string `json:"address"`
+		Remark         string `json:"remark"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -139,17 +147,24 @@ func (r *ReaderApi) UpdateReader(c *gin.Context) {
 		return
 	}
 
+	if req.PatronCategory != "" && !model.IsValidPatronCategory(model.PatronCategory(req.PatronCategory)) {
+		c.JSON(200, response.FailWithMessage("读者类型无效"))
+		return
+	}
+
 	var reader model.Reader
 	if err := global.GVA_DB.First(&reader, req.ID).Error; err != nil {
 		c.JSON(200, response.FailWithMessage("读者不存在"))
 		return
 	}
 
-	if req.MaxBorrow > 0 {
-		reader.MaxBorrow = req.MaxBorrow
+	before := map[string]interface{}{
+		"patron_category": reader.PatronCategory,
+		"address":         reader.Address,
+		"remark":          reader.Remark,
 	}
-	if req.BorrowDays > 0 {
-		reader.BorrowDays = req.BorrowDays
+	if req.PatronCategory != "" {
+		reader.PatronCategory = model.PatronCategory(req.PatronCategory)
 	}
 	if req.Address != "" {
 		reader.Address = req.Address
@@ -158,7 +173,17 @@ func (r *ReaderApi) UpdateReader(c *gin.Context) {
 		reader.Remark = req.Remark
 	}
 
-	if err := global.GVA_DB.Save(&reader).Error; err != nil {
+	if err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&reader).Error; err != nil {
+			return err
+		}
+		return service.GlobalAuditService.Record(tx, getAuditActor(c), model.AuditReaderUpdate, "reader", reader.ID, before,
+			map[string]interface{}{
+				"patron_category": reader.PatronCategory,
+				"address":         reader.Address,
+				"remark":          reader.Remark,
+			})
+	}); err != nil {
 		global.GVA_LOG.Error("更新读者信息失败", zap.Error(err))
 		c.JSON(200, response.FailWithMessage("更新失败"))
 		return
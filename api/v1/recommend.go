@@ -0,0 +1,65 @@
+package v1
+
+import (
+	"bookadmin/global"
+	"bookadmin/model/common/response"
+	"bookadmin/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type RecommendApi struct{}
+
+// recommendLimit 解析返回数量，默认10，最多30
+func recommendLimit(c *gin.Context) int {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 30 {
+		limit = 30
+	}
+	return limit
+}
+
+// GetAlsoBorrowed 借过这本书的读者还借过（公开接口）
+func (a *RecommendApi) GetAlsoBorrowed(c *gin.Context) {
+	bookID, err := strconv.ParseUint(c.Query("book_id"), 10, 32)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	resp, err := service.GlobalRecommendService.GetAlsoBorrowed(c.Request.Context(), uint(bookID), recommendLimit(c))
+	if err != nil {
+		global.GVA_LOG.Error("获取相关推荐失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取推荐失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(resp))
+}
+
+// GetForYou 为你推荐
+func (a *RecommendApi) GetForYou(c *gin.Context) {
+	resp, err := service.GlobalRecommendService.GetForYou(c.Request.Context(), c.GetUint("user_id"), recommendLimit(c))
+	if err != nil {
+		global.GVA_LOG.Error("获取个性化推荐失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取推荐失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(resp))
+}
+
+// Rebuild 在后台重新计算推荐
+func (a *RecommendApi) Rebuild(c *gin.Context) {
+	if err := service.GlobalRecommendService.Start(); err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithMessage("已开始计算推荐"))
+}
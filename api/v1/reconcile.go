@@ -0,0 +1,78 @@
+package v1
+
+import (
+	"bookadmin/global"
+	"bookadmin/model/common/response"
+	"bookadmin/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ReconcileApi struct{}
+
+// StartReconcile 手动执行一次点赞收藏校对
+func (a *ReconcileApi) StartReconcile(c *gin.Context) {
+	var req struct {
+		DryRun bool `json:"dry_run"` // 只检查不修复
+	}
+	_ = c.ShouldBindJSON(&req)
+
+	run, err := service.GlobalReconcileService.Start(req.DryRun, getAuditActor(c))
+	if err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(run, "校对任务已开始"))
+}
+
+// GetReconcileRuns 分页获取校对记录
+func (a *ReconcileApi) GetReconcileRuns(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	runs, total, err := service.GlobalReconcileService.GetRuns(page, pageSize)
+	if err != nil {
+		global.GVA_LOG.Error("获取校对记录失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取数据失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(response.PageResult{
+		List:     runs,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, "获取成功"))
+}
+
+// GetReconcileRun 获取校对结果和差异明细，不传id时返回最近一次
+func (a *ReconcileApi) GetReconcileRun(c *gin.Context) {
+	var id uint64
+	if v := c.Query("id"); v != "" {
+		var err error
+		if id, err = strconv.ParseUint(v, 10, 32); err != nil {
+			c.JSON(200, response.FailWithMessage("参数错误"))
+			return
+		}
+	}
+
+	run, details, err := service.GlobalReconcileService.GetRun(uint(id))
+	if err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(gin.H{
+		"run":     run,
+		"details": details,
+	}))
+}
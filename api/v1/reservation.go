@@ -41,17 +41,13 @@ func (r *ReservationApi) CreateReservation(c *gin.Context) {
 	if err := global.GVA_DB.Where("user_id = ?", userID).First(&reader).Error; err != nil {
 		// 如果读者不存在，自动创建
 		reader = model.Reader{
-			UserID:          userID,
-			ReaderNo:        fmt.Sprintf("R%d", userID),
-			Status:          model.ReaderStatusActive,
-			MaxBorrow:       5,
-			BorrowDays:      30,
-			MaxRenew:        2,
-			RenewDays:       15,
-			MaxReservations: 3,
-			TotalFine:       0,
-			UnpaidFine:      0,
-			IsBlacklisted:   false,
+			UserID:         userID,
+			ReaderNo:       fmt.Sprintf("R%d", userID),
+			Status:         model.ReaderStatusActive,
+			PatronCategory: model.DefaultPatronCategory,
+			TotalFine:      0,
+			UnpaidFine:     0,
+			IsBlacklisted:  false,
 		}
 		if err := global.GVA_DB.Create(&reader).Error; err != nil {
 			c.JSON(200, response.FailWithMessage("创建读者信息失败"))
@@ -168,7 +164,7 @@ func (r *ReservationApi) GetReservationList(c *gin.Context) {
 	// 状态筛选
 	status := c.Query("status")
 	if status != "" {
-		db = db.Where("status = ?", status)
+		db = db.Where("reservations.status = ?", status)
 	}
 
 	if err := db.Count(&total).Error; err != nil {
@@ -0,0 +1,189 @@
+package v1
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"bookadmin/model/common/response"
+	"bookadmin/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ReviewApi struct{}
+
+// SubmitReview 发表或修改评价（归还后才能评价）
+func (a *ReviewApi) SubmitReview(c *gin.Context) {
+	var req struct {
+		BookID  uint   `json:"book_id" binding:"required"`
+		Rating  int    `json:"rating" binding:"required"`
+		Content string `json:"content"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	review, err := service.GlobalReviewService.SubmitReview(c.GetUint("user_id"), req.BookID, req.Rating, req.Content)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	msg := "评价成功"
+	if review.Status == model.ReviewStatusPending {
+		msg = "评价已提交，审核通过后公开"
+	}
+	c.JSON(200, response.OkWithDetailed(review, msg))
+}
+
+// DeleteReview 删除自己的评价
+func (a *ReviewApi) DeleteReview(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	if err := service.GlobalReviewService.DeleteReview(c.GetUint("user_id"), uint(id)); err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithMessage("删除成功"))
+}
+
+// ReportReview 举报评价
+func (a *ReviewApi) ReportReview(c *gin.Context) {
+	var req struct {
+		ReviewID uint   `json:"review_id" binding:"required"`
+		Reason   string `json:"reason" binding:"max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	if err := service.GlobalReviewService.ReportReview(c.GetUint("user_id"), req.ReviewID, req.Reason); err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithMessage("举报成功"))
+}
+
+// GetBookReviews 获取图书已公开的评价（公开接口）
+func (a *ReviewApi) GetBookReviews(c *gin.Context) {
+	bookID, err := strconv.ParseUint(c.Query("book_id"), 10, 32)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	q := parseReviewQuery(c)
+	q.BookID = uint(bookID)
+	q.Status = model.ReviewStatusApproved
+	q.Reported = false
+	if q.Sort == model.ReviewSortReports {
+		q.Sort = model.ReviewSortNewest
+	}
+	reviewPage(c, q)
+}
+
+// GetRatingSummary 获取图书评分汇总（公开接口）
+func (a *ReviewApi) GetRatingSummary(c *gin.Context) {
+	bookID, err := strconv.ParseUint(c.Query("book_id"), 10, 32)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	summary, err := service.GlobalReviewService.GetRatingSummary(uint(bookID))
+	if err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(summary))
+}
+
+// GetMyReviews 获取我的评价（含待审核和已隐藏）
+func (a *ReviewApi) GetMyReviews(c *gin.Context) {
+	q := parseReviewQuery(c)
+	q.UserID = c.GetUint("user_id")
+	q.BookID = 0
+	q.Reported = false
+	reviewPage(c, q)
+}
+
+// GetReviewList 管理端评价列表，可按状态、图书、是否被举报筛选
+func (a *ReviewApi) GetReviewList(c *gin.Context) {
+	q := parseReviewQuery(c)
+	if q.Status != "" && q.Status != model.ReviewStatusPending &&
+		q.Status != model.ReviewStatusApproved && q.Status != model.ReviewStatusHidden {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+	reviewPage(c, q)
+}
+
+// ModerateReview 审核评价：approve 公开，hide 隐藏
+func (a *ReviewApi) ModerateReview(c *gin.Context) {
+	var req struct {
+		ID     uint   `json:"id" binding:"required"`
+		Action string `json:"action" binding:"required,oneof=approve hide"`
+		Note   string `json:"note" binding:"max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	review, err := service.GlobalReviewService.ModerateReview(req.ID, req.Action, req.Note, getAuditActor(c))
+	if err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(review, "操作成功"))
+}
+
+// parseReviewQuery 解析评价列表的分页、排序和筛选参数
+func parseReviewQuery(c *gin.Context) service.ReviewQuery {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+	bookID, _ := strconv.ParseUint(c.Query("book_id"), 10, 32)
+
+	return service.ReviewQuery{
+		BookID:   uint(bookID),
+		Status:   model.ReviewStatus(c.Query("status")),
+		Reported: c.Query("reported") == "true",
+		Sort:     model.ReviewSort(c.Query("sort")),
+		Page:     page,
+		PageSize: pageSize,
+	}
+}
+
+// reviewPage 查询并返回评价分页结果
+func reviewPage(c *gin.Context, q service.ReviewQuery) {
+	reviews, total, err := service.GlobalReviewService.ListReviews(q)
+	if err != nil {
+		global.GVA_LOG.Error("获取评价列表失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取数据失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(response.PageResult{
+		List:     reviews,
+		Total:    total,
+		Page:     q.Page,
+		PageSize: q.PageSize,
+	}, "获取成功"))
+}
@@ -4,6 +4,7 @@ import (
 	"bookadmin/global"
 	"bookadmin/model"
 	"bookadmin/model/common/response"
+	"bookadmin/service"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -95,13 +96,37 @@ func (s *StatisticsApi) GetBorrowStatistics(c *gin.Context) {
 }
 
 // GetPopularBooks 获取热门图书（借阅次数最多）
+// 传 period=week/month/year 时取借阅榜的当前周期，不传时统计全部借阅记录
 func (s *StatisticsApi) GetPopularBooks(c *gin.Context) {
-	var popularBooks []struct {
+	type popularBook struct {
 		BookID      uint   `json:"book_id"`
 		Title       string `json:"title"`
 		Author      string `json:"author"`
 		BorrowCount int64  `json:"borrow_count"`
 	}
+	var popularBooks []popularBook
+
+	if period := model.RankingPeriod(c.Query("period")); period != "" {
+		if period != model.RankingPeriodWeek && period != model.RankingPeriodMonth && period != model.RankingPeriodYear {
+			c.JSON(200, response.FailWithMessage("参数错误"))
+			return
+		}
+		ranking, err := service.NewRankingService().GetRanking(c.Request.Context(), model.RankingTypeBorrow, period, "", 0, 10)
+		if err != nil {
+			c.JSON(200, response.FailWithMessage("获取数据失败"))
+			return
+		}
+		for _, item := range ranking.Items {
+			popularBooks = append(popularBooks, popularBook{
+				BookID:      item.BookID,
+				Title:       item.Book.Title,
+				Author:      item.Book.Author,
+				BorrowCount: int64(item.Score),
+			})
+		}
+		c.JSON(200, response.OkWithData(popularBooks))
+		return
+	}
 
 	global.GVA_DB.Model(&model.BorrowRecord{}).
 		Select("book_id, COUNT(*) as borrow_count").
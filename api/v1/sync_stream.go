@@ -0,0 +1,105 @@
+package v1
+
+import (
+	"bookadmin/global"
+	"bookadmin/model/common/response"
+	"bookadmin/service"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type SyncStreamApi struct{}
+
+// GetSyncStats 获取同步Stream的消费状态和死信数量
+func (a *SyncStreamApi) GetSyncStats(c *gin.Context) {
+	stats, deadLetters, err := service.GlobalSyncStreamService.GetStats(c.Request.Context())
+	if err != nil {
+		global.GVA_LOG.Error("获取同步队列状态失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取数据失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(gin.H{
+		"streams":      stats,
+		"dead_letters": deadLetters,
+	}))
+}
+
+// GetPendingMessages 获取待确认列表中的消息
+func (a *SyncStreamApi) GetPendingMessages(c *gin.Context) {
+	count, _ := strconv.ParseInt(c.DefaultQuery("count", "50"), 10, 64)
+	if count <= 0 || count > 500 {
+		count = 50
+	}
+
+	list, err := service.GlobalSyncStreamService.GetPending(c.Request.Context(), c.Query("stream"), count)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage(err.Error()))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(list))
+}
+
+// GetDeadLetters 获取死信消息，before 传上一页最后一条的ID
+func (a *SyncStreamApi) GetDeadLetters(c *gin.Context) {
+	count, _ := strconv.ParseInt(c.DefaultQuery("count", "20"), 10, 64)
+	if count <= 0 || count > 200 {
+		count = 20
+	}
+
+	list, total, err := service.GlobalSyncStreamService.GetDeadLetters(c.Request.Context(), c.Query("before"), count)
+	if err != nil {
+		global.GVA_LOG.Error("获取死信消息失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取数据失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(gin.H{
+		"list":  list,
+		"total": total,
+	}))
+}
+
+// ReplayDeadLetters 重放死信消息
+func (a *SyncStreamApi) ReplayDeadLetters(c *gin.Context) {
+	var req struct {
+		IDs []string `json:"ids" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	count, err := service.GlobalSyncStreamService.ReplayDeadLetters(c.Request.Context(), req.IDs, getAuditActor(c))
+	if err != nil {
+		global.GVA_LOG.Error("重放死信消息失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage(fmt.Sprintf("重放失败（已重放%d条）: %v", count, err)))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(gin.H{"count": count}, fmt.Sprintf("已重放%d条消息", count)))
+}
+
+// DiscardDeadLetters 丢弃死信消息
+func (a *SyncStreamApi) DiscardDeadLetters(c *gin.Context) {
+	var req struct {
+		IDs []string `json:"ids" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	count, err := service.GlobalSyncStreamService.DiscardDeadLetters(c.Request.Context(), req.IDs, getAuditActor(c))
+	if err != nil {
+		global.GVA_LOG.Error("丢弃死信消息失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("丢弃失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithDetailed(gin.H{"count": count}, fmt.Sprintf("已丢弃%d条消息", count)))
+}
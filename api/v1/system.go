@@ -5,10 +5,14 @@ import (
 	"bookadmin/model"
 	"bookadmin/model/common/request"
 	"bookadmin/model/common/response"
+	"bookadmin/service"
+	"bookadmin/utils"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type SystemApi struct{}
@@ -80,9 +84,16 @@ func (s *SystemApi) CreateUser(c *gin.Context) {
 		return
 	}
 
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		global.GVA_LOG.Error("密码加密失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("创建失败"))
+		return
+	}
+
 	user := model.User{
 		Username: req.Username,
-		Password: req.Password, // 简化处理，实际应加密
+		Password: hashedPassword,
 		Email:    req.Email,
 		Phone:    req.Phone,
 		Role:     req.Role,
@@ -94,7 +105,12 @@ func (s *SystemApi) CreateUser(c *gin.Context) {
 		user.Status = req.Status
 	}
 
-	if err := global.GVA_DB.Create(&user).Error; err != nil {
+	if err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return service.GlobalAuditService.Record(tx, getAuditActor(c), model.AuditUserCreate, "user", user.ID, nil, user)
+	}); err != nil {
 		global.GVA_LOG.Error("创建用户失败", zap.Error(err))
 		c.JSON(200, response.FailWithMessage("创建失败"))
 		return
@@ -107,6 +123,7 @@ func (s *SystemApi) CreateUser(c *gin.Context) {
 func (s *SystemApi) UpdateUser(c *gin.Context) {
 	var req struct {
 		ID       uint           `json:"id" binding:"required"`
+		Password string         `json:"password"` // 非空时重置密码
 		Email    string         `json:"email"`
 		Phone    string         `json:"phone"`
 		Role     model.UserRole `json:"role"`
@@ -125,6 +142,9 @@ func (s *SystemApi) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	before := user
+	oldRole, oldStatus := user.Role, user.Status
+
 	if req.Email != "" {
 		user.Email = req.Email
 	}
@@ -140,13 +160,36 @@ func (s *SystemApi) UpdateUser(c *gin.Context) {
 	if req.Status != "" {
 		user.Status = req.Status
 	}
+	if req.Password != "" {
+		hashedPassword, err := utils.HashPassword(req.Password)
+		if err != nil {
+			global.GVA_LOG.Error("密码加密失败", zap.Error(err))
+			c.JSON(200, response.FailWithMessage("更新失败"))
+			return
+		}
+		user.Password = hashedPassword
+	}
 
-	if err := global.GVA_DB.Save(&user).Error; err != nil {
+	if err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		return service.GlobalAuditService.Record(tx, getAuditActor(c), model.AuditUserUpdate, "user", user.ID, before, user)
+	}); err != nil {
 		global.GVA_LOG.Error("更新用户失败", zap.Error(err))
 		c.JSON(200, response.FailWithMessage("更新失败"))
 		return
 	}
 
+	// 角色或状态变化后，已签发的令牌立即失效
+	if user.Role != oldRole || user.Status != oldStatus {
+		if err := tokenService.RevokeUser(c.Request.Context(), user.ID); err != nil {
+			global.GVA_LOG.Error("用户已更新，但吊销令牌失败", zap.Uint("user_id", user.ID), zap.Error(err))
+			c.JSON(200, response.FailWithMessage("用户已更新，但吊销已签发的令牌失败，旧令牌过期前仍然有效，请检查Redis"))
+			return
+		}
+	}
+
 	c.JSON(200, response.OkWithMessage("更新成功"))
 }
 
@@ -158,12 +201,86 @@ func (s *SystemApi) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	if err := global.GVA_DB.Delete(&model.User{}, req.ID).Error; err != nil {
+	var user model.User
+	if err := global.GVA_DB.First(&user, req.ID).Error; err != nil {
+		c.JSON(200, response.FailWithMessage("用户不存在"))
+		return
+	}
+
+	if err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&user).Error; err != nil {
+			return err
+		}
+		return service.GlobalAuditService.Record(tx, getAuditActor(c), model.AuditUserDelete, "user", user.ID, user, nil)
+	}); err != nil {
 		global.GVA_LOG.Error("删除用户失败", zap.Error(err))
 		c.JSON(200, response.FailWithMessage("删除失败"))
 		return
 	}
 
+	if err := tokenService.RevokeUser(c.Request.Context(), req.ID); err != nil {
+		global.GVA_LOG.Error("用户已删除，但吊销令牌失败", zap.Uint("user_id", req.ID), zap.Error(err))
+		c.JSON(200, response.FailWithMessage("用户已删除，但吊销已签发的令牌失败，旧令牌过期前仍然有效，请检查Redis"))
+		return
+	}
+
 	c.JSON(200, response.OkWithMessage("删除成功"))
 }
 
+// GetConfigList 获取系统配置列表
+func (s *SystemApi) GetConfigList(c *gin.Context) {
+	var configs []model.SystemConfig
+	if err := global.GVA_DB.Order("config_key ASC").Find(&configs).Error; err != nil {
+		global.GVA_LOG.Error("获取系统配置失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("获取数据失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithData(configs))
+}
+
+// UpdateConfig 修改系统配置
+func (s *SystemApi) UpdateConfig(c *gin.Context) {
+	var req struct {
+		ConfigKey   string `json:"config_key" binding:"required"`
+		ConfigValue string `json:"config_value"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(200, response.FailWithMessage("参数错误"))
+		return
+	}
+
+	var config model.SystemConfig
+	if err := global.GVA_DB.Where("config_key = ?", req.ConfigKey).First(&config).Error; err != nil {
+		c.JSON(200, response.FailWithMessage("配置项不存在"))
+		return
+	}
+
+	if !isValidConfigValue(config.ConfigType, req.ConfigValue) {
+		c.JSON(200, response.FailWithMessage("配置值类型错误"))
+		return
+	}
+
+	if err := service.GlobalConfigService.SetConfig(req.ConfigKey, req.ConfigValue, getAuditActor(c)); err != nil {
+		global.GVA_LOG.Error("修改系统配置失败", zap.Error(err))
+		c.JSON(200, response.FailWithMessage("更新失败"))
+		return
+	}
+
+	c.JSON(200, response.OkWithMessage("更新成功"))
+}
+
+// isValidConfigValue 校验配置值与声明的类型是否一致
+func isValidConfigValue(configType, value string) bool {
+	var err error
+	switch configType {
+	case "int":
+		_, err = strconv.Atoi(value)
+	case "float":
+		_, err = strconv.ParseFloat(value, 64)
+	case "bool":
+		_, err = strconv.ParseBool(value)
+	}
+	return err == nil
+}
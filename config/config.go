@@ -0,0 +1,346 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix 环境变量前缀，如 BOOKADMIN_MYSQL_PASSWORD 覆盖 mysql.password
+const EnvPrefix = "BOOKADMIN"
+
+// Config 应用配置（对应 config.yaml）
+type Config struct {
+	Server  Server  `yaml:"server"`
+	Mysql   Mysql   `yaml:"mysql"`
+	Redis   Redis   `yaml:"redis"`
+	JWT     JWT     `yaml:"jwt"`
+	Storage Storage `yaml:"storage"`
+	Search  Search  `yaml:"search"`
+	Notify  Notify  `yaml:"notify"`
+	Payment Payment `yaml:"payment"`
+}
+
+// Server 服务配置
+type Server struct {
+	Port        int    `yaml:"port"`         // 监听端口
+	Mode        string `yaml:"mode"`         // 运行模式：debug/release/test
+	AllowOrigin string `yaml:"allow-origin"` // 跨域允许的来源
+}
+
+// Mysql MySQL配置
+type Mysql struct {
+	Path         string `yaml:"path"`           // 地址:端口
+	DbName       string `yaml:"db-name"`        // 数据库名
+	Username     string `yaml:"username"`       // 用户名
+	Password     string `yaml:"password"`       // 密码
+	Config       string `yaml:"config"`         // 连接参数
+	MaxIdleConns int    `yaml:"max-idle-conns"` // 最大空闲连接数
+	MaxOpenConns int    `yaml:"max-open-conns"` // 最大打开连接数
+	LogMode      bool   `yaml:"log-mode"`       // 是否打印SQL
+}
+
+// Dsn 生成MySQL连接串
+func (m Mysql) Dsn() string {
+	return fmt.Sprintf("%s:%s@tcp(%s)/%s?%s", m.Username, m.Password, m.Path, m.DbName, m.Config)
+}
+
+// Redis Redis配置
+type Redis struct {
+	Addr         string `yaml:"addr"`           // 地址
+	Password     string `yaml:"password"`       // 密码
+	DB           int    `yaml:"db"`             // 数据库索引
+	PoolSize     int    `yaml:"pool-size"`      // 连接池大小
+	MinIdleConns int    `yaml:"min-idle-conns"` // 最小空闲连接数
+	MaxRetries   int    `yaml:"max-retries"`    // 最大重试次数
+	DialTimeout  int    `yaml:"dial-timeout"`   // 连接超时（秒）
+	ReadTimeout  int    `yaml:"read-timeout"`   // 读取超时（秒）
+	WriteTimeout int    `yaml:"write-timeout"`  // 写入超时（秒）
+}
+
+// JWT 令牌配置
+type JWT struct {
+	Secret             string        `yaml:"secret"`               // 签名密钥
+	ExpiresTime        time.Duration `yaml:"expires-time"`         // 访问令牌有效期，如 30m
+	RefreshExpiresTime time.Duration `yaml:"refresh-expires-time"` // 刷新令牌有效期，如 168h
+	Issuer             string        `yaml:"issuer"`               // 签发者
+}
+
+// Storage 文件存储配置
+type Storage struct {
+	Path          string `yaml:"path"`            // 导入导出文件的存放目录
+	MaxUploadSize int64  `yaml:"max-upload-size"` // 上传文件大小上限（MB）
+}
+
+// Search 图书搜索配置
+type Search struct {
+	Engine string `yaml:"engine"` // 搜索引擎：mysql（FULLTEXT ngram索引）/memory（进程内索引）
+}
+
+// Notify 邮件、短信通知配置
+type Notify struct {
+	SMTP SMTP `yaml:"smtp"`
+	SMS  SMS  `yaml:"sms"`
+}
+
+// SMTP 邮件服务器配置，host 为空时不发送邮件
+type SMTP struct {
+	Host     string `yaml:"host"`     // 服务器地址
+	Port     int    `yaml:"port"`     // 端口，465 一般为SSL，587/25 使用STARTTLS
+	Username string `yaml:"username"` // 用户名，为空时不认证
+	Password string `yaml:"password"` // 密码或授权码
+	From     string `yaml:"from"`     // 发件人，如 "图书馆 <library@example.com>"
+	SSL      bool   `yaml:"ssl"`      // 是否直接使用SSL连接
+}
+
+// SMS 短信配置
+type SMS struct {
+	Provider string `yaml:"provider"` // 短信服务商：fake（本地开发，只记录日志），为空时不发送短信
+}
+
+// Payment 罚款在线支付配置
+type Payment struct {
+	Provider    string        `yaml:"provider"`     // 支付渠道：mock（本地测试），为空时不开放在线支付
+	NotifyURL   string        `yaml:"notify-url"`   // 支付结果回调地址，需支付渠道可以访问
+	OrderExpire time.Duration `yaml:"order-expire"` // 订单支付有效期，如 15m
+	Mock        MockPayment   `yaml:"mock"`
+}
+
+// MockPayment 本地模拟支付渠道配置
+type MockPayment struct {
+	Secret string `yaml:"secret"` // 回调签名密钥
+}
+
+// Default 默认配置，与旧版硬编码的值保持一致
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Port:        8888,
+			Mode:        "debug",
+			AllowOrigin: "*",
+		},
+		Mysql: Mysql{
+			Path:         "127.0.0.1:3306",
+			DbName:       "bookadmin",
+			Username:     "root",
+			Password:     "root",
+			Config:       "charset=utf8mb4&parseTime=True&loc=Local",
+			MaxIdleConns: 10,
+			MaxOpenConns: 100,
+			LogMode:      true,
+		},
+		Redis: Redis{
+			Addr:         "127.0.0.1:6379",
+			PoolSize:     100,
+			MinIdleConns: 10,
+			MaxRetries:   3,
+			DialTimeout:  5,
+			ReadTimeout:  3,
+			WriteTimeout: 3,
+		},
+		JWT: JWT{
+			Secret:             "bookadmin-secret-key-change-in-production",
+			ExpiresTime:        30 * time.Minute,
+			RefreshExpiresTime: 7 * 24 * time.Hour,
+			Issuer:             "bookadmin",
+		},
+		Storage: Storage{
+			Path:          "uploads",
+			MaxUploadSize: 20,
+		},
+		Search: Search{
+			Engine: "mysql",
+		},
+		Notify: Notify{
+			SMTP: SMTP{
+				Port: 465,
+				SSL:  true,
+			},
+		},
+		Payment: Payment{
+			OrderExpire: 15 * time.Minute,
+		},
+	}
+}
+
+// Load 加载配置：默认值 -> YAML文件 -> 环境变量，最后校验
+// path 为空或文件不存在时只使用默认值和环境变量
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("读取配置文件失败: %w", err)
+		}
+		if err == nil {
+			if err := yaml.Unmarshal(data, cfg); err != nil {
+				return nil, fmt.Errorf("解析配置文件失败: %w", err)
+			}
+		}
+	}
+
+	if err := applyEnv(reflect.ValueOf(cfg).Elem(), EnvPrefix); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate 校验配置
+func (c *Config) Validate() error {
+	var errs []string
+
+	if c.Server.Port <= 0 || c.Server.Port > 65535 {
+		errs = append(errs, fmt.Sprintf("server.port 无效: %d", c.Server.Port))
+	}
+	switch c.Server.Mode {
+	case "debug", "release", "test":
+	default:
+		errs = append(errs, fmt.Sprintf("server.mode 无效: %s", c.Server.Mode))
+	}
+
+	if c.Mysql.Path == "" || c.Mysql.DbName == "" || c.Mysql.Username == "" {
+		errs = append(errs, "mysql.path、mysql.db-name、mysql.username 不能为空")
+	}
+	if c.Mysql.MaxOpenConns < c.Mysql.MaxIdleConns {
+		errs = append(errs, "mysql.max-open-conns 不能小于 max-idle-conns")
+	}
+
+	if c.Redis.Addr == "" {
+		errs = append(errs, "redis.addr 不能为空")
+	}
+
+	if c.JWT.Secret == "" {
+		errs = append(errs, "jwt.secret 不能为空")
+	} else if c.Server.Mode == "release" && (len(c.JWT.Secret) < 32 || c.JWT.Secret == Default().JWT.Secret) {
+		errs = append(errs, "release 模式下 jwt.secret 必须修改且不少于32个字符")
+	}
+	if c.JWT.ExpiresTime <= 0 {
+		errs = append(errs, "jwt.expires-time 必须大于0")
+	}
+	if c.JWT.RefreshExpiresTime <= c.JWT.ExpiresTime {
+		errs = append(errs, "jwt.refresh-expires-time 必须大于 expires-time")
+	}
+
+	if c.Storage.Path == "" {
+		errs = append(errs, "storage.path 不能为空")
+	}
+	if c.Storage.MaxUploadSize <= 0 {
+		errs = append(errs, "storage.max-upload-size 必须大于0")
+	}
+
+	switch c.Search.Engine {
+	case "mysql", "memory":
+	default:
+		errs = append(errs, fmt.Sprintf("search.engine 无效: %s", c.Search.Engine))
+	}
+
+	if c.Notify.SMTP.Host != "" && (c.Notify.SMTP.Port <= 0 || c.Notify.SMTP.From == "") {
+		errs = append(errs, "配置了 notify.smtp.host 时 port 和 from 不能为空")
+	}
+	switch c.Notify.SMS.Provider {
+	case "", "fake":
+	default:
+		errs = append(errs, fmt.Sprintf("notify.sms.provider 无效: %s", c.Notify.SMS.Provider))
+	}
+
+	switch c.Payment.Provider {
+	case "":
+	case "mock":
+		if c.Payment.Mock.Secret == "" {
+			errs = append(errs, "payment.mock.secret 不能为空")
+		}
+		if c.Server.Mode == "release" {
+			errs = append(errs, "release 模式下不能使用 mock 支付渠道")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("payment.provider 无效: %s", c.Payment.Provider))
+	}
+	if c.Payment.OrderExpire < time.Minute {
+		errs = append(errs, "payment.order-expire 不能小于1分钟")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("配置校验失败: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// applyEnv 按 yaml 标签生成环境变量名并覆盖字段
+// 例如 mysql.db-name 对应 BOOKADMIN_MYSQL_DB_NAME
+func applyEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(strings.ReplaceAll(tag, "-", "_"))
+		fv := v.Field(i)
+
+		if fv.Kind() == reflect.Struct {
+			if err := applyEnv(fv, name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setValue(fv, value); err != nil {
+			return fmt.Errorf("环境变量 %s 无效: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// setValue 将字符串写入字段
+func setValue(fv reflect.Value, value string) error {
+	if fv.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	default:
+		return fmt.Errorf("不支持的类型 %s", fv.Kind())
+	}
+	return nil
+}
@@ -66,6 +66,36 @@ func KeyRankFavoritesMonth(period string) string {
 	return fmt.Sprintf("rank:favorites:month:%s", period)
 }
 
+// 通用榜单 (Sorted Set - ZSet)
+// key: rank:{榜单名}:{week|month|year}:{周期标识}
+// 榜单名: likes, favorites, borrows, reservations, trending, ratings（与上面的点赞/收藏榜Key一致）
+// score: 次数，热度榜为按周期起点前向衰减放大后的热度值
+// member: book_id
+// 过期时间: 周榜8天，月榜35天，年榜370天
+func KeyRank(name, period, periodKey string) string {
+	return fmt.Sprintf("rank:%s:%s:%s", name, period, periodKey)
+}
+
+// 分类榜单 (Sorted Set - ZSet)
+// key: rank:{榜单名}:{week|month|year}:{周期标识}:cat:{category_id}
+// 图书属于多个分类时计入每个分类的榜单
+func KeyRankCategory(name, period, periodKey string, categoryID uint) string {
+	return fmt.Sprintf("rank:%s:%s:%s:cat:%d", name, period, periodKey, categoryID)
+}
+
+// 推荐结果 (String)
+// key: rec:{also_borrowed|for_you|category}:{图书ID或用户ID}
+// value: 推荐列表JSON，由每日推荐计算任务写入，缺失时从MySQL回填
+// 过期时间: 48小时
+func KeyRecommend(kind string, subjectID uint) string {
+	return fmt.Sprintf("rec:%s:%d", kind, subjectID)
+}
+
+// 实时通知频道 (Pub/Sub)
+// channel: notify:events
+// 消息为通知JSON，每个实例订阅后推送给连接在本实例的用户
+const ChannelNotify = "notify:events"
+
 // 点赞操作Stream (Stream)
 // key: stream:like:actions
 // 用于异步同步点赞操作到MySQL
@@ -76,6 +106,16 @@ const StreamLikeActions = "stream:like:actions"
 // 用于异步同步收藏操作到MySQL
 const StreamFavoriteActions = "stream:favorite:actions"
 
+// 同步Worker消费者组
+// 点赞、收藏Stream共用同一个消费者组
+const StreamSyncGroup = "sync-group"
+
+// 同步死信Stream (Stream)
+// key: stream:sync:deadletter
+// fields: stream(来源Stream), message_id(原消息ID), deliveries(投递次数), reason(原因), failed_at(时间戳), payload(原消息JSON)
+// 超过最大投递次数或格式错误的消息转入此Stream，由管理员重放或丢弃
+const StreamSyncDeadLetter = "stream:sync:deadletter"
+
 // 操作锁 (String)
 // key: lock:like:{user_id}:{book_id}
 // 用于防止重复点赞/收藏
@@ -88,6 +128,12 @@ func KeyFavoriteLock(userID, bookID uint) string {
 	return fmt.Sprintf("lock:favorite:%d:%d", userID, bookID)
 }
 
+// 校对任务锁 (String)
+// key: lock:reconcile
+// 防止多个实例同时执行点赞/收藏校对
+// 过期时间: 30分钟
+const KeyReconcileLock = "lock:reconcile"
+
 // 热点检测 (HyperLogLog)
 // key: hotspot:check:{minute}
 // 用于统计每分钟的操作UV
@@ -113,6 +159,37 @@ func KeyRateLimitUserFavorite(userID uint) string {
 	return fmt.Sprintf("rate:limit:user:%d:favorite", userID)
 }
 
+// 用户令牌版本 (String)
+// key: jwt:user:version:{user_id}
+// value: 版本号，递增后该用户已签发的令牌全部失效
+// 过期时间: 不过期
+func KeyUserTokenVersion(userID uint) string {
+	return fmt.Sprintf("jwt:user:version:%d", userID)
+}
+
+// 已吊销的令牌 (String)
+// key: jwt:revoked:{jti}
+// 过期时间: 令牌剩余有效期
+func KeyRevokedToken(tokenID string) string {
+	return fmt.Sprintf("jwt:revoked:%s", tokenID)
+}
+
+// 有效的刷新令牌 (String)
+// key: jwt:refresh:{jti}
+// value: user_id，刷新时删除（轮换），删除失败说明令牌已被使用或吊销
+// 过期时间: 刷新令牌有效期
+func KeyRefreshToken(tokenID string) string {
+	return fmt.Sprintf("jwt:refresh:%s", tokenID)
+}
+
+// 待执行事件已生效标记 (String)
+// key: outbox:done:{幂等键}
+// 用于不在数据库事务内的后续操作（如更新榜单），与操作在同一Lua脚本中写入，重复投递时跳过
+// 过期时间: 7天
+func KeyOutboxDone(idempotencyKey string) string {
+	return fmt.Sprintf("outbox:done:%s", idempotencyKey)
+}
+
 // ============================================
 // Redis Key 过期时间常量
 // ============================================
@@ -130,6 +207,12 @@ const (
 	// 月榜过期时间（35天）
 	ExpireMonthRank = 35 * 24 * 60 * 60
 
+	// 年榜过期时间（370天）
+	ExpireYearRank = 370 * 24 * 60 * 60
+
+	// 推荐结果过期时间（48小时）
+	ExpireRecommend = 48 * 60 * 60
+
 	// 操作锁过期时间（1秒）
 	ExpireLock = 1
 
@@ -138,5 +221,8 @@ const (
 
 	// 限流窗口（1分钟）
 	ExpireRateLimit = 60
+
+	// 待执行事件幂等标记过期时间（7天）
+	ExpireOutboxDone = 7 * 24 * 60 * 60
 )
 
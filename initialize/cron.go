@@ -1,7 +1,9 @@
 package initialize
 
 import (
+	"bookadmin/model"
 	"bookadmin/service"
+	"context"
 
 	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
@@ -84,6 +86,111 @@ func InitCronJobs() {
 		zap.L().Error("添加过期黑名单检查任务失败", zap.Error(err))
 	}
 
+	// 每天凌晨4点校对点赞收藏的 Redis 与 MySQL 数据
+	_, err = cronScheduler.AddFunc("0 0 4 * * *", func() {
+		zap.L().Info("开始校对点赞收藏数据...")
+		if _, err := service.GlobalReconcileService.Run("cron", false, model.SystemActor); err != nil {
+			zap.L().Error("校对点赞收藏数据失败", zap.Error(err))
+		} else {
+			zap.L().Info("点赞收藏数据校对完成")
+		}
+	})
+	if err != nil {
+		zap.L().Error("添加点赞收藏校对任务失败", zap.Error(err))
+	}
+
+	// 每天0点10分为刚结束的周期生成榜单快照（周一生成上周、每月1日生成上月、1月1日生成去年）
+	_, err = cronScheduler.AddFunc("0 10 0 * * *", func() {
+		zap.L().Info("开始生成榜单快照...")
+		if err := service.NewRankingService().SnapshotClosedPeriods(); err != nil {
+			zap.L().Error("生成榜单快照失败", zap.Error(err))
+		} else {
+			zap.L().Info("榜单快照生成完成")
+		}
+	})
+	if err != nil {
+		zap.L().Error("添加榜单快照任务失败", zap.Error(err))
+	}
+
+	// 每天凌晨4点半重建当前周期的全部榜单，修正实时更新遗漏的数据
+	_, err = cronScheduler.AddFunc("0 30 4 * * *", func() {
+		zap.L().Info("开始重建榜单...")
+		if err := service.NewRankingService().RebuildAllRankings(context.Background()); err != nil {
+			zap.L().Error("重建榜单失败", zap.Error(err))
+		} else {
+			zap.L().Info("榜单重建完成")
+		}
+	})
+	if err != nil {
+		zap.L().Error("添加榜单重建任务失败", zap.Error(err))
+	}
+
+	// 每天凌晨3点半重新计算图书推荐
+	_, err = cronScheduler.AddFunc("0 30 3 * * *", func() {
+		zap.L().Info("开始计算图书推荐...")
+		if err := service.GlobalRecommendService.Compute(); err != nil {
+			zap.L().Error("计算图书推荐失败", zap.Error(err))
+		} else {
+			zap.L().Info("图书推荐计算完成")
+		}
+	})
+	if err != nil {
+		zap.L().Error("添加图书推荐任务失败", zap.Error(err))
+	}
+
+	// 每天凌晨5点重建图书搜索索引，修正遗漏的索引更新
+	_, err = cronScheduler.AddFunc("0 0 5 * * *", func() {
+		zap.L().Info("开始重建搜索索引...")
+		if err := service.GlobalSearchService.Rebuild(); err != nil {
+			zap.L().Error("重建搜索索引失败", zap.Error(err))
+		} else {
+			zap.L().Info("搜索索引重建完成")
+		}
+	})
+	if err != nil {
+		zap.L().Error("添加搜索索引重建任务失败", zap.Error(err))
+	}
+
+	// 每分钟处理超过支付有效期的订单：已支付但回调丢失的补单，未支付的关闭
+	_, err = cronScheduler.AddFunc("0 * * * * *", func() {
+		if err := service.GlobalPaymentService.CloseExpiredOrders(); err != nil {
+			zap.L().Error("处理过期支付订单失败", zap.Error(err))
+		}
+	})
+	if err != nil {
+		zap.L().Error("添加过期支付订单任务失败", zap.Error(err))
+	}
+
+	// 每天凌晨4点45分核对罚款账务与读者缓存余额
+	_, err = cronScheduler.AddFunc("0 45 4 * * *", func() {
+		report, err := service.GlobalLedgerService.Reconcile(false, model.SystemActor)
+		if err != nil {
+			zap.L().Error("罚款对账失败", zap.Error(err))
+			return
+		}
+		if len(report.UnbalancedTxns) > 0 || len(report.Mismatches) > 0 {
+			zap.L().Warn("罚款账务不一致，请检查对账报告",
+				zap.Int("unbalanced", len(report.UnbalancedTxns)),
+				zap.Int("mismatches", len(report.Mismatches)))
+		}
+	})
+	if err != nil {
+		zap.L().Error("添加罚款对账任务失败", zap.Error(err))
+	}
+
+	// 每天凌晨5点15分清理已执行的待执行事件
+	_, err = cronScheduler.AddFunc("0 15 5 * * *", func() {
+		count, err := service.GlobalOutboxService.CleanupDone()
+		if err != nil {
+			zap.L().Error("清理待执行事件失败", zap.Error(err))
+			return
+		}
+		zap.L().Info("清理待执行事件完成", zap.Int64("count", count))
+	})
+	if err != nil {
+		zap.L().Error("添加清理待执行事件任务失败", zap.Error(err))
+	}
+
 	// 启动调度器
 	cronScheduler.Start()
 	zap.L().Info("定时任务调度器已启动")
@@ -1,14 +1,18 @@
 package initialize
 
 import (
+	"bookadmin/config"
 	"bookadmin/global"
 	"bookadmin/model"
 	"bookadmin/service"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"go.uber.org/zap"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
@@ -26,13 +30,39 @@ func Gorm() *gorm.DB {
 		&model.User{},
 		&model.Reader{},
 		&model.BorrowRecord{},
-		&model.BookLike{},     // 点赞表
-		&model.BookFavorite{}, // 收藏表
-		&model.Reservation{},  // 预约表
-		&model.FineRecord{},   // 罚款记录表
-		&model.Blacklist{},    // 黑名单表
-		&model.SystemConfig{}, // 系统配置表
-		&model.Message{},      // 消息表
+		&model.BookLike{},             // 点赞表
+		&model.BookFavorite{},         // 收藏表
+		&model.Reservation{},          // 预约表
+		&model.FineRecord{},           // 罚款记录表
+		&model.Blacklist{},            // 黑名单表
+		&model.SystemConfig{},         // 系统配置表
+		&model.Message{},              // 消息表
+		&model.BookCopy{},             // 馆藏副本表
+		&model.RolePermission{},       // 角色权限表
+		&model.RolePermissionSeed{},   // 已写入的默认角色权限表
+		&model.AuditLog{},             // 审计日志表
+		&model.OpeningHours{},         // 每周开放时间表
+		&model.CalendarException{},    // 日历例外表
+		&model.LoanPolicy{},           // 借阅规则表
+		&model.BookJob{},              // 图书批量导入导出任务表
+		&model.ReconcileRun{},         // 点赞收藏校对记录表
+		&model.RankingSnapshot{},      // 榜单快照表
+		&model.RankingSnapshotItem{},  // 榜单快照明细表
+		&model.BookReview{},           // 图书评价表
+		&model.BookReviewReport{},     // 评价举报表
+		&model.Recommendation{},       // 推荐结果表
+		&model.MessageTemplate{},      // 消息模板表
+		&model.NotifyPreference{},     // 通知偏好表
+		&model.NotifySetting{},        // 免打扰设置表
+		&model.NotificationDelivery{}, // 通知投递记录表
+		&model.PaymentOrder{},         // 支付订单表
+		&model.PaymentOrderItem{},     // 支付订单明细表
+		&model.PaymentRefund{},        // 退款记录表
+		&model.PaymentCallback{},      // 支付回调记录表
+		&model.LedgerTransaction{},    // 罚款账务交易表
+		&model.LedgerEntry{},          // 罚款账务分录表
+		&model.LossReport{},           // 丢失损坏赔偿记录表
+		&model.OutboxEvent{},          // 待执行事件表
 	)
 	if err != nil {
 		global.GVA_LOG.Error("自动迁移失败", zap.Error(err))
@@ -41,38 +71,404 @@ func Gorm() *gorm.DB {
 	// 初始化系统配置
 	InitSystemConfigs(m)
 
+	// 初始化角色权限
+	InitRolePermissions(m)
+
+	// 审计日志只允许追加
+	InitAuditLogGuard(m)
+
+	// 罚款账务只允许追加
+	InitLedgerGuard(m)
+
+	// 初始化每周开放时间
+	InitOpeningHours(m)
+
+	// 初始化邮件、短信消息模板
+	InitMessageTemplates(m)
+
+	// 初始化读者类型借阅规则（迁移旧的全局借阅配置）
+	InitLoanPolicies(m)
+
+	// 将旧的库存计数迁移为馆藏副本
+	InitBookCopies(m)
+
+	// 升级前产生的罚款补记账
+	if err := service.GlobalLedgerService.Backfill(); err != nil {
+		global.GVA_LOG.Error("罚款账务补记失败", zap.Error(err))
+	}
+
+	// 服务重启前未完成的批量任务标记为失败
+	service.GlobalBookJobService.RecoverInterruptedJobs()
+
 	return m
 }
 
 // InitSystemConfigs 初始化系统配置
+// 每次启动时逐项补齐缺少的配置，升级前已初始化的系统也能获得新增的配置项；已有的配置值不会被覆盖
 func InitSystemConfigs(db *gorm.DB) {
-	// 检查是否已经初始化
+	configs := []model.SystemConfig{
+		// 借阅数量、借期、续借、预约和罚款标准由 loan_policies 按读者类型配置
+		{ConfigKey: model.ConfigOverdueReminderDays, ConfigValue: "0", Description: "到期前提前提醒天数（0表示测试模式30秒）", ConfigType: "int", IsSystem: true},
+		{ConfigKey: model.ConfigOverdueBlockDays, ConfigValue: "7", Description: "逾期多久后禁止借书（天）", ConfigType: "int", IsSystem: true},
+		{ConfigKey: model.ConfigOverdueBlacklistDays, ConfigValue: "30", Description: "逾期多久后自动拉黑（天）", ConfigType: "int", IsSystem: true},
+		{ConfigKey: model.ConfigReviewRequireApproval, ConfigValue: "false", Description: "评价是否需要审核后才公开", ConfigType: "bool", IsSystem: true},
+		{ConfigKey: model.ConfigReviewReportThreshold, ConfigValue: "3", Description: "评价被举报多少次后转为待审核", ConfigType: "int", IsSystem: true},
+		{ConfigKey: model.ConfigLostReplacementRate, ConfigValue: "1", Description: "丢失赔偿金额（图书价格的倍数）", ConfigType: "float", IsSystem: true},
+		{ConfigKey: model.ConfigLostProcessingFee, ConfigValue: "10", Description: "丢失、严重损坏的加工费（元，找回不退）", ConfigType: "float", IsSystem: true},
+		{ConfigKey: model.ConfigLostDefaultPrice, ConfigValue: "50", Description: "图书未填写价格时的计费价格（元）", ConfigType: "float", IsSystem: true},
+		{ConfigKey: model.ConfigDamageMinorRate, ConfigValue: "0.2", Description: "轻微损坏赔偿金额（图书价格的倍数）", ConfigType: "float", IsSystem: true},
+		{ConfigKey: model.ConfigDamageSevereRate, ConfigValue: "1", Description: "严重损坏赔偿金额（图书价格的倍数）", ConfigType: "float", IsSystem: true},
+		{ConfigKey: model.ConfigLostBlacklistDays, ConfigValue: "0", Description: "丢失图书后拉黑天数（0表示不拉黑）", ConfigType: "int", IsSystem: true},
+		{ConfigKey: model.ConfigDamageBlacklistDays, ConfigValue: "0", Description: "损坏图书后拉黑天数（0表示不拉黑）", ConfigType: "int", IsSystem: true},
+	}
+
+	// config_key 唯一，已存在（包括已软删除）的配置项跳过
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&configs)
+	if result.Error != nil {
+		global.GVA_LOG.Error("初始化系统配置失败", zap.Error(result.Error))
+	} else if result.RowsAffected > 0 {
+		global.GVA_LOG.Info("系统配置初始化成功", zap.Int64("count", result.RowsAffected))
+	}
+}
+
+// InitLoanPolicies 初始化读者类型借阅规则（仅在规则表为空时写入）
+// 学生类型沿用旧的全局借阅配置，保证升级前后行为一致；迁移完成后删除已被取代的配置项
+func InitLoanPolicies(db *gorm.DB) {
 	var count int64
-	db.Model(&model.SystemConfig{}).Count(&count)
+	db.Model(&model.LoanPolicy{}).Count(&count)
 	if count > 0 {
-		// 已经有配置数据，跳过初始化
 		return
 	}
 
-	// 初始化默认配置
-	configs := []model.SystemConfig{
-		{ConfigKey: model.ConfigMaxBorrowBooks, ConfigValue: "5", Description: "每个用户最多可借图书数量", ConfigType: "int", IsSystem: true},
-		{ConfigKey: model.ConfigBorrowDays, ConfigValue: "0", Description: "图书借阅期限（天，0表示测试模式1分钟）", ConfigType: "int", IsSystem: true},
-		{ConfigKey: model.ConfigMaxRenewTimes, ConfigValue: "2", Description: "最大续借次数", ConfigType: "int", IsSystem: true},
-		{ConfigKey: model.ConfigRenewDays, ConfigValue: "15", Description: "每次续借延长天数", ConfigType: "int", IsSystem: true},
-		{ConfigKey: model.ConfigMaxReservations, ConfigValue: "3", Description: "每个用户最多预约数量", ConfigType: "int", IsSystem: true},
-		{ConfigKey: model.ConfigReservationPickupDays, ConfigValue: "3", Description: "预约取书有效期（天）", ConfigType: "int", IsSystem: true},
-		{ConfigKey: model.ConfigOverdueFinePerDay, ConfigValue: "0.5", Description: "逾期罚款（元/天）", ConfigType: "float", IsSystem: true},
-		{ConfigKey: model.ConfigMaxFineRate, ConfigValue: "0.5", Description: "罚款上限比例（图书价格的百分比）", ConfigType: "float", IsSystem: true},
-		{ConfigKey: model.ConfigOverdueReminderDays, ConfigValue: "0", Description: "到期前提前提醒天数（0表示测试模式30秒）", ConfigType: "int", IsSystem: true},
-		{ConfigKey: model.ConfigOverdueBlockDays, ConfigValue: "7", Description: "逾期多久后禁止借书（天）", ConfigType: "int", IsSystem: true},
-		{ConfigKey: model.ConfigOverdueBlacklistDays, ConfigValue: "30", Description: "逾期多久后自动拉黑（天）", ConfigType: "int", IsSystem: true},
+	var legacy []model.SystemConfig
+	db.Where("config_key IN ?", model.LegacyLoanConfigKeys).Find(&legacy)
+	values := make(map[string]string, len(legacy))
+	for _, c := range legacy {
+		values[c.ConfigKey] = c.ConfigValue
+	}
+	legacyInt := func(key string, def int) *int {
+		v, err := strconv.Atoi(values[key])
+		if err != nil {
+			v = def
+		}
+		return &v
+	}
+	legacyFloat := func(key string, def float64) *float64 {
+		v, err := strconv.ParseFloat(values[key], 64)
+		if err != nil {
+			v = def
+		}
+		return &v
 	}
+	intPtr := func(v int) *int { return &v }
+	floatPtr := func(v float64) *float64 { return &v }
 
-	if err := db.Create(&configs).Error; err != nil {
-		global.GVA_LOG.Error("初始化系统配置失败", zap.Error(err))
+	policies := []model.LoanPolicy{
+		{
+			PatronCategory:  model.PatronStudent,
+			MaxBorrow:       legacyInt(model.ConfigMaxBorrowBooks, 5),
+			BorrowDays:      legacyInt(model.ConfigBorrowDays, 30),
+			MaxRenew:        legacyInt(model.ConfigMaxRenewTimes, 2),
+			RenewDays:       legacyInt(model.ConfigRenewDays, 15),
+			MaxReservations: legacyInt(model.ConfigMaxReservations, 3),
+			PickupDays:      legacyInt(model.ConfigReservationPickupDays, 3),
+			FinePerDay:      legacyFloat(model.ConfigOverdueFinePerDay, 0.5),
+			MaxFineRate:     legacyFloat(model.ConfigMaxFineRate, 0.5),
+		},
+		{
+			PatronCategory:  model.PatronStaff,
+			MaxBorrow:       intPtr(10),
+			BorrowDays:      intPtr(60),
+			MaxRenew:        intPtr(2),
+			RenewDays:       intPtr(30),
+			MaxReservations: intPtr(5),
+			PickupDays:      intPtr(5),
+			FinePerDay:      floatPtr(0.5),
+			MaxFineRate:     floatPtr(0.5),
+		},
+		{
+			PatronCategory:  model.PatronFaculty,
+			MaxBorrow:       intPtr(20),
+			BorrowDays:      intPtr(90),
+			MaxRenew:        intPtr(3),
+			RenewDays:       intPtr(30),
+			MaxReservations: intPtr(10),
+			PickupDays:      intPtr(7),
+			FinePerDay:      floatPtr(0.3),
+			MaxFineRate:     floatPtr(0.5),
+		},
+		{
+			PatronCategory:  model.PatronGuest,
+			MaxBorrow:       intPtr(2),
+			BorrowDays:      intPtr(14),
+			MaxRenew:        intPtr(0),
+			RenewDays:       intPtr(0),
+			MaxReservations: intPtr(1),
+			PickupDays:      intPtr(2),
+			FinePerDay:      floatPtr(1),
+			MaxFineRate:     floatPtr(1),
+		},
+	}
+
+	if err := db.Create(&policies).Error; err != nil {
+		global.GVA_LOG.Error("初始化借阅规则失败", zap.Error(err))
+		return
+	}
+
+	for _, c := range legacy {
+		global.GVA_LOG.Info("旧借阅配置已迁移到学生借阅规则", zap.String("key", c.ConfigKey), zap.String("value", c.ConfigValue))
+	}
+	reportLegacyReaderLimits(db, &policies[0])
+
+	// 旧配置软删除，保留原值备查
+	if len(legacy) > 0 {
+		db.Where("config_key IN ?", model.LegacyLoanConfigKeys).Delete(&model.SystemConfig{})
+	}
+	global.GVA_LOG.Info("借阅规则初始化成功", zap.Int("count", len(policies)), zap.Int("migrated_configs", len(legacy)))
+}
+
+// reportLegacyReaderLimits 列出旧版 readers 表中单独设置了借阅数量、借阅天数、续借次数的读者
+// 这些读者升级后统一按读者类型的借阅规则借阅，需要管理员调整读者类型或新增规则
+func reportLegacyReaderLimits(db *gorm.DB, student *model.LoanPolicy) {
+	m := db.Migrator()
+	if !m.HasColumn(&model.Reader{}, "max_borrow") || !m.HasColumn(&model.Reader{}, "borrow_days") ||
+		!m.HasColumn(&model.Reader{}, "max_renew") {
+		return
+	}
+
+	var rows []struct {
+		ID         uint
+		ReaderNo   string
+		MaxBorrow  int
+		BorrowDays int
+		MaxRenew   int
+	}
+	if err := db.Table("readers").Select("id, reader_no, max_borrow, borrow_days, max_renew").
+		Where("deleted_at IS NULL AND (max_borrow <> ? OR borrow_days <> ? OR max_renew <> ?)",
+			*student.MaxBorrow, *student.BorrowDays, *student.MaxRenew).
+		Order("id ASC").Scan(&rows).Error; err != nil {
+		global.GVA_LOG.Error("查询旧版读者借阅限制失败", zap.Error(err))
+		return
+	}
+
+	for _, r := range rows {
+		global.GVA_LOG.Warn("读者的旧借阅限制与学生借阅规则不同，升级后按读者类型规则借阅，请调整读者类型",
+			zap.Uint("reader_id", r.ID), zap.String("reader_no", r.ReaderNo),
+			zap.Int("max_borrow", r.MaxBorrow), zap.Int("borrow_days", r.BorrowDays), zap.Int("max_renew", r.MaxRenew))
+	}
+	if len(rows) > 0 {
+		global.GVA_LOG.Warn("存在单独设置借阅限制的旧读者", zap.Int("count", len(rows)))
+	}
+}
+
+// InitBookCopies 将旧版整型库存迁移为馆藏副本
+// 只处理还没有任何副本记录的图书：按 TotalStock 生成副本，并把未归还的借阅记录绑定到副本上
+func InitBookCopies(db *gorm.DB) {
+	var books []model.Book
+	if err := db.Where("total_stock > 0 AND id NOT IN (?)",
+		db.Unscoped().Model(&model.BookCopy{}).Select("book_id")).
+		Find(&books).Error; err != nil {
+		global.GVA_LOG.Error("查询待迁移图书失败", zap.Error(err))
+		return
+	}
+
+	copyService := service.NewBookCopyService()
+	for _, book := range books {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var activeRecords []model.BorrowRecord
+			if err := tx.Where("book_id = ? AND copy_id IS NULL AND status IN ?", book.ID, []model.BorrowStatus{
+				model.BorrowStatusBorrowed,
+				model.BorrowStatusOverdue,
+			}).Order("id ASC").Find(&activeRecords).Error; err != nil {
+				return err
+			}
+
+			total := book.TotalStock
+			if len(activeRecords) > total {
+				total = len(activeRecords)
+			}
+
+			copies := make([]model.BookCopy, total)
+			for i := range copies {
+				copies[i].Remark = "由库存计数迁移生成"
+				if i < len(activeRecords) {
+					copies[i].Status = model.CopyStatusBorrowed
+				}
+			}
+			copies, err := copyService.CreateCopies(tx, book.ID, copies)
+			if err != nil {
+				return err
+			}
+
+			for i, record := range activeRecords {
+				if err := tx.Model(&model.BorrowRecord{}).Where("id = ?", record.ID).
+					Update("copy_id", copies[i].ID).Error; err != nil {
+					return err
+				}
+			}
+
+			return copyService.SyncBookStock(tx, book.ID)
+		})
+		if err != nil {
+			global.GVA_LOG.Error("迁移馆藏副本失败", zap.Uint("book_id", book.ID), zap.Error(err))
+			continue
+		}
+		global.GVA_LOG.Info("迁移馆藏副本成功", zap.Uint("book_id", book.ID), zap.Int("total_stock", book.TotalStock))
+	}
+}
+
+// InitRolePermissions 初始化角色权限
+// 每次启动时补写从未写入过的默认权限（记录在 role_permission_seeds 中），新版本新增的默认权限会授予已有系统；
+// 已写入过、后来被管理员撤销的权限不会重新授予
+func InitRolePermissions(db *gorm.DB) {
+	var seeds []model.RolePermissionSeed
+	if err := db.Find(&seeds).Error; err != nil {
+		global.GVA_LOG.Error("查询已写入的默认角色权限失败", zap.Error(err))
+		return
+	}
+	seeded := make(map[string]bool, len(seeds))
+	for _, seed := range seeds {
+		seeded[string(seed.Role)+"|"+string(seed.Permission)] = true
+	}
+
+	var pending []model.RolePermissionSeed
+	for role, perms := range model.DefaultRolePermissions {
+		for _, p := range perms {
+			if !seeded[string(role)+"|"+string(p)] {
+				pending = append(pending, model.RolePermissionSeed{Role: role, Permission: p})
+			}
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		records := make([]model.RolePermission, 0, len(pending))
+		for _, seed := range pending {
+			records = append(records, model.RolePermission{Role: seed.Role, Permission: seed.Permission})
+		}
+		// 角色已拥有的权限跳过
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&records).Error; err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&pending).Error
+	})
+	if err != nil {
+		global.GVA_LOG.Error("初始化角色权限失败", zap.Error(err))
+		return
+	}
+
+	for _, seed := range pending {
+		global.GVA_LOG.Info("写入默认角色权限", zap.String("role", string(seed.Role)), zap.String("permission", string(seed.Permission)))
+	}
+	global.GVA_LOG.Info("角色权限初始化成功", zap.Int("count", len(pending)))
+}
+
+// InitAuditLogGuard 为审计日志表创建触发器，禁止修改和删除记录
+func InitAuditLogGuard(db *gorm.DB) {
+	triggers := map[string]string{
+		"audit_logs_no_update": "CREATE TRIGGER audit_logs_no_update BEFORE UPDATE ON audit_logs FOR EACH ROW " +
+			"SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_logs is append-only'",
+		"audit_logs_no_delete": "CREATE TRIGGER audit_logs_no_delete BEFORE DELETE ON audit_logs FOR EACH ROW " +
+			"SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_logs is append-only'",
+	}
+
+	for name, ddl := range triggers {
+		var count int64
+		db.Raw("SELECT COUNT(*) FROM information_schema.TRIGGERS WHERE TRIGGER_SCHEMA = DATABASE() AND TRIGGER_NAME = ?", name).
+			Scan(&count)
+		if count > 0 {
+			continue
+		}
+		if err := db.Exec(ddl).Error; err != nil {
+			// 缺少TRIGGER权限时仅告警，应用层本身不提供修改和删除审计日志的接口
+			global.GVA_LOG.Warn("创建审计日志触发器失败", zap.String("trigger", name), zap.Error(err))
+		}
+	}
+}
+
+// InitLedgerGuard 为罚款账务表创建触发器，禁止修改和删除交易和分录，更正只能通过调整交易
+func InitLedgerGuard(db *gorm.DB) {
+	for _, table := range []string{"ledger_transactions", "ledger_entries"} {
+		for _, op := range []string{"UPDATE", "DELETE"} {
+			name := table + "_no_" + strings.ToLower(op)
+			var count int64
+			db.Raw("SELECT COUNT(*) FROM information_schema.TRIGGERS WHERE TRIGGER_SCHEMA = DATABASE() AND TRIGGER_NAME = ?", name).
+				Scan(&count)
+			if count > 0 {
+				continue
+			}
+			ddl := fmt.Sprintf("CREATE TRIGGER %s BEFORE %s ON %s FOR EACH ROW "+
+				"SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = '%s is append-only'", name, op, table, table)
+			if err := db.Exec(ddl).Error; err != nil {
+				global.GVA_LOG.Warn("创建罚款账务触发器失败", zap.String("trigger", name), zap.Error(err))
+			}
+		}
+	}
+}
+
+// InitOpeningHours 初始化每周开放时间（默认每天 08:00-22:00 开放）
+func InitOpeningHours(db *gorm.DB) {
+	var count int64
+	db.Model(&model.OpeningHours{}).Count(&count)
+	if count > 0 {
+		return
+	}
+
+	hours := make([]model.OpeningHours, 0, 7)
+	for weekday := 0; weekday < 7; weekday++ {
+		hours = append(hours, model.OpeningHours{
+			Weekday:   weekday,
+			IsOpen:    true,
+			OpenTime:  "08:00",
+			CloseTime: "22:00",
+		})
+	}
+
+	if err := db.Create(&hours).Error; err != nil {
+		global.GVA_LOG.Error("初始化开放时间失败", zap.Error(err))
+	}
+}
+
+// InitMessageTemplates 初始化邮件、短信消息模板
+func InitMessageTemplates(db *gorm.DB) {
+	var count int64
+	db.Model(&model.MessageTemplate{}).Count(&count)
+	if count > 0 {
+		return
+	}
+
+	templates := model.DefaultMessageTemplates()
+	if err := db.Create(&templates).Error; err != nil {
+		global.GVA_LOG.Error("初始化消息模板失败", zap.Error(err))
+	}
+}
+
+// InitCalendarCache 初始化开馆日历缓存
+func InitCalendarCache() {
+	if err := service.GlobalCalendarService.RefreshCache(); err != nil {
+		global.GVA_LOG.Error("开馆日历缓存初始化失败", zap.Error(err))
 	} else {
-		global.GVA_LOG.Info("系统配置初始化成功", zap.Int("count", len(configs)))
+		global.GVA_LOG.Info("开馆日历缓存初始化成功")
+	}
+}
+
+// InitPolicyCache 初始化借阅规则缓存
+func InitPolicyCache() {
+	if err := service.GlobalPolicyService.RefreshCache(); err != nil {
+		global.GVA_LOG.Error("借阅规则缓存初始化失败", zap.Error(err))
+	} else {
+		global.GVA_LOG.Info("借阅规则缓存初始化成功")
+	}
+}
+
+// InitPermissionCache 初始化权限缓存
+func InitPermissionCache() {
+	if err := service.GlobalPermissionService.RefreshCache(); err != nil {
+		global.GVA_LOG.Error("权限缓存初始化失败", zap.Error(err))
+	} else {
+		global.GVA_LOG.Info("权限缓存初始化成功")
 	}
 }
 
@@ -86,22 +482,14 @@ func InitConfigCache() {
 	}
 }
 
-func GormMysql() *gorm.DB {
+func GormMysql(cfg config.Mysql) *gorm.DB {
 	m := global.GVA_DB
 	if m != nil {
 		return m
 	}
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?%s",
-		"root",
-		"root",
-		"127.0.0.1:3306",
-		"bookadmin",
-		"charset=utf8mb4&parseTime=True&loc=Local",
-	)
-
 	mysqlConfig := mysql.Config{
-		DSN:                       dsn,
+		DSN:                       cfg.Dsn(),
 		DefaultStringSize:         191,
 		DisableDatetimePrecision:  true,
 		DontSupportRenameIndex:    true,
@@ -109,15 +497,20 @@ func GormMysql() *gorm.DB {
 		SkipInitializeWithVersion: false,
 	}
 
+	logLevel := logger.Warn
+	if cfg.LogMode {
+		logLevel = logger.Info
+	}
+
 	if db, err := gorm.Open(mysql.New(mysqlConfig), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: logger.Default.LogMode(logLevel),
 	}); err != nil {
 		global.GVA_LOG.Error("MySQL启动异常", zap.Error(err))
 		return nil
 	} else {
 		sqlDB, _ := db.DB()
-		sqlDB.SetMaxIdleConns(10)
-		sqlDB.SetMaxOpenConns(100)
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
 		global.GVA_DB = db
 		return db
 	}
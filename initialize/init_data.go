@@ -3,6 +3,7 @@ package initialize
 import (
 	"bookadmin/global"
 	"bookadmin/model"
+	"bookadmin/utils"
 
 	"go.uber.org/zap"
 )
@@ -12,9 +13,10 @@ func InitData() {
 	// 创建默认管理员账户
 	var adminUser model.User
 	if err := global.GVA_DB.Where("username = ?", "admin").First(&adminUser).Error; err != nil {
+		hashedPassword, _ := utils.HashPassword("admin123")
 		adminUser = model.User{
 			Username: "admin",
-			Password: "admin123", // 简化处理，实际应加密
+			Password: hashedPassword,
 			Email:    "admin@bookadmin.com",
 			Role:     model.RoleAdmin,
 			Status:   "active",
@@ -30,9 +32,10 @@ func InitData() {
 	// 创建默认图书管理员
 	var librarianUser model.User
 	if err := global.GVA_DB.Where("username = ?", "librarian").First(&librarianUser).Error; err != nil {
+		hashedPassword, _ := utils.HashPassword("librarian123")
 		librarianUser = model.User{
 			Username: "librarian",
-			Password: "librarian123",
+			Password: hashedPassword,
 			Email:    "librarian@bookadmin.com",
 			Role:     model.RoleLibrarian,
 			Status:   "active",
@@ -0,0 +1,21 @@
+package initialize
+
+import (
+	"bookadmin/config"
+	"bookadmin/global"
+	"bookadmin/service"
+
+	"go.uber.org/zap"
+)
+
+// InitNotify 初始化邮件、短信通知渠道并启动发送循环
+func InitNotify(cfg config.Notify) {
+	if err := service.GlobalNotifyDispatchService.Configure(cfg); err != nil {
+		global.GVA_LOG.Error("通知渠道初始化失败", zap.Error(err))
+	} else {
+		global.GVA_LOG.Info("通知渠道初始化成功",
+			zap.Bool("email", cfg.SMTP.Host != ""),
+			zap.String("sms", cfg.SMS.Provider))
+	}
+	service.GlobalNotifyDispatchService.Start()
+}
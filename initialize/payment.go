@@ -0,0 +1,19 @@
+package initialize
+
+import (
+	"bookadmin/config"
+	"bookadmin/global"
+	"bookadmin/service"
+
+	"go.uber.org/zap"
+)
+
+// InitPayment 初始化罚款在线支付渠道
+func InitPayment(cfg config.Payment) {
+	service.GlobalPaymentService.Configure(cfg)
+	if cfg.Provider == "" {
+		global.GVA_LOG.Info("未配置支付渠道，不开放罚款在线支付")
+		return
+	}
+	global.GVA_LOG.Info("罚款在线支付渠道初始化成功", zap.String("provider", cfg.Provider))
+}
@@ -1,7 +1,10 @@
 package initialize
 
 import (
+	"bookadmin/config"
 	"bookadmin/global"
+	"bookadmin/model"
+	"bookadmin/service"
 	"context"
 	"fmt"
 	"time"
@@ -11,17 +14,17 @@ import (
 )
 
 // Redis 初始化Redis连接
-func Redis() *redis.Client {
+func Redis(cfg config.Redis) *redis.Client {
 	client := redis.NewClient(&redis.Options{
-		Addr:         "127.0.0.1:6379", // Redis地址
-		Password:     "",               // Redis密码
-		DB:           0,                // Redis数据库
-		PoolSize:     100,              // 连接池大小
-		MinIdleConns: 10,               // 最小空闲连接数
-		MaxRetries:   3,                // 最大重试次数
-		DialTimeout:  5 * time.Second,  // 连接超时
-		ReadTimeout:  3 * time.Second,  // 读取超时
-		WriteTimeout: 3 * time.Second,  // 写入超时
+		Addr:         cfg.Addr,                                      // Redis地址
+		Password:     cfg.Password,                                  // Redis密码
+		DB:           cfg.DB,                                        // Redis数据库
+		PoolSize:     cfg.PoolSize,                                  // 连接池大小
+		MinIdleConns: cfg.MinIdleConns,                              // 最小空闲连接数
+		MaxRetries:   cfg.MaxRetries,                                // 最大重试次数
+		DialTimeout:  time.Duration(cfg.DialTimeout) * time.Second,  // 连接超时
+		ReadTimeout:  time.Duration(cfg.ReadTimeout) * time.Second,  // 读取超时
+		WriteTimeout: time.Duration(cfg.WriteTimeout) * time.Second, // 写入超时
 	})
 
 	// 测试连接
@@ -31,14 +34,39 @@ func Redis() *redis.Client {
 	_, err := client.Ping(ctx).Result()
 	if err != nil {
 		global.GVA_LOG.Error("Redis连接失败", zap.Error(err))
+		// 启动时连接失败也持续检查，恢复后自动切回Redis模式
+		service.GlobalRedisHealth.Watch(client, false, err)
 		return nil
 	}
 
 	global.GVA_LOG.Info("Redis连接成功")
 	global.GVA_REDIS = client
+	service.GlobalRedisHealth.Watch(client, true, nil)
 	return client
 }
 
+// WarmUpRedis Redis恢复后预热缓存：重建榜单，并用MySQL数据校对点赞/收藏缓存
+func WarmUpRedis() {
+	ctx := context.Background()
+	if err := service.NewRankingService().RebuildAllRankings(ctx); err != nil {
+		global.GVA_LOG.Error("Redis恢复后重建榜单失败", zap.Error(err))
+	}
+
+	// 降级前写入的同步消息处理完之前无法校对Redis，稍等后重试
+	for i := 0; i < 3; i++ {
+		run, err := service.GlobalReconcileService.Run("recover", false, model.SystemActor)
+		if err != nil {
+			global.GVA_LOG.Error("Redis恢复后校对缓存失败", zap.Error(err))
+			return
+		}
+		if !run.RedisSkipped {
+			return
+		}
+		time.Sleep(20 * time.Second)
+	}
+	global.GVA_LOG.Warn("Redis恢复后同步队列仍未处理完，缓存校对留待定时任务执行")
+}
+
 // InitRedisStreamGroups 初始化Redis Stream消费者组
 func InitRedisStreamGroups() {
 	if global.GVA_REDIS == nil {
@@ -0,0 +1,18 @@
+package initialize
+
+import (
+	"bookadmin/config"
+	"bookadmin/global"
+	"bookadmin/service"
+
+	"go.uber.org/zap"
+)
+
+// InitSearch 初始化图书搜索引擎并建立索引
+func InitSearch(cfg config.Search) {
+	if err := service.GlobalSearchService.Use(cfg.Engine); err != nil {
+		global.GVA_LOG.Error("图书搜索引擎初始化失败", zap.String("engine", cfg.Engine), zap.Error(err))
+	} else {
+		global.GVA_LOG.Info("图书搜索引擎初始化成功", zap.String("engine", cfg.Engine))
+	}
+}
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"bookadmin/config"
+	"bookadmin/global"
 	"bookadmin/initialize"
 	"bookadmin/router"
+	"bookadmin/service"
+	"bookadmin/utils"
 	"bookadmin/worker"
+	"flag"
 	"fmt"
+	"log"
 	"os"
 	"os/signal"
 	"syscall"
@@ -13,11 +19,26 @@ import (
 )
 
 func main() {
+	// 加载配置：-c 参数优先，其次 BOOKADMIN_CONFIG 环境变量
+	configPath := os.Getenv("BOOKADMIN_CONFIG")
+	if configPath == "" {
+		configPath = "config.yaml"
+	}
+	flag.StringVar(&configPath, "c", configPath, "配置文件路径")
+	flag.Parse()
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+	global.GVA_CONFIG = cfg
+	utils.InitJWT(cfg.JWT)
+
 	// 初始化日志
 	initialize.Zap()
 
 	// 初始化数据库
-	if initialize.GormMysql() == nil {
+	if initialize.GormMysql(cfg.Mysql) == nil {
 		zap.L().Error("数据库连接失败")
 		return
 	}
@@ -28,27 +49,60 @@ func main() {
 	// 初始化配置缓存
 	initialize.InitConfigCache()
 
+	// 初始化权限缓存
+	initialize.InitPermissionCache()
+
+	// 初始化开馆日历缓存
+	initialize.InitCalendarCache()
+
+	// 初始化借阅规则缓存
+	initialize.InitPolicyCache()
+
+	// 初始化图书搜索索引
+	initialize.InitSearch(cfg.Search)
+
+	// 初始化邮件、短信通知渠道
+	initialize.InitNotify(cfg.Notify)
+
+	// 初始化罚款在线支付渠道
+	initialize.InitPayment(cfg.Payment)
+
+	// 异步Worker池（5个Worker），Redis可用时启动
+	workerPool := worker.NewWorkerPool(5)
+
+	// Redis恢复后：创建消费者组、启动Worker、订阅通知频道、预热缓存，完成后切回Redis模式
+	service.GlobalRedisHealth.OnRecover(func() {
+		initialize.InitRedisStreamGroups()
+		workerPool.Start()
+		service.GlobalNotifyService.Start()
+		initialize.WarmUpRedis()
+	})
+
 	// 初始化Redis
-	var workerPool *worker.WorkerPool
-	if initialize.Redis() == nil {
-		zap.L().Warn("Redis连接失败，点赞/收藏功能将受限")
+	if initialize.Redis(cfg.Redis) == nil {
+		zap.L().Warn("Redis连接失败，点赞/收藏/榜单降级为只使用MySQL，Redis恢复后自动切回")
 	} else {
 		// 初始化Redis Stream消费者组
 		initialize.InitRedisStreamGroups()
 
-		// 启动异步Worker池（5个Worker）
-		workerPool = worker.NewWorkerPool(5)
+		// 启动异步Worker池
 		workerPool.Start()
+
+		// 订阅实时通知频道
+		service.GlobalNotifyService.Start()
 	}
 
 	// 初始化默认数据
 	initialize.InitData()
 
+	// 启动待执行事件循环（借还书、预约后的通知预约者、站内消息、榜单）
+	service.GlobalOutboxService.Start()
+
 	// 初始化定时任务
 	initialize.InitCronJobs()
 
 	// 初始化路由
-	Router := router.InitRouter()
+	Router := router.InitRouter(cfg.Server)
 
 	// 优雅关闭
 	quit := make(chan os.Signal, 1)
@@ -57,15 +111,13 @@ func main() {
 	go func() {
 		<-quit
 		zap.L().Info("收到关闭信号，正在优雅关闭...")
-		if workerPool != nil {
-			workerPool.Stop()
-		}
+		workerPool.Stop()
 		initialize.StopCronJobs()
 		os.Exit(0)
 	}()
 
 	// 启动服务器
-	port := 8888
+	port := cfg.Server.Port
 	zap.L().Info(fmt.Sprintf("服务器启动在端口: %d", port))
 	if err := Router.Run(fmt.Sprintf(":%d", port)); err != nil {
 		zap.L().Error("服务器启动失败", zap.Error(err))
@@ -1,14 +1,16 @@
 package middleware
 
 import (
-	"bookadmin/model"
 	"bookadmin/model/common/response"
+	"bookadmin/service"
 	"bookadmin/utils"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+var tokenService = service.NewTokenService()
+
 // JWTAuth JWT认证中间件
 func JWTAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -28,55 +30,50 @@ func JWTAuth() gin.HandlerFunc {
 			return
 		}
 
-		// 解析token
-		claims, err := utils.ParseToken(parts[1])
-		if err != nil {
-			c.JSON(200, response.FailWithMessage("未登录或非法访问"))
-			c.Abort()
-			return
-		}
-
-		// 将用户信息存储到上下文
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Set("role", claims.Role)
-
-		c.Next()
+		authenticate(c, parts[1])
 	}
 }
 
-// RequireRole 要求特定角色
-func RequireRole(roles ...model.UserRole) gin.HandlerFunc {
+// JWTAuthStream 推送连接的认证中间件
+// 浏览器的 EventSource 不能设置请求头，除 Authorization 头外也接受 access_token 查询参数
+func JWTAuthStream() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("role")
-		if !exists {
-			c.JSON(200, response.FailWithMessage("未登录或非法访问"))
-			c.Abort()
-			return
+		token := c.Query("access_token")
+		if parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2); len(parts) == 2 && parts[0] == "Bearer" {
+			token = parts[1]
 		}
-
-		userRole, ok := role.(model.UserRole)
-		if !ok {
+		if token == "" {
 			c.JSON(200, response.FailWithMessage("未登录或非法访问"))
 			c.Abort()
 			return
 		}
 
-		// 检查是否有权限
-		hasPermission := false
-		for _, r := range roles {
-			if userRole == r {
-				hasPermission = true
-				break
-			}
-		}
+		authenticate(c, token)
+	}
+}
 
-		if !hasPermission {
-			c.JSON(200, response.FailWithMessage("权限不足"))
-			c.Abort()
-			return
-		}
+// authenticate 校验令牌并将用户信息存储到上下文
+func authenticate(c *gin.Context, token string) {
+	// 解析token
+	claims, err := utils.ParseToken(token)
+	if err != nil {
+		c.JSON(200, response.FailWithMessage("未登录或非法访问"))
+		c.Abort()
+		return
+	}
 
-		c.Next()
+	// 检查令牌是否已被吊销
+	if err := tokenService.ValidateAccess(c.Request.Context(), claims); err != nil {
+		c.JSON(200, response.FailWithMessage("登录已失效，请重新登录"))
+		c.Abort()
+		return
 	}
+
+	// 将用户信息存储到上下文
+	c.Set("claims", claims)
+	c.Set("user_id", claims.UserID)
+	c.Set("username", claims.Username)
+	c.Set("role", claims.Role)
+
+	c.Next()
 }
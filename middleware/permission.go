@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"bookadmin/model"
+	"bookadmin/model/common/response"
+	"bookadmin/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission 要求当前用户的角色拥有全部指定权限
+func RequirePermission(perms ...model.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, exists := c.Get("role")
+		if !exists {
+			c.JSON(200, response.FailWithMessage("未登录或非法访问"))
+			c.Abort()
+			return
+		}
+
+		userRole, ok := role.(model.UserRole)
+		if !ok {
+			c.JSON(200, response.FailWithMessage("未登录或非法访问"))
+			c.Abort()
+			return
+		}
+
+		if !service.GlobalPermissionService.HasPermission(userRole, perms...) {
+			c.JSON(200, response.FailWithMessage("权限不足"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
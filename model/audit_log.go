@@ -0,0 +1,70 @@
+package model
+
+import (
+	"time"
+)
+
+// AuditAction 审计动作
+type AuditAction string
+
+const (
+	AuditBookCreate       AuditAction = "book.create"       // 新建图书
+	AuditBookUpdate       AuditAction = "book.update"       // 修改图书
+	AuditBookDelete       AuditAction = "book.delete"       // 删除图书
+	AuditCopyAdd          AuditAction = "copy.add"          // 新增副本
+	AuditCopyRetire       AuditAction = "copy.retire"       // 剔旧副本
+	AuditCopyRelocate     AuditAction = "copy.relocate"     // 调整架位
+	AuditCopyRepair       AuditAction = "copy.repair"       // 修补副本上架
+	AuditReaderStatus     AuditAction = "reader.status"     // 修改读者状态
+	AuditReaderUpdate     AuditAction = "reader.update"     // 修改读者信息
+	AuditFineWaive        AuditAction = "fine.waive"        // 豁免罚款
+	AuditBlacklistAdd     AuditAction = "blacklist.add"     // 加入黑名单
+	AuditBlacklistRemove  AuditAction = "blacklist.remove"  // 解除黑名单
+	AuditConfigUpdate     AuditAction = "config.update"     // 修改系统配置
+	AuditUserCreate       AuditAction = "user.create"       // 创建用户
+	AuditUserUpdate       AuditAction = "user.update"       // 修改用户
+	AuditUserDelete       AuditAction = "user.delete"       // 删除用户
+	AuditPermissionUpdate AuditAction = "permission.update" // 修改角色权限
+	AuditCalendarUpdate   AuditAction = "calendar.update"   // 修改开馆日历
+	AuditPolicyUpdate     AuditAction = "policy.update"     // 修改借阅规则
+	AuditSyncReplay       AuditAction = "sync.replay"       // 重放死信消息
+	AuditSyncDiscard      AuditAction = "sync.discard"      // 丢弃死信消息
+	AuditSyncReconcile    AuditAction = "sync.reconcile"    // 手动执行点赞收藏校对
+	AuditReviewModerate   AuditAction = "review.moderate"   // 审核/隐藏图书评价
+	AuditTemplateUpdate   AuditAction = "template.update"   // 修改消息模板
+	AuditPaymentRefund    AuditAction = "payment.refund"    // 支付订单退款
+	AuditLedgerAdjust     AuditAction = "ledger.adjust"     // 人工调整罚款账务
+	AuditLedgerReconcile  AuditAction = "ledger.reconcile"  // 按账务修正读者缓存余额
+	AuditLossLost         AuditAction = "loss.lost"         // 登记图书丢失
+	AuditLossDamage       AuditAction = "loss.damage"       // 损坏还书
+	AuditLossFound        AuditAction = "loss.found"        // 丢失的图书找回
+	AuditOutboxRetry      AuditAction = "outbox.retry"      // 重新执行失败的待执行事件
+)
+
+// AuditActor 操作人信息
+type AuditActor struct {
+	UserID   uint   // 操作人ID，系统任务为0
+	Username string // 操作人用户名
+	IP       string // 客户端IP
+}
+
+// SystemActor 定时任务等系统自动操作使用的操作人
+var SystemActor = AuditActor{Username: "system"}
+
+// AuditLog 审计日志表（只追加，不允许修改和删除）
+type AuditLog struct {
+	ID         uint        `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time   `json:"created_at" gorm:"index;comment:操作时间"`
+	ActorID    uint        `json:"actor_id" gorm:"index;comment:操作人ID"`
+	ActorName  string      `json:"actor_name" gorm:"type:varchar(64);comment:操作人用户名"`
+	Action     AuditAction `json:"action" gorm:"type:varchar(64);index;not null;comment:动作"`
+	TargetType string      `json:"target_type" gorm:"type:varchar(64);index:idx_audit_target;comment:目标实体类型"`
+	TargetID   uint        `json:"target_id" gorm:"index:idx_audit_target;comment:目标实体ID"`
+	Before     string      `json:"before" gorm:"type:text;comment:变更前（JSON）"`
+	After      string      `json:"after" gorm:"type:text;comment:变更后（JSON）"`
+	IP         string      `json:"ip" gorm:"type:varchar(64);comment:客户端IP"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
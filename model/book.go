@@ -37,6 +37,8 @@ type Book struct {
 	AvailableStock int            `json:"available_stock" gorm:"default:0;comment:可借库存"`
 	LikeCount      int            `json:"like_count" gorm:"default:0;index;comment:点赞总数"`
 	FavoriteCount  int            `json:"favorite_count" gorm:"default:0;index;comment:收藏总数"`
+	RatingAvg      float64        `json:"rating_avg" gorm:"type:decimal(3,2);default:0;comment:平均评分（已公开的评价）"`
+	RatingCount    int            `json:"rating_count" gorm:"default:0;comment:评分人数（已公开的评价）"`
 }
 
 func (Book) TableName() string {
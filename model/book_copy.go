@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BookCopyStatus 馆藏副本状态
+type BookCopyStatus string
+
+const (
+	CopyStatusAvailable   BookCopyStatus = "available"   // 在架可借
+	CopyStatusBorrowed    BookCopyStatus = "borrowed"    // 已借出
+	CopyStatusMaintenance BookCopyStatus = "maintenance" // 修补中
+	CopyStatusLost        BookCopyStatus = "lost"        // 已丢失
+	CopyStatusRetired     BookCopyStatus = "retired"     // 已剔旧（下架）
+)
+
+// BookCopyCondition 副本品相
+type BookCopyCondition string
+
+const (
+	CopyConditionNew     BookCopyCondition = "new"     // 全新
+	CopyConditionGood    BookCopyCondition = "good"    // 良好
+	CopyConditionFair    BookCopyCondition = "fair"    // 一般
+	CopyConditionDamaged BookCopyCondition = "damaged" // 破损
+)
+
+// BookCopy 馆藏副本表（每一本实体书对应一条记录）
+type BookCopy struct {
+	gorm.Model
+	BookID          uint              `json:"book_id" gorm:"not null;index;comment:图书ID"`
+	Book            *Book             `json:"book,omitempty" gorm:"foreignKey:BookID"`
+	Barcode         string            `json:"barcode" gorm:"type:varchar(64);uniqueIndex;not null;comment:条码号"`
+	ShelfLocation   string            `json:"shelf_location" gorm:"type:varchar(100);comment:架位"`
+	Condition       BookCopyCondition `json:"condition" gorm:"column:copy_condition;type:enum('new','good','fair','damaged');default:'good';comment:品相"`
+	AcquisitionDate *time.Time        `json:"acquisition_date" gorm:"comment:入藏日期"`
+	Status          BookCopyStatus    `json:"status" gorm:"type:enum('available','borrowed','maintenance','lost','retired');default:'available';comment:状态;index"`
+	RetiredDate     *time.Time        `json:"retired_date" gorm:"comment:剔旧日期"`
+	Remark          string            `json:"remark" gorm:"type:text;comment:备注"`
+}
+
+func (BookCopy) TableName() string {
+	return "book_copies"
+}
+
+// IsValidCopyCondition 校验品相取值
+func IsValidCopyCondition(condition BookCopyCondition) bool {
+	switch condition {
+	case CopyConditionNew, CopyConditionGood, CopyConditionFair, CopyConditionDamaged:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BookJobType 图书批量任务类型
+type BookJobType string
+
+const (
+	BookJobImport BookJobType = "import" // 导入
+	BookJobExport BookJobType = "export" // 导出
+)
+
+// BookJobStatus 图书批量任务状态
+type BookJobStatus string
+
+const (
+	BookJobStatusPending   BookJobStatus = "pending"   // 等待执行
+	BookJobStatusRunning   BookJobStatus = "running"   // 执行中
+	BookJobStatusCompleted BookJobStatus = "completed" // 已完成（导入可能有部分行失败）
+	BookJobStatusFailed    BookJobStatus = "failed"    // 失败
+)
+
+// BookFileFormat 图书导入导出文件格式
+type BookFileFormat string
+
+const (
+	BookFormatCSV     BookFileFormat = "csv"     // CSV（UTF-8）
+	BookFormatXLSX    BookFileFormat = "xlsx"    // Excel
+	BookFormatMARC    BookFileFormat = "marc"    // MARC21 ISO 2709（UTF-8）
+	BookFormatMARCXML BookFileFormat = "marcxml" // MARCXML
+)
+
+// BookJob 图书批量导入导出任务表
+type BookJob struct {
+	gorm.Model
+	JobType      BookJobType    `json:"job_type" gorm:"type:varchar(20);index;not null;comment:任务类型"`
+	Format       BookFileFormat `json:"format" gorm:"type:varchar(20);not null;comment:文件格式"`
+	Status       BookJobStatus  `json:"status" gorm:"type:varchar(20);index;default:'pending';comment:状态"`
+	FileName     string         `json:"file_name" gorm:"type:varchar(255);comment:原始文件名或导出文件名"`
+	FilePath     string         `json:"-" gorm:"type:varchar(500);comment:服务器上的文件路径"`
+	Options      string         `json:"options" gorm:"type:text;comment:任务参数(JSON)"`
+	TotalRows    int            `json:"total_rows" gorm:"default:0;comment:总行数"`
+	CreatedRows  int            `json:"created_rows" gorm:"default:0;comment:新建图书数"`
+	UpdatedRows  int            `json:"updated_rows" gorm:"default:0;comment:更新图书数"`
+	FailedRows   int            `json:"failed_rows" gorm:"default:0;comment:失败行数"`
+	ErrorReport  string         `json:"error_report,omitempty" gorm:"type:longtext;comment:逐行错误报告(JSON)"`
+	ErrorMessage string         `json:"error_message" gorm:"type:text;comment:任务失败原因"`
+	OperatorID   uint           `json:"operator_id" gorm:"index;comment:操作人ID"`
+	OperatorName string         `json:"operator_name" gorm:"type:varchar(64);comment:操作人用户名"`
+	StartedAt    *time.Time     `json:"started_at" gorm:"comment:开始时间"`
+	FinishedAt   *time.Time     `json:"finished_at" gorm:"comment:结束时间"`
+}
+
+func (BookJob) TableName() string {
+	return "book_jobs"
+}
+
+// BookJobRowError 导入的单行错误
+type BookJobRowError struct {
+	Row     int    `json:"row"`  // 文件中的行号（MARC为记录序号）
+	ISBN    string `json:"isbn"` // 该行的ISBN
+	Message string `json:"message"`
+}
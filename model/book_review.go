@@ -0,0 +1,68 @@
+package model
+
+import "time"
+
+// ReviewStatus 评价状态
+type ReviewStatus string
+
+const (
+	ReviewStatusPending  ReviewStatus = "pending"  // 待审核（开启先审后发，或被多次举报）
+	ReviewStatusApproved ReviewStatus = "approved" // 已公开
+	ReviewStatusHidden   ReviewStatus = "hidden"   // 已隐藏
+)
+
+// BookReview 图书评价表，每位用户对每本书只保留一条评价，重复提交视为修改
+// 只有已公开的评价计入图书的平均评分
+type BookReview struct {
+	ID             uint         `json:"id" gorm:"primaryKey"`
+	CreatedAt      time.Time    `json:"created_at" gorm:"index"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+	BookID         uint         `json:"book_id" gorm:"not null;uniqueIndex:uk_review_book_user;comment:图书ID"`
+	Book           *Book        `json:"book,omitempty" gorm:"foreignKey:BookID;constraint:-"`
+	UserID         uint         `json:"user_id" gorm:"not null;uniqueIndex:uk_review_book_user;index;comment:用户ID"`
+	Username       string       `json:"username" gorm:"->;-:migration"` // 评价人用户名，查询时关联users表
+	Rating         int          `json:"rating" gorm:"not null;comment:评分1-5"`
+	Content        string       `json:"content" gorm:"type:text;comment:评价内容"`
+	Status         ReviewStatus `json:"status" gorm:"type:varchar(20);not null;index;comment:状态"`
+	ReportCount    int          `json:"report_count" gorm:"default:0;comment:未处理的举报次数"`
+	ModeratorID    uint         `json:"moderator_id" gorm:"comment:最近审核人ID"`
+	ModeratedAt    *time.Time   `json:"moderated_at" gorm:"comment:最近审核时间"`
+	ModerationNote string       `json:"moderation_note" gorm:"type:varchar(255);comment:审核备注"`
+}
+
+func (BookReview) TableName() string {
+	return "book_reviews"
+}
+
+// BookReviewReport 评价举报记录，每位用户对同一条评价只能举报一次
+type BookReviewReport struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	ReviewID  uint      `json:"review_id" gorm:"not null;uniqueIndex:uk_report_review_user;comment:评价ID"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:uk_report_review_user;comment:举报人ID"`
+	Reason    string    `json:"reason" gorm:"type:varchar(255);comment:举报原因"`
+	Handled   bool      `json:"handled" gorm:"default:false;index;comment:是否已处理"`
+}
+
+func (BookReviewReport) TableName() string {
+	return "book_review_reports"
+}
+
+// ReviewSort 评价列表排序方式
+type ReviewSort string
+
+const (
+	ReviewSortNewest     ReviewSort = "newest"      // 最新
+	ReviewSortOldest     ReviewSort = "oldest"      // 最早
+	ReviewSortRatingDesc ReviewSort = "rating_desc" // 评分从高到低
+	ReviewSortRatingAsc  ReviewSort = "rating_asc"  // 评分从低到高
+	ReviewSortReports    ReviewSort = "reports"     // 举报次数（管理端）
+)
+
+// RatingSummary 图书评分汇总
+type RatingSummary struct {
+	BookID       uint        `json:"book_id"`
+	RatingAvg    float64     `json:"rating_avg"`   // 平均评分
+	RatingCount  int         `json:"rating_count"` // 评分人数
+	Distribution map[int]int `json:"distribution"` // 各星级人数
+}
@@ -17,6 +17,7 @@ const (
 	BorrowStatusRenewed  BorrowStatus = "renewed"  // 已续借
 	BorrowStatusReserved BorrowStatus = "reserved" // 已预约
 	BorrowStatusRejected BorrowStatus = "rejected" // 已拒绝
+	BorrowStatusLost     BorrowStatus = "lost"     // 已丢失（已赔偿，找回后改为已归还）
 )
 
 // BorrowRecord 借阅记录表
@@ -26,10 +27,12 @@ type BorrowRecord struct {
 	Reader        Reader       `json:"reader" gorm:"foreignKey:ReaderID"`
 	BookID        uint         `json:"book_id" gorm:"not null;comment:图书ID;index"`
 	Book          Book         `json:"book" gorm:"foreignKey:BookID"`
+	CopyID        *uint        `json:"copy_id" gorm:"comment:借出的副本ID;index"`
+	Copy          *BookCopy    `json:"copy,omitempty" gorm:"foreignKey:CopyID"`
 	BorrowDate    time.Time    `json:"borrow_date" gorm:"comment:借阅日期"`
 	DueDate       time.Time    `json:"due_date" gorm:"comment:应还日期;index"`
 	ReturnDate    *time.Time   `json:"return_date" gorm:"comment:实际归还日期"`
-	Status        BorrowStatus `json:"status" gorm:"type:enum('pending','borrowed','returned','overdue','renewed','reserved','rejected');default:'pending';comment:状态;index"`
+	Status        BorrowStatus `json:"status" gorm:"type:enum('pending','borrowed','returned','overdue','renewed','reserved','rejected','lost');default:'pending';comment:状态;index"`
 	RenewCount    int          `json:"renew_count" gorm:"default:0;comment:续借次数"`
 	MaxRenewCount int          `json:"max_renew_count" gorm:"default:2;comment:最大续借次数"`
 	FineAmount    float64      `json:"fine_amount" gorm:"default:0;comment:罚款金额"`
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OpeningHours 每周开放时间表（按星期配置）
+type OpeningHours struct {
+	gorm.Model
+	Weekday   int    `json:"weekday" gorm:"uniqueIndex;not null;comment:星期（0=周日，6=周六）"`
+	IsOpen    bool   `json:"is_open" gorm:"default:true;comment:是否开放"`
+	OpenTime  string `json:"open_time" gorm:"type:varchar(5);comment:开馆时间（HH:MM）"`
+	CloseTime string `json:"close_time" gorm:"type:varchar(5);comment:闭馆时间（HH:MM）"`
+}
+
+func (OpeningHours) TableName() string {
+	return "library_opening_hours"
+}
+
+// CalendarException 日历例外：节假日闭馆或调休开馆，覆盖每周开放时间表
+type CalendarException struct {
+	gorm.Model
+	StartDate time.Time `json:"start_date" gorm:"type:date;index;not null;comment:开始日期"`
+	EndDate   time.Time `json:"end_date" gorm:"type:date;index;not null;comment:结束日期（含）"`
+	IsOpen    bool      `json:"is_open" gorm:"default:false;comment:是否开放（false=闭馆，true=调休开馆）"`
+	OpenTime  string    `json:"open_time" gorm:"type:varchar(5);comment:开馆时间（调休开馆时有效）"`
+	CloseTime string    `json:"close_time" gorm:"type:varchar(5);comment:闭馆时间（调休开馆时有效）"`
+	Reason    string    `json:"reason" gorm:"type:varchar(100);comment:原因"`
+}
+
+func (CalendarException) TableName() string {
+	return "library_calendar_exceptions"
+}
+
+// CalendarDay 某一天的开放情况
+type CalendarDay struct {
+	Date      string `json:"date"`       // 日期（2024-01-01）
+	IsOpen    bool   `json:"is_open"`    // 是否开放
+	OpenTime  string `json:"open_time"`  // 开馆时间
+	CloseTime string `json:"close_time"` // 闭馆时间
+	Reason    string `json:"reason"`     // 例外原因（节假日名称等）
+}
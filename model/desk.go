@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// DeskHold 归还图书上的待处理预约
+type DeskHold struct {
+	ReservationID uint              `json:"reservation_id"`
+	ReaderID      uint              `json:"reader_id"`
+	ReaderNo      string            `json:"reader_no"`
+	Status        ReservationStatus `json:"status"`
+	QueuePosition int               `json:"queue_position"`
+}
+
+// CheckoutReceipt 流通台借书回执
+type CheckoutReceipt struct {
+	RecordID      uint      `json:"record_id"`      // 借阅记录ID
+	ReaderNo      string    `json:"reader_no"`      // 读者证号
+	ReaderName    string    `json:"reader_name"`    // 读者姓名
+	BookID        uint      `json:"book_id"`        // 图书ID
+	Title         string    `json:"title"`          // 书名
+	Barcode       string    `json:"barcode"`        // 副本条码
+	BorrowDate    time.Time `json:"borrow_date"`    // 借阅日期
+	DueDate       time.Time `json:"due_date"`       // 应还日期
+	BorrowedCount int64     `json:"borrowed_count"` // 当前在借数量
+	UnpaidFine    float64   `json:"unpaid_fine"`    // 未支付罚款
+}
+
+// CheckinReceipt 流通台还书回执
+type CheckinReceipt struct {
+	RecordID     uint       `json:"record_id"`     // 借阅记录ID
+	ReaderNo     string     `json:"reader_no"`     // 读者证号
+	ReaderName   string     `json:"reader_name"`   // 读者姓名
+	BookID       uint       `json:"book_id"`       // 图书ID
+	Title        string     `json:"title"`         // 书名
+	Barcode      string     `json:"barcode"`       // 副本条码
+	DueDate      time.Time  `json:"due_date"`      // 应还日期
+	ReturnDate   *time.Time `json:"return_date"`   // 归还日期
+	OverdueDays  int        `json:"overdue_days"`  // 逾期天数
+	FineAmount   float64    `json:"fine_amount"`   // 本次产生的罚款
+	UnpaidFine   float64    `json:"unpaid_fine"`   // 读者未支付罚款合计
+	PendingHolds []DeskHold `json:"pending_holds"` // 该书的待处理预约，非空时应放入预约架
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// RedisMode 点赞/收藏/榜单的运行模式
+type RedisMode string
+
+const (
+	RedisModeNormal     RedisMode = "redis"      // Redis可用，正常模式
+	RedisModeDegraded   RedisMode = "mysql"      // Redis不可用，只使用MySQL
+	RedisModeRecovering RedisMode = "recovering" // Redis已恢复，正在预热缓存，预热完成前仍只使用MySQL
+)
+
+// RedisHealth Redis健康状态
+type RedisHealth struct {
+	Mode      RedisMode  `json:"mode"`                 // 当前模式
+	Available bool       `json:"available"`            // 点赞/收藏是否走Redis
+	Since     time.Time  `json:"since"`                // 进入当前模式的时间
+	LastCheck *time.Time `json:"last_check,omitempty"` // 最近一次检查时间
+	LastError string     `json:"last_error,omitempty"` // 最近一次连接失败的原因
+}
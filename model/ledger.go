@@ -0,0 +1,95 @@
+package model
+
+import "time"
+
+// LedgerType 账务交易类型
+type LedgerType string
+
+const (
+	LedgerCharge     LedgerType = "charge"     // 产生罚款
+	LedgerPayment    LedgerType = "payment"    // 支付（现场收取、在线支付、使用预存余额）
+	LedgerWaiver     LedgerType = "waiver"     // 豁免
+	LedgerRefund     LedgerType = "refund"     // 退款
+	LedgerAdjustment LedgerType = "adjustment" // 人工调整
+)
+
+// LedgerAccount 会计科目
+type LedgerAccount string
+
+const (
+	AccountReceivable LedgerAccount = "receivable"  // 读者应收：借方为读者欠款，关联罚款的部分为该罚款的待付金额，未关联罚款的贷方余额为预存余额
+	AccountFineIncome LedgerAccount = "fine_income" // 罚款收入
+	AccountCash       LedgerAccount = "cash"        // 现场收款
+	AccountGateway    LedgerAccount = "gateway"     // 在线支付渠道
+	AccountWaiver     LedgerAccount = "waiver"      // 豁免减免
+	AccountAdjustment LedgerAccount = "adjustment"  // 人工调整
+)
+
+// LedgerTransaction 账务交易（只追加，不允许修改和删除）
+// 每笔交易由借贷相等的分录组成，读者的未支付罚款、累计罚款和预存余额都由分录汇总得出
+type LedgerTransaction struct {
+	ID             uint          `json:"id" gorm:"primarykey"`
+	CreatedAt      time.Time     `json:"created_at" gorm:"index;comment:记账时间"`
+	Type           LedgerType    `json:"type" gorm:"type:varchar(20);not null;index;comment:交易类型"`
+	ReaderID       uint          `json:"reader_id" gorm:"not null;index;comment:读者ID"`
+	FineID         *uint         `json:"fine_id" gorm:"index;comment:关联的罚款记录ID"`
+	PaymentOrderID *uint         `json:"payment_order_id" gorm:"index;comment:关联的支付订单ID"`
+	Amount         float64       `json:"amount" gorm:"type:decimal(12,2);not null;comment:交易金额"`
+	Memo           string        `json:"memo" gorm:"type:varchar(255);comment:摘要"`
+	OperatorID     uint          `json:"operator_id" gorm:"comment:操作人ID，系统操作为0"`
+	IdempotencyKey string        `json:"idempotency_key" gorm:"type:varchar(64);uniqueIndex;not null;comment:幂等键，同一业务事件只记账一次"`
+	Entries        []LedgerEntry `json:"entries,omitempty" gorm:"foreignKey:TransactionID"`
+}
+
+func (LedgerTransaction) TableName() string {
+	return "ledger_transactions"
+}
+
+// LedgerEntry 会计分录（只追加，不允许修改和删除）
+type LedgerEntry struct {
+	ID            uint          `json:"id" gorm:"primarykey"`
+	TransactionID uint          `json:"transaction_id" gorm:"not null;index;comment:交易ID"`
+	Account       LedgerAccount `json:"account" gorm:"type:varchar(20);not null;index:idx_ledger_account;comment:科目"`
+	ReaderID      uint          `json:"reader_id" gorm:"index:idx_ledger_account;comment:读者ID（读者应收科目）"`
+	FineID        *uint         `json:"fine_id" gorm:"index;comment:罚款记录ID（读者应收科目），为空表示预存余额"`
+	Debit         float64       `json:"debit" gorm:"type:decimal(12,2);default:0;comment:借方金额"`
+	Credit        float64       `json:"credit" gorm:"type:decimal(12,2);default:0;comment:贷方金额"`
+}
+
+func (LedgerEntry) TableName() string {
+	return "ledger_entries"
+}
+
+// ReaderBalance 由账务分录汇总得出的读者余额
+type ReaderBalance struct {
+	ReaderID     uint    `json:"reader_id"`
+	UnpaidFine   float64 `json:"unpaid_fine"`   // 各罚款待付金额合计
+	TotalFine    float64 `json:"total_fine"`    // 累计罚款
+	CreditAmount float64 `json:"credit_amount"` // 预存余额（多付的金额），产生新罚款时自动冲抵
+}
+
+// LedgerMismatch 对账发现的不一致
+type LedgerMismatch struct {
+	ReaderID uint           `json:"reader_id"`
+	ReaderNo string         `json:"reader_no"`
+	Cached   ReaderBalance  `json:"cached"` // readers 表中缓存的余额
+	Ledger   ReaderBalance  `json:"ledger"` // 账务汇总的余额
+	Fines    []FineMismatch `json:"fines,omitempty"`
+}
+
+// FineMismatch 罚款记录的待付金额与账务不一致
+type FineMismatch struct {
+	FineID       uint       `json:"fine_id"`
+	Status       FineStatus `json:"status"`
+	CachedUnpaid float64    `json:"cached_unpaid"` // 罚款记录的待付金额（金额-已付，已支付和已豁免为0）
+	LedgerUnpaid float64    `json:"ledger_unpaid"` // 账务汇总的待付金额
+}
+
+// LedgerReconcileReport 对账报告
+type LedgerReconcileReport struct {
+	CheckedAt      time.Time        `json:"checked_at"`
+	ReaderCount    int              `json:"reader_count"`
+	UnbalancedTxns []uint           `json:"unbalanced_txns"` // 借贷不相等的交易
+	Mismatches     []LedgerMismatch `json:"mismatches"`
+	Fixed          bool             `json:"fixed"` // 是否已按账务修正缓存
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// PatronCategory 读者类型
+type PatronCategory string
+
+const (
+	PatronStudent PatronCategory = "student" // 学生
+	PatronStaff   PatronCategory = "staff"   // 职工
+	PatronFaculty PatronCategory = "faculty" // 教师
+	PatronGuest   PatronCategory = "guest"   // 访客
+)
+
+// DefaultPatronCategory 新读者的默认类型
+const DefaultPatronCategory = PatronStudent
+
+// PatronCategoryNames 读者类型名称
+var PatronCategoryNames = map[PatronCategory]string{
+	PatronStudent: "学生",
+	PatronStaff:   "职工",
+	PatronFaculty: "教师",
+	PatronGuest:   "访客",
+}
+
+// IsValidPatronCategory 判断读者类型是否已定义
+func IsValidPatronCategory(c PatronCategory) bool {
+	_, ok := PatronCategoryNames[c]
+	return ok
+}
+
+// LoanPolicy 借阅规则表
+// CategoryID 为0时是读者类型的基础规则，所有字段必填；
+// 不为0时是该读者类型借阅某个图书分类时的覆盖规则，只有非空字段生效
+type LoanPolicy struct {
+	gorm.Model
+	PatronCategory  PatronCategory `json:"patron_category" gorm:"type:varchar(20);uniqueIndex:idx_loan_policy;not null;comment:读者类型"`
+	CategoryID      uint           `json:"category_id" gorm:"uniqueIndex:idx_loan_policy;default:0;comment:图书分类ID（0表示基础规则）"`
+	Category        *Category      `json:"category,omitempty" gorm:"foreignKey:CategoryID;constraint:-"`
+	MaxBorrow       *int           `json:"max_borrow" gorm:"comment:最大借阅数量（覆盖规则中表示该分类最多同时借阅数量）"`
+	BorrowDays      *int           `json:"borrow_days" gorm:"comment:借阅天数（0表示测试模式1分钟）"`
+	MaxRenew        *int           `json:"max_renew" gorm:"comment:最大续借次数"`
+	RenewDays       *int           `json:"renew_days" gorm:"comment:每次续借延长天数"`
+	MaxReservations *int           `json:"max_reservations" gorm:"comment:最大预约数量（仅基础规则）"`
+	PickupDays      *int           `json:"pickup_days" gorm:"comment:预约取书有效期（天，仅基础规则）"`
+	FinePerDay      *float64       `json:"fine_per_day" gorm:"type:decimal(10,2);comment:逾期罚款（元/天）"`
+	MaxFineRate     *float64       `json:"max_fine_rate" gorm:"type:decimal(5,2);comment:罚款上限比例（图书价格的百分比）"`
+	Remark          string         `json:"remark" gorm:"type:varchar(255);comment:备注"`
+}
+
+func (LoanPolicy) TableName() string {
+	return "loan_policies"
+}
+
+// EffectiveLoanPolicy 读者借阅某本书时最终生效的规则
+type EffectiveLoanPolicy struct {
+	PatronCategory  PatronCategory `json:"patron_category"`
+	MaxBorrow       int            `json:"max_borrow"`
+	BorrowDays      int            `json:"borrow_days"`
+	MaxRenew        int            `json:"max_renew"`
+	RenewDays       int            `json:"renew_days"`
+	MaxReservations int            `json:"max_reservations"`
+	PickupDays      int            `json:"pickup_days"`
+	FinePerDay      float64        `json:"fine_per_day"`
+	MaxFineRate     float64        `json:"max_fine_rate"`
+	// CategoryLimits 图书所属分类中设置了借阅数量上限的分类ID -> 上限
+	CategoryLimits map[uint]int `json:"category_limits,omitempty"`
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LossType 赔偿类型
+type LossType string
+
+const (
+	LossTypeLost   LossType = "lost"   // 丢失
+	LossTypeDamage LossType = "damage" // 损坏
+)
+
+// DamageLevel 损坏程度
+type DamageLevel string
+
+const (
+	DamageLevelMinor  DamageLevel = "minor"  // 轻微损坏，副本送修后继续流通
+	DamageLevelSevere DamageLevel = "severe" // 严重损坏，副本无法继续流通，按丢失处理
+)
+
+// LossStatus 赔偿记录状态
+type LossStatus string
+
+const (
+	LossStatusOpen  LossStatus = "open"  // 已赔偿
+	LossStatusFound LossStatus = "found" // 丢失的图书已找回
+)
+
+// LossReport 图书丢失、损坏赔偿记录表（每条借阅记录最多一条）
+type LossReport struct {
+	gorm.Model
+	BorrowRecordID uint        `json:"borrow_record_id" gorm:"not null;uniqueIndex;comment:借阅记录ID"`
+	ReaderID       uint        `json:"reader_id" gorm:"not null;index;comment:读者ID"`
+	Reader         *Reader     `json:"reader,omitempty" gorm:"foreignKey:ReaderID"`
+	BookID         uint        `json:"book_id" gorm:"not null;index;comment:图书ID"`
+	Book           *Book       `json:"book,omitempty" gorm:"foreignKey:BookID"`
+	CopyID         *uint       `json:"copy_id" gorm:"comment:副本ID"`
+	Type           LossType    `json:"type" gorm:"type:varchar(20);not null;index;comment:赔偿类型(lost/damage)"`
+	DamageLevel    DamageLevel `json:"damage_level" gorm:"type:varchar(20);comment:损坏程度(minor/severe)"`
+	BookPrice      float64     `json:"book_price" gorm:"type:decimal(10,2);comment:计费时的图书价格"`
+	ReplacementFee float64     `json:"replacement_fee" gorm:"type:decimal(10,2);comment:赔偿金额（找回时退还）"`
+	ProcessingFee  float64     `json:"processing_fee" gorm:"type:decimal(10,2);comment:加工费（找回时不退）"`
+	FineID         *uint       `json:"fine_id" gorm:"index;comment:罚款记录ID（赔偿金额为0时为空）"`
+	Status         LossStatus  `json:"status" gorm:"type:varchar(20);default:'open';index;comment:状态"`
+	FoundAt        *time.Time  `json:"found_at" gorm:"comment:找回时间"`
+	RefundAmount   float64     `json:"refund_amount" gorm:"type:decimal(10,2);default:0;comment:找回时退还的已付金额"`
+	OperatorID     uint        `json:"operator_id" gorm:"comment:操作员ID"`
+	Remark         string      `json:"remark" gorm:"type:text;comment:备注"`
+}
+
+func (LossReport) TableName() string {
+	return "loss_reports"
+}
+
+// IsValidDamageLevel 校验损坏程度取值
+func IsValidDamageLevel(level DamageLevel) bool {
+	return level == DamageLevelMinor || level == DamageLevelSevere
+}
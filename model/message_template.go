@@ -0,0 +1,59 @@
+package model
+
+import "time"
+
+// NotifyChannel 通知渠道
+type NotifyChannel string
+
+const (
+	ChannelInApp NotifyChannel = "in_app" // 站内消息（始终发送）
+	ChannelEmail NotifyChannel = "email"  // 邮件
+	ChannelSMS   NotifyChannel = "sms"    // 短信
+)
+
+// ExternalChannels 需要投递的外部渠道
+var ExternalChannels = []NotifyChannel{ChannelEmail, ChannelSMS}
+
+// MessageTypes 全部站内消息类型
+var MessageTypes = []MessageType{MessageTypeSystem, MessageTypeReservation, MessageTypeBorrow, MessageTypeOverdue, MessageTypeFine}
+
+// MessageTemplate 外部渠道的消息模板，按消息类型和渠道各一条
+// 模板使用 Go text/template 语法，可用变量：{{.Title}} {{.Content}} {{.Username}} {{.RealName}} {{.Type}} {{.CreatedAt}}
+type MessageTemplate struct {
+	ID          uint          `json:"id" gorm:"primaryKey"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	MessageType MessageType   `json:"message_type" gorm:"type:varchar(20);not null;uniqueIndex:idx_template_type_channel;comment:消息类型"`
+	Channel     NotifyChannel `json:"channel" gorm:"type:varchar(20);not null;uniqueIndex:idx_template_type_channel;comment:渠道"`
+	Subject     string        `json:"subject" gorm:"type:varchar(255);comment:邮件标题模板（短信不使用）"`
+	Body        string        `json:"body" gorm:"type:text;not null;comment:正文模板"`
+	Enabled     bool          `json:"enabled" gorm:"default:true;comment:是否启用，停用后该类型消息不再通过此渠道发送"`
+	UpdatedBy   uint          `json:"updated_by" gorm:"comment:最后修改人"`
+}
+
+func (MessageTemplate) TableName() string {
+	return "message_templates"
+}
+
+// DefaultMessageTemplates 初始的消息模板，所有消息类型共用同一套措辞
+func DefaultMessageTemplates() []MessageTemplate {
+	var templates []MessageTemplate
+	for _, t := range MessageTypes {
+		templates = append(templates,
+			MessageTemplate{
+				MessageType: t,
+				Channel:     ChannelEmail,
+				Subject:     "【图书馆】{{.Title}}",
+				Body:        "{{if .RealName}}{{.RealName}}{{else}}{{.Username}}{{end}}，您好：\n\n{{.Content}}\n\n此邮件由系统自动发送，请勿回复。",
+				Enabled:     true,
+			},
+			MessageTemplate{
+				MessageType: t,
+				Channel:     ChannelSMS,
+				Body:        "【图书馆】{{.Content}}",
+				Enabled:     true,
+			},
+		)
+	}
+	return templates
+}
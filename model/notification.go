@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// NotificationEvent 推送事件类型
+type NotificationEvent string
+
+const (
+	NotifyMessage              NotificationEvent = "message"               // 新的站内消息
+	NotifyUnreadCount          NotificationEvent = "unread_count"          // 未读消息数变化
+	NotifyReservationAvailable NotificationEvent = "reservation_available" // 预约的图书可以取书
+	NotifyLoanApproved         NotificationEvent = "loan_approved"         // 借阅申请已批准
+	NotifyLoanRejected         NotificationEvent = "loan_rejected"         // 借阅申请被拒绝
+)
+
+// Notification 推送给在线用户的事件，多实例部署时通过Redis发布订阅转发到用户连接所在的实例
+type Notification struct {
+	ID        string            `json:"id"`
+	UserID    uint              `json:"user_id"`
+	Event     NotificationEvent `json:"event"`
+	Data      interface{}       `json:"data"`
+	CreatedAt time.Time         `json:"created_at"`
+}
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// DeliveryStatus 外部渠道投递状态
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending DeliveryStatus = "pending" // 等待发送（含免打扰推迟和失败后等待重试）
+	DeliveryStatusSending DeliveryStatus = "sending" // 发送中
+	DeliveryStatusSent    DeliveryStatus = "sent"    // 已发送
+	DeliveryStatusFailed  DeliveryStatus = "failed"  // 重试次数用完仍失败
+	DeliveryStatusSkipped DeliveryStatus = "skipped" // 未发送：用户未填写邮箱/手机号或渠道未配置
+)
+
+// NotificationDelivery 站内消息通过邮件、短信投递的记录
+type NotificationDelivery struct {
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	MessageID     uint           `json:"message_id" gorm:"index;comment:站内消息ID"`
+	UserID        uint           `json:"user_id" gorm:"not null;index;comment:用户ID"`
+	MessageType   MessageType    `json:"message_type" gorm:"type:varchar(20);not null;comment:消息类型"`
+	Channel       NotifyChannel  `json:"channel" gorm:"type:varchar(20);not null;comment:渠道"`
+	Recipient     string         `json:"recipient" gorm:"type:varchar(255);comment:邮箱或手机号"`
+	Subject       string         `json:"subject" gorm:"type:varchar(255);comment:邮件标题"`
+	Body          string         `json:"body" gorm:"type:text;comment:正文"`
+	Status        DeliveryStatus `json:"status" gorm:"type:varchar(20);not null;index:idx_delivery_due,priority:1;comment:投递状态"`
+	Attempts      int            `json:"attempts" gorm:"default:0;comment:已尝试次数"`
+	NextAttemptAt time.Time      `json:"next_attempt_at" gorm:"index:idx_delivery_due,priority:2;comment:下次发送时间"`
+	LastError     string         `json:"last_error" gorm:"type:varchar(500);comment:最近一次失败原因"`
+	ProviderID    string         `json:"provider_id" gorm:"type:varchar(100);comment:服务商返回的消息ID"`
+	SentAt        *time.Time     `json:"sent_at" gorm:"comment:发送成功时间"`
+}
+
+func (NotificationDelivery) TableName() string {
+	return "notification_deliveries"
+}
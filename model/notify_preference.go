@@ -0,0 +1,56 @@
+package model
+
+import "time"
+
+// NotifyPreference 用户对某类消息某个渠道的开关，只保存与默认值不同的设置
+type NotifyPreference struct {
+	ID          uint          `json:"id" gorm:"primaryKey"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	UserID      uint          `json:"user_id" gorm:"not null;uniqueIndex:idx_notify_pref;comment:用户ID"`
+	MessageType MessageType   `json:"message_type" gorm:"type:varchar(20);not null;uniqueIndex:idx_notify_pref;comment:消息类型"`
+	Channel     NotifyChannel `json:"channel" gorm:"type:varchar(20);not null;uniqueIndex:idx_notify_pref;comment:渠道"`
+	Enabled     bool          `json:"enabled" gorm:"comment:是否接收"`
+}
+
+func (NotifyPreference) TableName() string {
+	return "notify_preferences"
+}
+
+// NotifySetting 用户的免打扰时段，时段内的邮件和短信推迟到时段结束后发送
+type NotifySetting struct {
+	UserID     uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false;comment:用户ID"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	QuietStart string    `json:"quiet_start" gorm:"type:varchar(5);comment:免打扰开始时间 HH:MM，为空表示不启用"`
+	QuietEnd   string    `json:"quiet_end" gorm:"type:varchar(5);comment:免打扰结束时间 HH:MM，可跨零点"`
+}
+
+func (NotifySetting) TableName() string {
+	return "notify_settings"
+}
+
+// DefaultChannelEnabled 未设置时的默认开关：邮件全部接收，短信只接收预约到书和逾期提醒
+func DefaultChannelEnabled(t MessageType, channel NotifyChannel) bool {
+	switch channel {
+	case ChannelInApp, ChannelEmail:
+		return true
+	case ChannelSMS:
+		return t == MessageTypeReservation || t == MessageTypeOverdue
+	}
+	return false
+}
+
+// NotifyPreferenceItem 偏好设置中的一项
+type NotifyPreferenceItem struct {
+	MessageType MessageType   `json:"message_type"`
+	Channel     NotifyChannel `json:"channel"`
+	Enabled     bool          `json:"enabled"`
+}
+
+// NotifyPreferences 用户的通知偏好
+type NotifyPreferences struct {
+	Items      []NotifyPreferenceItem `json:"items"`
+	QuietStart string                 `json:"quiet_start"`
+	QuietEnd   string                 `json:"quiet_end"`
+	Email      string                 `json:"email"` // 接收邮件的地址（用户资料中的邮箱）
+	Phone      string                 `json:"phone"` // 接收短信的号码（用户资料中的手机号）
+}
@@ -0,0 +1,65 @@
+package model
+
+import "time"
+
+// OutboxStatus 待执行事件状态
+type OutboxStatus string
+
+const (
+	OutboxStatusPending    OutboxStatus = "pending"    // 等待执行（含失败后等待重试）
+	OutboxStatusProcessing OutboxStatus = "processing" // 执行中
+	OutboxStatusDone       OutboxStatus = "done"       // 已执行
+	OutboxStatusFailed     OutboxStatus = "failed"     // 重试次数用完仍失败，需要人工重试
+)
+
+// OutboxTopic 事件类型，对应一种后续操作
+type OutboxTopic string
+
+const (
+	OutboxHoldNotify OutboxTopic = "reservation.hold" // 图书有可借副本，为排队的预约保留并通知读者
+	OutboxMessage    OutboxTopic = "message.create"   // 创建站内消息并推送、发送邮件短信
+	OutboxRanking    OutboxTopic = "ranking.event"    // 借阅、预约计入榜单
+)
+
+// OutboxEvent 业务事务内写入的待执行事件，提交后由后台循环至少执行一次
+// 同一业务事件的幂等键相同，重复写入只保留一条
+type OutboxEvent struct {
+	ID             uint         `json:"id" gorm:"primaryKey"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+	Topic          OutboxTopic  `json:"topic" gorm:"type:varchar(50);not null;index;comment:事件类型"`
+	IdempotencyKey string       `json:"idempotency_key" gorm:"type:varchar(128);uniqueIndex;not null;comment:幂等键"`
+	Payload        string       `json:"payload" gorm:"type:text;comment:事件内容（JSON）"`
+	Status         OutboxStatus `json:"status" gorm:"type:varchar(20);not null;index:idx_outbox_due,priority:1;comment:状态"`
+	Attempts       int          `json:"attempts" gorm:"default:0;comment:已尝试次数"`
+	NextAttemptAt  time.Time    `json:"next_attempt_at" gorm:"index:idx_outbox_due,priority:2;comment:下次执行时间"`
+	LastError      string       `json:"last_error" gorm:"type:varchar(500);comment:最近一次失败原因"`
+	ProcessedAt    *time.Time   `json:"processed_at" gorm:"comment:执行成功时间"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// HoldNotifyPayload 预约保留事件内容
+type HoldNotifyPayload struct {
+	BookID uint `json:"book_id"`
+}
+
+// MessagePayload 站内消息事件内容，Event 不为空时同时推送该实时事件
+type MessagePayload struct {
+	UserID      uint                   `json:"user_id"`
+	Type        MessageType            `json:"type"`
+	Title       string                 `json:"title"`
+	Content     string                 `json:"content"`
+	RelatedID   *uint                  `json:"related_id,omitempty"`
+	RelatedType string                 `json:"related_type,omitempty"`
+	Event       NotificationEvent      `json:"event,omitempty"`
+	EventData   map[string]interface{} `json:"event_data,omitempty"`
+}
+
+// RankingPayload 榜单事件内容
+type RankingPayload struct {
+	Type   RankingType `json:"type"`
+	BookID uint        `json:"book_id"`
+}
@@ -0,0 +1,82 @@
+package model
+
+import "time"
+
+// PaymentStatus 支付订单状态
+type PaymentStatus string
+
+const (
+	PaymentStatusPending  PaymentStatus = "pending"  // 待支付
+	PaymentStatusPaid     PaymentStatus = "paid"     // 已支付
+	PaymentStatusClosed   PaymentStatus = "closed"   // 已关闭：超时未支付或被新订单取代
+	PaymentStatusRefunded PaymentStatus = "refunded" // 已退款
+)
+
+// PaymentOrder 罚款在线支付订单
+type PaymentOrder struct {
+	ID             uint               `json:"id" gorm:"primaryKey"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+	OrderNo        string             `json:"order_no" gorm:"type:varchar(32);uniqueIndex;not null;comment:订单号"`
+	ReaderID       uint               `json:"reader_id" gorm:"not null;index;comment:读者ID"`
+	UserID         uint               `json:"user_id" gorm:"not null;comment:下单用户ID"`
+	Provider       string             `json:"provider" gorm:"type:varchar(20);not null;comment:支付渠道"`
+	Subject        string             `json:"subject" gorm:"type:varchar(128);comment:订单标题"`
+	Amount         float64            `json:"amount" gorm:"not null;comment:订单金额"`
+	AppliedAmount  float64            `json:"applied_amount" gorm:"default:0;comment:实际冲抵罚款的金额"`
+	RefundedAmount float64            `json:"refunded_amount" gorm:"default:0;comment:已退款金额"`
+	Status         PaymentStatus      `json:"status" gorm:"type:varchar(20);not null;index;comment:状态"`
+	TradeNo        string             `json:"trade_no" gorm:"type:varchar(64);comment:支付渠道交易号"`
+	PayURL         string             `json:"pay_url" gorm:"type:varchar(512);comment:支付页面地址"`
+	ExpireAt       time.Time          `json:"expire_at" gorm:"comment:支付截止时间"`
+	PaidAt         *time.Time         `json:"paid_at" gorm:"comment:支付时间"`
+	Items          []PaymentOrderItem `json:"items,omitempty" gorm:"foreignKey:OrderID"`
+}
+
+func (PaymentOrder) TableName() string {
+	return "payment_orders"
+}
+
+// PaymentOrderItem 订单包含的罚款
+type PaymentOrderItem struct {
+	ID            uint        `json:"id" gorm:"primaryKey"`
+	OrderID       uint        `json:"order_id" gorm:"not null;index;comment:订单ID"`
+	FineID        uint        `json:"fine_id" gorm:"not null;index;comment:罚款记录ID"`
+	Fine          *FineRecord `json:"fine,omitempty" gorm:"foreignKey:FineID"`
+	Amount        float64     `json:"amount" gorm:"not null;comment:下单时该罚款的待付金额"`
+	AppliedAmount float64     `json:"applied_amount" gorm:"default:0;comment:支付后实际冲抵的金额"`
+}
+
+func (PaymentOrderItem) TableName() string {
+	return "payment_order_items"
+}
+
+// PaymentRefund 退款记录
+type PaymentRefund struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt  time.Time `json:"created_at"`
+	RefundNo   string    `json:"refund_no" gorm:"type:varchar(40);uniqueIndex;not null;comment:退款单号"`
+	OrderID    uint      `json:"order_id" gorm:"not null;index;comment:订单ID"`
+	Amount     float64   `json:"amount" gorm:"not null;comment:退款金额"`
+	Reason     string    `json:"reason" gorm:"type:varchar(255);comment:退款原因"`
+	ProviderID string    `json:"provider_id" gorm:"type:varchar(64);comment:支付渠道退款单号"`
+	OperatorID uint      `json:"operator_id" gorm:"comment:操作人ID，系统自动退款为0"`
+}
+
+func (PaymentRefund) TableName() string {
+	return "payment_refunds"
+}
+
+// PaymentCallback 已处理的支付回调，用于识别支付渠道的重复通知
+type PaymentCallback struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	Provider  string    `json:"provider" gorm:"type:varchar(20);not null;uniqueIndex:idx_payment_callback;comment:支付渠道"`
+	EventID   string    `json:"event_id" gorm:"type:varchar(64);not null;uniqueIndex:idx_payment_callback;comment:通知ID"`
+	OrderNo   string    `json:"order_no" gorm:"type:varchar(32);index;comment:订单号"`
+	Payload   string    `json:"payload" gorm:"type:text;comment:通知原文"`
+}
+
+func (PaymentCallback) TableName() string {
+	return "payment_callbacks"
+}
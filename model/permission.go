@@ -0,0 +1,141 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Permission 权限标识，格式为 资源:操作
+type Permission string
+
+const (
+	PermBookWrite        Permission = "book:write"        // 新建/修改/删除图书
+	PermCopyManage       Permission = "copy:manage"       // 管理馆藏副本
+	PermCategoryWrite    Permission = "category:write"    // 新建/修改/删除分类
+	PermReaderView       Permission = "reader:view"       // 查看读者列表
+	PermReaderManage     Permission = "reader:manage"     // 审核读者、修改读者状态
+	PermBorrowView       Permission = "borrow:view"       // 查看全部借阅记录
+	PermBorrowApprove    Permission = "borrow:approve"    // 审批借阅申请
+	PermBorrowOnBehalf   Permission = "borrow:on_behalf"  // 为其他读者办理借书
+	PermCirculationDesk  Permission = "circulation:desk"  // 流通台扫码借还
+	PermReservationView  Permission = "reservation:view"  // 查看全部预约
+	PermLossManage       Permission = "loss:manage"       // 登记图书丢失、损坏并收取赔偿
+	PermFineView         Permission = "fine:view"         // 查看全部罚款
+	PermFineWaive        Permission = "fine:waive"        // 豁免罚款
+	PermFineCollect      Permission = "fine:collect"      // 现场收取罚款
+	PermPaymentRefund    Permission = "payment:refund"    // 在线支付订单退款
+	PermLedgerAdjust     Permission = "ledger:adjust"     // 调整罚款账务、修正读者缓存余额
+	PermBlacklistManage  Permission = "blacklist:manage"  // 管理黑名单
+	PermStatisticsView   Permission = "statistics:view"   // 查看统计信息
+	PermRankingRebuild   Permission = "ranking:rebuild"   // 重建榜单和推荐
+	PermUserManage       Permission = "user:manage"       // 管理系统用户
+	PermConfigEdit       Permission = "config:edit"       // 修改系统配置
+	PermPermissionManage Permission = "permission:manage" // 管理角色权限
+	PermAuditView        Permission = "audit:view"        // 查看和导出审计日志
+	PermCalendarManage   Permission = "calendar:manage"   // 管理开馆日历
+	PermPolicyManage     Permission = "policy:manage"     // 管理读者类型借阅规则
+	PermSyncManage       Permission = "sync:manage"       // 管理点赞收藏同步队列、死信和数据校对
+	PermReviewModerate   Permission = "review:moderate"   // 审核、隐藏图书评价，处理举报
+	PermNotifyManage     Permission = "notify:manage"     // 管理消息模板，查看和重发邮件短信投递记录
+	PermOutboxManage     Permission = "outbox:manage"     // 查看和重新执行借还书、预约的待执行事件
+)
+
+// PermissionInfo 权限说明
+type PermissionInfo struct {
+	Code  Permission `json:"code"`
+	Name  string     `json:"name"`
+	Group string     `json:"group"`
+}
+
+// AllPermissions 系统中定义的全部权限
+var AllPermissions = []PermissionInfo{
+	{Code: PermBookWrite, Name: "维护图书", Group: "图书"},
+	{Code: PermCopyManage, Name: "管理馆藏副本", Group: "图书"},
+	{Code: PermCategoryWrite, Name: "维护分类", Group: "图书"},
+	{Code: PermReaderView, Name: "查看读者", Group: "读者"},
+	{Code: PermReaderManage, Name: "审核读者", Group: "读者"},
+	{Code: PermBorrowView, Name: "查看借阅记录", Group: "流通"},
+	{Code: PermBorrowApprove, Name: "审批借阅", Group: "流通"},
+	{Code: PermBorrowOnBehalf, Name: "代读者借书", Group: "流通"},
+	{Code: PermCirculationDesk, Name: "流通台借还", Group: "流通"},
+	{Code: PermReservationView, Name: "查看预约", Group: "流通"},
+	{Code: PermLossManage, Name: "丢失损坏赔偿", Group: "流通"},
+	{Code: PermFineView, Name: "查看罚款", Group: "罚款"},
+	{Code: PermFineWaive, Name: "豁免罚款", Group: "罚款"},
+	{Code: PermFineCollect, Name: "收取罚款", Group: "罚款"},
+	{Code: PermPaymentRefund, Name: "支付订单退款", Group: "罚款"},
+	{Code: PermLedgerAdjust, Name: "调整罚款账务", Group: "罚款"},
+	{Code: PermBlacklistManage, Name: "管理黑名单", Group: "读者"},
+	{Code: PermStatisticsView, Name: "查看统计", Group: "统计"},
+	{Code: PermRankingRebuild, Name: "重建榜单和推荐", Group: "统计"},
+	{Code: PermUserManage, Name: "管理用户", Group: "系统"},
+	{Code: PermConfigEdit, Name: "修改系统配置", Group: "系统"},
+	{Code: PermPermissionManage, Name: "管理角色权限", Group: "系统"},
+	{Code: PermAuditView, Name: "查看审计日志", Group: "系统"},
+	{Code: PermCalendarManage, Name: "管理开馆日历", Group: "系统"},
+	{Code: PermPolicyManage, Name: "管理借阅规则", Group: "流通"},
+	{Code: PermSyncManage, Name: "管理同步队列", Group: "系统"},
+	{Code: PermReviewModerate, Name: "审核评价", Group: "图书"},
+	{Code: PermNotifyManage, Name: "管理消息通知", Group: "系统"},
+	{Code: PermOutboxManage, Name: "管理待执行事件", Group: "系统"},
+}
+
+// IsValidPermission 判断权限标识是否已定义
+func IsValidPermission(p Permission) bool {
+	for _, info := range AllPermissions {
+		if info.Code == p {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultRolePermissions 初始的角色权限，与原先按角色限制的接口保持一致
+// 管理员始终拥有全部权限，不在此列出
+var DefaultRolePermissions = map[UserRole][]Permission{
+	RoleLibrarian: {
+		PermBookWrite,
+		PermCopyManage,
+		PermCategoryWrite,
+		PermReaderView,
+		PermReaderManage,
+		PermBorrowView,
+		PermBorrowApprove,
+		PermBorrowOnBehalf,
+		PermCirculationDesk,
+		PermReservationView,
+		PermLossManage,
+		PermFineView,
+		PermFineWaive,
+		PermFineCollect,
+		PermBlacklistManage,
+		PermStatisticsView,
+		PermReviewModerate,
+	},
+	RoleReader: {},
+}
+
+// RolePermission 角色权限表
+type RolePermission struct {
+	gorm.Model
+	Role       UserRole   `json:"role" gorm:"type:varchar(20);uniqueIndex:idx_role_permission;not null;comment:角色"`
+	Permission Permission `json:"permission" gorm:"type:varchar(64);uniqueIndex:idx_role_permission;not null;comment:权限标识"`
+}
+
+func (RolePermission) TableName() string {
+	return "role_permissions"
+}
+
+// RolePermissionSeed 已写入过的默认角色权限
+// 启动时只补写从未写入过的默认权限，管理员撤销的默认权限不会被重新授予
+type RolePermissionSeed struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Role       UserRole   `json:"role" gorm:"type:varchar(20);uniqueIndex:idx_role_permission_seed;not null;comment:角色"`
+	Permission Permission `json:"permission" gorm:"type:varchar(64);uniqueIndex:idx_role_permission_seed;not null;comment:权限标识"`
+}
+
+func (RolePermissionSeed) TableName() string {
+	return "role_permission_seeds"
+}
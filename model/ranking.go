@@ -6,6 +6,10 @@ type RankingType string
 const (
 	RankingTypeLike     RankingType = "like"     // 点赞榜
 	RankingTypeFavorite RankingType = "favorite" // 收藏榜
+	RankingTypeBorrow   RankingType = "borrow"   // 借阅榜
+	RankingTypeReserve  RankingType = "reserve"  // 预约榜
+	RankingTypeTrending RankingType = "trending" // 热度榜（借阅/预约/收藏/点赞按时间衰减加权）
+	RankingTypeRating   RankingType = "rating"   // 评分榜（周期内已公开评价的贝叶斯平均分）
 )
 
 // RankingPeriod 榜单周期
@@ -14,6 +18,7 @@ type RankingPeriod string
 const (
 	RankingPeriodWeek  RankingPeriod = "week"  // 周榜
 	RankingPeriodMonth RankingPeriod = "month" // 月榜
+	RankingPeriodYear  RankingPeriod = "year"  // 年榜
 )
 
 // RankingItem 榜单项
@@ -21,7 +26,10 @@ type RankingItem struct {
 	Rank  int   `json:"rank"`  // 排名
 	BookID uint  `json:"book_id"` // 图书ID
 	Book  *Book `json:"book,omitempty"` // 图书详情
-	Score int64 `json:"score"` // 分数（点赞数或收藏数）
+	Score float64 `json:"score"` // 分数（点赞/收藏/借阅/预约次数，热度榜为衰减后的热度值，评分榜为平均分）
+	PrevRank   int    `json:"prev_rank"`   // 上一周期排名，0表示上一周期未上榜
+	RankChange int    `json:"rank_change"` // 排名变化，正数为上升的名次
+	Trend      string `json:"trend"`       // new 新上榜 / up 上升 / down 下降 / same 持平
 }
 
 // RankingResponse 榜单响应
@@ -29,6 +37,9 @@ type RankingResponse struct {
 	Type      RankingType    `json:"type"`      // 榜单类型
 	Period    RankingPeriod  `json:"period"`    // 榜单周期
 	PeriodKey string         `json:"period_key"` // 周期标识（如：2025-W45）
+	CategoryID uint          `json:"category_id,omitempty"` // 分类ID，为空表示全部分类
+	PrevPeriodKey string     `json:"prev_period_key"` // 用于计算排名变化的上一周期
+	Snapshot  bool           `json:"snapshot"`   // 是否为已结束周期的快照
 	Items     []RankingItem  `json:"items"`     // 榜单数据
 	Total     int            `json:"total"`     // 总数
 	UpdatedAt string         `json:"updated_at"` // 更新时间
@@ -36,8 +47,10 @@ type RankingResponse struct {
 
 // RankingRequest 榜单查询请求
 type RankingRequest struct {
-	Type   RankingType   `json:"type" form:"type" binding:"required,oneof=like favorite"` // 榜单类型
-	Period RankingPeriod `json:"period" form:"period" binding:"required,oneof=week month"` // 榜单周期
+	Type   RankingType   `json:"type" form:"type" binding:"required,oneof=like favorite borrow reserve trending rating"` // 榜单类型
+	Period RankingPeriod `json:"period" form:"period" binding:"required,oneof=week month year"` // 榜单周期
+	CategoryID uint      `json:"category_id" form:"category_id"` // 分类ID，不传为全部分类
+	PeriodKey  string    `json:"period_key" form:"period_key"` // 周期标识（如：2025-W45、2025-11、2025），不传为当前周期
 	Limit  int           `json:"limit" form:"limit"` // 返回数量，默认100
 }
 
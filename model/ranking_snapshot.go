@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// RankingSnapshot 已结束周期的榜单快照
+// 周期结束后从MySQL明细表统计一次并永久保存，用于查询历史榜单和计算排名变化
+type RankingSnapshot struct {
+	ID         uint          `json:"id" gorm:"primaryKey"`
+	CreatedAt  time.Time     `json:"created_at"`
+	Type       RankingType   `json:"type" gorm:"type:varchar(20);not null;uniqueIndex:uk_ranking_snapshot;comment:榜单类型"`
+	Period     RankingPeriod `json:"period" gorm:"type:varchar(10);not null;uniqueIndex:uk_ranking_snapshot;comment:榜单周期"`
+	PeriodKey  string        `json:"period_key" gorm:"type:varchar(20);not null;uniqueIndex:uk_ranking_snapshot;comment:周期标识"`
+	CategoryID uint          `json:"category_id" gorm:"not null;default:0;uniqueIndex:uk_ranking_snapshot;comment:分类ID，0为全部分类"`
+	ItemCount  int           `json:"item_count" gorm:"default:0;comment:上榜图书数"`
+}
+
+func (RankingSnapshot) TableName() string {
+	return "ranking_snapshots"
+}
+
+// RankingSnapshotItem 榜单快照中的一本书
+type RankingSnapshotItem struct {
+	ID         uint    `json:"id" gorm:"primaryKey"`
+	SnapshotID uint    `json:"snapshot_id" gorm:"not null;uniqueIndex:uk_snapshot_rank;comment:快照ID"`
+	Rank       int     `json:"rank" gorm:"not null;uniqueIndex:uk_snapshot_rank;comment:排名"`
+	BookID     uint    `json:"book_id" gorm:"not null;index;comment:图书ID"`
+	Score      float64 `json:"score" gorm:"comment:分数，热度榜为周期结束时的热度值"`
+}
+
+func (RankingSnapshotItem) TableName() string {
+	return "ranking_snapshot_items"
+}
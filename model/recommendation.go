@@ -0,0 +1,58 @@
+package model
+
+import "time"
+
+// RecommendKind 推荐结果类型
+type RecommendKind string
+
+const (
+	RecommendAlsoBorrowed RecommendKind = "also_borrowed" // 借过这本书的读者还借过：主体为图书，推荐项为图书
+	RecommendForYou       RecommendKind = "for_you"       // 为你推荐：主体为用户，推荐项为图书
+	RecommendCategory     RecommendKind = "category"      // 分类偏好：主体为用户，推荐项为分类
+)
+
+// 为你推荐的推荐理由
+const (
+	RecommendReasonAlsoBorrowed = "also_borrowed" // 与你借过、收藏、点赞的书经常被同一批读者借阅
+	RecommendReasonCategory     = "category"      // 你偏好的分类中的热门图书
+	RecommendReasonPopular      = "popular"       // 暂无足够行为数据时推荐近期热门图书
+)
+
+// Recommendation 离线计算的推荐结果，每次计算整体替换
+type Recommendation struct {
+	ID         uint          `json:"id" gorm:"primaryKey"`
+	Kind       RecommendKind `json:"kind" gorm:"type:varchar(20);not null;index:idx_recommend_subject,priority:1;comment:推荐类型"`
+	SubjectID  uint          `json:"subject_id" gorm:"not null;index:idx_recommend_subject,priority:2;comment:主体ID（图书或用户）"`
+	ItemID     uint          `json:"item_id" gorm:"not null;comment:推荐项ID（图书或分类）"`
+	Rank       int           `json:"rank" gorm:"not null;comment:排名"`
+	Score      float64       `json:"score" gorm:"comment:推荐分数"`
+	Reason     string        `json:"reason" gorm:"type:varchar(20);comment:推荐理由"`
+	ComputedAt time.Time     `json:"computed_at" gorm:"comment:计算时间"`
+}
+
+func (Recommendation) TableName() string {
+	return "recommendations"
+}
+
+// RecommendItem 推荐的一本书
+type RecommendItem struct {
+	BookID uint    `json:"book_id"`
+	Book   *Book   `json:"book,omitempty"`
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason,omitempty"`
+}
+
+// CategoryAffinity 用户对分类的偏好，Score 为借阅/收藏/点赞加权后的占比
+type CategoryAffinity struct {
+	CategoryID uint    `json:"category_id"`
+	Name       string  `json:"name,omitempty"`
+	Score      float64 `json:"score"`
+}
+
+// RecommendResponse 推荐结果响应
+type RecommendResponse struct {
+	Items      []RecommendItem    `json:"items"`
+	Categories []CategoryAffinity `json:"categories,omitempty"` // 为你推荐时返回用户的分类偏好
+	Source     string             `json:"source"`               // redis / mysql / popular
+	ComputedAt *time.Time         `json:"computed_at,omitempty"`
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReconcileStatus 校对任务状态
+type ReconcileStatus string
+
+const (
+	ReconcileStatusRunning   ReconcileStatus = "running"   // 执行中
+	ReconcileStatusCompleted ReconcileStatus = "completed" // 已完成
+	ReconcileStatusFailed    ReconcileStatus = "failed"    // 失败
+)
+
+// ReconcileDiffKind 差异类型
+type ReconcileDiffKind string
+
+const (
+	ReconcileDiffBookCounter  ReconcileDiffKind = "book_counter"  // books表的点赞/收藏计数与明细表不一致
+	ReconcileDiffRedisCounter ReconcileDiffKind = "redis_counter" // Redis图书统计与明细表不一致
+	ReconcileDiffUserSet      ReconcileDiffKind = "user_set"      // Redis用户点赞/收藏集合与明细表不一致
+)
+
+// ReconcileRun 点赞/收藏 Redis 与 MySQL 校对记录
+// 以 book_likes、book_favorites 明细表为准，修复 books 表计数、Redis 图书统计和用户集合
+type ReconcileRun struct {
+	gorm.Model
+	Trigger           string          `json:"trigger" gorm:"type:varchar(20);comment:触发方式：cron/manual/recover"`
+	DryRun            bool            `json:"dry_run" gorm:"default:false;comment:只检查不修复"`
+	Status            ReconcileStatus `json:"status" gorm:"type:varchar(20);index;comment:状态"`
+	BooksChecked      int             `json:"books_checked" gorm:"default:0;comment:检查的图书数"`
+	UsersChecked      int             `json:"users_checked" gorm:"default:0;comment:检查的用户数"`
+	BookCounterDiffs  int             `json:"book_counter_diffs" gorm:"default:0;comment:books表计数差异数"`
+	RedisCounterDiffs int             `json:"redis_counter_diffs" gorm:"default:0;comment:Redis图书统计差异数"`
+	UserSetDiffs      int             `json:"user_set_diffs" gorm:"default:0;comment:Redis用户集合差异数"`
+	RedisSkipped      bool            `json:"redis_skipped" gorm:"default:false;comment:是否跳过Redis校对"`
+	RedisSkipReason   string          `json:"redis_skip_reason" gorm:"type:varchar(255);comment:跳过Redis校对的原因"`
+	Details           string          `json:"-" gorm:"type:longtext;comment:差异明细（JSON，最多保留200条）"`
+	ErrorMessage      string          `json:"error_message" gorm:"type:varchar(500);comment:失败原因"`
+	OperatorID        uint            `json:"operator_id" gorm:"comment:操作人ID，定时任务为0"`
+	OperatorName      string          `json:"operator_name" gorm:"type:varchar(64);comment:操作人"`
+	StartedAt         *time.Time      `json:"started_at" gorm:"comment:开始时间"`
+	FinishedAt        *time.Time      `json:"finished_at" gorm:"comment:结束时间"`
+}
+
+func (ReconcileRun) TableName() string {
+	return "reconcile_runs"
+}
+
+// ReconcileDiff 一条差异
+type ReconcileDiff struct {
+	Kind     ReconcileDiffKind `json:"kind"`              // 差异类型
+	BookID   uint              `json:"book_id,omitempty"` // 图书ID（计数差异）
+	UserID   uint              `json:"user_id,omitempty"` // 用户ID（集合差异）
+	Field    string            `json:"field"`             // like_count/favorite_count/likes/favorites
+	Expected int64             `json:"expected"`          // 明细表中的值
+	Actual   int64             `json:"actual"`            // 校对前的值
+	Missing  []uint            `json:"missing,omitempty"` // 集合中缺少的图书ID
+	Extra    []uint            `json:"extra,omitempty"`   // 集合中多出的图书ID
+}
@@ -0,0 +1,60 @@
+package model
+
+// SearchSort 图书搜索排序方式
+type SearchSort string
+
+const (
+	SearchSortRelevance SearchSort = "relevance"  // 相关度（有关键词时的默认排序）
+	SearchSortNewest    SearchSort = "newest"     // 最新入库（无关键词时的默认排序）
+	SearchSortPriceAsc  SearchSort = "price_asc"  // 价格从低到高
+	SearchSortPriceDesc SearchSort = "price_desc" // 价格从高到低
+	SearchSortRating    SearchSort = "rating"     // 评分从高到低
+)
+
+// SearchQuery 图书搜索条件
+type SearchQuery struct {
+	Keyword    string     `form:"keyword"`     // 关键词，匹配书名、作者、出版社、简介和ISBN
+	CategoryID uint       `form:"category_id"` // 分类
+	Publisher  string     `form:"publisher"`   // 出版社（精确匹配）
+	YearFrom   int        `form:"year_from"`   // 出版年份起
+	YearTo     int        `form:"year_to"`     // 出版年份止
+	Available  *bool      `form:"available"`   // true 只看可借，false 只看无可借副本
+	PriceMin   *float64   `form:"price_min"`   // 最低价格
+	PriceMax   *float64   `form:"price_max"`   // 最高价格
+	Sort       SearchSort `form:"sort"`        // 排序方式
+	Page       int        `form:"page"`        // 页码
+	PageSize   int        `form:"pageSize"`    // 每页大小
+	Facets     bool       `form:"facets"`      // 是否返回分面统计
+}
+
+// SearchHit 一条搜索结果
+type SearchHit struct {
+	Book       Book              `json:"book"`
+	Score      float64           `json:"score"`                // 相关度，无关键词时为0
+	Highlights map[string]string `json:"highlights,omitempty"` // 命中字段的高亮片段，关键词用 <em></em> 包裹
+}
+
+// FacetCount 分面统计的一项
+type FacetCount struct {
+	Value string `json:"value"` // 分类ID、出版社、年份，可借状态为 true/false
+	Label string `json:"label"` // 展示名称
+	Count int64  `json:"count"`
+}
+
+// SearchFacets 分面统计，每个维度按除自身以外的其他条件统计，便于多选切换
+type SearchFacets struct {
+	Categories   []FacetCount `json:"categories"`
+	Publishers   []FacetCount `json:"publishers"`
+	Years        []FacetCount `json:"years"`
+	Availability []FacetCount `json:"availability"`
+}
+
+// SearchResult 图书搜索结果
+type SearchResult struct {
+	List     []SearchHit   `json:"list"`
+	Total    int64         `json:"total"`
+	Page     int           `json:"page"`
+	PageSize int           `json:"pageSize"`
+	Engine   string        `json:"engine"` // mysql / memory
+	Facets   *SearchFacets `json:"facets,omitempty"`
+}
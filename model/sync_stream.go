@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// SyncStreamStats 点赞/收藏同步Stream的消费状态
+type SyncStreamStats struct {
+	Stream    string           `json:"stream"`    // Stream名称
+	Length    int64            `json:"length"`    // Stream中的消息数
+	Pending   int64            `json:"pending"`   // 已投递未确认的消息数
+	Consumers map[string]int64 `json:"consumers"` // 各消费者名下的待确认消息数
+}
+
+// SyncPendingMessage 待确认列表中的消息
+type SyncPendingMessage struct {
+	ID          string `json:"id"`           // 消息ID
+	Consumer    string `json:"consumer"`     // 当前持有的消费者
+	IdleSeconds int64  `json:"idle_seconds"` // 距上次投递的秒数
+	Deliveries  int64  `json:"deliveries"`   // 投递次数
+}
+
+// SyncDeadLetter 死信消息
+type SyncDeadLetter struct {
+	ID         string            `json:"id"`         // 死信Stream中的消息ID
+	Stream     string            `json:"stream"`     // 来源Stream
+	MessageID  string            `json:"message_id"` // 原消息ID
+	Deliveries int64             `json:"deliveries"` // 转入死信前的投递次数
+	Reason     string            `json:"reason"`     // 转入原因
+	FailedAt   time.Time         `json:"failed_at"`  // 转入时间
+	Payload    map[string]string `json:"payload"`    // 原消息内容
+}
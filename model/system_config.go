@@ -20,6 +20,7 @@ func (SystemConfig) TableName() string {
 
 // 系统配置键常量
 const (
+	// 借阅、预约、罚款规则已由 loan_policies 按读者类型配置取代，以下键仅用于升级时迁移旧配置
 	// 借阅规则
 	ConfigMaxBorrowBooks = "max_borrow_books" // 最大借阅数量
 	ConfigBorrowDays     = "borrow_days"      // 借阅天数
@@ -38,4 +39,29 @@ const (
 	ConfigOverdueReminderDays  = "overdue_reminder_days"  // 提前提醒天数
 	ConfigOverdueBlockDays     = "overdue_block_days"     // 逾期多久禁止借书（天）
 	ConfigOverdueBlacklistDays = "overdue_blacklist_days" // 逾期多久自动拉黑（天）
+
+	// 评价规则
+	ConfigReviewRequireApproval = "review_require_approval" // 评价是否需要审核后才公开
+	ConfigReviewReportThreshold = "review_report_threshold" // 评价被举报多少次后转为待审核
+
+	// 丢失、损坏赔偿规则
+	ConfigLostReplacementRate = "lost_replacement_rate" // 丢失赔偿金额（图书价格的倍数）
+	ConfigLostProcessingFee   = "lost_processing_fee"   // 丢失、严重损坏的加工费（元，找回不退）
+	ConfigLostDefaultPrice    = "lost_default_price"    // 图书未填写价格时的计费价格（元）
+	ConfigDamageMinorRate     = "damage_minor_rate"     // 轻微损坏赔偿金额（图书价格的倍数）
+	ConfigDamageSevereRate    = "damage_severe_rate"    // 严重损坏赔偿金额（图书价格的倍数）
+	ConfigLostBlacklistDays   = "lost_blacklist_days"   // 丢失图书后拉黑天数（0表示不拉黑）
+	ConfigDamageBlacklistDays = "damage_blacklist_days" // 损坏图书后拉黑天数（0表示不拉黑）
 )
+
+// LegacyLoanConfigKeys 已迁移到借阅规则表的旧配置键
+var LegacyLoanConfigKeys = []string{
+	ConfigMaxBorrowBooks,
+	ConfigBorrowDays,
+	ConfigMaxRenewTimes,
+	ConfigRenewDays,
+	ConfigMaxReservations,
+	ConfigReservationPickupDays,
+	ConfigOverdueFinePerDay,
+	ConfigMaxFineRate,
+}
@@ -0,0 +1,20 @@
+package router
+
+import (
+	v1 "bookadmin/api/v1"
+	"bookadmin/middleware"
+	"bookadmin/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitAuditRouter(Router *gin.RouterGroup) {
+	auditRouter := Router.Group("audit")
+	auditApi := v1.AuditApi{}
+	{
+		auditRouter.Use(middleware.JWTAuth())
+		auditRouter.Use(middleware.RequirePermission(model.PermAuditView))
+		auditRouter.GET("getAuditLogList", auditApi.GetAuditLogList) // 查询审计日志
+		auditRouter.GET("export", auditApi.ExportAuditLogs)          // 导出审计日志CSV
+	}
+}
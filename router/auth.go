@@ -12,10 +12,12 @@ func InitAuthRouter(Router *gin.RouterGroup) {
 	{
 	authRouter.POST("login", authApi.Login)           // 登录
 	authRouter.POST("register", authApi.Register)     // 注册
+	authRouter.POST("refreshToken", authApi.RefreshToken) // 刷新令牌
 		
 	// 需要认证的接口
 	authRouter.Use(middleware.JWTAuth())
 	authRouter.GET("userInfo", authApi.GetUserInfo)   // 获取用户信息
+	authRouter.POST("logout", authApi.Logout)         // 退出登录
 	}
 }
 
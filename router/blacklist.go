@@ -17,9 +17,9 @@ func InitBlacklistRouter(Router *gin.RouterGroup) {
 		blacklistRouter.GET("getMyStatus", blacklistApi.GetMyBlacklistStatus)  // 获取我的黑名单状态
 		
 		// 管理员接口
-		blacklistRouter.GET("getBlacklistList", middleware.RequireRole(model.RoleAdmin, model.RoleLibrarian), blacklistApi.GetBlacklistList)    // 获取黑名单列表
-		blacklistRouter.POST("add", middleware.RequireRole(model.RoleAdmin, model.RoleLibrarian), blacklistApi.AddToBlacklist)                  // 添加黑名单
-		blacklistRouter.POST("remove/:id", middleware.RequireRole(model.RoleAdmin, model.RoleLibrarian), blacklistApi.RemoveFromBlacklist)      // 解除黑名单
+		blacklistRouter.GET("getBlacklistList", middleware.RequirePermission(model.PermBlacklistManage), blacklistApi.GetBlacklistList)    // 获取黑名单列表
+		blacklistRouter.POST("add", middleware.RequirePermission(model.PermBlacklistManage), blacklistApi.AddToBlacklist)                  // 添加黑名单
+		blacklistRouter.POST("remove/:id", middleware.RequirePermission(model.PermBlacklistManage), blacklistApi.RemoveFromBlacklist)      // 解除黑名单
 	}
 }
 
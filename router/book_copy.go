@@ -0,0 +1,24 @@
+package router
+
+import (
+	v1 "bookadmin/api/v1"
+	"bookadmin/middleware"
+	"bookadmin/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitBookCopyRouter(Router *gin.RouterGroup) {
+	copyRouter := Router.Group("copy")
+	copyApi := v1.BookCopyApi{}
+	{
+		copyRouter.Use(middleware.JWTAuth())
+		copyRouter.Use(middleware.RequirePermission(model.PermCopyManage))
+		copyRouter.GET("getCopyList", copyApi.GetCopyList)           // 获取图书副本列表
+		copyRouter.GET("getCopyByBarcode", copyApi.GetCopyByBarcode) // 根据条码查询副本
+		copyRouter.POST("addCopies", copyApi.AddCopies)              // 新增副本
+		copyRouter.POST("retire/:id", copyApi.RetireCopy)            // 剔旧副本
+		copyRouter.POST("repair/:id", copyApi.RepairCopy)            // 修补完成重新上架
+		copyRouter.PUT("relocate", copyApi.RelocateCopy)             // 调整架位
+	}
+}
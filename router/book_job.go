@@ -0,0 +1,25 @@
+package router
+
+import (
+	v1 "bookadmin/api/v1"
+	"bookadmin/middleware"
+	"bookadmin/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitBookJobRouter(Router *gin.RouterGroup) {
+	bookJobRouter := Router.Group("bookJob")
+	bookJobApi := v1.BookJobApi{}
+	{
+		bookJobRouter.Use(middleware.JWTAuth())
+		bookJobRouter.Use(middleware.RequirePermission(model.PermBookWrite))
+		bookJobRouter.POST("importBooks", bookJobApi.ImportBooks)                // 上传文件批量导入图书
+		bookJobRouter.POST("exportBooks", bookJobApi.ExportBooks)                // 批量导出图书
+		bookJobRouter.GET("getJobList", bookJobApi.GetBookJobList)               // 获取导入导出任务列表
+		bookJobRouter.GET("getJob", bookJobApi.GetBookJob)                       // 获取任务详情和逐行错误
+		bookJobRouter.GET("downloadErrorReport", bookJobApi.DownloadErrorReport) // 下载导入错误报告
+		bookJobRouter.GET("downloadExport", bookJobApi.DownloadExport)           // 下载导出文件
+		bookJobRouter.GET("getImportTemplate", bookJobApi.GetImportTemplate)     // 下载导入模板
+	}
+}
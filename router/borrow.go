@@ -14,8 +14,8 @@ func InitBorrowRouter(Router *gin.RouterGroup) {
 	{
 		borrowRouter.Use(middleware.JWTAuth())
 		// 管理员接口
-		borrowRouter.GET("getBorrowList", middleware.RequireRole(model.RoleAdmin, model.RoleLibrarian), borrowApi.GetBorrowList)   // 获取借阅记录列表
-		borrowRouter.POST("approve", middleware.RequireRole(model.RoleAdmin, model.RoleLibrarian), borrowApi.ApproveBorrowRequest) // 审批借阅申请
+		borrowRouter.GET("getBorrowList", middleware.RequirePermission(model.PermBorrowView), borrowApi.GetBorrowList)      // 获取借阅记录列表
+		borrowRouter.POST("approve", middleware.RequirePermission(model.PermBorrowApprove), borrowApi.ApproveBorrowRequest) // 审批借阅申请
 
 		// 普通用户接口（所有登录用户都可以访问）
 		borrowRouter.POST("borrowBook", borrowApi.BorrowBook)                   // 借书（普通用户提交申请，管理员直接借出）
@@ -0,0 +1,27 @@
+package router
+
+import (
+	v1 "bookadmin/api/v1"
+	"bookadmin/middleware"
+	"bookadmin/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitCalendarRouter(Router *gin.RouterGroup) {
+	calendarRouter := Router.Group("calendar")
+	calendarApi := v1.CalendarApi{}
+	{
+		// 公开接口
+		calendarRouter.GET("getCalendar", calendarApi.GetCalendar) // 获取开馆日历
+
+		// 需要日历管理权限
+		calendarRouter.Use(middleware.JWTAuth())
+		calendarRouter.Use(middleware.RequirePermission(model.PermCalendarManage))
+		calendarRouter.GET("getOpeningHours", calendarApi.GetOpeningHours)        // 获取每周开放时间
+		calendarRouter.PUT("updateOpeningHours", calendarApi.UpdateOpeningHours)  // 更新每周开放时间
+		calendarRouter.GET("getExceptions", calendarApi.GetExceptions)            // 获取节假日/调休列表
+		calendarRouter.POST("createException", calendarApi.CreateException)       // 新增节假日/调休
+		calendarRouter.DELETE("deleteException/:id", calendarApi.DeleteException) // 删除节假日/调休
+	}
+}
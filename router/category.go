@@ -17,7 +17,7 @@ func InitCategoryRouter(Router *gin.RouterGroup) {
 
 		// 需要认证的接口
 		categoryRouter.Use(middleware.JWTAuth())
-		categoryRouter.Use(middleware.RequireRole(model.RoleAdmin, model.RoleLibrarian))
+		categoryRouter.Use(middleware.RequirePermission(model.PermCategoryWrite))
 		categoryRouter.POST("createCategory", categoryApi.CreateCategory)   // 新建分类
 		categoryRouter.PUT("updateCategory", categoryApi.UpdateCategory)      // 更新分类
 		categoryRouter.DELETE("deleteCategory", categoryApi.DeleteCategory)  // 删除分类
@@ -0,0 +1,20 @@
+package router
+
+import (
+	v1 "bookadmin/api/v1"
+	"bookadmin/middleware"
+	"bookadmin/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitDeskRouter(Router *gin.RouterGroup) {
+	deskRouter := Router.Group("desk")
+	deskApi := v1.DeskApi{}
+	{
+		deskRouter.Use(middleware.JWTAuth())
+		deskRouter.Use(middleware.RequirePermission(model.PermCirculationDesk))
+		deskRouter.POST("checkout", deskApi.Checkout) // 扫码借书（读者证号 + 副本条码）
+		deskRouter.POST("checkin", deskApi.Checkin)   // 扫码还书（副本条码）
+	}
+}
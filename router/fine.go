@@ -15,11 +15,14 @@ func InitFineRouter(Router *gin.RouterGroup) {
 		fineRouter.Use(middleware.JWTAuth())
 		// 普通用户接口
 		fineRouter.GET("getMyFines", fineApi.GetMyFines)  // 获取我的罚款
-		fineRouter.POST("pay", fineApi.PayFine)           // 支付罚款
+		fineRouter.POST("createPayment", fineApi.CreatePayment) // 在线支付我的罚款
+		fineRouter.GET("getMyPayments", fineApi.GetMyPayments)  // 我的支付订单
+		fineRouter.GET("getPayment", fineApi.GetPayment)        // 查询我的支付订单
 		
 		// 管理员接口
-		fineRouter.GET("getFineList", middleware.RequireRole(model.RoleAdmin, model.RoleLibrarian), fineApi.GetFineList)  // 获取罚款列表
-		fineRouter.POST("waive/:id", middleware.RequireRole(model.RoleAdmin, model.RoleLibrarian), fineApi.WaiveFine)     // 豁免罚款
+		fineRouter.GET("getFineList", middleware.RequirePermission(model.PermFineView), fineApi.GetFineList)  // 获取罚款列表
+		fineRouter.POST("waive/:id", middleware.RequirePermission(model.PermFineWaive), fineApi.WaiveFine)     // 豁免罚款
+		fineRouter.POST("pay", middleware.RequirePermission(model.PermFineCollect), fineApi.PayFine)          // 现场收取罚款
 	}
 }
 
@@ -0,0 +1,12 @@
+package router
+
+import (
+	v1 "bookadmin/api/v1"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitHealthRouter(Router *gin.RouterGroup) {
+	healthApi := v1.HealthApi{}
+	Router.GET("health", healthApi.GetHealth) // 健康检查（公开接口）
+}
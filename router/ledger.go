@@ -0,0 +1,25 @@
+package router
+
+import (
+	v1 "bookadmin/api/v1"
+	"bookadmin/middleware"
+	"bookadmin/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitLedgerRouter(Router *gin.RouterGroup) {
+	ledgerRouter := Router.Group("ledger")
+	ledgerApi := v1.LedgerApi{}
+	{
+		ledgerRouter.Use(middleware.JWTAuth())
+		// 普通用户接口
+		ledgerRouter.GET("getMyLedger", ledgerApi.GetMyLedger) // 我的罚款账务
+
+		// 管理接口
+		ledgerRouter.GET("getReaderLedger", middleware.RequirePermission(model.PermFineView), ledgerApi.GetReaderLedger) // 读者罚款账务
+		ledgerRouter.GET("reconcile", middleware.RequirePermission(model.PermFineView), ledgerApi.GetReconcileReport)    // 对账报告
+		ledgerRouter.POST("reconcile/fix", middleware.RequirePermission(model.PermLedgerAdjust), ledgerApi.FixReconcile) // 按账务修正缓存余额
+		ledgerRouter.POST("adjust", middleware.RequirePermission(model.PermLedgerAdjust), ledgerApi.Adjust)              // 人工调整
+	}
+}
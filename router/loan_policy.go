@@ -0,0 +1,22 @@
+package router
+
+import (
+	v1 "bookadmin/api/v1"
+	"bookadmin/middleware"
+	"bookadmin/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitLoanPolicyRouter(Router *gin.RouterGroup) {
+	policyRouter := Router.Group("loanPolicy")
+	policyApi := v1.LoanPolicyApi{}
+	{
+		policyRouter.Use(middleware.JWTAuth())
+		policyRouter.GET("getPatronCategories", policyApi.GetPatronCategories)                                                // 获取读者类型列表
+		policyRouter.GET("getPolicyList", policyApi.GetPolicyList)                                                            // 获取借阅规则列表
+		policyRouter.GET("resolvePolicy", middleware.RequirePermission(model.PermReaderView), policyApi.ResolvePolicy)        // 查看读者生效的借阅规则
+		policyRouter.PUT("savePolicy", middleware.RequirePermission(model.PermPolicyManage), policyApi.SavePolicy)            // 保存借阅规则
+		policyRouter.DELETE("deletePolicy/:id", middleware.RequirePermission(model.PermPolicyManage), policyApi.DeletePolicy) // 删除分类覆盖规则
+	}
+}
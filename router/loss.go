@@ -0,0 +1,23 @@
+package router
+
+import (
+	v1 "bookadmin/api/v1"
+	"bookadmin/middleware"
+	"bookadmin/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitLossRouter(Router *gin.RouterGroup) {
+	lossRouter := Router.Group("loss")
+	lossApi := v1.LossApi{}
+	{
+		lossRouter.Use(middleware.JWTAuth())
+		lossRouter.Use(middleware.RequirePermission(model.PermLossManage))
+		lossRouter.GET("assess", lossApi.Assess)                // 预览赔偿金额
+		lossRouter.POST("declareLost", lossApi.DeclareLost)     // 登记图书丢失
+		lossRouter.POST("returnDamaged", lossApi.ReturnDamaged) // 损坏还书
+		lossRouter.POST("found/:id", lossApi.Found)             // 丢失的图书找回
+		lossRouter.GET("getLossList", lossApi.GetLossList)      // 赔偿记录列表
+	}
+}
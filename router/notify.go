@@ -0,0 +1,29 @@
+package router
+
+import (
+	v1 "bookadmin/api/v1"
+	"bookadmin/middleware"
+	"bookadmin/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitNotifyRouter(Router *gin.RouterGroup) {
+	notifyRouter := Router.Group("notify")
+	notifyApi := v1.NotifyApi{}
+	{
+		// 推送连接（EventSource 不能设置请求头，允许通过 access_token 参数传递令牌）
+		notifyRouter.GET("stream", middleware.JWTAuthStream(), notifyApi.Stream) // 实时通知推送（SSE）
+
+		// 需要认证的接口
+		notifyRouter.Use(middleware.JWTAuth())
+		notifyRouter.GET("getPreferences", notifyApi.GetPreferences)        // 我的通知偏好
+		notifyRouter.POST("updatePreferences", notifyApi.UpdatePreferences) // 修改通知偏好
+
+		// 通知管理接口
+		notifyRouter.GET("getTemplates", middleware.RequirePermission(model.PermNotifyManage), notifyApi.GetTemplates)        // 消息模板列表
+		notifyRouter.POST("updateTemplate", middleware.RequirePermission(model.PermNotifyManage), notifyApi.UpdateTemplate)   // 修改消息模板
+		notifyRouter.GET("getDeliveries", middleware.RequirePermission(model.PermNotifyManage), notifyApi.GetDeliveries)      // 投递记录
+		notifyRouter.POST("retryDelivery/:id", middleware.RequirePermission(model.PermNotifyManage), notifyApi.RetryDelivery) // 重新发送失败的投递
+	}
+}
@@ -0,0 +1,21 @@
+package router
+
+import (
+	v1 "bookadmin/api/v1"
+	"bookadmin/middleware"
+	"bookadmin/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitOutboxRouter(Router *gin.RouterGroup) {
+	outboxRouter := Router.Group("outbox")
+	outboxApi := v1.OutboxApi{}
+	{
+		outboxRouter.Use(middleware.JWTAuth())
+		outboxRouter.Use(middleware.RequirePermission(model.PermOutboxManage))
+		outboxRouter.GET("getStats", outboxApi.GetStats)          // 各状态的事件数
+		outboxRouter.GET("getEventList", outboxApi.GetEventList)  // 事件列表
+		outboxRouter.POST("retryEvent/:id", outboxApi.RetryEvent) // 重新执行失败的事件
+	}
+}
@@ -0,0 +1,24 @@
+package router
+
+import (
+	v1 "bookadmin/api/v1"
+	"bookadmin/middleware"
+	"bookadmin/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitPaymentRouter(Router *gin.RouterGroup) {
+	paymentRouter := Router.Group("payment")
+	paymentApi := v1.PaymentApi{}
+	{
+		// 支付渠道调用的接口，通过签名校验
+		paymentRouter.POST("callback/:provider", paymentApi.Callback) // 支付结果回调
+		paymentRouter.GET("mock/pay", paymentApi.MockPay)             // 模拟支付页面（仅 mock 渠道）
+
+		// 管理接口
+		paymentRouter.Use(middleware.JWTAuth())
+		paymentRouter.GET("getOrderList", middleware.RequirePermission(model.PermFineView), paymentApi.GetOrderList) // 支付订单列表
+		paymentRouter.POST("refund", middleware.RequirePermission(model.PermPaymentRefund), paymentApi.Refund)       // 订单退款
+	}
+}
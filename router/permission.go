@@ -0,0 +1,21 @@
+package router
+
+import (
+	v1 "bookadmin/api/v1"
+	"bookadmin/middleware"
+	"bookadmin/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitPermissionRouter(Router *gin.RouterGroup) {
+	permissionRouter := Router.Group("permission")
+	permissionApi := v1.PermissionApi{}
+	{
+		permissionRouter.Use(middleware.JWTAuth())
+		permissionRouter.Use(middleware.RequirePermission(model.PermPermissionManage))
+		permissionRouter.GET("getPermissionList", permissionApi.GetPermissionList)         // 获取全部权限
+		permissionRouter.GET("getRolePermissions", permissionApi.GetRolePermissions)       // 获取角色权限
+		permissionRouter.PUT("updateRolePermissions", permissionApi.UpdateRolePermissions) // 更新角色权限
+	}
+}
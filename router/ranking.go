@@ -2,6 +2,8 @@ package router
 
 import (
 	v1 "bookadmin/api/v1"
+	"bookadmin/middleware"
+	"bookadmin/model"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,6 +17,7 @@ func InitRankingRouter(router *gin.RouterGroup) {
 		// 通用榜单查询接口
 		rankingRouter.GET("/list", rankingAPI.GetRanking)   // 统一榜单查询
 		rankingRouter.GET("/query", rankingAPI.GetRanking)  // 兼容旧接口
+		rankingRouter.GET("/periods", rankingAPI.GetSnapshotPeriods) // 有历史快照的周期
 		
 		// 点赞榜
 		rankingRouter.GET("/likes/week", rankingAPI.GetLikeWeekRanking)    // 点赞周榜
@@ -25,7 +28,7 @@ func InitRankingRouter(router *gin.RouterGroup) {
 		rankingRouter.GET("/favorites/month", rankingAPI.GetFavoriteMonthRanking)  // 收藏月榜
 		
 		// 管理员接口
-		rankingRouter.POST("/rebuild", rankingAPI.RebuildRankings) // 重建榜单
+		rankingRouter.POST("/rebuild", middleware.JWTAuth(), middleware.RequirePermission(model.PermRankingRebuild), rankingAPI.RebuildRankings) // 重建榜单
 	}
 }
 
@@ -12,10 +12,10 @@ func InitReaderRouter(Router *gin.RouterGroup) {
 	readerApi := v1.ReaderApi{}
 	{
 		readerRouter.Use(middleware.JWTAuth())
-		readerRouter.GET("getReaderList", middleware.RequireRole(model.RoleAdmin, model.RoleLibrarian), readerApi.GetReaderList) // 获取读者列表
+		readerRouter.GET("getReaderList", middleware.RequirePermission(model.PermReaderView), readerApi.GetReaderList) // 获取读者列表
 		readerRouter.GET("getReader", readerApi.GetReader)                                                                        // 获取读者信息
-		readerRouter.PUT("updateReaderStatus", middleware.RequireRole(model.RoleAdmin, model.RoleLibrarian), readerApi.UpdateReaderStatus) // 更新读者状态（审核）
-		readerRouter.PUT("updateReader", readerApi.UpdateReader)                                                                  // 更新读者信息
+		readerRouter.PUT("updateReaderStatus", middleware.RequirePermission(model.PermReaderManage), readerApi.UpdateReaderStatus) // 更新读者状态（审核）
+		readerRouter.PUT("updateReader", middleware.RequirePermission(model.PermReaderManage), readerApi.UpdateReader)             // 更新读者信息
 	}
 }
 
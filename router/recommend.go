@@ -0,0 +1,23 @@
+package router
+
+import (
+	v1 "bookadmin/api/v1"
+	"bookadmin/middleware"
+	"bookadmin/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitRecommendRouter(Router *gin.RouterGroup) {
+	recommendRouter := Router.Group("recommend")
+	recommendApi := v1.RecommendApi{}
+	{
+		// 公开接口：图书详情页的相关推荐
+		recommendRouter.GET("getAlsoBorrowed", recommendApi.GetAlsoBorrowed) // 借过这本书的读者还借过
+
+		// 需要认证的接口
+		recommendRouter.Use(middleware.JWTAuth())
+		recommendRouter.GET("getForYou", recommendApi.GetForYou)                                                      // 为你推荐
+		recommendRouter.POST("rebuild", middleware.RequirePermission(model.PermRankingRebuild), recommendApi.Rebuild) // 重新计算推荐
+	}
+}
@@ -0,0 +1,21 @@
+package router
+
+import (
+	v1 "bookadmin/api/v1"
+	"bookadmin/middleware"
+	"bookadmin/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitReconcileRouter(Router *gin.RouterGroup) {
+	reconcileRouter := Router.Group("reconcile")
+	reconcileApi := v1.ReconcileApi{}
+	{
+		reconcileRouter.Use(middleware.JWTAuth())
+		reconcileRouter.Use(middleware.RequirePermission(model.PermSyncManage))
+		reconcileRouter.POST("start", reconcileApi.StartReconcile)       // 手动执行校对
+		reconcileRouter.GET("getRunList", reconcileApi.GetReconcileRuns) // 校对记录列表
+		reconcileRouter.GET("getRun", reconcileApi.GetReconcileRun)      // 校对结果和差异明细
+	}
+}
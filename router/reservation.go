@@ -19,6 +19,6 @@ func InitReservationRouter(Router *gin.RouterGroup) {
 		reservationRouter.GET("getMyReservations", reservationApi.GetMyReservations) // 获取我的预约
 
 		// 管理员接口
-		reservationRouter.GET("getReservationList", middleware.RequireRole(model.RoleAdmin, model.RoleLibrarian), reservationApi.GetReservationList) // 获取预约列表
+		reservationRouter.GET("getReservationList", middleware.RequirePermission(model.PermReservationView), reservationApi.GetReservationList) // 获取预约列表
 	}
 }
@@ -0,0 +1,30 @@
+package router
+
+import (
+	v1 "bookadmin/api/v1"
+	"bookadmin/middleware"
+	"bookadmin/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitReviewRouter(Router *gin.RouterGroup) {
+	reviewRouter := Router.Group("review")
+	reviewApi := v1.ReviewApi{}
+	{
+		// 公开接口：图书评价和评分汇总
+		reviewRouter.GET("getBookReviews", reviewApi.GetBookReviews)     // 图书已公开的评价
+		reviewRouter.GET("getRatingSummary", reviewApi.GetRatingSummary) // 图书评分汇总
+
+		// 需要认证的接口
+		reviewRouter.Use(middleware.JWTAuth())
+		reviewRouter.POST("submit", reviewApi.SubmitReview)       // 发表或修改评价
+		reviewRouter.DELETE("delete/:id", reviewApi.DeleteReview) // 删除自己的评价
+		reviewRouter.POST("report", reviewApi.ReportReview)       // 举报评价
+		reviewRouter.GET("getMyReviews", reviewApi.GetMyReviews)  // 我的评价
+
+		// 审核接口
+		reviewRouter.GET("getReviewList", middleware.RequirePermission(model.PermReviewModerate), reviewApi.GetReviewList) // 管理端评价列表
+		reviewRouter.POST("moderate", middleware.RequirePermission(model.PermReviewModerate), reviewApi.ModerateReview)    // 审核评价
+	}
+}
@@ -1,15 +1,18 @@
 package router
 
 import (
+	"bookadmin/config"
+
 	"github.com/gin-gonic/gin"
 )
 
-func InitRouter() *gin.Engine {
+func InitRouter(cfg config.Server) *gin.Engine {
+	gin.SetMode(cfg.Mode)
 	Router := gin.Default()
 
 	// 跨域配置
 	Router.Use(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		c.Writer.Header().Set("Access-Control-Allow-Origin", cfg.AllowOrigin)
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
@@ -25,12 +28,21 @@ func InitRouter() *gin.Engine {
 	// API路由组
 	apiRouter := Router.Group("api")
 	{
+		// 健康检查（无需JWT）
+		InitHealthRouter(apiRouter)
+
 		// 认证相关（无需JWT）
 		InitAuthRouter(apiRouter)
 
 		// 图书管理（需要JWT，部分接口需要管理员权限）
 		InitBookRouter(apiRouter)
 
+		// 馆藏副本管理
+		InitBookCopyRouter(apiRouter)
+
+		// 图书批量导入导出
+		InitBookJobRouter(apiRouter)
+
 		// 分类管理
 		InitCategoryRouter(apiRouter)
 
@@ -40,12 +52,30 @@ func InitRouter() *gin.Engine {
 		// 借还管理
 		InitBorrowRouter(apiRouter)
 
+		// 图书丢失、损坏赔偿
+		InitLossRouter(apiRouter)
+
+		// 流通台扫码借还
+		InitDeskRouter(apiRouter)
+
 		// 统计查询
 		InitStatisticsRouter(apiRouter)
 
 		// 系统管理
 		InitSystemRouter(apiRouter)
 
+		// 角色权限管理
+		InitPermissionRouter(apiRouter)
+
+		// 审计日志
+		InitAuditRouter(apiRouter)
+
+		// 开馆日历
+		InitCalendarRouter(apiRouter)
+
+		// 读者类型借阅规则
+		InitLoanPolicyRouter(apiRouter)
+
 		// 点赞功能
 		InitLikeRouter(apiRouter)
 
@@ -55,17 +85,41 @@ func InitRouter() *gin.Engine {
 		// 榜单功能
 		InitRankingRouter(apiRouter)
 
+		// 图书评价
+		InitReviewRouter(apiRouter)
+
+		// 图书推荐
+		InitRecommendRouter(apiRouter)
+
+		// 点赞收藏同步队列管理
+		InitSyncStreamRouter(apiRouter)
+
+		// 点赞收藏 Redis 与 MySQL 校对
+		InitReconcileRouter(apiRouter)
+
 		// 预约管理
 		InitReservationRouter(apiRouter)
 
 		// 罚款管理
 		InitFineRouter(apiRouter)
 
+		// 罚款在线支付
+		InitPaymentRouter(apiRouter)
+
+		// 罚款账务
+		InitLedgerRouter(apiRouter)
+
 		// 黑名单管理
 		InitBlacklistRouter(apiRouter)
 
 		// 消息管理
 		InitMessageRouter(apiRouter)
+
+		// 实时通知推送
+		InitNotifyRouter(apiRouter)
+
+		// 待执行事件
+		InitOutboxRouter(apiRouter)
 	}
 
 	return Router
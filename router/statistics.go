@@ -12,7 +12,7 @@ func InitStatisticsRouter(Router *gin.RouterGroup) {
 	statisticsApi := v1.StatisticsApi{}
 	{
 		statisticsRouter.Use(middleware.JWTAuth())
-		statisticsRouter.Use(middleware.RequireRole(model.RoleAdmin, model.RoleLibrarian))
+		statisticsRouter.Use(middleware.RequirePermission(model.PermStatisticsView))
 		statisticsRouter.GET("getStatistics", statisticsApi.GetStatistics)           // 获取统计信息
 		statisticsRouter.GET("getBorrowStatistics", statisticsApi.GetBorrowStatistics) // 获取借阅统计
 		statisticsRouter.GET("getPopularBooks", statisticsApi.GetPopularBooks)        // 获取热门图书
@@ -0,0 +1,23 @@
+package router
+
+import (
+	v1 "bookadmin/api/v1"
+	"bookadmin/middleware"
+	"bookadmin/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InitSyncStreamRouter(Router *gin.RouterGroup) {
+	syncRouter := Router.Group("syncStream")
+	syncApi := v1.SyncStreamApi{}
+	{
+		syncRouter.Use(middleware.JWTAuth())
+		syncRouter.Use(middleware.RequirePermission(model.PermSyncManage))
+		syncRouter.GET("getStats", syncApi.GetSyncStats)                  // 同步队列消费状态
+		syncRouter.GET("getPending", syncApi.GetPendingMessages)          // 待确认消息列表
+		syncRouter.GET("getDeadLetters", syncApi.GetDeadLetters)          // 死信消息列表
+		syncRouter.POST("replayDeadLetters", syncApi.ReplayDeadLetters)   // 重放死信消息
+		syncRouter.POST("discardDeadLetters", syncApi.DiscardDeadLetters) // 丢弃死信消息
+	}
+}
@@ -12,11 +12,12 @@ func InitSystemRouter(Router *gin.RouterGroup) {
 	systemApi := v1.SystemApi{}
 	{
 		systemRouter.Use(middleware.JWTAuth())
-		systemRouter.Use(middleware.RequireRole(model.RoleAdmin))
-		systemRouter.GET("getUserList", systemApi.GetUserList)   // 获取用户列表
-		systemRouter.POST("createUser", systemApi.CreateUser)    // 创建用户
-		systemRouter.PUT("updateUser", systemApi.UpdateUser)     // 更新用户
-		systemRouter.DELETE("deleteUser", systemApi.DeleteUser)  // 删除用户
+		systemRouter.GET("getUserList", middleware.RequirePermission(model.PermUserManage), systemApi.GetUserList)   // 获取用户列表
+		systemRouter.POST("createUser", middleware.RequirePermission(model.PermUserManage), systemApi.CreateUser)    // 创建用户
+		systemRouter.PUT("updateUser", middleware.RequirePermission(model.PermUserManage), systemApi.UpdateUser)     // 更新用户
+		systemRouter.DELETE("deleteUser", middleware.RequirePermission(model.PermUserManage), systemApi.DeleteUser)  // 删除用户
+		systemRouter.GET("getConfigList", middleware.RequirePermission(model.PermConfigEdit), systemApi.GetConfigList) // 获取系统配置
+		systemRouter.PUT("updateConfig", middleware.RequirePermission(model.PermConfigEdit), systemApi.UpdateConfig)   // 修改系统配置
 	}
 }
 
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AuditService 审计日志服务
+// 审计记录必须与业务变更写在同一个事务中，写入失败时整个操作回滚
+type AuditService struct{}
+
+// AuditQuery 审计日志查询条件
+type AuditQuery struct {
+	ActorID    uint
+	Action     string
+	TargetType string
+	TargetID   uint
+	StartTime  *time.Time
+	EndTime    *time.Time
+}
+
+// Record 在事务中写入一条审计日志，before/after 会序列化为JSON
+func (s *AuditService) Record(tx *gorm.DB, actor model.AuditActor, action model.AuditAction, targetType string, targetID uint, before, after interface{}) error {
+	log := model.AuditLog{
+		ActorID:    actor.UserID,
+		ActorName:  actor.Username,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Before:     marshalAuditValue(before),
+		After:      marshalAuditValue(after),
+		IP:         actor.IP,
+	}
+
+	if err := tx.Create(&log).Error; err != nil {
+		global.GVA_LOG.Error("写入审计日志失败", zap.String("action", string(action)), zap.Error(err))
+		return errors.New("写入审计日志失败")
+	}
+	return nil
+}
+
+// Query 构造审计日志查询
+func (s *AuditService) Query(q AuditQuery) *gorm.DB {
+	db := global.GVA_DB.Model(&model.AuditLog{})
+	if q.ActorID > 0 {
+		db = db.Where("actor_id = ?", q.ActorID)
+	}
+	if q.Action != "" {
+		db = db.Where("action = ?", q.Action)
+	}
+	if q.TargetType != "" {
+		db = db.Where("target_type = ?", q.TargetType)
+	}
+	if q.TargetID > 0 {
+		db = db.Where("target_id = ?", q.TargetID)
+	}
+	if q.StartTime != nil {
+		db = db.Where("created_at >= ?", *q.StartTime)
+	}
+	if q.EndTime != nil {
+		db = db.Where("created_at < ?", *q.EndTime)
+	}
+	return db
+}
+
+func marshalAuditValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		global.GVA_LOG.Warn("审计数据序列化失败", zap.Error(err))
+		return ""
+	}
+	return string(data)
+}
+
+// 全局审计服务实例
+var GlobalAuditService = &AuditService{}
@@ -13,7 +13,7 @@ import (
 type BlacklistService struct{}
 
 // AddToBlacklist 添加到黑名单
-func (s *BlacklistService) AddToBlacklist(readerID uint, reason model.BlacklistReason, description string, endDate *time.Time, operatorID uint) error {
+func (s *BlacklistService) AddToBlacklist(readerID uint, reason model.BlacklistReason, description string, endDate *time.Time, actor model.AuditActor) error {
 	tx := global.GVA_DB.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -37,7 +37,7 @@ func (s *BlacklistService) AddToBlacklist(readerID uint, reason model.BlacklistR
 		Status:      model.BlacklistStatusActive,
 		StartDate:   time.Now(),
 		EndDate:     endDate,
-		OperatorID:  operatorID,
+		OperatorID:  actor.UserID,
 	}
 
 	if err := tx.Create(&blacklist).Error; err != nil {
@@ -56,6 +56,11 @@ func (s *BlacklistService) AddToBlacklist(readerID uint, reason model.BlacklistR
 		return errors.New("更新读者状态失败")
 	}
 
+	if err := GlobalAuditService.Record(tx, actor, model.AuditBlacklistAdd, "reader", readerID, nil, blacklist); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return errors.New("添加黑名单失败")
 	}
@@ -65,7 +70,7 @@ func (s *BlacklistService) AddToBlacklist(readerID uint, reason model.BlacklistR
 }
 
 // RemoveFromBlacklist 从黑名单移除（解禁）
-func (s *BlacklistService) RemoveFromBlacklist(blacklistID uint, operatorID uint, remark string) error {
+func (s *BlacklistService) RemoveFromBlacklist(blacklistID uint, actor model.AuditActor, remark string) error {
 	tx := global.GVA_DB.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -86,13 +91,15 @@ func (s *BlacklistService) RemoveFromBlacklist(blacklistID uint, operatorID uint
 	}
 
 	// 更新黑名单状态
+	before := map[string]interface{}{"status": blacklist.Status, "remark": blacklist.Remark}
 	now := time.Now()
-	if err := tx.Model(&blacklist).Updates(map[string]interface{}{
+	after := map[string]interface{}{
 		"status":      model.BlacklistStatusLifted,
 		"lifted_date": now,
-		"operator_id": operatorID,
+		"operator_id": actor.UserID,
 		"remark":      remark,
-	}).Error; err != nil {
+	}
+	if err := tx.Model(&blacklist).Updates(after).Error; err != nil {
 		tx.Rollback()
 		return errors.New("更新黑名单记录失败")
 	}
@@ -115,6 +122,11 @@ func (s *BlacklistService) RemoveFromBlacklist(blacklistID uint, operatorID uint
 		}
 	}
 
+	if err := GlobalAuditService.Record(tx, actor, model.AuditBlacklistRemove, "blacklist", blacklistID, before, after); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return errors.New("解除黑名单失败")
 	}
@@ -146,12 +158,15 @@ func (s *BlacklistService) CheckAndAddOverdueBlacklist() error {
 			continue
 		}
 
-		// 计算逾期天数
-		overdueDays := int(time.Now().Sub(record.DueDate).Hours() / 24)
+		// 计算逾期天数（闭馆日不计入）
+		overdueDays := GlobalCalendarService.CountOverdueDays(record.DueDate, time.Now())
+		if overdueDays < blacklistDays {
+			continue
+		}
 
 		// 添加到黑名单
 		description := fmt.Sprintf("借阅《%s》逾期%d天未还", record.Book.Title, overdueDays)
-		if err := s.AddToBlacklist(record.ReaderID, model.BlacklistReasonOverdue, description, nil, 0); err != nil {
+		if err := s.AddToBlacklist(record.ReaderID, model.BlacklistReasonOverdue, description, nil, model.SystemActor); err != nil {
 			global.GVA_LOG.Error("自动拉黑失败", zap.Error(err), zap.Uint("reader_id", record.ReaderID))
 			continue
 		}
@@ -0,0 +1,427 @@
+package service
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BookCopyService 馆藏副本服务
+// 图书的 TotalStock/AvailableStock 由副本状态推导，所有副本变更后都需要调用 SyncBookStock；
+// 变更副本状态前先调用 LockBook 锁定图书行，同一本书的借出、归还、上架依次执行
+type BookCopyService struct{}
+
+// NewBookCopyService 创建馆藏副本服务实例
+func NewBookCopyService() *BookCopyService {
+	return &BookCopyService{}
+}
+
+// AddCopies 为图书新增副本，条码为空时自动生成
+func (s *BookCopyService) AddCopies(bookID uint, copies []model.BookCopy, actor model.AuditActor) ([]model.BookCopy, error) {
+	if len(copies) == 0 {
+		return nil, errors.New("副本列表不能为空")
+	}
+
+	tx := global.GVA_DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err := s.LockBook(tx, bookID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	created, err := s.CreateCopies(tx, bookID, copies)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := s.SyncBookStock(tx, bookID); err != nil {
+		tx.Rollback()
+		return nil, errors.New("更新库存失败")
+	}
+
+	barcodes := make([]string, 0, len(created))
+	for _, c := range created {
+		barcodes = append(barcodes, c.Barcode)
+	}
+	if err := GlobalAuditService.Record(tx, actor, model.AuditCopyAdd, "book", bookID, nil, map[string]interface{}{
+		"barcodes": barcodes,
+	}); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 新副本可借，通知排队中的预约者
+	if err := NewReservationService().EnqueueHoldNotify(tx, bookID, fmt.Sprintf("hold:copy:%d", created[0].ID)); err != nil {
+		tx.Rollback()
+		return nil, errors.New("新增副本失败")
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, errors.New("新增副本失败")
+	}
+	GlobalOutboxService.Wake()
+
+	global.GVA_LOG.Info("新增馆藏副本成功", zap.Uint("book_id", bookID), zap.Int("count", len(created)))
+
+	return created, nil
+}
+
+// GenerateCopies 按数量自动生成副本（创建图书时使用，需在事务中调用）
+func (s *BookCopyService) GenerateCopies(tx *gorm.DB, bookID uint, count int, shelfLocation string) error {
+	if count > 0 {
+		copies := make([]model.BookCopy, count)
+		for i := range copies {
+			copies[i].ShelfLocation = shelfLocation
+		}
+		if _, err := s.CreateCopies(tx, bookID, copies); err != nil {
+			return err
+		}
+	}
+	return s.SyncBookStock(tx, bookID)
+}
+
+// RetireCopy 剔旧副本（借出中的副本不能剔旧）
+func (s *BookCopyService) RetireCopy(copyID uint, remark string, actor model.AuditActor) error {
+	tx := global.GVA_DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var bookCopy model.BookCopy
+	if err := tx.First(&bookCopy, copyID).Error; err != nil {
+		tx.Rollback()
+		return errors.New("副本不存在")
+	}
+
+	// 锁定图书后重新读取副本状态，防止与借出同时进行时剔旧借出中的副本
+	if _, err := s.LockBook(tx, bookCopy.BookID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&bookCopy, copyID).Error; err != nil {
+		tx.Rollback()
+		return errors.New("副本不存在")
+	}
+
+	if bookCopy.Status == model.CopyStatusBorrowed {
+		tx.Rollback()
+		return errors.New("副本借出中，无法剔旧")
+	}
+	if bookCopy.Status == model.CopyStatusRetired {
+		tx.Rollback()
+		return errors.New("副本已剔旧")
+	}
+
+	now := time.Now()
+	if err := tx.Model(&bookCopy).Updates(map[string]interface{}{
+		"status":       model.CopyStatusRetired,
+		"retired_date": now,
+		"remark":       remark,
+	}).Error; err != nil {
+		tx.Rollback()
+		return errors.New("剔旧副本失败")
+	}
+
+	if err := s.SyncBookStock(tx, bookCopy.BookID); err != nil {
+		tx.Rollback()
+		return errors.New("更新库存失败")
+	}
+
+	if err := GlobalAuditService.Record(tx, actor, model.AuditCopyRetire, "book_copy", copyID,
+		map[string]interface{}{"status": bookCopy.Status, "remark": bookCopy.Remark},
+		map[string]interface{}{"status": model.CopyStatusRetired, "remark": remark},
+	); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.New("剔旧副本失败")
+	}
+
+	global.GVA_LOG.Info("剔旧副本成功", zap.Uint("copy_id", copyID), zap.String("barcode", bookCopy.Barcode))
+	return nil
+}
+
+// RepairCopy 修补完成的副本重新上架：状态由修补中改为在架可借，品相记为一般，
+// 同步库存后通知预约队列
+func (s *BookCopyService) RepairCopy(copyID uint, remark string, actor model.AuditActor) error {
+	tx := global.GVA_DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var bookCopy model.BookCopy
+	if err := tx.First(&bookCopy, copyID).Error; err != nil {
+		tx.Rollback()
+		return errors.New("副本不存在")
+	}
+
+	if _, err := s.LockBook(tx, bookCopy.BookID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&bookCopy, copyID).Error; err != nil {
+		tx.Rollback()
+		return errors.New("副本不存在")
+	}
+
+	if bookCopy.Status != model.CopyStatusMaintenance {
+		tx.Rollback()
+		return errors.New("副本不在修补中")
+	}
+
+	updates := map[string]interface{}{
+		"status":         model.CopyStatusAvailable,
+		"copy_condition": model.CopyConditionFair,
+	}
+	if remark != "" {
+		updates["remark"] = remark
+	}
+	if err := tx.Model(&bookCopy).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		return errors.New("副本上架失败")
+	}
+
+	if err := s.SyncBookStock(tx, bookCopy.BookID); err != nil {
+		tx.Rollback()
+		return errors.New("更新库存失败")
+	}
+
+	// 同一副本可能多次送修，幂等键带上时间戳区分每次上架
+	key := fmt.Sprintf("hold:repair:%d:%d", copyID, time.Now().UnixNano())
+	if err := NewReservationService().EnqueueHoldNotify(tx, bookCopy.BookID, key); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := GlobalAuditService.Record(tx, actor, model.AuditCopyRepair, "book_copy", copyID,
+		map[string]interface{}{"status": bookCopy.Status, "condition": bookCopy.Condition},
+		updates,
+	); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.New("副本上架失败")
+	}
+	GlobalOutboxService.Wake()
+
+	global.GVA_LOG.Info("修补副本上架", zap.Uint("copy_id", copyID), zap.String("barcode", bookCopy.Barcode))
+	return nil
+}
+
+// RelocateCopy 调整副本架位
+func (s *BookCopyService) RelocateCopy(copyID uint, shelfLocation string, actor model.AuditActor) error {
+	shelfLocation = strings.TrimSpace(shelfLocation)
+	if shelfLocation == "" {
+		return errors.New("架位不能为空")
+	}
+
+	tx := global.GVA_DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var bookCopy model.BookCopy
+	if err := tx.Where("id = ? AND status <> ?", copyID, model.CopyStatusRetired).First(&bookCopy).Error; err != nil {
+		tx.Rollback()
+		return errors.New("副本不存在或已剔旧")
+	}
+
+	if err := tx.Model(&bookCopy).Update("shelf_location", shelfLocation).Error; err != nil {
+		tx.Rollback()
+		global.GVA_LOG.Error("调整架位失败", zap.Error(err))
+		return errors.New("调整架位失败")
+	}
+
+	if err := GlobalAuditService.Record(tx, actor, model.AuditCopyRelocate, "book_copy", copyID,
+		map[string]interface{}{"shelf_location": bookCopy.ShelfLocation},
+		map[string]interface{}{"shelf_location": shelfLocation},
+	); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.New("调整架位失败")
+	}
+
+	global.GVA_LOG.Info("调整架位成功", zap.Uint("copy_id", copyID), zap.String("shelf_location", shelfLocation))
+	return nil
+}
+
+// GetCopyByBarcode 根据条码查询副本
+func (s *BookCopyService) GetCopyByBarcode(barcode string) (*model.BookCopy, error) {
+	var bookCopy model.BookCopy
+	if err := global.GVA_DB.Where("barcode = ?", strings.TrimSpace(barcode)).First(&bookCopy).Error; err != nil {
+		return nil, errors.New("条码不存在")
+	}
+	return &bookCopy, nil
+}
+
+// LockBook 锁定图书行（需在事务中调用）
+// 锁定顺序为 借阅记录 → 读者 → 图书 → 副本、读者 → 罚款，各业务按此顺序加锁以避免死锁
+func (s *BookCopyService) LockBook(tx *gorm.DB, bookID uint) (*model.Book, error) {
+	var book model.Book
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&book, bookID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("图书不存在")
+		}
+		return nil, err
+	}
+	return &book, nil
+}
+
+// CheckoutCopy 借出一本副本（需在事务中调用）
+// copyID 为空时自动挑选一本在架副本
+func (s *BookCopyService) CheckoutCopy(tx *gorm.DB, bookID uint, copyID *uint) (*model.BookCopy, error) {
+	if _, err := s.LockBook(tx, bookID); err != nil {
+		return nil, err
+	}
+
+	// 加锁读取，看到其他事务刚提交的借出和归还
+	var bookCopy model.BookCopy
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("book_id = ? AND status = ?", bookID, model.CopyStatusAvailable)
+	if copyID != nil {
+		query = query.Where("id = ?", *copyID)
+	}
+	if err := query.Order("id ASC").First(&bookCopy).Error; err != nil {
+		if copyID != nil {
+			return nil, errors.New("该副本不可借")
+		}
+		return nil, errors.New("图书库存不足")
+	}
+
+	// 条件更新，防止同一副本被重复借出
+	result := tx.Model(&model.BookCopy{}).
+		Where("id = ? AND status = ?", bookCopy.ID, model.CopyStatusAvailable).
+		Update("status", model.CopyStatusBorrowed)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("该副本不可借")
+	}
+	bookCopy.Status = model.CopyStatusBorrowed
+
+	if err := s.SyncBookStock(tx, bookID); err != nil {
+		return nil, err
+	}
+	return &bookCopy, nil
+}
+
+// CheckinCopy 归还副本（需在事务中调用）
+func (s *BookCopyService) CheckinCopy(tx *gorm.DB, bookID uint, copyID *uint) error {
+	if _, err := s.LockBook(tx, bookID); err != nil {
+		return err
+	}
+	if copyID != nil {
+		if err := tx.Model(&model.BookCopy{}).
+			Where("id = ? AND status = ?", *copyID, model.CopyStatusBorrowed).
+			Update("status", model.CopyStatusAvailable).Error; err != nil {
+			return err
+		}
+	} else {
+		global.GVA_LOG.Warn("借阅记录未关联副本，仅重新计算库存", zap.Uint("book_id", bookID))
+	}
+	return s.SyncBookStock(tx, bookID)
+}
+
+// SyncBookStock 根据副本状态重新计算图书库存（需在事务中调用）
+// 统计使用加锁读取，按最新提交的副本状态计算，不受事务开始时快照的影响
+func (s *BookCopyService) SyncBookStock(tx *gorm.DB, bookID uint) error {
+	var totalStock, availableStock int64
+	if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).Model(&model.BookCopy{}).
+		Where("book_id = ? AND status NOT IN ?", bookID, []model.BookCopyStatus{model.CopyStatusLost, model.CopyStatusRetired}).
+		Count(&totalStock).Error; err != nil {
+		return err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).Model(&model.BookCopy{}).
+		Where("book_id = ? AND status = ?", bookID, model.CopyStatusAvailable).
+		Count(&availableStock).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&model.Book{}).Where("id = ?", bookID).Updates(map[string]interface{}{
+		"total_stock":     totalStock,
+		"available_stock": availableStock,
+	}).Error
+}
+
+// CreateCopies 批量创建副本，条码为空时自动生成（需在事务中调用）
+func (s *BookCopyService) CreateCopies(tx *gorm.DB, bookID uint, copies []model.BookCopy) ([]model.BookCopy, error) {
+	// 已存在（含已删除）的副本数，用于生成条码序号
+	var seq int64
+	tx.Unscoped().Model(&model.BookCopy{}).Where("book_id = ?", bookID).Count(&seq)
+
+	now := time.Now()
+	for i := range copies {
+		c := &copies[i]
+		c.ID = 0
+		c.BookID = bookID
+		c.Barcode = strings.TrimSpace(c.Barcode)
+		if c.Condition == "" {
+			c.Condition = model.CopyConditionGood
+		}
+		if !model.IsValidCopyCondition(c.Condition) {
+			return nil, fmt.Errorf("品相取值无效: %s", c.Condition)
+		}
+		if c.Status == "" {
+			c.Status = model.CopyStatusAvailable
+		}
+		if c.AcquisitionDate == nil {
+			c.AcquisitionDate = &now
+		}
+
+		if c.Barcode == "" {
+			for {
+				seq++
+				c.Barcode = GenerateCopyBarcode(bookID, int(seq))
+				var exist int64
+				tx.Unscoped().Model(&model.BookCopy{}).Where("barcode = ?", c.Barcode).Count(&exist)
+				if exist == 0 {
+					break
+				}
+			}
+		} else {
+			var exist int64
+			tx.Unscoped().Model(&model.BookCopy{}).Where("barcode = ?", c.Barcode).Count(&exist)
+			if exist > 0 {
+				return nil, fmt.Errorf("条码已存在: %s", c.Barcode)
+			}
+		}
+	}
+
+	if err := tx.Create(&copies).Error; err != nil {
+		global.GVA_LOG.Error("创建副本失败", zap.Error(err))
+		return nil, errors.New("创建副本失败")
+	}
+	return copies, nil
+}
+
+// GenerateCopyBarcode 生成副本条码（B + 6位图书ID + 4位序号）
+func GenerateCopyBarcode(bookID uint, seq int) string {
+	return fmt.Sprintf("B%06d%04d", bookID, seq)
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"bookadmin/utils"
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// bookExportBatchSize 导出时每批读取的图书数量
+const bookExportBatchSize = 500
+
+// bookExportHeader 表格导出的表头，与导入识别的表头一致，导出的文件可以直接再导入
+var bookExportHeader = []string{"ISBN", "书名", "作者", "出版社", "出版日期", "价格", "分类", "描述", "封面图片", "总库存", "可借库存"}
+
+// bookRowWriter 按格式写出图书
+type bookRowWriter interface {
+	Write(book *model.Book) error
+	Close() error
+}
+
+// runExport 执行导出任务，按批读取图书写入文件
+func (s *BookJobService) runExport(job *model.BookJob, opts BookExportOptions) error {
+	job.FilePath = filepath.Join(global.GVA_CONFIG.Storage.Path, "exports", fmt.Sprintf("%d%s", job.ID, bookFileExt(job.Format)))
+	if err := os.MkdirAll(filepath.Dir(job.FilePath), 0o755); err != nil {
+		return errors.New("创建导出目录失败")
+	}
+
+	f, err := os.Create(job.FilePath)
+	if err != nil {
+		return errors.New("创建导出文件失败")
+	}
+	defer f.Close()
+
+	buf := bufio.NewWriter(f)
+	writer, err := newBookRowWriter(buf, job.Format)
+	if err != nil {
+		return err
+	}
+
+	db := global.GVA_DB.Model(&model.Book{}).Preload("Categories")
+	if opts.CategoryID > 0 {
+		db = db.Where("id IN (?)", global.GVA_DB.Model(&model.BookCategory{}).Select("book_id").Where("category_id = ?", opts.CategoryID))
+	}
+	if keyword := strings.TrimSpace(opts.Keyword); keyword != "" {
+		like := "%" + keyword + "%"
+		db = db.Where("title LIKE ? OR author LIKE ? OR publisher LIKE ? OR isbn LIKE ?", like, like, like, like)
+	}
+
+	var books []model.Book
+	var writeErr error
+	result := db.FindInBatches(&books, bookExportBatchSize, func(tx *gorm.DB, batch int) error {
+		for i := range books {
+			if err := writer.Write(&books[i]); err != nil {
+				writeErr = err
+				return err
+			}
+			job.TotalRows++
+		}
+		s.saveProgress(job)
+		return nil
+	})
+	if writeErr != nil {
+		return errors.New("写入导出文件失败")
+	}
+	if result.Error != nil {
+		return errors.New("读取图书失败")
+	}
+
+	if err := writer.Close(); err != nil {
+		return errors.New("写入导出文件失败")
+	}
+	if err := buf.Flush(); err != nil {
+		return errors.New("写入导出文件失败")
+	}
+	return nil
+}
+
+func newBookRowWriter(w *bufio.Writer, format model.BookFileFormat) (bookRowWriter, error) {
+	switch format {
+	case model.BookFormatCSV:
+		// 写入BOM，保证Excel正确识别UTF-8
+		_, _ = w.WriteString("\xEF\xBB\xBF")
+		cw := csv.NewWriter(w)
+		if err := cw.Write(bookExportHeader); err != nil {
+			return nil, err
+		}
+		return &csvBookWriter{w: cw}, nil
+	case model.BookFormatXLSX:
+		xw, err := utils.NewXLSXWriter(w, "图书")
+		if err != nil {
+			return nil, err
+		}
+		if err := xw.WriteRow(bookExportHeader); err != nil {
+			return nil, err
+		}
+		return &xlsxBookWriter{w: xw}, nil
+	case model.BookFormatMARC:
+		return &marcBookWriter{w: w}, nil
+	case model.BookFormatMARCXML:
+		mw, err := utils.NewMarcXMLWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		return &marcXMLBookWriter{w: mw}, nil
+	default:
+		return nil, errors.New("不支持的文件格式")
+	}
+}
+
+// bookTableRow 图书的表格行
+func bookTableRow(book *model.Book) []string {
+	names := make([]string, 0, len(book.Categories))
+	for _, c := range book.Categories {
+		names = append(names, c.Name)
+	}
+	return []string{
+		book.ISBN,
+		book.Title,
+		book.Author,
+		book.Publisher,
+		book.PublishDate,
+		strconv.FormatFloat(book.Price, 'f', 2, 64),
+		strings.Join(names, ";"),
+		book.Description,
+		book.CoverImage,
+		strconv.Itoa(book.TotalStock),
+		strconv.Itoa(book.AvailableStock),
+	}
+}
+
+// bookMarcRecord 把图书转换为MARC21书目记录，字段与导入时的映射一致
+func bookMarcRecord(book *model.Book) utils.MarcRecord {
+	record := utils.MarcRecord{}
+	record.Fields = append(record.Fields, utils.MarcField{Tag: "001", Value: strconv.FormatUint(uint64(book.ID), 10)})
+
+	price := ""
+	if book.Price > 0 {
+		price = strconv.FormatFloat(book.Price, 'f', 2, 64)
+	}
+	record.AddDataField("020", " ", " ", utils.MarcSubfield{Code: "a", Value: book.ISBN}, utils.MarcSubfield{Code: "c", Value: price})
+	record.AddDataField("100", "1", " ", utils.MarcSubfield{Code: "a", Value: book.Author})
+	record.AddDataField("245", "1", "0", utils.MarcSubfield{Code: "a", Value: book.Title})
+	record.AddDataField("264", " ", "1", utils.MarcSubfield{Code: "b", Value: book.Publisher}, utils.MarcSubfield{Code: "c", Value: book.PublishDate})
+	record.AddDataField("520", " ", " ", utils.MarcSubfield{Code: "a", Value: book.Description})
+	for _, c := range book.Categories {
+		record.AddDataField("650", " ", "4", utils.MarcSubfield{Code: "a", Value: c.Name})
+	}
+	record.AddDataField("856", "4", "0", utils.MarcSubfield{Code: "u", Value: book.CoverImage})
+	return record
+}
+
+type csvBookWriter struct{ w *csv.Writer }
+
+func (c *csvBookWriter) Write(book *model.Book) error { return c.w.Write(bookTableRow(book)) }
+func (c *csvBookWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type xlsxBookWriter struct{ w *utils.XLSXWriter }
+
+func (x *xlsxBookWriter) Write(book *model.Book) error { return x.w.WriteRow(bookTableRow(book)) }
+func (x *xlsxBookWriter) Close() error                 { return x.w.Close() }
+
+type marcBookWriter struct{ w *bufio.Writer }
+
+func (m *marcBookWriter) Write(book *model.Book) error {
+	return utils.WriteMARC21(m.w, bookMarcRecord(book))
+}
+func (m *marcBookWriter) Close() error { return nil }
+
+type marcXMLBookWriter struct{ w *utils.MarcXMLWriter }
+
+func (m *marcXMLBookWriter) Write(book *model.Book) error { return m.w.Write(bookMarcRecord(book)) }
+func (m *marcXMLBookWriter) Close() error                 { return m.w.Close() }
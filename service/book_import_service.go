@@ -0,0 +1,551 @@
+package service
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"bookadmin/utils"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// bookImportMaxRows 单个文件最多导入的行数
+	bookImportMaxRows = 50000
+	// bookImportMaxCopies 单行最多生成的副本数
+	bookImportMaxCopies = 1000
+	// bookImportProgressEvery 每处理多少行保存一次进度
+	bookImportProgressEvery = 200
+)
+
+// bookImportColumns 表头别名 -> 字段，表头不区分大小写，导出文件的表头也能直接导入
+var bookImportColumns = map[string]string{
+	"isbn":           "isbn",
+	"title":          "title",
+	"书名":             "title",
+	"题名":             "title",
+	"author":         "author",
+	"作者":             "author",
+	"责任者":            "author",
+	"publisher":      "publisher",
+	"出版社":            "publisher",
+	"publish_date":   "publish_date",
+	"出版日期":           "publish_date",
+	"出版时间":           "publish_date",
+	"price":          "price",
+	"价格":             "price",
+	"定价":             "price",
+	"description":    "description",
+	"描述":             "description",
+	"简介":             "description",
+	"categories":     "categories",
+	"category":       "categories",
+	"分类":             "categories",
+	"cover_image":    "cover_image",
+	"封面图片":           "cover_image",
+	"copies":         "copies",
+	"副本数":            "copies",
+	"total_stock":    "copies",
+	"总库存":            "copies",
+	"shelf_location": "shelf_location",
+	"架位":             "shelf_location",
+}
+
+// categorySeparator 分类列中多个分类的分隔符
+var categorySeparator = regexp.MustCompile(`[|;；]`)
+
+// priceNumber 从MARC价格字段（如 CNY45.00）中提取数字
+var priceNumber = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// bookRow 导入文件中的一行，字段为空表示未提供
+type bookRow struct {
+	Row           int
+	ISBN          string
+	Title         string
+	Author        string
+	Publisher     string
+	PublishDate   string
+	Price         string
+	Description   string
+	Categories    []string
+	CoverImage    string
+	Copies        string
+	ShelfLocation string
+}
+
+// runImport 执行导入任务：解析文件，逐行校验并写入，每行一个事务，失败的行记录到错误报告
+func (s *BookJobService) runImport(job *model.BookJob, opts BookImportOptions, actor model.AuditActor) error {
+	rows, rowErrors, err := parseBookFile(job.FilePath, job.Format)
+	if err != nil {
+		return err
+	}
+	if len(rows)+len(rowErrors) > bookImportMaxRows {
+		return fmt.Errorf("单个文件最多导入%d行", bookImportMaxRows)
+	}
+	job.TotalRows = len(rows) + len(rowErrors)
+	job.FailedRows = len(rowErrors)
+	s.saveProgress(job)
+
+	existing, err := loadBookISBNIndex()
+	if err != nil {
+		return errors.New("加载图书ISBN失败")
+	}
+	categories, err := loadCategoryIndex()
+	if err != nil {
+		return errors.New("加载分类失败")
+	}
+
+	for i, row := range rows {
+		created, err := s.importRow(row, existing, categories, opts, actor)
+		switch {
+		case err != nil:
+			job.FailedRows++
+			rowErrors = append(rowErrors, model.BookJobRowError{Row: row.Row, ISBN: row.ISBN, Message: err.Error()})
+		case created:
+			job.CreatedRows++
+		default:
+			job.UpdatedRows++
+		}
+
+		if (i+1)%bookImportProgressEvery == 0 {
+			s.saveProgress(job)
+		}
+	}
+
+	if len(rowErrors) > 0 {
+		report, _ := json.Marshal(rowErrors)
+		job.ErrorReport = string(report)
+	}
+	return nil
+}
+
+// importRow 导入一行，返回是否新建了图书
+func (s *BookJobService) importRow(row bookRow, existing map[string]uint, categories map[string]uint, opts BookImportOptions, actor model.AuditActor) (bool, error) {
+	if row.ISBN == "" {
+		return false, errors.New("ISBN不能为空")
+	}
+	isbn, err := utils.NormalizeISBN(row.ISBN)
+	if err != nil {
+		return false, err
+	}
+
+	var price float64
+	if row.Price != "" {
+		price, err = strconv.ParseFloat(row.Price, 64)
+		if err != nil || price < 0 {
+			return false, errors.New("价格格式错误")
+		}
+	}
+
+	copies := 0
+	if row.Copies != "" {
+		copies, err = strconv.Atoi(row.Copies)
+		if err != nil || copies < 0 || copies > bookImportMaxCopies {
+			return false, fmt.Errorf("副本数应为0-%d的整数", bookImportMaxCopies)
+		}
+	}
+
+	bookID, exists := existing[isbn]
+	if exists && !opts.UpdateExisting {
+		return false, errors.New("ISBN已存在")
+	}
+
+	categoryIDs, err := resolveImportCategories(row.Categories, categories, opts.CreateCategories)
+	if err != nil {
+		return false, err
+	}
+
+	tx := global.GVA_DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if exists {
+		var book model.Book
+		if err := tx.First(&book, bookID).Error; err != nil {
+			tx.Rollback()
+			return false, errors.New("匹配的图书已被删除")
+		}
+		before := book
+
+		// 只更新文件中提供的字段，库存由副本管理维护，不随导入修改
+		updateData := map[string]interface{}{}
+		setIfPresent(updateData, "title", row.Title)
+		setIfPresent(updateData, "author", row.Author)
+		setIfPresent(updateData, "publisher", row.Publisher)
+		setIfPresent(updateData, "publish_date", row.PublishDate)
+		setIfPresent(updateData, "description", row.Description)
+		setIfPresent(updateData, "cover_image", row.CoverImage)
+		if row.Price != "" {
+			updateData["price"] = price
+		}
+
+		if len(updateData) > 0 {
+			if err := tx.Model(&book).Updates(updateData).Error; err != nil {
+				tx.Rollback()
+				return false, errors.New("更新图书失败")
+			}
+		}
+		if len(categoryIDs) > 0 {
+			if err := replaceBookCategories(tx, &book, categoryIDs); err != nil {
+				tx.Rollback()
+				return false, err
+			}
+			updateData["category_ids"] = categoryIDs
+		}
+
+		if err := GlobalAuditService.Record(tx, actor, model.AuditBookUpdate, "book", book.ID, before, updateData); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+		if err := tx.Commit().Error; err != nil {
+			return false, errors.New("更新图书失败")
+		}
+		GlobalSearchService.IndexBooks(book.ID)
+		return false, nil
+	}
+
+	if row.Title == "" || row.Author == "" {
+		tx.Rollback()
+		return false, errors.New("新建图书时书名和作者不能为空")
+	}
+
+	book := model.Book{
+		Title:       row.Title,
+		Author:      row.Author,
+		Publisher:   row.Publisher,
+		PublishDate: row.PublishDate,
+		ISBN:        isbn,
+		Price:       price,
+		Description: row.Description,
+		CoverImage:  row.CoverImage,
+	}
+	if err := tx.Create(&book).Error; err != nil {
+		tx.Rollback()
+		global.GVA_LOG.Warn("导入新建图书失败", zap.String("isbn", isbn), zap.Error(err))
+		return false, errors.New("新建图书失败（ISBN可能与已删除的图书重复）")
+	}
+	if len(categoryIDs) > 0 {
+		if err := replaceBookCategories(tx, &book, categoryIDs); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+
+	shelfLocation := row.ShelfLocation
+	if shelfLocation == "" {
+		shelfLocation = opts.ShelfLocation
+	}
+	if err := s.copyService.GenerateCopies(tx, book.ID, copies, shelfLocation); err != nil {
+		tx.Rollback()
+		return false, errors.New("生成馆藏副本失败")
+	}
+
+	if err := GlobalAuditService.Record(tx, actor, model.AuditBookCreate, "book", book.ID, nil, map[string]interface{}{
+		"book":         book,
+		"category_ids": categoryIDs,
+		"copies":       copies,
+	}); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return false, errors.New("新建图书失败")
+	}
+	GlobalSearchService.IndexBooks(book.ID)
+
+	// 同一文件中后面出现的相同ISBN按更新处理
+	existing[isbn] = book.ID
+	return true, nil
+}
+
+func setIfPresent(data map[string]interface{}, column, value string) {
+	if value != "" {
+		data[column] = value
+	}
+}
+
+// replaceBookCategories 替换图书的分类关联
+func replaceBookCategories(tx *gorm.DB, book *model.Book, categoryIDs []uint) error {
+	var categories []model.Category
+	if err := tx.Where("id IN ?", categoryIDs).Find(&categories).Error; err != nil {
+		return errors.New("查找分类失败")
+	}
+	if err := tx.Model(book).Association("Categories").Replace(categories); err != nil {
+		return errors.New("关联分类失败")
+	}
+	return nil
+}
+
+// resolveImportCategories 按名称匹配分类，允许时自动创建不存在的分类
+func resolveImportCategories(names []string, categories map[string]uint, create bool) ([]uint, error) {
+	ids := make([]uint, 0, len(names))
+	for _, name := range names {
+		if id, ok := categories[name]; ok {
+			ids = append(ids, id)
+			continue
+		}
+		if !create {
+			return nil, fmt.Errorf("分类不存在: %s", name)
+		}
+
+		category := model.Category{Name: name}
+		if err := global.GVA_DB.Where("name = ?", name).FirstOrCreate(&category).Error; err != nil {
+			return nil, fmt.Errorf("创建分类失败: %s", name)
+		}
+		categories[name] = category.ID
+		ids = append(ids, category.ID)
+	}
+	return ids, nil
+}
+
+// loadBookISBNIndex 加载现有图书的 规范化ISBN -> 图书ID
+func loadBookISBNIndex() (map[string]uint, error) {
+	var books []model.Book
+	if err := global.GVA_DB.Select("id", "isbn").Where("isbn <> ''").Find(&books).Error; err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]uint, len(books))
+	for _, b := range books {
+		if isbn, err := utils.NormalizeISBN(b.ISBN); err == nil {
+			index[isbn] = b.ID
+		}
+	}
+	return index, nil
+}
+
+// loadCategoryIndex 加载 分类名称 -> 分类ID
+func loadCategoryIndex() (map[string]uint, error) {
+	var categories []model.Category
+	if err := global.GVA_DB.Select("id", "name").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]uint, len(categories))
+	for _, c := range categories {
+		index[c.Name] = c.ID
+	}
+	return index, nil
+}
+
+// parseBookFile 解析导入文件，返回可导入的行和无法解析的行
+func parseBookFile(path string, format model.BookFileFormat) ([]bookRow, []model.BookJobRowError, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, errors.New("读取导入文件失败")
+	}
+	defer f.Close()
+
+	switch format {
+	case model.BookFormatCSV:
+		reader := csv.NewReader(stripBOM(f))
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, nil, errors.New("解析CSV失败: " + err.Error())
+		}
+		return tableToBookRows(records, false)
+	case model.BookFormatXLSX:
+		info, err := f.Stat()
+		if err != nil {
+			return nil, nil, errors.New("读取导入文件失败")
+		}
+		records, err := utils.ReadXLSX(f, info.Size())
+		if err != nil {
+			return nil, nil, err
+		}
+		return tableToBookRows(records, true)
+	case model.BookFormatMARC:
+		records, err := utils.ReadMARC21(f)
+		if err != nil {
+			return nil, nil, errors.New("解析MARC文件失败: " + err.Error())
+		}
+		return marcToBookRows(records), nil, nil
+	case model.BookFormatMARCXML:
+		records, err := utils.ReadMARCXML(f)
+		if err != nil {
+			return nil, nil, errors.New("解析MARCXML文件失败: " + err.Error())
+		}
+		return marcToBookRows(records), nil, nil
+	default:
+		return nil, nil, errors.New("不支持的文件格式")
+	}
+}
+
+// stripBOM 去掉UTF-8 BOM（Excel另存的CSV会带BOM）
+func stripBOM(r io.Reader) io.Reader {
+	buf := make([]byte, 3)
+	n, _ := io.ReadFull(r, buf)
+	if n == 3 && bytes.Equal(buf, []byte("\xEF\xBB\xBF")) {
+		return r
+	}
+	return io.MultiReader(bytes.NewReader(buf[:n]), r)
+}
+
+// tableToBookRows 按表头把表格数据转换为导入行，第一行必须是表头
+func tableToBookRows(records [][]string, fromExcel bool) ([]bookRow, []model.BookJobRowError, error) {
+	if len(records) == 0 {
+		return nil, nil, errors.New("文件为空")
+	}
+
+	columns := make(map[int]string)
+	for i, header := range records[0] {
+		if field, ok := bookImportColumns[strings.ToLower(strings.TrimSpace(header))]; ok {
+			columns[i] = field
+		}
+	}
+	hasISBN := false
+	for _, field := range columns {
+		if field == "isbn" {
+			hasISBN = true
+		}
+	}
+	if !hasISBN {
+		return nil, nil, errors.New("表头中缺少ISBN列")
+	}
+
+	var rows []bookRow
+	for i, record := range records[1:] {
+		row := bookRow{Row: i + 2} // 行号从表头所在的第1行开始计
+		empty := true
+		for col, value := range record {
+			field, ok := columns[col]
+			value = strings.TrimSpace(value)
+			if !ok || value == "" {
+				continue
+			}
+			empty = false
+			switch field {
+			case "isbn":
+				row.ISBN = value
+			case "title":
+				row.Title = value
+			case "author":
+				row.Author = value
+			case "publisher":
+				row.Publisher = value
+			case "publish_date":
+				row.PublishDate = value
+				if fromExcel {
+					row.PublishDate = excelSerialToDate(value)
+				}
+			case "price":
+				row.Price = value
+			case "description":
+				row.Description = value
+			case "categories":
+				row.Categories = splitCategories(value)
+			case "cover_image":
+				row.CoverImage = value
+			case "copies":
+				row.Copies = value
+			case "shelf_location":
+				row.ShelfLocation = value
+			}
+		}
+		if !empty {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil, nil
+}
+
+// excelSerialToDate 把Excel日期序列号转换为 yyyy-mm-dd，其他取值原样返回
+func excelSerialToDate(value string) string {
+	serial, err := strconv.ParseFloat(value, 64)
+	if err != nil || serial < 20000 || serial > 80000 {
+		return value
+	}
+	return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(serial)).Format("2006-01-02")
+}
+
+func splitCategories(value string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range categorySeparator.Split(value, -1) {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// marcToBookRows 按MARC21书目字段映射为导入行
+// 020$a ISBN、020$c/365$b 价格、100/110/700$a 作者、245$a$b 书名、264/260$b$c 出版社和出版日期、
+// 520$a 简介、650$a 分类、856$u 封面
+func marcToBookRows(records []utils.MarcRecord) []bookRow {
+	rows := make([]bookRow, 0, len(records))
+	for i, record := range records {
+		row := bookRow{Row: i + 1}
+
+		// 020$a 可能带有限定说明，如 "9787111547426 (平装)"，取第一个合法的ISBN
+		for _, value := range record.SubfieldValues("020", "a") {
+			candidate := strings.Fields(value)
+			if len(candidate) == 0 {
+				continue
+			}
+			if row.ISBN == "" {
+				row.ISBN = candidate[0]
+			}
+			if _, err := utils.NormalizeISBN(candidate[0]); err == nil {
+				row.ISBN = candidate[0]
+				break
+			}
+		}
+
+		row.Title = trimISBD(strings.TrimSpace(record.FirstSubfield("245", "a") + " " + trimISBD(record.FirstSubfield("245", "b"))))
+		for _, tag := range []string{"100", "110", "700"} {
+			if author := trimISBD(record.FirstSubfield(tag, "a")); author != "" {
+				row.Author = author
+				break
+			}
+		}
+		for _, tag := range []string{"264", "260"} {
+			if publisher := trimISBD(record.FirstSubfield(tag, "b")); publisher != "" {
+				row.Publisher = publisher
+				row.PublishDate = strings.Trim(trimISBD(record.FirstSubfield(tag, "c")), "[]c©")
+				break
+			}
+		}
+
+		price := record.FirstSubfield("020", "c")
+		if price == "" {
+			price = record.FirstSubfield("365", "b")
+		}
+		row.Price = priceNumber.FindString(price)
+
+		row.Description = strings.TrimSpace(record.FirstSubfield("520", "a"))
+		row.CoverImage = strings.TrimSpace(record.FirstSubfield("856", "u"))
+		for _, subject := range record.SubfieldValues("650", "a") {
+			if name := trimISBD(subject); name != "" {
+				row.Categories = append(row.Categories, name)
+			}
+		}
+		row.Categories = splitCategories(strings.Join(row.Categories, "|"))
+
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// trimISBD 去掉MARC字段末尾的ISBD标点
+func trimISBD(value string) string {
+	return strings.TrimSpace(strings.TrimRight(strings.TrimSpace(value), " /:;,.="))
+}
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// bookJobConcurrency 同时执行的批量任务数，其余任务排队等待
+const bookJobConcurrency = 2
+
+// BookImportOptions 导入参数
+type BookImportOptions struct {
+	UpdateExisting   bool   `json:"update_existing"`   // ISBN已存在时更新图书，否则该行报错
+	CreateCategories bool   `json:"create_categories"` // 自动创建不存在的分类，否则该行报错
+	ShelfLocation    string `json:"shelf_location"`    // 新建图书生成副本时的默认架位
+}
+
+// BookExportOptions 导出参数
+type BookExportOptions struct {
+	CategoryID uint   `json:"category_id"` // 只导出该分类的图书
+	Keyword    string `json:"keyword"`     // 书名、作者、出版社、ISBN关键字
+}
+
+// BookJobService 图书批量导入导出服务
+// 上传的文件先保存到 storage.path，任务在后台执行，状态和逐行错误报告保存在 book_jobs 表中
+type BookJobService struct {
+	slots       chan struct{}
+	copyService *BookCopyService
+}
+
+// DetectBookFileFormat 根据文件扩展名判断文件格式
+func DetectBookFileFormat(fileName string) (model.BookFileFormat, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return model.BookFormatCSV, nil
+	case ".xlsx":
+		return model.BookFormatXLSX, nil
+	case ".mrc", ".marc", ".iso":
+		return model.BookFormatMARC, nil
+	case ".xml":
+		return model.BookFormatMARCXML, nil
+	default:
+		return "", errors.New("不支持的文件格式，请上传 csv、xlsx、mrc 或 MARCXML 文件")
+	}
+}
+
+// bookFileExt 导出文件的扩展名
+func bookFileExt(format model.BookFileFormat) string {
+	switch format {
+	case model.BookFormatXLSX:
+		return ".xlsx"
+	case model.BookFormatMARC:
+		return ".mrc"
+	case model.BookFormatMARCXML:
+		return ".xml"
+	default:
+		return ".csv"
+	}
+}
+
+// CreateImportJob 保存上传的文件并创建后台导入任务
+func (s *BookJobService) CreateImportJob(file *multipart.FileHeader, format model.BookFileFormat, opts BookImportOptions, actor model.AuditActor) (*model.BookJob, error) {
+	maxSize := global.GVA_CONFIG.Storage.MaxUploadSize << 20
+	if file.Size > maxSize {
+		return nil, fmt.Errorf("文件不能超过%dMB", global.GVA_CONFIG.Storage.MaxUploadSize)
+	}
+
+	options, _ := json.Marshal(opts)
+	job := model.BookJob{
+		JobType:      model.BookJobImport,
+		Format:       format,
+		Status:       model.BookJobStatusPending,
+		FileName:     filepath.Base(file.Filename),
+		Options:      string(options),
+		OperatorID:   actor.UserID,
+		OperatorName: actor.Username,
+	}
+	if err := global.GVA_DB.Create(&job).Error; err != nil {
+		return nil, errors.New("创建导入任务失败")
+	}
+
+	job.FilePath = filepath.Join(global.GVA_CONFIG.Storage.Path, "imports", fmt.Sprintf("%d%s", job.ID, strings.ToLower(filepath.Ext(file.Filename))))
+	if err := saveUploadedFile(file, job.FilePath); err != nil {
+		global.GVA_LOG.Error("保存导入文件失败", zap.Error(err))
+		s.finish(&job, errors.New("保存导入文件失败"))
+		return nil, errors.New("保存导入文件失败")
+	}
+	if err := global.GVA_DB.Model(&job).Update("file_path", job.FilePath).Error; err != nil {
+		return nil, errors.New("创建导入任务失败")
+	}
+
+	go s.run(job.ID, actor)
+	return &job, nil
+}
+
+// CreateExportJob 创建后台导出任务
+func (s *BookJobService) CreateExportJob(format model.BookFileFormat, opts BookExportOptions, actor model.AuditActor) (*model.BookJob, error) {
+	options, _ := json.Marshal(opts)
+	job := model.BookJob{
+		JobType:      model.BookJobExport,
+		Format:       format,
+		Status:       model.BookJobStatusPending,
+		FileName:     fmt.Sprintf("books_%s%s", time.Now().Format("20060102150405"), bookFileExt(format)),
+		Options:      string(options),
+		OperatorID:   actor.UserID,
+		OperatorName: actor.Username,
+	}
+	if err := global.GVA_DB.Create(&job).Error; err != nil {
+		return nil, errors.New("创建导出任务失败")
+	}
+
+	go s.run(job.ID, actor)
+	return &job, nil
+}
+
+// run 排队执行任务
+func (s *BookJobService) run(jobID uint, actor model.AuditActor) {
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+
+	var job model.BookJob
+	if err := global.GVA_DB.First(&job, jobID).Error; err != nil {
+		global.GVA_LOG.Error("批量任务不存在", zap.Uint("job_id", jobID), zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	job.Status = model.BookJobStatusRunning
+	job.StartedAt = &now
+	global.GVA_DB.Model(&job).Updates(map[string]interface{}{
+		"status":     job.Status,
+		"started_at": now,
+	})
+
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			global.GVA_LOG.Error("批量任务异常", zap.Uint("job_id", jobID), zap.Any("panic", r))
+			err = fmt.Errorf("任务异常: %v", r)
+		}
+		s.finish(&job, err)
+	}()
+
+	switch job.JobType {
+	case model.BookJobImport:
+		var opts BookImportOptions
+		_ = json.Unmarshal([]byte(job.Options), &opts)
+		err = s.runImport(&job, opts, actor)
+	case model.BookJobExport:
+		var opts BookExportOptions
+		_ = json.Unmarshal([]byte(job.Options), &opts)
+		err = s.runExport(&job, opts)
+	default:
+		err = errors.New("未知的任务类型")
+	}
+}
+
+// finish 保存任务结果
+func (s *BookJobService) finish(job *model.BookJob, err error) {
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Status = model.BookJobStatusCompleted
+	if err != nil {
+		job.Status = model.BookJobStatusFailed
+		job.ErrorMessage = err.Error()
+	}
+
+	if dbErr := global.GVA_DB.Model(job).Updates(map[string]interface{}{
+		"status":        job.Status,
+		"file_path":     job.FilePath,
+		"total_rows":    job.TotalRows,
+		"created_rows":  job.CreatedRows,
+		"updated_rows":  job.UpdatedRows,
+		"failed_rows":   job.FailedRows,
+		"error_report":  job.ErrorReport,
+		"error_message": job.ErrorMessage,
+		"finished_at":   now,
+	}).Error; dbErr != nil {
+		global.GVA_LOG.Error("保存批量任务结果失败", zap.Uint("job_id", job.ID), zap.Error(dbErr))
+	}
+
+	global.GVA_LOG.Info("批量任务结束",
+		zap.Uint("job_id", job.ID),
+		zap.String("type", string(job.JobType)),
+		zap.String("status", string(job.Status)),
+		zap.Int("total", job.TotalRows),
+		zap.Int("failed", job.FailedRows))
+}
+
+// saveProgress 保存导入进度，供前端轮询
+func (s *BookJobService) saveProgress(job *model.BookJob) {
+	global.GVA_DB.Model(job).Updates(map[string]interface{}{
+		"total_rows":   job.TotalRows,
+		"created_rows": job.CreatedRows,
+		"updated_rows": job.UpdatedRows,
+		"failed_rows":  job.FailedRows,
+	})
+}
+
+// GetJob 获取任务详情
+func (s *BookJobService) GetJob(id uint) (*model.BookJob, error) {
+	var job model.BookJob
+	if err := global.GVA_DB.First(&job, id).Error; err != nil {
+		return nil, errors.New("任务不存在")
+	}
+	return &job, nil
+}
+
+// GetJobRowErrors 解析任务的逐行错误报告
+func (s *BookJobService) GetJobRowErrors(job *model.BookJob) []model.BookJobRowError {
+	var rowErrors []model.BookJobRowError
+	if job.ErrorReport != "" {
+		_ = json.Unmarshal([]byte(job.ErrorReport), &rowErrors)
+	}
+	return rowErrors
+}
+
+// GetJobList 分页获取任务列表（不含错误报告）
+func (s *BookJobService) GetJobList(jobType model.BookJobType, page, pageSize int) ([]model.BookJob, int64, error) {
+	var jobs []model.BookJob
+	var total int64
+	db := global.GVA_DB.Model(&model.BookJob{})
+	if jobType != "" {
+		db = db.Where("job_type = ?", jobType)
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Omit("error_report").Order("id DESC").Limit(pageSize).Offset((page - 1) * pageSize).Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+	return jobs, total, nil
+}
+
+// GetExportFile 获取已完成的导出任务文件
+func (s *BookJobService) GetExportFile(id uint) (*model.BookJob, error) {
+	job, err := s.GetJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.JobType != model.BookJobExport {
+		return nil, errors.New("不是导出任务")
+	}
+	if job.Status != model.BookJobStatusCompleted {
+		return nil, errors.New("导出尚未完成")
+	}
+	if _, err := os.Stat(job.FilePath); err != nil {
+		return nil, errors.New("导出文件不存在")
+	}
+	return job, nil
+}
+
+// RecoverInterruptedJobs 将服务重启前未执行完的任务标记为失败
+func (s *BookJobService) RecoverInterruptedJobs() {
+	result := global.GVA_DB.Model(&model.BookJob{}).
+		Where("status IN ?", []model.BookJobStatus{model.BookJobStatusPending, model.BookJobStatusRunning}).
+		Updates(map[string]interface{}{
+			"status":        model.BookJobStatusFailed,
+			"error_message": "服务重启，任务中断，请重新提交",
+			"finished_at":   time.Now(),
+		})
+	if result.Error != nil {
+		global.GVA_LOG.Error("恢复中断的批量任务失败", zap.Error(result.Error))
+	} else if result.RowsAffected > 0 {
+		global.GVA_LOG.Warn("存在被中断的批量任务", zap.Int64("count", result.RowsAffected))
+	}
+}
+
+// saveUploadedFile 将上传的文件保存到指定路径
+func saveUploadedFile(file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, src)
+	return err
+}
+
+// 全局图书批量任务服务实例
+var GlobalBookJobService = &BookJobService{
+	slots:       make(chan struct{}, bookJobConcurrency),
+	copyService: NewBookCopyService(),
+}
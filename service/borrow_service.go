@@ -5,15 +5,19 @@ import (
 	"bookadmin/model"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type BorrowService struct {
 	fineService        *FineService
 	reservationService *ReservationService
 	blacklistService   *BlacklistService
+	copyService        *BookCopyService
 }
 
 // NewBorrowService 创建借还书服务实例
@@ -22,11 +26,36 @@ func NewBorrowService() *BorrowService {
 		fineService:        &FineService{},
 		reservationService: NewReservationService(),
 		blacklistService:   &BlacklistService{},
+		copyService:        NewBookCopyService(),
 	}
 }
 
+// borrowOptions 借书选项
+type borrowOptions struct {
+	copyID *uint // 指定借出的副本（流通台扫码借书），为空时自动挑选
+	direct bool  // 是否直接借出（不经过审批）
+}
+
 // BorrowBook 借书（增强版）
 func (s *BorrowService) BorrowBook(userID, bookID uint, operatorID uint, reservationID *uint) (*model.BorrowRecord, error) {
+	// 判断是否为普通用户借书（userID == operatorID）还是管理员为读者借书
+	return s.borrowBook(userID, bookID, operatorID, reservationID, borrowOptions{direct: userID != operatorID})
+}
+
+// BorrowCopy 借出指定副本（流通台使用，直接借出）
+func (s *BorrowService) BorrowCopy(userID, copyID uint, operatorID uint) (*model.BorrowRecord, error) {
+	var bookCopy model.BookCopy
+	if err := global.GVA_DB.First(&bookCopy, copyID).Error; err != nil {
+		return nil, errors.New("副本不存在")
+	}
+	if bookCopy.Status != model.CopyStatusAvailable {
+		return nil, errors.New("该副本不可借")
+	}
+	return s.borrowBook(userID, bookCopy.BookID, operatorID, nil, borrowOptions{copyID: &copyID, direct: true})
+}
+
+// borrowBook 借书核心流程，资格检查对所有入口一致
+func (s *BorrowService) borrowBook(userID, bookID uint, operatorID uint, reservationID *uint, opts borrowOptions) (*model.BorrowRecord, error) {
 	tx := global.GVA_DB.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -35,28 +64,29 @@ func (s *BorrowService) BorrowBook(userID, bookID uint, operatorID uint, reserva
 	}()
 
 	// 1. 检查读者是否存在且状态正常（根据UserID查询）
+	// 锁定读者行，同一读者同时借书时依次检查借阅数量，不会超过上限
 	var reader model.Reader
-	if err := tx.Where("user_id = ?", userID).First(&reader).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&reader).Error; err != nil {
 		tx.Rollback()
 		// 如果读者不存在，自动创建一个
 		reader = model.Reader{
-			UserID:          userID,
-			ReaderNo:        fmt.Sprintf("R%d", userID),
-			Status:          model.ReaderStatusActive,
-			MaxBorrow:       5,
-			BorrowDays:      30,
-			MaxRenew:        2,
-			RenewDays:       15,
-			MaxReservations: 3,
-			TotalFine:       0,
-			UnpaidFine:      0,
-			IsBlacklisted:   false,
+			UserID:         userID,
+			ReaderNo:       fmt.Sprintf("R%d", userID),
+			Status:         model.ReaderStatusActive,
+			PatronCategory: model.DefaultPatronCategory,
+			TotalFine:      0,
+			UnpaidFine:     0,
+			IsBlacklisted:  false,
 		}
 		if err := global.GVA_DB.Create(&reader).Error; err != nil {
 			return nil, errors.New("创建读者信息失败")
 		}
 		// 重新开始事务
 		tx = global.GVA_DB.Begin()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reader, reader.ID).Error; err != nil {
+			tx.Rollback()
+			return nil, errors.New("创建读者信息失败")
+		}
 	}
 
 	if reader.Status != model.ReaderStatusActive {
@@ -89,8 +119,9 @@ func (s *BorrowService) BorrowBook(userID, bookID uint, operatorID uint, reserva
 	}
 
 	// 5. 检查图书是否存在
+	// 锁定图书行，库存和预约按最新提交的数据检查，同一本书的最后一本不会同时借给两人
 	var book model.Book
-	if err := tx.First(&book, bookID).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&book, bookID).Error; err != nil {
 		tx.Rollback()
 		return nil, errors.New("图书不存在")
 	}
@@ -106,8 +137,9 @@ func (s *BorrowService) BorrowBook(userID, bookID uint, operatorID uint, reserva
 		now := time.Now()
 		// 查找状态为available且未过期的预约
 		var availableReservations []model.Reservation
-		err := tx.Where("book_id = ? AND status = ? AND (pickup_deadline IS NULL OR pickup_deadline > ?)",
-			bookID, model.ReservationStatusAvailable, now).
+		err := tx.Clauses(clause.Locking{Strength: "SHARE"}).
+			Where("book_id = ? AND status = ? AND (pickup_deadline IS NULL OR pickup_deadline > ?)",
+				bookID, model.ReservationStatusAvailable, now).
 			Find(&availableReservations).Error
 
 		if err == nil && len(availableReservations) > 0 {
@@ -141,12 +173,36 @@ func (s *BorrowService) BorrowBook(userID, bookID uint, operatorID uint, reserva
 		}).
 		Count(&borrowCount)
 
-	maxBorrow := GlobalConfigService.GetIntConfig(model.ConfigMaxBorrowBooks, reader.MaxBorrow)
-	if int(borrowCount) >= maxBorrow {
+	// 借阅规则由读者类型决定，并按图书分类覆盖
+	policy, err := GlobalPolicyService.ResolveForBook(tx, &reader, bookID)
+	if err != nil {
+		tx.Rollback()
+		return nil, errors.New("获取借阅规则失败")
+	}
+
+	if int(borrowCount) >= policy.MaxBorrow {
 		tx.Rollback()
 		return nil, errors.New("已达到最大借阅数量")
 	}
 
+	// 检查分类借阅数量上限
+	for categoryID, limit := range policy.CategoryLimits {
+		if limit == 0 {
+			tx.Rollback()
+			return nil, errors.New("该分类图书不对" + model.PatronCategoryNames[reader.PatronCategory] + "读者外借")
+		}
+		var categoryCount int64
+		tx.Model(&model.BorrowRecord{}).
+			Joins("JOIN book_categories ON book_categories.book_id = borrow_records.book_id").
+			Where("borrow_records.reader_id = ? AND borrow_records.status IN (?) AND book_categories.category_id = ?",
+				reader.ID, []model.BorrowStatus{model.BorrowStatusBorrowed, model.BorrowStatusOverdue}, categoryID).
+			Count(&categoryCount)
+		if int(categoryCount) >= limit {
+			tx.Rollback()
+			return nil, errors.New("已达到该分类图书的最大借阅数量")
+		}
+	}
+
 	// 8. 检查是否已借阅该书
 	var existRecord model.BorrowRecord
 	if err := tx.Where("reader_id = ? AND book_id = ? AND status IN (?)", reader.ID, bookID, []model.BorrowStatus{
@@ -160,7 +216,7 @@ func (s *BorrowService) BorrowBook(userID, bookID uint, operatorID uint, reserva
 	// 9. 如果是通过预约借书，验证预约
 	if reservationID != nil {
 		var reservation model.Reservation
-		if err := tx.First(&reservation, *reservationID).Error; err != nil {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reservation, *reservationID).Error; err != nil {
 			tx.Rollback()
 			return nil, errors.New("预约记录不存在")
 		}
@@ -188,8 +244,8 @@ func (s *BorrowService) BorrowBook(userID, bookID uint, operatorID uint, reserva
 
 	// 10. 创建借阅记录
 	now := time.Now()
-	borrowDays := GlobalConfigService.GetIntConfig(model.ConfigBorrowDays, reader.BorrowDays)
-	maxRenewCount := GlobalConfigService.GetIntConfig(model.ConfigMaxRenewTimes, reader.MaxRenew)
+	borrowDays := policy.BorrowDays
+	maxRenewCount := policy.MaxRenew
 
 	// 测试模式：借阅期限改为1分钟
 	// 生产环境：使用天数
@@ -198,13 +254,13 @@ func (s *BorrowService) BorrowBook(userID, bookID uint, operatorID uint, reserva
 		// 如果配置为0或异常大值，使用1分钟（测试模式）
 		dueDate = now.Add(1 * time.Minute)
 	} else {
-		dueDate = now.AddDate(0, 0, borrowDays)
+		// 遇闭馆日顺延到下一个开馆日
+		dueDate = GlobalCalendarService.DueDate(now, borrowDays)
 	}
 
-	// 判断是否为普通用户借书（userID == operatorID）还是管理员为读者借书
 	var borrowStatus model.BorrowStatus
 	var shouldUpdateStock bool
-	if userID == operatorID {
+	if !opts.direct {
 		// 普通用户自己借书，需要管理员审批
 		borrowStatus = model.BorrowStatusPending
 		shouldUpdateStock = false // 待审批时不减库存
@@ -225,17 +281,27 @@ func (s *BorrowService) BorrowBook(userID, bookID uint, operatorID uint, reserva
 		OperatorID:    operatorID,
 	}
 
+	// 11. 借出副本并更新库存（仅管理员直接借书时）
+	if shouldUpdateStock {
+		bookCopy, err := s.copyService.CheckoutCopy(tx, bookID, opts.copyID)
+		if err != nil {
+			tx.Rollback()
+			global.GVA_LOG.Error("借出副本失败", zap.Error(err))
+			return nil, err
+		}
+		record.CopyID = &bookCopy.ID
+	}
+
 	if err := tx.Create(&record).Error; err != nil {
 		tx.Rollback()
 		global.GVA_LOG.Error("创建借阅记录失败", zap.Error(err))
 		return nil, errors.New("借书失败")
 	}
 
-	// 11. 更新图书库存（仅管理员直接借书时）
-	if shouldUpdateStock {
-		if err := tx.Model(&book).Update("available_stock", book.AvailableStock-1).Error; err != nil {
+	// 直接借出时计入借阅榜单，需审批的在批准时计入
+	if borrowStatus == model.BorrowStatusBorrowed {
+		if err := enqueueRankingEvent(tx, model.RankingTypeBorrow, bookID, borrowRankingKey(record.ID)); err != nil {
 			tx.Rollback()
-			global.GVA_LOG.Error("更新库存失败", zap.Error(err))
 			return nil, errors.New("借书失败")
 		}
 	}
@@ -243,6 +309,7 @@ func (s *BorrowService) BorrowBook(userID, bookID uint, operatorID uint, reserva
 	if err := tx.Commit().Error; err != nil {
 		return nil, errors.New("借书失败")
 	}
+	GlobalOutboxService.Wake()
 
 	if borrowStatus == model.BorrowStatusPending {
 		global.GVA_LOG.Info("借书申请提交成功", zap.Uint("reader_id", reader.ID), zap.Uint("book_id", bookID))
@@ -261,9 +328,9 @@ func (s *BorrowService) ApproveBorrowRequest(recordID uint, operatorID uint, app
 		}
 	}()
 
-	// 1. 查找借阅记录
+	// 1. 查找借阅记录（加锁，防止同一申请被重复审批）
 	var record model.BorrowRecord
-	if err := tx.Preload("Book").Preload("Reader").First(&record, recordID).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Book").Preload("Reader").First(&record, recordID).Error; err != nil {
 		tx.Rollback()
 		return errors.New("借阅记录不存在")
 	}
@@ -276,7 +343,7 @@ func (s *BorrowService) ApproveBorrowRequest(recordID uint, operatorID uint, app
 
 	// 3. 检查图书是否还有库存
 	var book model.Book
-	if err := tx.First(&book, record.BookID).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&book, record.BookID).Error; err != nil {
 		tx.Rollback()
 		return errors.New("图书不存在")
 	}
@@ -288,21 +355,23 @@ func (s *BorrowService) ApproveBorrowRequest(recordID uint, operatorID uint, app
 			return errors.New("图书库存不足，无法批准")
 		}
 
+		// 借出副本并更新库存
+		bookCopy, err := s.copyService.CheckoutCopy(tx, book.ID, nil)
+		if err != nil {
+			tx.Rollback()
+			return errors.New("图书库存不足，无法批准")
+		}
+
 		// 更新借阅记录状态
 		if err := tx.Model(&record).Updates(map[string]interface{}{
 			"status":      model.BorrowStatusBorrowed,
+			"copy_id":     bookCopy.ID,
 			"operator_id": operatorID,
 		}).Error; err != nil {
 			tx.Rollback()
 			return errors.New("更新借阅记录失败")
 		}
 
-		// 减少图书库存
-		if err := tx.Model(&book).Update("available_stock", book.AvailableStock-1).Error; err != nil {
-			tx.Rollback()
-			return errors.New("更新库存失败")
-		}
-
 		global.GVA_LOG.Info("批准借阅申请", zap.Uint("record_id", recordID), zap.Uint("operator_id", operatorID))
 	} else {
 		// 拒绝借阅
@@ -318,9 +387,26 @@ func (s *BorrowService) ApproveBorrowRequest(recordID uint, operatorID uint, app
 		global.GVA_LOG.Info("拒绝借阅申请", zap.Uint("record_id", recordID), zap.Uint("operator_id", operatorID))
 	}
 
+	if approved {
+		if err := enqueueRankingEvent(tx, model.RankingTypeBorrow, record.BookID, borrowRankingKey(record.ID)); err != nil {
+			tx.Rollback()
+			return errors.New("操作失败")
+		}
+	}
+
+	// 通知读者审批结果，与审批在同一事务内写入
+	if record.Reader.UserID > 0 {
+		if err := (&MessageService{}).SendLoanDecisionMessage(tx, &record, approved, rejectReason); err != nil {
+			tx.Rollback()
+			global.GVA_LOG.Error("写入审批结果消息失败", zap.Uint("record_id", recordID), zap.Error(err))
+			return errors.New("操作失败")
+		}
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return errors.New("操作失败")
 	}
+	GlobalOutboxService.Wake()
 
 	return nil
 }
@@ -336,7 +422,7 @@ func (s *BorrowService) CancelBorrowRequest(recordID uint, userID uint) error {
 
 	// 1. 查找借阅记录
 	var record model.BorrowRecord
-	if err := tx.Preload("Reader").First(&record, recordID).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Reader").First(&record, recordID).Error; err != nil {
 		tx.Rollback()
 		return errors.New("借阅记录不存在")
 	}
@@ -379,9 +465,9 @@ func (s *BorrowService) ReturnBook(recordID uint, operatorID uint) (*model.Borro
 		}
 	}()
 
-	// 1. 查找借阅记录
+	// 1. 查找借阅记录（加锁，防止同一本书被重复归还、重复计算罚款）
 	var record model.BorrowRecord
-	if err := tx.Preload("Book").Preload("Reader").First(&record, recordID).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Book").Preload("Reader").First(&record, recordID).Error; err != nil {
 		tx.Rollback()
 		return nil, 0, errors.New("借阅记录不存在")
 	}
@@ -416,31 +502,31 @@ func (s *BorrowService) ReturnBook(recordID uint, operatorID uint) (*model.Borro
 		return nil, 0, errors.New("还书失败")
 	}
 
-	// 4. 如果有罚款，创建罚款记录
+	// 4. 如果有罚款，在同一事务内创建罚款记录并记账
 	if fineAmount > 0 {
-		if err := s.fineService.CreateFineRecord(record.ReaderID, record.ID, "overdue", fineAmount, overdueDays, operatorID); err != nil {
-			global.GVA_LOG.Error("创建罚款记录失败", zap.Error(err))
-			// 不回滚，允许还书成功但罚款记录创建失败
+		if _, err := s.fineService.CreateFineRecord(tx, record.ReaderID, record.ID, "overdue", fineAmount, overdueDays, operatorID); err != nil {
+			tx.Rollback()
+			return nil, 0, errors.New("还书失败")
 		}
 	}
 
-	// 5. 更新图书库存
-	if err := tx.Model(&record.Book).Update("available_stock", record.Book.AvailableStock+1).Error; err != nil {
+	// 5. 归还副本并更新库存
+	if err := s.copyService.CheckinCopy(tx, record.BookID, record.CopyID); err != nil {
 		tx.Rollback()
 		global.GVA_LOG.Error("更新库存失败", zap.Error(err))
 		return nil, 0, errors.New("还书失败")
 	}
 
-	// 6. 检查并通知预约者
-	go func() {
-		if err := s.reservationService.CheckAndNotifyAvailableReservations(record.BookID); err != nil {
-			global.GVA_LOG.Error("通知预约者失败", zap.Error(err))
-		}
-	}()
+	// 6. 写入通知预约者事件，随还书一起提交，提交后才会执行
+	if err := s.reservationService.EnqueueHoldNotify(tx, record.BookID, "hold:return:"+strconv.FormatUint(uint64(record.ID), 10)); err != nil {
+		tx.Rollback()
+		return nil, 0, errors.New("还书失败")
+	}
 
 	if err := tx.Commit().Error; err != nil {
 		return nil, 0, errors.New("还书失败")
 	}
+	GlobalOutboxService.Wake()
 
 	global.GVA_LOG.Info("还书成功", zap.Uint("record_id", recordID), zap.Float64("fine", fineAmount))
 	return &record, fineAmount, nil
@@ -457,7 +543,7 @@ func (s *BorrowService) RenewBook(recordID, readerID uint) error {
 
 	// 1. 查找借阅记录
 	var record model.BorrowRecord
-	if err := tx.Preload("Reader").First(&record, recordID).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Reader").First(&record, recordID).Error; err != nil {
 		tx.Rollback()
 		return errors.New("借阅记录不存在")
 	}
@@ -487,8 +573,13 @@ func (s *BorrowService) RenewBook(recordID, readerID uint) error {
 	}
 
 	// 6. 检查该书是否有人预约
+	// 锁定图书行后加锁统计，与同时提交的预约依次执行
+	if _, err := s.copyService.LockBook(tx, record.BookID); err != nil {
+		tx.Rollback()
+		return err
+	}
 	var reservationCount int64
-	tx.Model(&model.Reservation{}).
+	tx.Clauses(clause.Locking{Strength: "SHARE"}).Model(&model.Reservation{}).
 		Where("book_id = ? AND status IN (?)", record.BookID, []model.ReservationStatus{
 			model.ReservationStatusPending,
 			model.ReservationStatusAvailable,
@@ -501,8 +592,13 @@ func (s *BorrowService) RenewBook(recordID, readerID uint) error {
 	}
 
 	// 7. 更新借阅记录
-	renewDays := GlobalConfigService.GetIntConfig(model.ConfigRenewDays, record.Reader.RenewDays)
-	record.DueDate = record.DueDate.AddDate(0, 0, renewDays)
+	policy, err := GlobalPolicyService.ResolveForBook(tx, &record.Reader, record.BookID)
+	if err != nil {
+		tx.Rollback()
+		return errors.New("获取借阅规则失败")
+	}
+	renewDays := policy.RenewDays
+	record.DueDate = GlobalCalendarService.DueDate(record.DueDate, renewDays)
 	record.RenewCount++
 
 	if err := tx.Save(&record).Error; err != nil {
@@ -527,6 +623,8 @@ func (s *BorrowService) CheckOverdueRecords() error {
 	var overdueRecords []model.BorrowRecord
 
 	if err := global.GVA_DB.Where("status = ? AND due_date < ?", model.BorrowStatusBorrowed, now).
+		Preload("Reader").
+		Preload("Book").
 		Find(&overdueRecords).Error; err != nil {
 		return err
 	}
@@ -535,71 +633,63 @@ func (s *BorrowService) CheckOverdueRecords() error {
 		return nil
 	}
 
-	// 批量更新状态为逾期
-	recordIDs := make([]uint, len(overdueRecords))
-	for i, r := range overdueRecords {
-		recordIDs[i] = r.ID
-	}
-
-	if err := global.GVA_DB.Model(&model.BorrowRecord{}).
-		Where("id IN ?", recordIDs).
-		Update("status", model.BorrowStatusOverdue).Error; err != nil {
-		return err
-	}
-
-	global.GVA_LOG.Info("更新逾期记录", zap.Int("count", len(overdueRecords)))
-
-	// 发送逾期提醒通知
+	// 状态改为逾期与逾期提醒消息在同一事务内写入，不会出现已改状态但提醒丢失
 	messageService := &MessageService{}
-	for _, record := range overdueRecords {
-		// 预加载读者和图书信息
-		if err := global.GVA_DB.Preload("Reader").Preload("Book").First(&record, record.ID).Error; err != nil {
-			global.GVA_LOG.Error("预加载借阅记录失败", zap.Error(err))
-			continue
-		}
-
-		// 计算逾期时间
-		overdueDuration := time.Since(record.DueDate)
-		overdueDays := int(overdueDuration.Hours() / 24)
-		overdueMinutes := int(overdueDuration.Minutes())
-
-		// 如果逾期时间小于1天，显示分钟数（测试模式）
-		var content string
-		if overdueDays < 1 {
-			if overdueMinutes < 1 {
-				overdueSeconds := int(overdueDuration.Seconds())
-				content = fmt.Sprintf("您借阅的《%s》已逾期 %d 秒，请尽快归还。逾期将产生罚款。",
-					record.Book.Title, overdueSeconds)
+	updated := 0
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		for _, record := range overdueRecords {
+			result := tx.Model(&model.BorrowRecord{}).
+				Where("id = ? AND status = ?", record.ID, model.BorrowStatusBorrowed).
+				Update("status", model.BorrowStatusOverdue)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+			updated++
+
+			// 计算逾期时间
+			overdueDuration := time.Since(record.DueDate)
+			overdueDays := GlobalCalendarService.CountOverdueDays(record.DueDate, time.Now()) // 闭馆日不计入
+			overdueMinutes := int(overdueDuration.Minutes())
+
+			// 如果逾期时间小于1天，显示分钟数（测试模式）
+			var content string
+			if overdueDuration < 24*time.Hour {
+				if overdueMinutes < 1 {
+					overdueSeconds := int(overdueDuration.Seconds())
+					content = fmt.Sprintf("您借阅的《%s》已逾期 %d 秒，请尽快归还。逾期将产生罚款。",
+						record.Book.Title, overdueSeconds)
+				} else {
+					content = fmt.Sprintf("您借阅的《%s》已逾期 %d 分钟，请尽快归还。逾期将产生罚款。",
+						record.Book.Title, overdueMinutes)
+				}
 			} else {
-				content = fmt.Sprintf("您借阅的《%s》已逾期 %d 分钟，请尽快归还。逾期将产生罚款。",
-					record.Book.Title, overdueMinutes)
+				content = fmt.Sprintf("您借阅的《%s》已逾期 %d 天，请尽快归还。逾期将产生罚款。",
+					record.Book.Title, overdueDays)
 			}
-		} else {
-			content = fmt.Sprintf("您借阅的《%s》已逾期 %d 天，请尽快归还。逾期将产生罚款。",
-				record.Book.Title, overdueDays)
-		}
-
-		// 发送站内消息
-		title := "⚠️ 图书已逾期"
 
-		relatedID := record.ID
-		if err := messageService.CreateMessage(
-			record.Reader.UserID,
-			model.MessageTypeOverdue,
-			title,
-			content,
-			&relatedID,
-			"borrow",
-		); err != nil {
-			global.GVA_LOG.Error("发送逾期提醒消息失败", zap.Error(err))
-		} else {
-			global.GVA_LOG.Info("发送逾期提醒消息成功",
-				zap.Uint("reader_id", record.ReaderID),
-				zap.Uint("book_id", record.BookID),
-				zap.Int("overdue_days", overdueDays))
+			relatedID := record.ID
+			if err := messageService.EnqueueMessage(tx, "message:overdue:"+strconv.FormatUint(uint64(record.ID), 10), model.MessagePayload{
+				UserID:      record.Reader.UserID,
+				Type:        model.MessageTypeOverdue,
+				Title:       "⚠️ 图书已逾期",
+				Content:     content,
+				RelatedID:   &relatedID,
+				RelatedType: "borrow",
+			}); err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	GlobalOutboxService.Wake()
 
+	global.GVA_LOG.Info("更新逾期记录", zap.Int("count", updated))
 	return nil
 }
 
@@ -632,8 +722,24 @@ func (s *BorrowService) SendDueReminders() error {
 		return err
 	}
 
+	// 站内消息创建后按用户偏好同时发送邮件、短信
+	messageService := &MessageService{}
 	for _, record := range dueRecords {
-		// TODO: 发送提醒通知（邮件、短信、站内信等）
+		content := fmt.Sprintf("您借阅的《%s》将于 %s 到期，请按时归还或办理续借。",
+			record.Book.Title, record.DueDate.Format("2006-01-02 15:04"))
+
+		relatedID := record.ID
+		if err := messageService.CreateMessage(
+			record.Reader.UserID,
+			model.MessageTypeBorrow,
+			"⏰ 图书即将到期",
+			content,
+			&relatedID,
+			"borrow",
+		); err != nil {
+			global.GVA_LOG.Error("发送到期提醒消息失败", zap.Error(err))
+			continue
+		}
 		global.GVA_LOG.Info("发送到期提醒",
 			zap.Uint("reader_id", record.ReaderID),
 			zap.Uint("book_id", record.BookID),
@@ -642,3 +748,8 @@ func (s *BorrowService) SendDueReminders() error {
 
 	return nil
 }
+
+// borrowRankingKey 借阅计入榜单的幂等键，直接借出和审批通过共用，同一借阅只计入一次
+func borrowRankingKey(recordID uint) string {
+	return "ranking:borrow:" + strconv.FormatUint(uint64(recordID), 10)
+}
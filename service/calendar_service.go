@@ -0,0 +1,375 @@
+package service
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxCalendarScanDays 向后查找开放日的最大天数，防止日历配置为全部闭馆时死循环
+const maxCalendarScanDays = 366
+
+// CalendarService 开馆日历服务
+// 应还日期、续借、预约取书截止日期和逾期天数都通过日历计算，跳过闭馆日
+type CalendarService struct {
+	mu         sync.RWMutex
+	weekly     map[time.Weekday]model.OpeningHours
+	exceptions []model.CalendarException
+}
+
+// dayOf 返回某时间所在日期的零点
+func dayOf(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// GetDay 获取某一天的开放情况（例外优先于每周时间表）
+func (s *CalendarService) GetDay(t time.Time) model.CalendarDay {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	day := dayOf(t)
+	result := model.CalendarDay{Date: day.Format("2006-01-02"), IsOpen: true}
+
+	// 未配置时间表时视为每天开放
+	if hours, ok := s.weekly[day.Weekday()]; ok {
+		result.IsOpen = hours.IsOpen
+		result.OpenTime = hours.OpenTime
+		result.CloseTime = hours.CloseTime
+	}
+
+	// 后添加的例外优先
+	for i := len(s.exceptions) - 1; i >= 0; i-- {
+		e := s.exceptions[i]
+		if day.Before(dayOf(e.StartDate.In(t.Location()))) || day.After(dayOf(e.EndDate.In(t.Location()))) {
+			continue
+		}
+		result.IsOpen = e.IsOpen
+		result.Reason = e.Reason
+		if e.IsOpen && e.OpenTime != "" && e.CloseTime != "" {
+			result.OpenTime = e.OpenTime
+			result.CloseTime = e.CloseTime
+		}
+		break
+	}
+	return result
+}
+
+// IsOpenDay 判断某天是否开馆
+func (s *CalendarService) IsOpenDay(t time.Time) bool {
+	return s.GetDay(t).IsOpen
+}
+
+// NextOpenDay 返回t当天或之后的第一个开馆日（保留时刻）
+func (s *CalendarService) NextOpenDay(t time.Time) time.Time {
+	for i := 0; i < maxCalendarScanDays; i++ {
+		candidate := t.AddDate(0, 0, i)
+		if s.IsOpenDay(candidate) {
+			return candidate
+		}
+	}
+	global.GVA_LOG.Warn("一年内没有开馆日，请检查开馆日历", zap.Time("from", t))
+	return t
+}
+
+// closingTime 返回某个开馆日的闭馆时刻，未配置闭馆时间时返回原时刻
+func (s *CalendarService) closingTime(t time.Time) time.Time {
+	day := s.GetDay(t)
+	var hour, minute int
+	if _, err := fmt.Sscanf(day.CloseTime, "%d:%d", &hour, &minute); err != nil {
+		return t
+	}
+	y, m, d := t.Date()
+	return time.Date(y, m, d, hour, minute, 0, 0, t.Location())
+}
+
+// DueDate 计算应还日期：from 之后 days 天，遇闭馆日顺延到下一个开馆日的闭馆时间
+func (s *CalendarService) DueDate(from time.Time, days int) time.Time {
+	return s.closingTime(s.NextOpenDay(from.AddDate(0, 0, days)))
+}
+
+// RollForward 将落在闭馆日的截止时间顺延到下一个开馆日的闭馆时间，开馆日则保持不变
+func (s *CalendarService) RollForward(deadline time.Time) time.Time {
+	if s.IsOpenDay(deadline) {
+		return deadline
+	}
+	return s.closingTime(s.NextOpenDay(deadline))
+}
+
+// CountOverdueDays 计算逾期天数：应还日期之后经过的整天中，只统计开馆日
+func (s *CalendarService) CountOverdueDays(dueDate, now time.Time) int {
+	if !now.After(dueDate) {
+		return 0
+	}
+
+	elapsedDays := int(now.Sub(dueDate).Hours() / 24)
+	if elapsedDays > 10*maxCalendarScanDays {
+		elapsedDays = 10 * maxCalendarScanDays
+	}
+
+	overdueDays := 0
+	for i := 1; i <= elapsedDays; i++ {
+		if s.IsOpenDay(dueDate.AddDate(0, 0, i)) {
+			overdueDays++
+		}
+	}
+	return overdueDays
+}
+
+// GetCalendar 获取日期区间内每天的开放情况
+func (s *CalendarService) GetCalendar(start, end time.Time) []model.CalendarDay {
+	days := make([]model.CalendarDay, 0)
+	for day := dayOf(start); !day.After(end) && len(days) < maxCalendarScanDays; day = day.AddDate(0, 0, 1) {
+		days = append(days, s.GetDay(day))
+	}
+	return days
+}
+
+// GetOpeningHours 获取每周开放时间表
+func (s *CalendarService) GetOpeningHours() ([]model.OpeningHours, error) {
+	var hours []model.OpeningHours
+	if err := global.GVA_DB.Order("weekday ASC").Find(&hours).Error; err != nil {
+		return nil, err
+	}
+	return hours, nil
+}
+
+// UpdateOpeningHours 更新每周开放时间表
+func (s *CalendarService) UpdateOpeningHours(hours []model.OpeningHours, actor model.AuditActor) error {
+	for _, h := range hours {
+		if h.Weekday < 0 || h.Weekday > 6 {
+			return errors.New("星期取值应为0-6")
+		}
+		if h.IsOpen && !isValidClock(h.OpenTime, h.CloseTime) {
+			return errors.New("开馆时间格式错误，应为HH:MM且早于闭馆时间")
+		}
+	}
+
+	before, err := s.GetOpeningHours()
+	if err != nil {
+		return errors.New("获取开放时间失败")
+	}
+
+	tx := global.GVA_DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, h := range hours {
+		if err := tx.Where("weekday = ?", h.Weekday).
+			Assign(map[string]interface{}{
+				"is_open":    h.IsOpen,
+				"open_time":  h.OpenTime,
+				"close_time": h.CloseTime,
+			}).
+			FirstOrCreate(&model.OpeningHours{Weekday: h.Weekday}).Error; err != nil {
+			tx.Rollback()
+			return errors.New("更新开放时间失败")
+		}
+	}
+
+	if err := GlobalAuditService.Record(tx, actor, model.AuditCalendarUpdate, "opening_hours", 0, before, hours); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.New("更新开放时间失败")
+	}
+
+	return s.RefreshCache()
+}
+
+// GetExceptions 获取日历例外，year为0时返回全部
+func (s *CalendarService) GetExceptions(year int) ([]model.CalendarException, error) {
+	var exceptions []model.CalendarException
+	db := global.GVA_DB.Model(&model.CalendarException{})
+	if year > 0 {
+		start := time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
+		db = db.Where("end_date >= ? AND start_date < ?", start, start.AddDate(1, 0, 0))
+	}
+	if err := db.Order("start_date ASC").Find(&exceptions).Error; err != nil {
+		return nil, err
+	}
+	return exceptions, nil
+}
+
+// CreateException 新增日历例外
+// 新增闭馆日时，将落在闭馆期间的在借图书应还日期和预约取书截止日期顺延，与新增例外在同一事务内提交
+func (s *CalendarService) CreateException(exception *model.CalendarException, actor model.AuditActor) error {
+	if exception.EndDate.Before(exception.StartDate) {
+		return errors.New("结束日期不能早于开始日期")
+	}
+	if exception.IsOpen && (exception.OpenTime != "" || exception.CloseTime != "") &&
+		!isValidClock(exception.OpenTime, exception.CloseTime) {
+		return errors.New("开馆时间格式错误，应为HH:MM且早于闭馆时间")
+	}
+
+	tx := global.GVA_DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Create(exception).Error; err != nil {
+		tx.Rollback()
+		return errors.New("新增日历例外失败")
+	}
+
+	if err := GlobalAuditService.Record(tx, actor, model.AuditCalendarUpdate, "calendar_exception", exception.ID, nil, exception); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if !exception.IsOpen {
+		// 缓存在提交后才刷新，按加入新例外后的日历计算顺延日期
+		if err := s.withException(*exception).rollForwardDeadlines(tx, exception.StartDate, exception.EndDate); err != nil {
+			tx.Rollback()
+			global.GVA_LOG.Error("顺延截止日期失败", zap.Error(err))
+			return errors.New("顺延截止日期失败")
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.New("新增日历例外失败")
+	}
+
+	return s.RefreshCache()
+}
+
+// DeleteException 删除日历例外
+func (s *CalendarService) DeleteException(id uint, actor model.AuditActor) error {
+	tx := global.GVA_DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var exception model.CalendarException
+	if err := tx.First(&exception, id).Error; err != nil {
+		tx.Rollback()
+		return errors.New("日历例外不存在")
+	}
+
+	if err := tx.Delete(&exception).Error; err != nil {
+		tx.Rollback()
+		return errors.New("删除日历例外失败")
+	}
+
+	if err := GlobalAuditService.Record(tx, actor, model.AuditCalendarUpdate, "calendar_exception", exception.ID, exception, nil); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.New("删除日历例外失败")
+	}
+
+	return s.RefreshCache()
+}
+
+// withException 返回加入一条例外后的日历副本，不修改当前缓存
+func (s *CalendarService) withException(exception model.CalendarException) *CalendarService {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	exceptions := make([]model.CalendarException, 0, len(s.exceptions)+1)
+	exceptions = append(exceptions, s.exceptions...)
+	return &CalendarService{weekly: s.weekly, exceptions: append(exceptions, exception)}
+}
+
+// rollForwardDeadlines 顺延落在闭馆期间的应还日期和取书截止日期（需在事务中调用）
+func (s *CalendarService) rollForwardDeadlines(tx *gorm.DB, start, end time.Time) error {
+	from := dayOf(start.In(time.Local))
+	to := dayOf(end.In(time.Local)).AddDate(0, 0, 1)
+	activeStatuses := []model.BorrowStatus{model.BorrowStatusBorrowed, model.BorrowStatusOverdue}
+
+	var records []model.BorrowRecord
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("status IN ? AND due_date >= ? AND due_date < ?", activeStatuses, from, to).
+		Find(&records).Error; err != nil {
+		return fmt.Errorf("查询需顺延的借阅记录失败: %w", err)
+	}
+	for _, r := range records {
+		newDue := s.RollForward(r.DueDate)
+		if newDue.Equal(r.DueDate) {
+			continue
+		}
+		if err := tx.Model(&model.BorrowRecord{}).Where("id = ?", r.ID).
+			UpdateColumn("due_date", newDue).Error; err != nil {
+			return fmt.Errorf("顺延借阅记录 %d 的应还日期失败: %w", r.ID, err)
+		}
+	}
+
+	var reservations []model.Reservation
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("status = ? AND pickup_deadline >= ? AND pickup_deadline < ?", model.ReservationStatusAvailable, from, to).
+		Find(&reservations).Error; err != nil {
+		return fmt.Errorf("查询需顺延的预约失败: %w", err)
+	}
+	for _, r := range reservations {
+		newDeadline := s.RollForward(*r.PickupDeadline)
+		if err := tx.Model(&model.Reservation{}).Where("id = ?", r.ID).
+			UpdateColumn("pickup_deadline", newDeadline).Error; err != nil {
+			return fmt.Errorf("顺延预约 %d 的取书截止日期失败: %w", r.ID, err)
+		}
+	}
+
+	global.GVA_LOG.Info("闭馆期间的截止日期已顺延",
+		zap.Int("borrow_records", len(records)),
+		zap.Int("reservations", len(reservations)))
+	return nil
+}
+
+// RefreshCache 从数据库重新加载开馆日历
+func (s *CalendarService) RefreshCache() error {
+	var hours []model.OpeningHours
+	if err := global.GVA_DB.Find(&hours).Error; err != nil {
+		return err
+	}
+
+	var exceptions []model.CalendarException
+	if err := global.GVA_DB.Order("id ASC").Find(&exceptions).Error; err != nil {
+		return err
+	}
+
+	weekly := make(map[time.Weekday]model.OpeningHours, len(hours))
+	for _, h := range hours {
+		weekly[time.Weekday(h.Weekday)] = h
+	}
+
+	s.mu.Lock()
+	s.weekly = weekly
+	s.exceptions = exceptions
+	s.mu.Unlock()
+
+	global.GVA_LOG.Info("开馆日历缓存已刷新", zap.Int("exceptions", len(exceptions)))
+	return nil
+}
+
+// isValidClock 校验开闭馆时间
+func isValidClock(open, close string) bool {
+	openTime, err := time.Parse("15:04", open)
+	if err != nil {
+		return false
+	}
+	closeTime, err := time.Parse("15:04", close)
+	if err != nil {
+		return false
+	}
+	return openTime.Before(closeTime)
+}
+
+// 全局开馆日历服务实例
+var GlobalCalendarService = &CalendarService{}
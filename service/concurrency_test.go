@@ -0,0 +1,392 @@
+package service_test
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"bookadmin/config"
+	"bookadmin/global"
+	"bookadmin/initialize"
+	"bookadmin/model"
+	"bookadmin/service"
+
+	"go.uber.org/zap"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 并发测试连接独立的测试库（会创建测试图书和读者，不要指向正式数据）
+// 例：BOOKADMIN_TEST_DSN="root:root@tcp(127.0.0.1:3306)/bookadmin_test?charset=utf8mb4&parseTime=True&loc=Local"
+const testDSNEnv = "BOOKADMIN_TEST_DSN"
+
+var (
+	testDBReady bool
+	testSeq     int64
+)
+
+func TestMain(m *testing.M) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn != "" {
+		global.GVA_LOG = zap.NewNop()
+		global.GVA_CONFIG = &config.Config{}
+		db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "连接测试数据库失败: %v\n", err)
+			os.Exit(1)
+		}
+		sqlDB, _ := db.DB()
+		sqlDB.SetMaxOpenConns(50)
+		global.GVA_DB = db
+
+		initialize.Gorm()
+		initialize.InitConfigCache()
+		initialize.InitPermissionCache()
+		initialize.InitCalendarCache()
+		initialize.InitPolicyCache()
+		service.GlobalOutboxService.Start()
+		testDBReady = true
+	}
+	os.Exit(m.Run())
+}
+
+func requireTestDB(t *testing.T) {
+	t.Helper()
+	if !testDBReady {
+		t.Skipf("未设置 %s，跳过并发测试", testDSNEnv)
+	}
+}
+
+func nextSuffix() string {
+	return fmt.Sprintf("%d%d", time.Now().UnixNano()%1e9, atomic.AddInt64(&testSeq, 1))
+}
+
+// createReaders 创建 n 个状态正常的用户和读者
+func createReaders(t *testing.T, n int) []model.Reader {
+	t.Helper()
+	readers := make([]model.Reader, n)
+	for i := range readers {
+		sfx := nextSuffix()
+		user := model.User{
+			Username: "ct_" + sfx,
+			Password: "x",
+			Email:    "ct_" + sfx + "@test.local",
+			Role:     model.RoleReader,
+			Status:   "active",
+		}
+		if err := global.GVA_DB.Create(&user).Error; err != nil {
+			t.Fatalf("创建用户失败: %v", err)
+		}
+		readers[i] = model.Reader{
+			UserID:         user.ID,
+			ReaderNo:       "CT" + sfx,
+			IDCard:         "CT" + sfx,
+			Status:         model.ReaderStatusActive,
+			PatronCategory: model.DefaultPatronCategory,
+		}
+		if err := global.GVA_DB.Create(&readers[i]).Error; err != nil {
+			t.Fatalf("创建读者失败: %v", err)
+		}
+	}
+	return readers
+}
+
+// createBook 创建一本带 copies 个在架副本的图书
+func createBook(t *testing.T, copies int) (model.Book, []model.BookCopy) {
+	t.Helper()
+	sfx := nextSuffix()
+	book := model.Book{Title: "并发测试" + sfx, Author: "test", ISBN: "CT" + sfx}
+	if err := global.GVA_DB.Create(&book).Error; err != nil {
+		t.Fatalf("创建图书失败: %v", err)
+	}
+	created, err := service.NewBookCopyService().AddCopies(book.ID, make([]model.BookCopy, copies), model.SystemActor)
+	if err != nil {
+		t.Fatalf("创建副本失败: %v", err)
+	}
+	return book, created
+}
+
+// race 同时启动 n 个 goroutine 执行 fn，返回成功次数
+func race(n int, fn func(i int) error) int {
+	var (
+		wg      sync.WaitGroup
+		success int64
+		start   = make(chan struct{})
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			if fn(i) == nil {
+				atomic.AddInt64(&success, 1)
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return int(success)
+}
+
+// drainHolds 等待预约保留事件执行完毕
+func drainHolds(t *testing.T) {
+	t.Helper()
+	for i := 0; i < 50; i++ {
+		if err := service.GlobalOutboxService.ProcessDue(); err != nil {
+			t.Fatalf("执行待执行事件失败: %v", err)
+		}
+		var pending int64
+		global.GVA_DB.Model(&model.OutboxEvent{}).
+			Where("topic = ? AND status IN ?", model.OutboxHoldNotify, []model.OutboxStatus{model.OutboxStatusPending, model.OutboxStatusProcessing}).
+			Count(&pending)
+		if pending == 0 {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatal("预约保留事件未在限定时间内执行完毕")
+}
+
+// assertStock 检查图书库存与副本状态一致，借出副本数与在借记录数一致
+func assertStock(t *testing.T, bookID uint) {
+	t.Helper()
+	var book model.Book
+	if err := global.GVA_DB.First(&book, bookID).Error; err != nil {
+		t.Fatalf("查询图书失败: %v", err)
+	}
+	var available, total, borrowed, active int64
+	global.GVA_DB.Model(&model.BookCopy{}).Where("book_id = ? AND status = ?", bookID, model.CopyStatusAvailable).Count(&available)
+	global.GVA_DB.Model(&model.BookCopy{}).
+		Where("book_id = ? AND status NOT IN ?", bookID, []model.BookCopyStatus{model.CopyStatusLost, model.CopyStatusRetired}).
+		Count(&total)
+	global.GVA_DB.Model(&model.BookCopy{}).Where("book_id = ? AND status = ?", bookID, model.CopyStatusBorrowed).Count(&borrowed)
+	global.GVA_DB.Model(&model.BorrowRecord{}).
+		Where("book_id = ? AND status IN ?", bookID, []model.BorrowStatus{model.BorrowStatusBorrowed, model.BorrowStatusOverdue}).
+		Count(&active)
+
+	if book.AvailableStock < 0 {
+		t.Errorf("available_stock = %d，不应为负数", book.AvailableStock)
+	}
+	if int64(book.AvailableStock) != available {
+		t.Errorf("available_stock = %d，在架副本数 = %d", book.AvailableStock, available)
+	}
+	if int64(book.TotalStock) != total {
+		t.Errorf("total_stock = %d，馆藏副本数 = %d", book.TotalStock, total)
+	}
+	if borrowed != active {
+		t.Errorf("借出副本数 = %d，在借记录数 = %d", borrowed, active)
+	}
+}
+
+// assertQueue 检查排队中的预约队列位置为 1..n，无空缺无重复
+func assertQueue(t *testing.T, bookID uint, want int) {
+	t.Helper()
+	var row struct {
+		Cnt      int64
+		Distinct int64
+		MinPos   int64
+		MaxPos   int64
+	}
+	global.GVA_DB.Model(&model.Reservation{}).
+		Select("COUNT(*) AS cnt, COUNT(DISTINCT queue_position) AS `distinct`, COALESCE(MIN(queue_position), 0) AS min_pos, COALESCE(MAX(queue_position), 0) AS max_pos").
+		Where("book_id = ? AND status = ?", bookID, model.ReservationStatusPending).
+		Scan(&row)
+
+	if row.Cnt != int64(want) {
+		t.Errorf("排队人数 = %d，期望 %d", row.Cnt, want)
+	}
+	if row.Cnt == 0 {
+		return
+	}
+	if row.Distinct != row.Cnt || row.MinPos != 1 || row.MaxPos != row.Cnt {
+		t.Errorf("队列位置不连续: count=%d distinct=%d min=%d max=%d", row.Cnt, row.Distinct, row.MinPos, row.MaxPos)
+	}
+}
+
+func TestConcurrentBorrowLastCopy(t *testing.T) {
+	requireTestDB(t)
+	borrowService := service.NewBorrowService()
+	book, _ := createBook(t, 1)
+	readers := createReaders(t, 20)
+
+	success := race(len(readers), func(i int) error {
+		_, err := borrowService.BorrowBook(readers[i].UserID, book.ID, 0, nil)
+		return err
+	})
+	if success != 1 {
+		t.Errorf("最后一本被借出 %d 次，期望 1 次", success)
+	}
+	assertStock(t, book.ID)
+}
+
+func TestConcurrentReserveAndReturn(t *testing.T) {
+	requireTestDB(t)
+	borrowService := service.NewBorrowService()
+	reservationService := service.NewReservationService()
+	book, _ := createBook(t, 1)
+	borrower := createReaders(t, 1)[0]
+	readers := createReaders(t, 19)
+
+	record, err := borrowService.BorrowBook(borrower.UserID, book.ID, 0, nil)
+	if err != nil {
+		t.Fatalf("借书失败: %v", err)
+	}
+
+	// 无可借副本时同时排队预约
+	success := race(len(readers), func(i int) error {
+		_, err := reservationService.CreateReservation(readers[i].ID, book.ID)
+		return err
+	})
+	if success != len(readers) {
+		t.Errorf("预约成功 %d 次，期望 %d 次", success, len(readers))
+	}
+	assertQueue(t, book.ID, len(readers))
+
+	// 同一条借阅记录同时归还
+	success = race(5, func(int) error {
+		_, _, err := borrowService.ReturnBook(record.ID, 0)
+		return err
+	})
+	if success != 1 {
+		t.Errorf("同一记录归还成功 %d 次，期望 1 次", success)
+	}
+
+	// 归还后为队首保留副本，其余预约重新编号
+	drainHolds(t)
+	var held int64
+	global.GVA_DB.Model(&model.Reservation{}).
+		Where("book_id = ? AND status = ?", book.ID, model.ReservationStatusAvailable).
+		Count(&held)
+	if held != 1 {
+		t.Errorf("可取预约 %d 个，期望 1 个", held)
+	}
+	assertQueue(t, book.ID, len(readers)-1)
+	assertStock(t, book.ID)
+
+	var refreshed model.Book
+	global.GVA_DB.First(&refreshed, book.ID)
+	if held > int64(refreshed.AvailableStock) {
+		t.Errorf("可取预约 %d 个，超过在架副本 %d 本", held, refreshed.AvailableStock)
+	}
+}
+
+func TestConcurrentApprove(t *testing.T) {
+	requireTestDB(t)
+	borrowService := service.NewBorrowService()
+	book, _ := createBook(t, 1)
+	readers := createReaders(t, 2)
+
+	// 读者自己提交借阅申请（userID == operatorID），等待审批
+	var recordIDs []uint
+	for _, reader := range readers {
+		record, err := borrowService.BorrowBook(reader.UserID, book.ID, reader.UserID, nil)
+		if err != nil {
+			t.Fatalf("提交借阅申请失败: %v", err)
+		}
+		recordIDs = append(recordIDs, record.ID)
+	}
+
+	// 两个申请各被重复审批 3 次，只有一本副本
+	success := race(len(recordIDs)*3, func(i int) error {
+		return borrowService.ApproveBorrowRequest(recordIDs[i%len(recordIDs)], 0, true, "")
+	})
+	if success != 1 {
+		t.Errorf("审批成功 %d 次，期望 1 次", success)
+	}
+	assertStock(t, book.ID)
+}
+
+func TestConcurrentRenew(t *testing.T) {
+	requireTestDB(t)
+	borrowService := service.NewBorrowService()
+	book, _ := createBook(t, 1)
+	reader := createReaders(t, 1)[0]
+
+	record, err := borrowService.BorrowBook(reader.UserID, book.ID, 0, nil)
+	if err != nil {
+		t.Fatalf("借书失败: %v", err)
+	}
+
+	success := race(10, func(int) error {
+		return borrowService.RenewBook(record.ID, reader.ID)
+	})
+
+	var refreshed model.BorrowRecord
+	if err := global.GVA_DB.First(&refreshed, record.ID).Error; err != nil {
+		t.Fatalf("查询借阅记录失败: %v", err)
+	}
+	if success != refreshed.MaxRenewCount {
+		t.Errorf("续借成功 %d 次，最大续借次数 %d", success, refreshed.MaxRenewCount)
+	}
+	if refreshed.RenewCount != success {
+		t.Errorf("续借次数 = %d，成功续借 %d 次", refreshed.RenewCount, success)
+	}
+	assertStock(t, book.ID)
+}
+
+func TestConcurrentDeskCheckout(t *testing.T) {
+	requireTestDB(t)
+	borrowService := service.NewBorrowService()
+	book, copies := createBook(t, 2)
+	readers := createReaders(t, 20)
+	copyID := copies[0].ID
+
+	// 流通台同时扫描同一条码借给不同读者
+	success := race(len(readers), func(i int) error {
+		_, err := borrowService.BorrowCopy(readers[i].UserID, copyID, 0)
+		return err
+	})
+	if success != 1 {
+		t.Errorf("同一副本借出 %d 次，期望 1 次", success)
+	}
+
+	var active int64
+	global.GVA_DB.Model(&model.BorrowRecord{}).
+		Where("copy_id = ? AND status IN ?", copyID, []model.BorrowStatus{model.BorrowStatusBorrowed, model.BorrowStatusOverdue}).
+		Count(&active)
+	if active != 1 {
+		t.Errorf("副本在借记录 %d 条，期望 1 条", active)
+	}
+	assertStock(t, book.ID)
+}
+
+func TestConcurrentBorrowAndReturn(t *testing.T) {
+	requireTestDB(t)
+	borrowService := service.NewBorrowService()
+	book, _ := createBook(t, 3)
+	readers := createReaders(t, 12)
+
+	// 第一轮：6 人同时借 3 本
+	var (
+		mu      sync.Mutex
+		records []uint
+	)
+	success := race(6, func(i int) error {
+		record, err := borrowService.BorrowBook(readers[i].UserID, book.ID, 0, nil)
+		if err == nil {
+			mu.Lock()
+			records = append(records, record.ID)
+			mu.Unlock()
+		}
+		return err
+	})
+	if success != 3 {
+		t.Errorf("借出 %d 本，期望 3 本", success)
+	}
+	assertStock(t, book.ID)
+
+	// 第二轮：归还与其他读者借书同时进行
+	race(len(records)+6, func(i int) error {
+		if i < len(records) {
+			_, _, err := borrowService.ReturnBook(records[i], 0)
+			return err
+		}
+		_, err := borrowService.BorrowBook(readers[i-len(records)+6].UserID, book.ID, 0, nil)
+		return err
+	})
+	assertStock(t, book.ID)
+}
@@ -66,9 +66,17 @@ func (s *ConfigService) GetBoolConfig(key string, defaultValue bool) bool {
 }
 
 // SetConfig 设置配置值
-func (s *ConfigService) SetConfig(key, value string) error {
+func (s *ConfigService) SetConfig(key, value string, actor model.AuditActor) error {
+	tx := global.GVA_DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
 	var config model.SystemConfig
-	result := global.GVA_DB.Where("config_key = ?", key).First(&config)
+	result := tx.Where("config_key = ?", key).First(&config)
+	var before interface{}
 
 	if result.Error != nil {
 		// 不存在，创建新配置
@@ -76,16 +84,29 @@ func (s *ConfigService) SetConfig(key, value string) error {
 			ConfigKey:   key,
 			ConfigValue: value,
 		}
-		if err := global.GVA_DB.Create(&config).Error; err != nil {
+		if err := tx.Create(&config).Error; err != nil {
+			tx.Rollback()
 			return err
 		}
 	} else {
 		// 存在，更新配置
-		if err := global.GVA_DB.Model(&config).Update("config_value", value).Error; err != nil {
+		before = map[string]string{"config_value": config.ConfigValue}
+		if err := tx.Model(&config).Update("config_value", value).Error; err != nil {
+			tx.Rollback()
 			return err
 		}
 	}
 
+	if err := GlobalAuditService.Record(tx, actor, model.AuditConfigUpdate, "system_config", config.ID,
+		before, map[string]string{"config_key": key, "config_value": value}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
 	// 更新缓存
 	s.configCache.Store(key, value)
 	return nil
@@ -6,7 +6,6 @@ import (
 	"bookadmin/model"
 	"context"
 	"errors"
-	"fmt"
 	"time"
 
 	"go.uber.org/zap"
@@ -27,11 +26,17 @@ func NewFavoriteService() *FavoriteService {
 
 // ToggleFavorite 切换收藏状态（收藏/取消收藏）
 func (s *FavoriteService) ToggleFavorite(ctx context.Context, userID, bookID uint) (*model.FavoriteStatus, error) {
+	// Redis不可用时只使用MySQL
+	if !GlobalRedisHealth.Available() {
+		return s.toggleFavoriteInDB(userID, bookID)
+	}
+
 	// 1. 防重复操作：加锁
 	lockKey := constants.KeyFavoriteLock(userID, bookID)
 	locked, err := s.redis.TryLock(ctx, lockKey, time.Second)
 	if err != nil {
-		return nil, err
+		GlobalRedisHealth.MarkUnavailable(err)
+		return s.toggleFavoriteInDB(userID, bookID)
 	}
 	if !locked {
 		return nil, errors.New("操作太频繁，请稍后再试")
@@ -98,10 +103,23 @@ func (s *FavoriteService) ToggleFavorite(ctx context.Context, userID, bookID uin
 
 // GetFavoriteStatus 查询收藏状态
 func (s *FavoriteService) GetFavoriteStatus(ctx context.Context, userID, bookID uint) (*model.FavoriteStatus, error) {
+	// Redis不可用时只使用MySQL
+	if !GlobalRedisHealth.Available() {
+		isFavorited, err := s.checkFavoriteStatusFromDB(userID, bookID)
+		if err != nil {
+			return nil, err
+		}
+		return &model.FavoriteStatus{
+			IsFavorited:   isFavorited,
+			FavoriteCount: s.getBookFavoriteCountFromDB(bookID),
+		}, nil
+	}
+
 	// 1. 先从Redis查询
 	isFavorited, err := s.checkFavoriteStatusFromRedis(ctx, userID, bookID)
 	if err != nil {
 		// Redis查询失败，从MySQL查询
+		GlobalRedisHealth.MarkUnavailable(err)
 		isFavorited, err = s.checkFavoriteStatusFromDB(userID, bookID)
 		if err != nil {
 			return nil, err
@@ -126,11 +144,17 @@ func (s *FavoriteService) BatchGetFavoriteStatus(ctx context.Context, userID uin
 		return []model.BatchFavoriteStatus{}, nil
 	}
 
+	// Redis不可用时只使用MySQL
+	if !GlobalRedisHealth.Available() {
+		return s.batchGetFavoriteStatusFromDB(userID, bookIDs)
+	}
+
 	// 批量查询用户收藏状态
 	favoriteMap, err := s.redis.BatchCheckUserFavorites(ctx, userID, bookIDs)
 	if err != nil {
-		global.GVA_LOG.Warn("批量查询收藏状态失败", zap.Error(err))
-		favoriteMap = make(map[uint]bool)
+		global.GVA_LOG.Warn("批量查询收藏状态失败，改为从MySQL查询", zap.Error(err))
+		GlobalRedisHealth.MarkUnavailable(err)
+		return s.batchGetFavoriteStatusFromDB(userID, bookIDs)
 	}
 
 	// 构建结果
@@ -209,24 +233,8 @@ func (s *FavoriteService) unfavoriteInRedis(ctx context.Context, userID, bookID
 }
 
 func (s *FavoriteService) updateRankings(ctx context.Context, bookID uint, delta float64) error {
-	now := time.Now()
-
-	// 周榜
-	year, week := now.ISOWeek()
-	weekPeriod := fmt.Sprintf("%d-W%02d", year, week)
-	weekKey := constants.KeyRankFavoritesWeek(weekPeriod)
-	if err := s.redis.IncrRankingScore(ctx, weekKey, bookID, delta); err != nil {
-		return err
-	}
-
-	// 月榜
-	monthPeriod := fmt.Sprintf("%d-%02d", now.Year(), now.Month())
-	monthKey := constants.KeyRankFavoritesMonth(monthPeriod)
-	if err := s.redis.IncrRankingScore(ctx, monthKey, bookID, delta); err != nil {
-		return err
-	}
-
-	return nil
+	// 周榜、月榜、年榜及所属分类榜，收藏同时计入热度榜
+	return updateRankingBoards(ctx, model.RankingTypeFavorite, bookID, delta)
 }
 
 func (s *FavoriteService) sendToStream(ctx context.Context, userID, bookID uint, action string) error {
@@ -274,6 +282,79 @@ func (s *FavoriteService) syncToMySQLDirectly(userID, bookID uint, action string
 	}
 }
 
+// toggleFavoriteInDB MySQL模式下切换收藏状态
+// 收藏记录和图书计数在同一个事务中修改，依靠唯一索引保证并发安全，不需要Redis锁
+func (s *FavoriteService) toggleFavoriteInDB(userID, bookID uint) (*model.FavoriteStatus, error) {
+	var book model.Book
+	if err := global.GVA_DB.Select("id").First(&book, bookID).Error; err != nil {
+		return nil, errors.New("图书不存在")
+	}
+
+	isFavorited := false
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		// 已收藏则取消
+		result := tx.Where("user_id = ? AND book_id = ?", userID, bookID).Delete(&model.BookFavorite{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return tx.Model(&model.Book{}).Where("id = ?", bookID).
+				UpdateColumn("favorite_count", gorm.Expr("favorite_count - ?", 1)).Error
+		}
+
+		// 未收藏则收藏，并发收藏由唯一索引拦截
+		favorite := model.BookFavorite{UserID: userID, BookID: bookID}
+		if err := tx.Create(&favorite).Error; err != nil {
+			return err
+		}
+		isFavorited = true
+		return tx.Model(&model.Book{}).Where("id = ?", bookID).
+			UpdateColumn("favorite_count", gorm.Expr("favorite_count + ?", 1)).Error
+	})
+	if err != nil {
+		global.GVA_LOG.Error("MySQL模式切换收藏失败", zap.Error(err))
+		return nil, errors.New("操作失败，请稍后再试")
+	}
+
+	return &model.FavoriteStatus{
+		IsFavorited:   isFavorited,
+		FavoriteCount: s.getBookFavoriteCountFromDB(bookID),
+	}, nil
+}
+
+// batchGetFavoriteStatusFromDB 从MySQL批量查询收藏状态
+func (s *FavoriteService) batchGetFavoriteStatusFromDB(userID uint, bookIDs []uint) ([]model.BatchFavoriteStatus, error) {
+	var favoritedIDs []uint
+	if err := global.GVA_DB.Model(&model.BookFavorite{}).
+		Where("user_id = ? AND book_id IN ?", userID, bookIDs).
+		Pluck("book_id", &favoritedIDs).Error; err != nil {
+		return nil, err
+	}
+	favorited := make(map[uint]bool, len(favoritedIDs))
+	for _, id := range favoritedIDs {
+		favorited[id] = true
+	}
+
+	var books []model.Book
+	if err := global.GVA_DB.Select("id", "favorite_count").Where("id IN ?", bookIDs).Find(&books).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[uint]int, len(books))
+	for _, book := range books {
+		counts[book.ID] = book.FavoriteCount
+	}
+
+	result := make([]model.BatchFavoriteStatus, 0, len(bookIDs))
+	for _, bookID := range bookIDs {
+		result = append(result, model.BatchFavoriteStatus{
+			BookID:        bookID,
+			IsFavorited:   favorited[bookID],
+			FavoriteCount: counts[bookID],
+		})
+	}
+	return result, nil
+}
+
 func (s *FavoriteService) getBookFavoriteCountFromDB(bookID uint) int {
 	var count int64
 	global.GVA_DB.Model(&model.BookFavorite{}).Where("book_id = ?", bookID).Count(&count)
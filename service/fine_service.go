@@ -4,11 +4,13 @@ import (
 	"bookadmin/global"
 	"bookadmin/model"
 	"errors"
+	"fmt"
 	"math"
 	"time"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type FineService struct{}
@@ -22,22 +24,31 @@ func (s *FineService) CalculateOverdueFine(borrowRecord *model.BorrowRecord) (fl
 		return 0, 0, nil
 	}
 
-	overdueDays := int(now.Sub(borrowRecord.DueDate).Hours() / 24)
+	// 闭馆日不计入逾期天数
+	overdueDays := GlobalCalendarService.CountOverdueDays(borrowRecord.DueDate, now)
 	if overdueDays <= 0 {
 		return 0, 0, nil
 	}
 
-	// 获取罚款配置
-	finePerDay := GlobalConfigService.GetFloatConfig(model.ConfigOverdueFinePerDay, 0.5)
-	maxFineRate := GlobalConfigService.GetFloatConfig(model.ConfigMaxFineRate, 0.5)
+	// 罚款标准由读者类型决定，并按图书分类覆盖
+	reader := borrowRecord.Reader
+	if reader.ID == 0 {
+		if err := global.GVA_DB.First(&reader, borrowRecord.ReaderID).Error; err != nil {
+			return 0, 0, errors.New("读者不存在")
+		}
+	}
+	policy, err := GlobalPolicyService.ResolveForBook(global.GVA_DB, &reader, borrowRecord.BookID)
+	if err != nil {
+		return 0, 0, errors.New("获取借阅规则失败")
+	}
 
 	// 计算基础罚款
-	fineAmount := float64(overdueDays) * finePerDay
+	fineAmount := float64(overdueDays) * policy.FinePerDay
 
 	// 获取图书价格，计算罚款上限
 	var book model.Book
 	if err := global.GVA_DB.First(&book, borrowRecord.BookID).Error; err == nil {
-		maxFine := book.Price * maxFineRate
+		maxFine := book.Price * policy.MaxFineRate
 		if fineAmount > maxFine {
 			fineAmount = maxFine
 		}
@@ -49,8 +60,8 @@ func (s *FineService) CalculateOverdueFine(borrowRecord *model.BorrowRecord) (fl
 	return fineAmount, overdueDays, nil
 }
 
-// CreateFinetRecord 创建罚款记录
-func (s *FineService) CreateFineRecord(readerID uint, borrowRecordID uint, fineType string, amount float64, overdueDays int, operatorID uint) error {
+// CreateFineRecord 创建罚款记录并记账，在调用方的事务内执行；读者有预存余额时自动冲抵
+func (s *FineService) CreateFineRecord(tx *gorm.DB, readerID uint, borrowRecordID uint, fineType string, amount float64, overdueDays int, operatorID uint) (*model.FineRecord, error) {
 	fine := model.FineRecord{
 		ReaderID:       readerID,
 		BorrowRecordID: borrowRecordID,
@@ -63,82 +74,195 @@ func (s *FineService) CreateFineRecord(readerID uint, borrowRecordID uint, fineT
 		OperatorID:     operatorID,
 	}
 
-	if err := global.GVA_DB.Create(&fine).Error; err != nil {
+	if err := tx.Create(&fine).Error; err != nil {
 		global.GVA_LOG.Error("创建罚款记录失败", zap.Error(err))
-		return errors.New("创建罚款记录失败")
+		return nil, errors.New("创建罚款记录失败")
+	}
+
+	if err := GlobalLedgerService.Charge(tx, &fine, operatorID); err != nil {
+		global.GVA_LOG.Error("罚款记账失败", zap.Error(err))
+		return nil, errors.New("创建罚款记录失败")
 	}
 
-	// 更新读者的罚款金额
-	if err := global.GVA_DB.Model(&model.Reader{}).Where("id = ?", readerID).
-		UpdateColumn("unpaid_fine", gorm.Expr("unpaid_fine + ?", amount)).
-		UpdateColumn("total_fine", gorm.Expr("total_fine + ?", amount)).
-		Error; err != nil {
-		global.GVA_LOG.Error("更新读者罚款金额失败", zap.Error(err))
+	if err := s.applyCredit(tx, readerID, operatorID); err != nil {
+		global.GVA_LOG.Error("使用预存余额冲抵罚款失败", zap.Error(err))
+		return nil, errors.New("创建罚款记录失败")
 	}
 
 	global.GVA_LOG.Info("创建罚款记录成功", zap.Uint("reader_id", readerID), zap.Float64("amount", amount))
-	return nil
+	return &fine, nil
 }
 
-// PayFine 支付罚款
-func (s *FineService) PayFine(fineID uint, paidAmount float64, operatorID uint) error {
-	tx := global.GVA_DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+// PayFine 现场收取罚款，超出该罚款待付金额的部分先冲抵读者其他未结清的罚款，仍有剩余的存为预存余额
+// requestID 为收款单号等调用方生成的请求编号，重复提交同一请求只收取一次；为空时按读者当前的账务状态去重
+// 返回收取后读者的预存余额
+func (s *FineService) PayFine(fineID uint, paidAmount float64, requestID string, operatorID uint) (float64, error) {
+	paidCents := toCents(paidAmount)
+	if paidCents <= 0 {
+		return 0, errors.New("支付金额必须大于0")
+	}
+
+	var credit float64
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		// 查找罚款记录，先锁读者再锁罚款
+		var fine model.FineRecord
+		if err := tx.First(&fine, fineID).Error; err != nil {
+			return errors.New("罚款记录不存在")
+		}
+		if err := GlobalLedgerService.LockReader(tx, fine.ReaderID); err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&fine, fineID).Error; err != nil {
+			return errors.New("罚款记录不存在")
 		}
-	}()
 
-	// 查找罚款记录
-	var fine model.FineRecord
-	if err := tx.First(&fine, fineID).Error; err != nil {
-		tx.Rollback()
-		return errors.New("罚款记录不存在")
-	}
+		key := fmt.Sprintf("cash:fine:%d:%s", fine.ID, requestID)
+		if requestID == "" {
+			var err error
+			if key, err = GlobalLedgerService.stateKey(tx, fmt.Sprintf("cash:fine:%d", fine.ID), fine.ReaderID); err != nil {
+				return err
+			}
+		}
+		// 重复提交的收款不再记账，返回当前余额
+		if posted, err := GlobalLedgerService.Posted(tx, key); err != nil {
+			return err
+		} else if posted != nil {
+			balance, err := GlobalLedgerService.Balance(tx, fine.ReaderID)
+			if err != nil {
+				return err
+			}
+			credit = balance.CreditAmount
+			return nil
+		}
 
-	if fine.Status == model.FineStatusPaid {
-		tx.Rollback()
-		return errors.New("罚款已支付")
-	}
+		if fine.Status == model.FineStatusPaid {
+			return errors.New("罚款已支付")
+		}
 
-	if fine.Status == model.FineStatusWaived {
-		tx.Rollback()
-		return errors.New("罚款已豁免")
-	}
+		if fine.Status == model.FineStatusWaived {
+			return errors.New("罚款已豁免")
+		}
 
-	// 更新罚款记录
-	now := time.Now()
-	fine.PaidAmount += paidAmount
-	fine.OperatorID = operatorID
+		// 更新罚款记录，最多结清该罚款的待付金额
+		applied := toCents(fine.Amount) - toCents(fine.PaidAmount)
+		if applied > paidCents {
+			applied = paidCents
+		}
+		now := time.Now()
+		fine.PaidAmount = fromCents(toCents(fine.PaidAmount) + applied)
+		fine.OperatorID = operatorID
+
+		if toCents(fine.PaidAmount) >= toCents(fine.Amount) {
+			fine.Status = model.FineStatusPaid
+			fine.PaidDate = &now
+		}
+
+		if err := tx.Save(&fine).Error; err != nil {
+			return errors.New("更新罚款记录失败")
+		}
+
+		if err := GlobalLedgerService.Payment(tx, LedgerPayment{
+			ReaderID:    fine.ReaderID,
+			Source:      model.AccountCash,
+			Amount:      fromCents(paidCents),
+			Allocations: []FineAllocation{{FineID: fine.ID, Amount: fromCents(applied)}},
+			Key:         key,
+			Memo:        "现场收取罚款",
+			OperatorID:  operatorID,
+		}); err != nil {
+			global.GVA_LOG.Error("罚款记账失败", zap.Error(err))
+			return errors.New("更新读者罚款金额失败")
+		}
+
+		if paidCents > applied {
+			if err := s.applyCredit(tx, fine.ReaderID, operatorID); err != nil {
+				return err
+			}
+		}
 
-	if fine.PaidAmount >= fine.Amount {
-		fine.Status = model.FineStatusPaid
-		fine.PaidDate = &now
+		balance, err := GlobalLedgerService.Balance(tx, fine.ReaderID)
+		if err != nil {
+			return err
+		}
+		credit = balance.CreditAmount
+		return nil
+	})
+	if err != nil {
+		return 0, err
 	}
 
-	if err := tx.Save(&fine).Error; err != nil {
-		tx.Rollback()
-		return errors.New("更新罚款记录失败")
+	global.GVA_LOG.Info("支付罚款成功", zap.Uint("fine_id", fineID), zap.Float64("amount", paidAmount))
+	return credit, nil
+}
+
+// applyCredit 读者有预存余额时按罚款日期先后冲抵未结清的罚款
+func (s *FineService) applyCredit(tx *gorm.DB, readerID uint, operatorID uint) error {
+	balance, err := GlobalLedgerService.Balance(tx, readerID)
+	if err != nil {
+		return err
+	}
+	credit := toCents(balance.CreditAmount)
+	if credit <= 0 {
+		return nil
 	}
 
-	// 更新读者的未支付罚款
-	if err := tx.Model(&model.Reader{}).Where("id = ?", fine.ReaderID).
-		UpdateColumn("unpaid_fine", gorm.Expr("unpaid_fine - ?", paidAmount)).
-		Error; err != nil {
-		tx.Rollback()
-		return errors.New("更新读者罚款金额失败")
+	var fines []model.FineRecord
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("reader_id = ? AND status = ?", readerID, model.FineStatusUnpaid).
+		Order("fine_date ASC, id ASC").Find(&fines).Error; err != nil {
+		return err
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		return errors.New("支付罚款失败")
+	now := time.Now()
+	var allocations []FineAllocation
+	var total int64
+	for i := range fines {
+		if credit == 0 {
+			break
+		}
+		fine := &fines[i]
+		apply := toCents(fine.Amount) - toCents(fine.PaidAmount)
+		if apply > credit {
+			apply = credit
+		}
+		if apply <= 0 {
+			continue
+		}
+
+		fine.PaidAmount = fromCents(toCents(fine.PaidAmount) + apply)
+		if toCents(fine.PaidAmount) >= toCents(fine.Amount) {
+			fine.Status = model.FineStatusPaid
+			fine.PaidDate = &now
+		}
+		if err := tx.Save(fine).Error; err != nil {
+			return err
+		}
+		allocations = append(allocations, FineAllocation{FineID: fine.ID, Amount: fromCents(apply)})
+		credit -= apply
+		total += apply
+	}
+	if total == 0 {
+		return nil
 	}
 
-	global.GVA_LOG.Info("支付罚款成功", zap.Uint("fine_id", fineID), zap.Float64("amount", paidAmount))
-	return nil
+	// 调用方已锁定读者（或记账时已更新读者行），账务状态在事务内不变
+	key, err := GlobalLedgerService.stateKey(tx, "credit:reader", readerID)
+	if err != nil {
+		return err
+	}
+	return GlobalLedgerService.Payment(tx, LedgerPayment{
+		ReaderID:    readerID,
+		Source:      model.AccountReceivable,
+		Amount:      fromCents(total),
+		Allocations: allocations,
+		Key:         key,
+		Memo:        "使用预存余额冲抵罚款",
+		OperatorID:  operatorID,
+	})
 }
 
 // WaiveFine 豁免罚款
-func (s *FineService) WaiveFine(fineID uint, operatorID uint, remark string) error {
+func (s *FineService) WaiveFine(fineID uint, actor model.AuditActor, remark string) error {
 	tx := global.GVA_DB.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -146,12 +270,20 @@ func (s *FineService) WaiveFine(fineID uint, operatorID uint, remark string) err
 		}
 	}()
 
-	// 查找罚款记录
+	// 查找罚款记录，先锁读者再锁罚款
 	var fine model.FineRecord
 	if err := tx.First(&fine, fineID).Error; err != nil {
 		tx.Rollback()
 		return errors.New("罚款记录不存在")
 	}
+	if err := GlobalLedgerService.LockReader(tx, fine.ReaderID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&fine, fineID).Error; err != nil {
+		tx.Rollback()
+		return errors.New("罚款记录不存在")
+	}
 
 	if fine.Status == model.FineStatusPaid {
 		tx.Rollback()
@@ -164,11 +296,17 @@ func (s *FineService) WaiveFine(fineID uint, operatorID uint, remark string) err
 	}
 
 	// 计算未支付的金额
-	unpaidAmount := fine.Amount - fine.PaidAmount
+	unpaidAmount := fromCents(toCents(fine.Amount) - toCents(fine.PaidAmount))
+	before := map[string]interface{}{
+		"status":      fine.Status,
+		"amount":      fine.Amount,
+		"paid_amount": fine.PaidAmount,
+		"remark":      fine.Remark,
+	}
 
 	// 更新罚款记录
 	fine.Status = model.FineStatusWaived
-	fine.OperatorID = operatorID
+	fine.OperatorID = actor.UserID
 	fine.Remark = remark
 
 	if err := tx.Save(&fine).Error; err != nil {
@@ -176,16 +314,24 @@ func (s *FineService) WaiveFine(fineID uint, operatorID uint, remark string) err
 		return errors.New("更新罚款记录失败")
 	}
 
-	// 更新读者的未支付罚款
+	// 记账并更新读者的未支付罚款
 	if unpaidAmount > 0 {
-		if err := tx.Model(&model.Reader{}).Where("id = ?", fine.ReaderID).
-			UpdateColumn("unpaid_fine", gorm.Expr("unpaid_fine - ?", unpaidAmount)).
-			Error; err != nil {
+		if err := GlobalLedgerService.Waive(tx, &fine, unpaidAmount, remark, actor.UserID); err != nil {
 			tx.Rollback()
+			global.GVA_LOG.Error("罚款记账失败", zap.Error(err))
 			return errors.New("更新读者罚款金额失败")
 		}
 	}
 
+	if err := GlobalAuditService.Record(tx, actor, model.AuditFineWaive, "fine_record", fine.ID, before, map[string]interface{}{
+		"status":        fine.Status,
+		"waived_amount": unpaidAmount,
+		"remark":        remark,
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return errors.New("豁免罚款失败")
 	}
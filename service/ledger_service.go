@@ -0,0 +1,637 @@
+package service
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrLedgerDuplicate 同一业务事件重复记账
+var ErrLedgerDuplicate = errors.New("该业务已记账")
+
+// FineAllocation 一笔款项在某条罚款上的分配
+type FineAllocation struct {
+	FineID uint
+	Amount float64
+}
+
+// LedgerPayment 支付记账参数
+type LedgerPayment struct {
+	ReaderID       uint
+	Source         model.LedgerAccount // 资金来源：现场收款、在线支付渠道，或读者应收（使用预存余额）
+	Amount         float64             // 收到的金额，超出分配合计的部分计入预存余额
+	Allocations    []FineAllocation
+	PaymentOrderID *uint
+	Key            string
+	Memo           string
+	OperatorID     uint
+}
+
+// LedgerRefund 退款记账参数
+type LedgerRefund struct {
+	ReaderID       uint
+	Destination    model.LedgerAccount // 退回的渠道，读者应收表示转入预存余额
+	Amount         float64             // 退款金额，超出重新打开的罚款合计的部分从预存余额中扣除
+	Reopen         []FineAllocation    // 退款后恢复为待付的罚款金额
+	PaymentOrderID *uint
+	Key            string
+	Memo           string
+	OperatorID     uint
+}
+
+// LedgerQuery 账务交易查询条件
+type LedgerQuery struct {
+	ReaderID uint
+	FineID   uint
+	Type     model.LedgerType
+	Page     int
+	PageSize int
+}
+
+// LedgerService 罚款账务
+// 罚款、支付、豁免、退款和调整都记为借贷相等的交易，交易和分录只追加不修改；
+// readers 表的未支付罚款、累计罚款和预存余额是账务汇总的缓存，每次记账后在同一事务内重新计算
+type LedgerService struct{}
+
+// Charge 记录产生罚款：借 读者应收（该罚款），贷 罚款收入
+func (s *LedgerService) Charge(tx *gorm.DB, fine *model.FineRecord, operatorID uint) error {
+	fineID := fine.ID
+	return s.post(tx, &model.LedgerTransaction{
+		Type:           model.LedgerCharge,
+		ReaderID:       fine.ReaderID,
+		FineID:         &fineID,
+		Amount:         fine.Amount,
+		Memo:           fmt.Sprintf("罚款（%s）", fine.FineType),
+		OperatorID:     operatorID,
+		IdempotencyKey: fmt.Sprintf("charge:fine:%d", fine.ID),
+	}, []model.LedgerEntry{
+		{Account: model.AccountReceivable, ReaderID: fine.ReaderID, FineID: &fineID, Debit: fine.Amount},
+		{Account: model.AccountFineIncome, Credit: fine.Amount},
+	})
+}
+
+// Payment 记录支付：借 资金来源，贷 读者应收（各罚款），多出的部分贷记读者预存余额
+func (s *LedgerService) Payment(tx *gorm.DB, p LedgerPayment) error {
+	entries := []model.LedgerEntry{s.sourceEntry(p.Source, p.ReaderID, p.Amount, true)}
+	remaining := toCents(p.Amount)
+	var fineID *uint
+	for _, a := range p.Allocations {
+		id := a.FineID
+		entries = append(entries, model.LedgerEntry{Account: model.AccountReceivable, ReaderID: p.ReaderID, FineID: &id, Credit: a.Amount})
+		remaining -= toCents(a.Amount)
+		if len(p.Allocations) == 1 {
+			fineID = &id
+		}
+	}
+	if remaining < 0 {
+		return errors.New("分配金额超过支付金额")
+	}
+	if remaining > 0 {
+		if p.Source == model.AccountReceivable {
+			return errors.New("使用预存余额时必须全部分配到罚款")
+		}
+		entries = append(entries, model.LedgerEntry{Account: model.AccountReceivable, ReaderID: p.ReaderID, Credit: fromCents(remaining)})
+	}
+
+	return s.post(tx, &model.LedgerTransaction{
+		Type:           model.LedgerPayment,
+		ReaderID:       p.ReaderID,
+		FineID:         fineID,
+		PaymentOrderID: p.PaymentOrderID,
+		Amount:         p.Amount,
+		Memo:           p.Memo,
+		OperatorID:     p.OperatorID,
+		IdempotencyKey: p.Key,
+	}, entries)
+}
+
+// Waive 记录豁免：借 豁免减免，贷 读者应收（该罚款）
+func (s *LedgerService) Waive(tx *gorm.DB, fine *model.FineRecord, amount float64, memo string, operatorID uint) error {
+	fineID := fine.ID
+	return s.post(tx, &model.LedgerTransaction{
+		Type:           model.LedgerWaiver,
+		ReaderID:       fine.ReaderID,
+		FineID:         &fineID,
+		Amount:         amount,
+		Memo:           memo,
+		OperatorID:     operatorID,
+		IdempotencyKey: fmt.Sprintf("waiver:fine:%d", fine.ID),
+	}, []model.LedgerEntry{
+		{Account: model.AccountWaiver, Debit: amount},
+		{Account: model.AccountReceivable, ReaderID: fine.ReaderID, FineID: &fineID, Credit: amount},
+	})
+}
+
+// Reduce 冲减罚款金额（如丢失的图书找回后退还赔偿）：借 人工调整，贷 读者应收（该罚款）
+// 罚款已支付的部分超过冲减后的金额时，该罚款的待付金额为负，需要随后退款
+func (s *LedgerService) Reduce(tx *gorm.DB, fine *model.FineRecord, amount float64, memo, key string, operatorID uint) error {
+	fineID := fine.ID
+	return s.post(tx, &model.LedgerTransaction{
+		Type:           model.LedgerAdjustment,
+		ReaderID:       fine.ReaderID,
+		FineID:         &fineID,
+		Amount:         amount,
+		Memo:           memo,
+		OperatorID:     operatorID,
+		IdempotencyKey: key,
+	}, []model.LedgerEntry{
+		{Account: model.AccountAdjustment, Debit: amount},
+		{Account: model.AccountReceivable, ReaderID: fine.ReaderID, FineID: &fineID, Credit: amount},
+	})
+}
+
+// Refund 记录退款：借 读者应收（恢复待付的罚款，其余从预存余额扣除），贷 退回的渠道
+func (s *LedgerService) Refund(tx *gorm.DB, r LedgerRefund) error {
+	entries := []model.LedgerEntry{s.sourceEntry(r.Destination, r.ReaderID, r.Amount, false)}
+	remaining := toCents(r.Amount)
+	for _, a := range r.Reopen {
+		id := a.FineID
+		entries = append(entries, model.LedgerEntry{Account: model.AccountReceivable, ReaderID: r.ReaderID, FineID: &id, Debit: a.Amount})
+		remaining -= toCents(a.Amount)
+	}
+	if remaining < 0 {
+		return errors.New("恢复的罚款金额超过退款金额")
+	}
+	if remaining > 0 {
+		entries = append(entries, model.LedgerEntry{Account: model.AccountReceivable, ReaderID: r.ReaderID, Debit: fromCents(remaining)})
+	}
+
+	return s.post(tx, &model.LedgerTransaction{
+		Type:           model.LedgerRefund,
+		ReaderID:       r.ReaderID,
+		PaymentOrderID: r.PaymentOrderID,
+		Amount:         r.Amount,
+		Memo:           r.Memo,
+		OperatorID:     r.OperatorID,
+		IdempotencyKey: r.Key,
+	}, entries)
+}
+
+// Adjust 人工调整，amount 为正表示增加读者欠款，为负表示减少欠款（或增加预存余额）
+// fine 不为空时调整该罚款的金额，否则调整预存余额；
+// requestID 为调用方生成的请求编号，重复提交同一请求只调整一次，为空时按读者当前的账务状态去重
+func (s *LedgerService) Adjust(readerID uint, fineID *uint, amount float64, memo, requestID string, actor model.AuditActor) (*model.LedgerTransaction, error) {
+	cents := toCents(amount)
+	if cents == 0 {
+		return nil, errors.New("调整金额不能为0")
+	}
+	if memo == "" {
+		return nil, errors.New("请填写调整原因")
+	}
+	abs := fromCents(cents)
+	if cents < 0 {
+		abs = fromCents(-cents)
+	}
+
+	txn := &model.LedgerTransaction{
+		Type:       model.LedgerAdjustment,
+		ReaderID:   readerID,
+		FineID:     fineID,
+		Amount:     abs,
+		Memo:       memo,
+		OperatorID: actor.UserID,
+	}
+	receivable := model.LedgerEntry{Account: model.AccountReceivable, ReaderID: readerID, FineID: fineID}
+	counter := model.LedgerEntry{Account: model.AccountAdjustment}
+	if cents > 0 {
+		receivable.Debit, counter.Credit = abs, abs
+	} else {
+		receivable.Credit, counter.Debit = abs, abs
+	}
+
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		var reader model.Reader
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reader, readerID).Error; err != nil {
+			return errors.New("读者不存在")
+		}
+
+		if requestID != "" {
+			txn.IdempotencyKey = fmt.Sprintf("adjust:%d:%s", readerID, requestID)
+		} else {
+			key, err := s.stateKey(tx, "adjust", readerID)
+			if err != nil {
+				return err
+			}
+			txn.IdempotencyKey = key
+		}
+		// 重复提交的请求返回已记账的交易
+		if posted, err := s.Posted(tx, txn.IdempotencyKey); err != nil {
+			return err
+		} else if posted != nil {
+			txn = posted
+			return nil
+		}
+
+		before, err := s.Balance(tx, readerID)
+		if err != nil {
+			return err
+		}
+		if fineID == nil && cents > toCents(before.CreditAmount) {
+			return errors.New("扣减的金额超过读者的预存余额")
+		}
+
+		if fineID != nil {
+			var fine model.FineRecord
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id = ? AND reader_id = ?", *fineID, readerID).First(&fine).Error; err != nil {
+				return errors.New("罚款记录不存在")
+			}
+			if fine.Status == model.FineStatusWaived {
+				return errors.New("罚款已豁免，不能调整")
+			}
+			outstanding := toCents(fine.Amount) - toCents(fine.PaidAmount)
+			if fine.Status == model.FineStatusPaid {
+				outstanding = 0
+			}
+			if outstanding+cents < 0 {
+				return errors.New("减少的金额超过该罚款的待付金额")
+			}
+
+			// 罚款金额随调整变化，待付金额为0时视为已结清
+			fine.Amount = fromCents(toCents(fine.PaidAmount) + outstanding + cents)
+			if outstanding+cents == 0 {
+				now := time.Now()
+				fine.Status = model.FineStatusPaid
+				if fine.PaidDate == nil {
+					fine.PaidDate = &now
+				}
+			} else {
+				fine.Status = model.FineStatusUnpaid
+				fine.PaidDate = nil
+			}
+			if err := tx.Save(&fine).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := s.post(tx, txn, []model.LedgerEntry{receivable, counter}); err != nil {
+			return err
+		}
+		after, err := s.Balance(tx, readerID)
+		if err != nil {
+			return err
+		}
+		return GlobalAuditService.Record(tx, actor, model.AuditLedgerAdjust, "reader", readerID, before, map[string]interface{}{
+			"balance": after,
+			"fine_id": fineID,
+			"amount":  fromCents(cents),
+			"memo":    memo,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
+
+// Balance 由账务分录汇总读者余额
+func (s *LedgerService) Balance(db *gorm.DB, readerID uint) (model.ReaderBalance, error) {
+	balances, err := s.balances(db, []uint{readerID})
+	if err != nil {
+		return model.ReaderBalance{}, err
+	}
+	if b, ok := balances[readerID]; ok {
+		return b, nil
+	}
+	return model.ReaderBalance{ReaderID: readerID}, nil
+}
+
+// ListTransactions 查询账务交易（含分录），按时间倒序
+func (s *LedgerService) ListTransactions(q LedgerQuery) ([]model.LedgerTransaction, int64, error) {
+	db := global.GVA_DB.Model(&model.LedgerTransaction{})
+	if q.ReaderID > 0 {
+		db = db.Where("reader_id = ?", q.ReaderID)
+	}
+	if q.FineID > 0 {
+		db = db.Where("id IN (?)", global.GVA_DB.Model(&model.LedgerEntry{}).Select("transaction_id").Where("fine_id = ?", q.FineID))
+	}
+	if q.Type != "" {
+		db = db.Where("type = ?", q.Type)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var list []model.LedgerTransaction
+	err := db.Preload("Entries").Order("id DESC").Limit(q.PageSize).Offset((q.Page - 1) * q.PageSize).Find(&list).Error
+	return list, total, err
+}
+
+// Reconcile 对账：检查借贷不平的交易，以及读者缓存余额、罚款记录待付金额与账务汇总不一致的读者
+// fix 为true时按账务重新计算不一致读者的缓存余额（罚款记录的不一致需要人工调整）
+func (s *LedgerService) Reconcile(fix bool, actor model.AuditActor) (*model.LedgerReconcileReport, error) {
+	report := &model.LedgerReconcileReport{CheckedAt: time.Now(), UnbalancedTxns: []uint{}, Mismatches: []model.LedgerMismatch{}}
+
+	if err := global.GVA_DB.Model(&model.LedgerEntry{}).
+		Select("transaction_id").
+		Group("transaction_id").
+		Having("ROUND(SUM(debit) - SUM(credit), 2) <> 0").
+		Scan(&report.UnbalancedTxns).Error; err != nil {
+		return nil, err
+	}
+
+	var readers []model.Reader
+	if err := global.GVA_DB.Select("id", "reader_no", "unpaid_fine", "total_fine", "fine_credit").
+		Order("id ASC").Find(&readers).Error; err != nil {
+		return nil, err
+	}
+	report.ReaderCount = len(readers)
+
+	balances, err := s.balances(global.GVA_DB, nil)
+	if err != nil {
+		return nil, err
+	}
+	fineMismatches, err := s.fineMismatches()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range readers {
+		ledger := balances[r.ID]
+		ledger.ReaderID = r.ID
+		cached := model.ReaderBalance{ReaderID: r.ID, UnpaidFine: r.UnpaidFine, TotalFine: r.TotalFine, CreditAmount: r.FineCredit}
+		fines := fineMismatches[r.ID]
+		if sameBalance(cached, ledger) && len(fines) == 0 {
+			continue
+		}
+		report.Mismatches = append(report.Mismatches, model.LedgerMismatch{
+			ReaderID: r.ID,
+			ReaderNo: r.ReaderNo,
+			Cached:   cached,
+			Ledger:   ledger,
+			Fines:    fines,
+		})
+	}
+
+	if !fix || len(report.Mismatches) == 0 {
+		return report, nil
+	}
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		var fixed []uint
+		for _, m := range report.Mismatches {
+			if sameBalance(m.Cached, m.Ledger) {
+				continue
+			}
+			if err := s.refreshReader(tx, m.ReaderID); err != nil {
+				return err
+			}
+			fixed = append(fixed, m.ReaderID)
+		}
+		return GlobalAuditService.Record(tx, actor, model.AuditLedgerReconcile, "reader", 0, nil, map[string]interface{}{
+			"fixed_readers": fixed,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Fixed = true
+	return report, nil
+}
+
+// Backfill 为尚未记账的罚款记录补记账（每次启动时执行），已支付的部分记为现场收款
+// 以罚款的记账幂等键判断是否已记账，上次中途失败的罚款会在下次启动时重试；金额为0的罚款不记账
+func (s *LedgerService) Backfill() error {
+	var fines []model.FineRecord
+	if err := global.GVA_DB.
+		Where("NOT EXISTS (?)", global.GVA_DB.Model(&model.LedgerTransaction{}).Select("1").
+			Where("ledger_transactions.idempotency_key = CONCAT('charge:fine:', fine_records.id)")).
+		Order("id ASC").Find(&fines).Error; err != nil {
+		return err
+	}
+
+	var done, skipped, failed int
+	for i := range fines {
+		fine := &fines[i]
+		if toCents(fine.Amount) <= 0 {
+			skipped++
+			continue
+		}
+		err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+			if err := s.Charge(tx, fine, fine.OperatorID); err != nil {
+				return err
+			}
+			if paid := toCents(fine.PaidAmount); paid > 0 {
+				applied := paid
+				if amount := toCents(fine.Amount); applied > amount {
+					applied = amount
+				}
+				if err := s.Payment(tx, LedgerPayment{
+					ReaderID:    fine.ReaderID,
+					Source:      model.AccountCash,
+					Amount:      fine.PaidAmount,
+					Allocations: []FineAllocation{{FineID: fine.ID, Amount: fromCents(applied)}},
+					Key:         fmt.Sprintf("payment:migrate:fine:%d", fine.ID),
+					Memo:        "历史数据迁移",
+					OperatorID:  fine.OperatorID,
+				}); err != nil {
+					return err
+				}
+			}
+			if fine.Status == model.FineStatusWaived {
+				if remaining := toCents(fine.Amount) - toCents(fine.PaidAmount); remaining > 0 {
+					return s.Waive(tx, fine, fromCents(remaining), "历史数据迁移", fine.OperatorID)
+				}
+			}
+			return nil
+		})
+		switch {
+		case err == nil:
+			done++
+		case errors.Is(err, ErrLedgerDuplicate):
+			// 其他实例已补记
+		default:
+			failed++
+			global.GVA_LOG.Error("罚款记录补记账失败", zap.Uint("fine_id", fine.ID), zap.Error(err))
+		}
+	}
+
+	if done > 0 {
+		global.GVA_LOG.Info("罚款账务补记完成", zap.Int("fines", done), zap.Int("zero_amount_skipped", skipped))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 条罚款记录补记账失败，将在下次启动时重试", failed)
+	}
+	return nil
+}
+
+// post 校验借贷相等后写入交易和分录，并重新计算读者缓存余额
+func (s *LedgerService) post(tx *gorm.DB, txn *model.LedgerTransaction, entries []model.LedgerEntry) error {
+	var debit, credit int64
+	for _, e := range entries {
+		if e.Debit < 0 || e.Credit < 0 {
+			return errors.New("分录金额不能为负")
+		}
+		debit += toCents(e.Debit)
+		credit += toCents(e.Credit)
+	}
+	if debit == 0 || debit != credit {
+		return fmt.Errorf("借贷不平衡: 借方 %.2f 贷方 %.2f", fromCents(debit), fromCents(credit))
+	}
+
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Omit("Entries").Create(txn)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLedgerDuplicate
+	}
+	for i := range entries {
+		entries[i].TransactionID = txn.ID
+	}
+	if err := tx.Create(&entries).Error; err != nil {
+		return err
+	}
+	txn.Entries = entries
+
+	return s.refreshReader(tx, txn.ReaderID)
+}
+
+// sourceEntry 资金科目的分录，使用预存余额时为读者应收中未关联罚款的部分
+func (s *LedgerService) sourceEntry(account model.LedgerAccount, readerID uint, amount float64, debit bool) model.LedgerEntry {
+	e := model.LedgerEntry{Account: account}
+	if account == model.AccountReceivable {
+		e.ReaderID = readerID
+	}
+	if debit {
+		e.Debit = amount
+	} else {
+		e.Credit = amount
+	}
+	return e
+}
+
+// Posted 按幂等键查询已记账的交易，未记账时返回 nil
+func (s *LedgerService) Posted(tx *gorm.DB, key string) (*model.LedgerTransaction, error) {
+	var txn model.LedgerTransaction
+	err := tx.Where("idempotency_key = ?", key).First(&txn).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &txn, nil
+}
+
+// stateKey 由读者最近一笔交易生成幂等键（需在锁定读者后调用）
+// 同一账务状态下重复执行得到相同的键，只记账一次；每次记账后读者的最近交易改变，下一次操作得到新的键
+func (s *LedgerService) stateKey(tx *gorm.DB, prefix string, readerID uint) (string, error) {
+	var last uint
+	if err := tx.Model(&model.LedgerTransaction{}).Where("reader_id = ?", readerID).
+		Select("COALESCE(MAX(id), 0)").Scan(&last).Error; err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%d:%d", prefix, readerID, last), nil
+}
+
+// LockReader 锁定读者行（需在事务中调用）
+// 记账会更新读者行的缓存余额，变更罚款前先锁读者再锁罚款，与 产生罚款 → 冲抵预存余额 的加锁顺序一致
+func (s *LedgerService) LockReader(tx *gorm.DB, readerID uint) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&model.Reader{}, readerID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("读者不存在")
+		}
+		return err
+	}
+	return nil
+}
+
+// refreshReader 按账务重新计算读者的缓存余额
+func (s *LedgerService) refreshReader(tx *gorm.DB, readerID uint) error {
+	b, err := s.Balance(tx, readerID)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&model.Reader{}).Where("id = ?", readerID).UpdateColumns(map[string]interface{}{
+		"unpaid_fine": b.UnpaidFine,
+		"total_fine":  b.TotalFine,
+		"fine_credit": b.CreditAmount,
+	}).Error
+}
+
+// balances 汇总读者余额，readerIDs 为空时汇总全部读者
+func (s *LedgerService) balances(db *gorm.DB, readerIDs []uint) (map[uint]model.ReaderBalance, error) {
+	query := db.Table("ledger_entries e").
+		Joins("JOIN ledger_transactions t ON t.id = e.transaction_id").
+		Select(`e.reader_id AS reader_id,
+			COALESCE(SUM(CASE WHEN e.fine_id IS NOT NULL THEN e.debit - e.credit ELSE 0 END), 0) AS unpaid_fine,
+			COALESCE(SUM(CASE WHEN e.fine_id IS NOT NULL AND t.type IN ? THEN e.debit - e.credit ELSE 0 END), 0) AS total_fine,
+			COALESCE(SUM(CASE WHEN e.fine_id IS NULL THEN e.credit - e.debit ELSE 0 END), 0) AS credit_amount`,
+			[]model.LedgerType{model.LedgerCharge, model.LedgerAdjustment}).
+		Where("e.account = ?", model.AccountReceivable).
+		Group("e.reader_id")
+	if len(readerIDs) > 0 {
+		query = query.Where("e.reader_id IN ?", readerIDs)
+	}
+
+	var rows []model.ReaderBalance
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[uint]model.ReaderBalance, len(rows))
+	for _, r := range rows {
+		r.UnpaidFine = fromCents(toCents(r.UnpaidFine))
+		r.TotalFine = fromCents(toCents(r.TotalFine))
+		r.CreditAmount = fromCents(toCents(r.CreditAmount))
+		result[r.ReaderID] = r
+	}
+	return result, nil
+}
+
+// fineMismatches 罚款记录的待付金额与账务不一致的罚款，按读者分组
+func (s *LedgerService) fineMismatches() (map[uint][]model.FineMismatch, error) {
+	var ledgerRows []struct {
+		FineID uint
+		Unpaid float64
+	}
+	if err := global.GVA_DB.Model(&model.LedgerEntry{}).
+		Select("fine_id, COALESCE(SUM(debit - credit), 0) AS unpaid").
+		Where("account = ? AND fine_id IS NOT NULL", model.AccountReceivable).
+		Group("fine_id").
+		Scan(&ledgerRows).Error; err != nil {
+		return nil, err
+	}
+	ledgerUnpaid := make(map[uint]int64, len(ledgerRows))
+	for _, r := range ledgerRows {
+		ledgerUnpaid[r.FineID] = toCents(r.Unpaid)
+	}
+
+	var fines []model.FineRecord
+	if err := global.GVA_DB.Select("id", "reader_id", "amount", "paid_amount", "status").Find(&fines).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[uint][]model.FineMismatch)
+	for _, f := range fines {
+		var cached int64
+		if f.Status == model.FineStatusUnpaid {
+			cached = toCents(f.Amount) - toCents(f.PaidAmount)
+		}
+		if cached == ledgerUnpaid[f.ID] {
+			continue
+		}
+		result[f.ReaderID] = append(result[f.ReaderID], model.FineMismatch{
+			FineID:       f.ID,
+			Status:       f.Status,
+			CachedUnpaid: fromCents(cached),
+			LedgerUnpaid: fromCents(ledgerUnpaid[f.ID]),
+		})
+	}
+	return result, nil
+}
+
+// sameBalance 两个余额按分比较是否一致
+func sameBalance(a, b model.ReaderBalance) bool {
+	return toCents(a.UnpaidFine) == toCents(b.UnpaidFine) &&
+		toCents(a.TotalFine) == toCents(b.TotalFine) &&
+		toCents(a.CreditAmount) == toCents(b.CreditAmount)
+}
+
+// 全局罚款账务服务实例
+var GlobalLedgerService = &LedgerService{}
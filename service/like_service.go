@@ -6,7 +6,6 @@ import (
 	"bookadmin/model"
 	"context"
 	"errors"
-	"fmt"
 	"time"
 
 	"go.uber.org/zap"
@@ -30,11 +29,17 @@ func NewLikeService() *LikeService {
 // 1. 先操作Redis（快速返回）
 // 2. 发送消息到Stream异步同步MySQL
 func (s *LikeService) ToggleLike(ctx context.Context, userID, bookID uint) (*model.LikeStatus, error) {
+	// Redis不可用时只使用MySQL
+	if !GlobalRedisHealth.Available() {
+		return s.toggleLikeInDB(userID, bookID)
+	}
+
 	// 1. 防重复操作：加锁（1秒内同一用户对同一本书只能操作一次）
 	lockKey := constants.KeyLikeLock(userID, bookID)
 	locked, err := s.redis.TryLock(ctx, lockKey, time.Second)
 	if err != nil {
-		return nil, err
+		GlobalRedisHealth.MarkUnavailable(err)
+		return s.toggleLikeInDB(userID, bookID)
 	}
 	if !locked {
 		return nil, errors.New("操作太频繁，请稍后再试")
@@ -103,10 +108,23 @@ func (s *LikeService) ToggleLike(ctx context.Context, userID, bookID uint) (*mod
 
 // GetLikeStatus 查询点赞状态
 func (s *LikeService) GetLikeStatus(ctx context.Context, userID, bookID uint) (*model.LikeStatus, error) {
+	// Redis不可用时只使用MySQL
+	if !GlobalRedisHealth.Available() {
+		isLiked, err := s.checkLikeStatusFromDB(userID, bookID)
+		if err != nil {
+			return nil, err
+		}
+		return &model.LikeStatus{
+			IsLiked:   isLiked,
+			LikeCount: s.getBookLikeCountFromDB(bookID),
+		}, nil
+	}
+
 	// 1. 先从Redis查询用户是否已点赞
 	isLiked, err := s.checkLikeStatusFromRedis(ctx, userID, bookID)
 	if err != nil {
 		// Redis查询失败，从MySQL查询
+		GlobalRedisHealth.MarkUnavailable(err)
 		isLiked, err = s.checkLikeStatusFromDB(userID, bookID)
 		if err != nil {
 			return nil, err
@@ -132,11 +150,17 @@ func (s *LikeService) BatchGetLikeStatus(ctx context.Context, userID uint, bookI
 		return []model.BatchLikeStatus{}, nil
 	}
 
+	// Redis不可用时只使用MySQL
+	if !GlobalRedisHealth.Available() {
+		return s.batchGetLikeStatusFromDB(userID, bookIDs)
+	}
+
 	// 批量查询用户点赞状态
 	likeMap, err := s.redis.BatchCheckUserLikes(ctx, userID, bookIDs)
 	if err != nil {
-		global.GVA_LOG.Warn("批量查询点赞状态失败", zap.Error(err))
-		likeMap = make(map[uint]bool)
+		global.GVA_LOG.Warn("批量查询点赞状态失败，改为从MySQL查询", zap.Error(err))
+		GlobalRedisHealth.MarkUnavailable(err)
+		return s.batchGetLikeStatusFromDB(userID, bookIDs)
 	}
 
 	// 构建结果
@@ -228,24 +252,8 @@ func (s *LikeService) unlikeInRedis(ctx context.Context, userID, bookID uint) er
 
 // updateRankings 更新榜单
 func (s *LikeService) updateRankings(ctx context.Context, bookID uint, delta float64) error {
-	now := time.Now()
-
-	// 周榜：2025-W45
-	year, week := now.ISOWeek()
-	weekPeriod := fmt.Sprintf("%d-W%02d", year, week)
-	weekKey := constants.KeyRankLikesWeek(weekPeriod)
-	if err := s.redis.IncrRankingScore(ctx, weekKey, bookID, delta); err != nil {
-		return err
-	}
-
-	// 月榜：2025-11
-	monthPeriod := fmt.Sprintf("%d-%02d", now.Year(), now.Month())
-	monthKey := constants.KeyRankLikesMonth(monthPeriod)
-	if err := s.redis.IncrRankingScore(ctx, monthKey, bookID, delta); err != nil {
-		return err
-	}
-
-	return nil
+	// 周榜、月榜、年榜及所属分类榜，点赞同时计入热度榜
+	return updateRankingBoards(ctx, model.RankingTypeLike, bookID, delta)
 }
 
 // sendToStream 发送操作到Stream异步队列
@@ -300,6 +308,79 @@ func (s *LikeService) syncToMySQLDirectly(userID, bookID uint, action string) {
 	}
 }
 
+// toggleLikeInDB MySQL模式下切换点赞状态
+// 点赞记录和图书计数在同一个事务中修改，依靠唯一索引保证并发安全，不需要Redis锁
+func (s *LikeService) toggleLikeInDB(userID, bookID uint) (*model.LikeStatus, error) {
+	var book model.Book
+	if err := global.GVA_DB.Select("id").First(&book, bookID).Error; err != nil {
+		return nil, errors.New("图书不存在")
+	}
+
+	isLiked := false
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		// 已点赞则取消
+		result := tx.Where("user_id = ? AND book_id = ?", userID, bookID).Delete(&model.BookLike{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return tx.Model(&model.Book{}).Where("id = ?", bookID).
+				UpdateColumn("like_count", gorm.Expr("like_count - ?", 1)).Error
+		}
+
+		// 未点赞则点赞，并发点赞由唯一索引拦截
+		like := model.BookLike{UserID: userID, BookID: bookID}
+		if err := tx.Create(&like).Error; err != nil {
+			return err
+		}
+		isLiked = true
+		return tx.Model(&model.Book{}).Where("id = ?", bookID).
+			UpdateColumn("like_count", gorm.Expr("like_count + ?", 1)).Error
+	})
+	if err != nil {
+		global.GVA_LOG.Error("MySQL模式切换点赞失败", zap.Error(err))
+		return nil, errors.New("操作失败，请稍后再试")
+	}
+
+	return &model.LikeStatus{
+		IsLiked:   isLiked,
+		LikeCount: s.getBookLikeCountFromDB(bookID),
+	}, nil
+}
+
+// batchGetLikeStatusFromDB 从MySQL批量查询点赞状态
+func (s *LikeService) batchGetLikeStatusFromDB(userID uint, bookIDs []uint) ([]model.BatchLikeStatus, error) {
+	var likedIDs []uint
+	if err := global.GVA_DB.Model(&model.BookLike{}).
+		Where("user_id = ? AND book_id IN ?", userID, bookIDs).
+		Pluck("book_id", &likedIDs).Error; err != nil {
+		return nil, err
+	}
+	liked := make(map[uint]bool, len(likedIDs))
+	for _, id := range likedIDs {
+		liked[id] = true
+	}
+
+	var books []model.Book
+	if err := global.GVA_DB.Select("id", "like_count").Where("id IN ?", bookIDs).Find(&books).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[uint]int, len(books))
+	for _, book := range books {
+		counts[book.ID] = book.LikeCount
+	}
+
+	result := make([]model.BatchLikeStatus, 0, len(bookIDs))
+	for _, bookID := range bookIDs {
+		result = append(result, model.BatchLikeStatus{
+			BookID:    bookID,
+			IsLiked:   liked[bookID],
+			LikeCount: counts[bookID],
+		})
+	}
+	return result, nil
+}
+
 // getBookLikeCountFromDB 从MySQL获取图书点赞数
 func (s *LikeService) getBookLikeCountFromDB(bookID uint) int {
 	var count int64
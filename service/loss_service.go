@@ -0,0 +1,492 @@
+package service
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LossAssessment 丢失、损坏赔偿计费结果
+type LossAssessment struct {
+	Type           model.LossType    `json:"type"`
+	DamageLevel    model.DamageLevel `json:"damage_level,omitempty"`
+	BookPrice      float64           `json:"book_price"`      // 计费价格，图书未填写价格时为默认价格
+	ReplacementFee float64           `json:"replacement_fee"` // 赔偿金额，丢失的图书找回时退还
+	ProcessingFee  float64           `json:"processing_fee"`  // 加工费，找回时不退
+	Total          float64           `json:"total"`
+}
+
+// LossQuery 赔偿记录查询条件
+type LossQuery struct {
+	ReaderID uint
+	Type     model.LossType
+	Status   model.LossStatus
+	Page     int
+	PageSize int
+}
+
+// LossService 图书丢失、损坏赔偿
+// 登记丢失或损坏还书时结束借阅，按图书价格和配置的比例产生赔偿罚款，并调整副本状态和库存；
+// 丢失的图书找回后退还赔偿金额（加工费不退），已支付的部分存入预存余额或现场退还
+type LossService struct {
+	fineService        *FineService
+	copyService        *BookCopyService
+	reservationService *ReservationService
+	blacklistService   *BlacklistService
+}
+
+// NewLossService 创建赔偿服务实例
+func NewLossService() *LossService {
+	return &LossService{
+		fineService:        &FineService{},
+		copyService:        NewBookCopyService(),
+		reservationService: NewReservationService(),
+		blacklistService:   &BlacklistService{},
+	}
+}
+
+// Assess 预览借阅记录的赔偿金额
+func (s *LossService) Assess(recordID uint, lossType model.LossType, level model.DamageLevel) (*LossAssessment, error) {
+	if err := validateLoss(lossType, level); err != nil {
+		return nil, err
+	}
+	var record model.BorrowRecord
+	if err := global.GVA_DB.Preload("Book").First(&record, recordID).Error; err != nil {
+		return nil, errors.New("借阅记录不存在")
+	}
+	a := s.assess(&record.Book, lossType, level)
+	return &a, nil
+}
+
+// DeclareLost 登记借出的图书丢失：结束借阅，产生逾期罚款和丢失赔偿，副本标记为丢失并从库存中扣除
+func (s *LossService) DeclareLost(recordID uint, remark string, actor model.AuditActor) (*model.LossReport, error) {
+	var report *model.LossReport
+	var record model.BorrowRecord
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if record, err = s.lockActiveLoan(tx, recordID); err != nil {
+			return err
+		}
+		before := map[string]interface{}{"status": record.Status, "copy_id": record.CopyID}
+
+		a := s.assess(&record.Book, model.LossTypeLost, "")
+		if report, err = s.charge(tx, &record, a, remark, actor); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&record).Updates(map[string]interface{}{
+			"status":       model.BorrowStatusLost,
+			"fine_amount":  record.FineAmount,
+			"overdue_days": record.OverdueDays,
+		}).Error; err != nil {
+			return errors.New("更新借阅记录失败")
+		}
+
+		if err := s.updateCopy(tx, &record, map[string]interface{}{"status": model.CopyStatusLost}); err != nil {
+			return err
+		}
+
+		return GlobalAuditService.Record(tx, actor, model.AuditLossLost, "borrow_record", record.ID, before, report)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	global.GVA_LOG.Info("登记图书丢失", zap.Uint("record_id", recordID), zap.Float64("fee", report.ReplacementFee+report.ProcessingFee))
+	s.blacklist(record.ReaderID, model.ConfigLostBlacklistDays, model.BlacklistReasonLost,
+		fmt.Sprintf("丢失图书《%s》", record.Book.Title), actor)
+	return report, nil
+}
+
+// ReturnDamaged 损坏还书：结束借阅，产生逾期罚款和损坏赔偿；
+// 轻微损坏的副本送修，严重损坏的副本剔旧并从库存中扣除
+func (s *LossService) ReturnDamaged(recordID uint, level model.DamageLevel, remark string, actor model.AuditActor) (*model.LossReport, error) {
+	if err := validateLoss(model.LossTypeDamage, level); err != nil {
+		return nil, err
+	}
+
+	var report *model.LossReport
+	var record model.BorrowRecord
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if record, err = s.lockActiveLoan(tx, recordID); err != nil {
+			return err
+		}
+		before := map[string]interface{}{"status": record.Status, "copy_id": record.CopyID}
+
+		a := s.assess(&record.Book, model.LossTypeDamage, level)
+		if report, err = s.charge(tx, &record, a, remark, actor); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&record).Updates(map[string]interface{}{
+			"status":       model.BorrowStatusReturned,
+			"return_date":  now,
+			"fine_amount":  record.FineAmount,
+			"overdue_days": record.OverdueDays,
+		}).Error; err != nil {
+			return errors.New("更新借阅记录失败")
+		}
+
+		copyUpdates := map[string]interface{}{
+			"status":         model.CopyStatusMaintenance,
+			"copy_condition": model.CopyConditionDamaged,
+		}
+		if level == model.DamageLevelSevere {
+			copyUpdates["status"] = model.CopyStatusRetired
+			copyUpdates["retired_date"] = now
+			copyUpdates["remark"] = "借阅中严重损坏"
+		}
+		if err := s.updateCopy(tx, &record, copyUpdates); err != nil {
+			return err
+		}
+
+		return GlobalAuditService.Record(tx, actor, model.AuditLossDamage, "borrow_record", record.ID, before, report)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	global.GVA_LOG.Info("损坏还书", zap.Uint("record_id", recordID), zap.String("level", string(level)),
+		zap.Float64("fee", report.ReplacementFee+report.ProcessingFee))
+	s.blacklist(record.ReaderID, model.ConfigDamageBlacklistDays, model.BlacklistReasonDamage,
+		fmt.Sprintf("损坏图书《%s》", record.Book.Title), actor)
+	return report, nil
+}
+
+// FoundLost 丢失的图书找回：借阅改为已归还，副本重新上架；
+// 赔偿罚款冲减赔偿金额，已支付超出部分存入读者预存余额（refundCash 为 true 时现场退还）
+func (s *LossService) FoundLost(reportID uint, refundCash bool, actor model.AuditActor) (*model.LossReport, error) {
+	var report model.LossReport
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&report, reportID).Error; err != nil {
+			return errors.New("赔偿记录不存在")
+		}
+		if report.Type != model.LossTypeLost {
+			return errors.New("只有丢失的图书可以登记找回")
+		}
+		if report.Status != model.LossStatusOpen {
+			return errors.New("该图书已登记找回")
+		}
+		before := map[string]interface{}{"status": report.Status}
+
+		var record model.BorrowRecord
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&record, report.BorrowRecordID).Error; err != nil {
+			return errors.New("借阅记录不存在")
+		}
+		if record.Status != model.BorrowStatusLost {
+			return errors.New("借阅记录不是丢失状态")
+		}
+
+		refund, err := s.reverseCharge(tx, &report, refundCash, actor.UserID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&record).Updates(map[string]interface{}{
+			"status":      model.BorrowStatusReturned,
+			"return_date": now,
+			"fine_amount": fromCents(toCents(record.FineAmount) - toCents(report.ReplacementFee)),
+		}).Error; err != nil {
+			return errors.New("更新借阅记录失败")
+		}
+
+		if _, err := s.copyService.LockBook(tx, record.BookID); err != nil {
+			return err
+		}
+		if record.CopyID != nil {
+			if err := tx.Model(&model.BookCopy{}).
+				Where("id = ? AND status = ?", *record.CopyID, model.CopyStatusLost).
+				Update("status", model.CopyStatusAvailable).Error; err != nil {
+				return err
+			}
+		}
+		if err := s.copyService.SyncBookStock(tx, record.BookID); err != nil {
+			return errors.New("更新库存失败")
+		}
+
+		report.Status = model.LossStatusFound
+		report.FoundAt = &now
+		report.RefundAmount = refund
+		if err := tx.Model(&report).Updates(map[string]interface{}{
+			"status":        report.Status,
+			"found_at":      now,
+			"refund_amount": refund,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := GlobalAuditService.Record(tx, actor, model.AuditLossFound, "loss_report", report.ID, before, map[string]interface{}{
+			"status":        report.Status,
+			"refund_amount": refund,
+			"refund_cash":   refundCash,
+		}); err != nil {
+			return err
+		}
+
+		// 副本重新上架，通知预约者
+		return s.reservationService.EnqueueHoldNotify(tx, report.BookID, fmt.Sprintf("hold:found:%d", report.ID))
+	})
+	if err != nil {
+		return nil, err
+	}
+	GlobalOutboxService.Wake()
+
+	global.GVA_LOG.Info("丢失图书找回", zap.Uint("report_id", reportID), zap.Float64("refund", report.RefundAmount))
+	return &report, nil
+}
+
+// ListReports 赔偿记录列表
+func (s *LossService) ListReports(q LossQuery) ([]model.LossReport, int64, error) {
+	db := global.GVA_DB.Model(&model.LossReport{})
+	if q.ReaderID > 0 {
+		db = db.Where("reader_id = ?", q.ReaderID)
+	}
+	if q.Type != "" {
+		db = db.Where("type = ?", q.Type)
+	}
+	if q.Status != "" {
+		db = db.Where("status = ?", q.Status)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var list []model.LossReport
+	err := db.Preload("Reader").Preload("Book").
+		Order("id DESC").Limit(q.PageSize).Offset((q.Page - 1) * q.PageSize).Find(&list).Error
+	return list, total, err
+}
+
+// lockActiveLoan 锁定借出中的借阅记录
+func (s *LossService) lockActiveLoan(tx *gorm.DB, recordID uint) (model.BorrowRecord, error) {
+	var record model.BorrowRecord
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&record, recordID).Error; err != nil {
+		return record, errors.New("借阅记录不存在")
+	}
+	if record.Status != model.BorrowStatusBorrowed && record.Status != model.BorrowStatusOverdue {
+		return record, errors.New("该借阅记录不是借出状态")
+	}
+	if err := tx.First(&record.Book, record.BookID).Error; err != nil {
+		return record, errors.New("图书不存在")
+	}
+	if err := tx.First(&record.Reader, record.ReaderID).Error; err != nil {
+		return record, errors.New("读者不存在")
+	}
+	return record, nil
+}
+
+// charge 产生逾期罚款和赔偿罚款，并保存赔偿记录
+func (s *LossService) charge(tx *gorm.DB, record *model.BorrowRecord, a LossAssessment, remark string, actor model.AuditActor) (*model.LossReport, error) {
+	overdueFine, overdueDays, err := s.fineService.CalculateOverdueFine(record)
+	if err != nil {
+		return nil, err
+	}
+	if overdueFine > 0 {
+		if _, err := s.fineService.CreateFineRecord(tx, record.ReaderID, record.ID, "overdue", overdueFine, overdueDays, actor.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 图书价格为0且未设置加工费时不产生赔偿罚款，分类账不接受0金额的分录
+	var fineID *uint
+	if toCents(a.Total) > 0 {
+		fine, err := s.fineService.CreateFineRecord(tx, record.ReaderID, record.ID, string(a.Type), a.Total, 0, actor.UserID)
+		if err != nil {
+			return nil, err
+		}
+		fineRemark := fmt.Sprintf("《%s》赔偿 %.2f 元，加工费 %.2f 元", record.Book.Title, a.ReplacementFee, a.ProcessingFee)
+		if err := tx.Model(fine).Update("remark", fineRemark).Error; err != nil {
+			return nil, err
+		}
+		fineID = &fine.ID
+	}
+
+	record.FineAmount = fromCents(toCents(overdueFine) + toCents(a.Total))
+	record.OverdueDays = overdueDays
+
+	report := &model.LossReport{
+		BorrowRecordID: record.ID,
+		ReaderID:       record.ReaderID,
+		BookID:         record.BookID,
+		CopyID:         record.CopyID,
+		Type:           a.Type,
+		DamageLevel:    a.DamageLevel,
+		BookPrice:      a.BookPrice,
+		ReplacementFee: a.ReplacementFee,
+		ProcessingFee:  a.ProcessingFee,
+		FineID:         fineID,
+		Status:         model.LossStatusOpen,
+		OperatorID:     actor.UserID,
+		Remark:         remark,
+	}
+	if err := tx.Create(report).Error; err != nil {
+		global.GVA_LOG.Error("创建赔偿记录失败", zap.Error(err))
+		return nil, errors.New("创建赔偿记录失败")
+	}
+	return report, nil
+}
+
+// reverseCharge 冲减赔偿罚款中的赔偿金额，返回退还给读者的已付金额
+func (s *LossService) reverseCharge(tx *gorm.DB, report *model.LossReport, refundCash bool, operatorID uint) (float64, error) {
+	if report.FineID == nil {
+		return 0, nil // 未产生赔偿罚款
+	}
+	if err := GlobalLedgerService.LockReader(tx, report.ReaderID); err != nil {
+		return 0, err
+	}
+	var fine model.FineRecord
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&fine, *report.FineID).Error; err != nil {
+		return 0, errors.New("赔偿罚款不存在")
+	}
+	if fine.Status == model.FineStatusWaived {
+		return 0, nil // 已豁免，无需退还
+	}
+
+	// 罚款金额可能被人工调整过，最多冲减到0
+	reduce := toCents(report.ReplacementFee)
+	if amount := toCents(fine.Amount); reduce > amount {
+		reduce = amount
+	}
+	if reduce <= 0 {
+		return 0, nil
+	}
+	if err := GlobalLedgerService.Reduce(tx, &fine, fromCents(reduce), "丢失的图书已找回，退还赔偿",
+		fmt.Sprintf("found:fine:%d", fine.ID), operatorID); err != nil {
+		return 0, err
+	}
+
+	amount := toCents(fine.Amount) - reduce
+	paid := toCents(fine.PaidAmount)
+	overpaid := int64(0)
+	if paid > amount {
+		overpaid = paid - amount
+		paid = amount
+	}
+	fine.Amount = fromCents(amount)
+	fine.PaidAmount = fromCents(paid)
+	if paid >= amount && fine.Status != model.FineStatusPaid {
+		now := time.Now()
+		fine.Status = model.FineStatusPaid
+		fine.PaidDate = &now
+	}
+	fine.Remark = fine.Remark + "；图书已找回，赔偿已冲减"
+	if err := tx.Save(&fine).Error; err != nil {
+		return 0, err
+	}
+
+	if overpaid == 0 {
+		return 0, nil
+	}
+	// 已付超出的部分先转入预存余额，现场退还时再从预存余额退出
+	if err := GlobalLedgerService.Refund(tx, LedgerRefund{
+		ReaderID:    fine.ReaderID,
+		Destination: model.AccountReceivable,
+		Amount:      fromCents(overpaid),
+		Reopen:      []FineAllocation{{FineID: fine.ID, Amount: fromCents(overpaid)}},
+		Key:         fmt.Sprintf("found:credit:fine:%d", fine.ID),
+		Memo:        "丢失的图书已找回，已付赔偿转入预存余额",
+		OperatorID:  operatorID,
+	}); err != nil {
+		return 0, err
+	}
+	if refundCash {
+		err := GlobalLedgerService.Refund(tx, LedgerRefund{
+			ReaderID:    fine.ReaderID,
+			Destination: model.AccountCash,
+			Amount:      fromCents(overpaid),
+			Key:         fmt.Sprintf("found:cash:fine:%d", fine.ID),
+			Memo:        "丢失的图书已找回，现场退还赔偿",
+			OperatorID:  operatorID,
+		})
+		return fromCents(overpaid), err
+	}
+	return fromCents(overpaid), s.fineService.applyCredit(tx, fine.ReaderID, operatorID)
+}
+
+// updateCopy 更新借出副本的状态并重新计算库存
+func (s *LossService) updateCopy(tx *gorm.DB, record *model.BorrowRecord, updates map[string]interface{}) error {
+	if _, err := s.copyService.LockBook(tx, record.BookID); err != nil {
+		return err
+	}
+	if record.CopyID != nil {
+		if err := tx.Model(&model.BookCopy{}).
+			Where("id = ? AND status = ?", *record.CopyID, model.CopyStatusBorrowed).
+			Updates(updates).Error; err != nil {
+			return err
+		}
+	} else {
+		global.GVA_LOG.Warn("借阅记录未关联副本，仅重新计算库存", zap.Uint("book_id", record.BookID))
+	}
+	if err := s.copyService.SyncBookStock(tx, record.BookID); err != nil {
+		return errors.New("更新库存失败")
+	}
+	return nil
+}
+
+// assess 按图书价格和配置的比例计算赔偿金额
+func (s *LossService) assess(book *model.Book, lossType model.LossType, level model.DamageLevel) LossAssessment {
+	price := book.Price
+	if price <= 0 {
+		price = GlobalConfigService.GetFloatConfig(model.ConfigLostDefaultPrice, 50)
+	}
+
+	a := LossAssessment{Type: lossType, BookPrice: price}
+	var rate float64
+	switch {
+	case lossType == model.LossTypeLost:
+		rate = GlobalConfigService.GetFloatConfig(model.ConfigLostReplacementRate, 1)
+		a.ProcessingFee = GlobalConfigService.GetFloatConfig(model.ConfigLostProcessingFee, 10)
+	case level == model.DamageLevelSevere:
+		a.DamageLevel = level
+		rate = GlobalConfigService.GetFloatConfig(model.ConfigDamageSevereRate, 1)
+		a.ProcessingFee = GlobalConfigService.GetFloatConfig(model.ConfigLostProcessingFee, 10)
+	default:
+		a.DamageLevel = level
+		rate = GlobalConfigService.GetFloatConfig(model.ConfigDamageMinorRate, 0.2)
+	}
+
+	a.ReplacementFee = math.Round(math.Max(price*rate, 0)*100) / 100
+	a.ProcessingFee = math.Round(math.Max(a.ProcessingFee, 0)*100) / 100
+	a.Total = fromCents(toCents(a.ReplacementFee) + toCents(a.ProcessingFee))
+	return a
+}
+
+// blacklist 按配置的天数拉黑读者，天数为0时不拉黑
+func (s *LossService) blacklist(readerID uint, daysKey string, reason model.BlacklistReason, description string, actor model.AuditActor) {
+	days := GlobalConfigService.GetIntConfig(daysKey, 0)
+	if days <= 0 {
+		return
+	}
+	endDate := time.Now().AddDate(0, 0, days)
+	if err := s.blacklistService.AddToBlacklist(readerID, reason, description, &endDate, actor); err != nil {
+		global.GVA_LOG.Warn("赔偿后拉黑读者失败", zap.Uint("reader_id", readerID), zap.Error(err))
+	}
+}
+
+// validateLoss 校验赔偿类型和损坏程度
+func validateLoss(lossType model.LossType, level model.DamageLevel) error {
+	switch lossType {
+	case model.LossTypeLost:
+		return nil
+	case model.LossTypeDamage:
+		if !model.IsValidDamageLevel(level) {
+			return errors.New("损坏程度取值无效")
+		}
+		return nil
+	}
+	return errors.New("赔偿类型取值无效")
+}
+
+// 全局赔偿服务实例
+var GlobalLossService = NewLossService()
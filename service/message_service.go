@@ -3,11 +3,13 @@ package service
 import (
 	"bookadmin/global"
 	"bookadmin/model"
+	"encoding/json"
 	"errors"
 	"strconv"
 	"time"
 
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type MessageService struct{}
@@ -30,9 +32,51 @@ func (s *MessageService) CreateMessage(userID uint, msgType model.MessageType, t
 	}
 
 	global.GVA_LOG.Info("创建消息成功", zap.Uint("user_id", userID), zap.String("title", title))
+	s.afterCreate(&message)
 	return nil
 }
 
+// EnqueueMessage 在业务事务内写入待发送的消息，事务提交后由待执行事件循环创建并推送
+// key 为幂等键，同一业务事件重复写入只发送一次
+func (s *MessageService) EnqueueMessage(tx *gorm.DB, key string, p model.MessagePayload) error {
+	return GlobalOutboxService.Enqueue(tx, model.OutboxMessage, key, p)
+}
+
+// afterCreate 消息写入后推送给在线用户，并按用户偏好生成邮件、短信投递
+func (s *MessageService) afterCreate(message *model.Message) {
+	GlobalNotifyService.Publish(message.UserID, model.NotifyMessage, message)
+	GlobalNotifyService.PublishUnreadCount(message.UserID)
+	GlobalNotifyDispatchService.Dispatch(message)
+}
+
+// handleMessageEvent 执行待发送的消息：与事件标记在同一事务内写入消息，提交后再推送
+func (s *MessageService) handleMessageEvent(tx *gorm.DB, e *model.OutboxEvent) (func(), error) {
+	var p model.MessagePayload
+	if err := json.Unmarshal([]byte(e.Payload), &p); err != nil {
+		return nil, err
+	}
+
+	message := model.Message{
+		UserID:      p.UserID,
+		Type:        p.Type,
+		Title:       p.Title,
+		Content:     p.Content,
+		IsRead:      false,
+		RelatedID:   p.RelatedID,
+		RelatedType: p.RelatedType,
+	}
+	if err := tx.Create(&message).Error; err != nil {
+		return nil, err
+	}
+
+	return func() {
+		s.afterCreate(&message)
+		if p.Event != "" {
+			GlobalNotifyService.Publish(p.UserID, p.Event, p.EventData)
+		}
+	}, nil
+}
+
 // GetUserMessages 获取用户消息列表
 func (s *MessageService) GetUserMessages(userID uint, page, pageSize int) ([]model.Message, int64, error) {
 	var messages []model.Message
@@ -86,18 +130,24 @@ func (s *MessageService) MarkAsRead(messageID, userID uint) error {
 		return errors.New("消息不存在或无权操作")
 	}
 
+	GlobalNotifyService.PublishUnreadCount(userID)
 	return nil
 }
 
 // MarkAllAsRead 标记所有消息为已读
 func (s *MessageService) MarkAllAsRead(userID uint) error {
 	now := time.Now()
-	return global.GVA_DB.Model(&model.Message{}).
+	if err := global.GVA_DB.Model(&model.Message{}).
 		Where("user_id = ? AND is_read = ?", userID, false).
 		Updates(map[string]interface{}{
 			"is_read": true,
 			"read_at": now,
-		}).Error
+		}).Error; err != nil {
+		return err
+	}
+
+	GlobalNotifyService.PublishUnreadCount(userID)
+	return nil
 }
 
 // DeleteMessage 删除消息
@@ -113,15 +163,59 @@ func (s *MessageService) DeleteMessage(messageID, userID uint) error {
 		return errors.New("消息不存在或无权操作")
 	}
 
+	GlobalNotifyService.PublishUnreadCount(userID)
 	return nil
 }
 
-// SendReservationAvailableMessage 发送预约可取书消息
-func (s *MessageService) SendReservationAvailableMessage(userID uint, bookTitle string, reservationID uint, pickupDays int) error {
-	title := "📚 预约图书已可借阅"
-	content := "您预约的《" + bookTitle + "》现在可以借阅了！请在 " +
-		strconv.Itoa(pickupDays) + " 天内前往图书管理员处登记借书。逾期预约将自动取消。"
-
+// SendReservationAvailableMessage 在事务内写入预约可取书消息
+func (s *MessageService) SendReservationAvailableMessage(tx *gorm.DB, userID uint, bookTitle string, reservationID uint, pickupDays int) error {
 	relatedID := reservationID
-	return s.CreateMessage(userID, model.MessageTypeReservation, title, content, &relatedID, "reservation")
+	return s.EnqueueMessage(tx, "message:hold:"+strconv.FormatUint(uint64(reservationID), 10), model.MessagePayload{
+		UserID: userID,
+		Type:   model.MessageTypeReservation,
+		Title:  "📚 预约图书已可借阅",
+		Content: "您预约的《" + bookTitle + "》现在可以借阅了！请在 " +
+			strconv.Itoa(pickupDays) + " 天内前往图书管理员处登记借书。逾期预约将自动取消。",
+		RelatedID:   &relatedID,
+		RelatedType: "reservation",
+		Event:       model.NotifyReservationAvailable,
+		EventData: map[string]interface{}{
+			"reservation_id": reservationID,
+			"book_title":     bookTitle,
+			"pickup_days":    pickupDays,
+		},
+	})
+}
+
+// SendLoanDecisionMessage 在事务内写入借阅申请审批结果消息
+func (s *MessageService) SendLoanDecisionMessage(tx *gorm.DB, record *model.BorrowRecord, approved bool, rejectReason string) error {
+	title := "✅ 借阅申请已批准"
+	content := "您申请借阅的《" + record.Book.Title + "》已批准，请在 " + record.DueDate.Format("2006-01-02") + " 前归还。"
+	event := model.NotifyLoanApproved
+	if !approved {
+		title = "❌ 借阅申请未通过"
+		content = "您申请借阅的《" + record.Book.Title + "》未通过审批。"
+		if rejectReason != "" {
+			content += "原因：" + rejectReason
+		}
+		event = model.NotifyLoanRejected
+	}
+
+	relatedID := record.ID
+	return s.EnqueueMessage(tx, "message:loan_decision:"+strconv.FormatUint(uint64(record.ID), 10), model.MessagePayload{
+		UserID:      record.Reader.UserID,
+		Type:        model.MessageTypeBorrow,
+		Title:       title,
+		Content:     content,
+		RelatedID:   &relatedID,
+		RelatedType: "borrow",
+		Event:       event,
+		EventData: map[string]interface{}{
+			"record_id":     record.ID,
+			"book_id":       record.BookID,
+			"book_title":    record.Book.Title,
+			"due_date":      record.DueDate,
+			"reject_reason": rejectReason,
+		},
+	})
 }
@@ -0,0 +1,517 @@
+package service
+
+import (
+	"bookadmin/config"
+	"bookadmin/global"
+	"bookadmin/model"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 投递参数
+const (
+	deliveryMaxAttempts  = 5                // 最多尝试次数
+	deliveryBatchSize    = 50               // 每轮最多处理的投递数
+	deliveryPollInterval = 15 * time.Second // 检查待发送投递的间隔
+	deliveryStuckTimeout = 10 * time.Minute // 发送中超过此时间视为实例崩溃遗留
+)
+
+// deliveryBackoff 第N次失败后的重试间隔
+var deliveryBackoff = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour}
+
+// DeliveryQuery 投递记录查询条件
+type DeliveryQuery struct {
+	Status    model.DeliveryStatus
+	Channel   model.NotifyChannel
+	UserID    uint
+	MessageID uint
+	Page      int
+	PageSize  int
+}
+
+// messageTemplateData 模板可用的变量
+type messageTemplateData struct {
+	Title     string
+	Content   string
+	Username  string
+	RealName  string
+	Type      model.MessageType
+	CreatedAt string
+}
+
+// NotifyDispatchService 邮件、短信通知分发
+// 站内消息创建后按用户偏好和模板生成投递记录，后台循环发送，失败按退避间隔重试；
+// 免打扰时段内的投递推迟到时段结束后发送
+type NotifyDispatchService struct {
+	mu      sync.RWMutex
+	senders map[model.NotifyChannel]NotificationSender
+	kick    chan struct{}
+	once    sync.Once
+}
+
+// Configure 按配置创建邮件和短信渠道，未配置的渠道生成的投递标记为跳过
+func (s *NotifyDispatchService) Configure(cfg config.Notify) error {
+	senders := make(map[model.NotifyChannel]NotificationSender)
+	if cfg.SMTP.Host != "" {
+		sender, err := newSmtpSender(cfg.SMTP)
+		if err != nil {
+			return err
+		}
+		senders[model.ChannelEmail] = sender
+	}
+	if provider := newSmsProvider(cfg.SMS); provider != nil {
+		senders[model.ChannelSMS] = &smsSender{provider: provider}
+	}
+
+	s.mu.Lock()
+	s.senders = senders
+	s.mu.Unlock()
+	return nil
+}
+
+// sender 渠道对应的发送器，未配置时返回nil
+func (s *NotifyDispatchService) sender(channel model.NotifyChannel) NotificationSender {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.senders[channel]
+}
+
+// Start 启动后台发送循环
+func (s *NotifyDispatchService) Start() {
+	s.once.Do(func() {
+		s.kick = make(chan struct{}, 1)
+		go func() {
+			ticker := time.NewTicker(deliveryPollInterval)
+			defer ticker.Stop()
+			for {
+				if err := s.ProcessDue(); err != nil {
+					global.GVA_LOG.Error("发送通知失败", zap.Error(err))
+				}
+				select {
+				case <-ticker.C:
+				case <-s.kick:
+				}
+			}
+		}()
+	})
+}
+
+// wake 有新的待发送投递时立即唤醒发送循环
+func (s *NotifyDispatchService) wake() {
+	if s.kick == nil {
+		return
+	}
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+}
+
+// Dispatch 为站内消息生成邮件、短信投递记录，失败只记录日志，不影响站内消息
+func (s *NotifyDispatchService) Dispatch(message *model.Message) {
+	var user model.User
+	if err := global.GVA_DB.First(&user, message.UserID).Error; err != nil {
+		global.GVA_LOG.Warn("通知分发时用户不存在", zap.Uint("user_id", message.UserID))
+		return
+	}
+
+	enabled, err := s.enabledChannels(message.UserID, message.Type)
+	if err != nil {
+		global.GVA_LOG.Error("读取通知偏好失败", zap.Error(err))
+		return
+	}
+
+	var templates []model.MessageTemplate
+	if err := global.GVA_DB.Where("message_type = ? AND enabled = ?", message.Type, true).Find(&templates).Error; err != nil {
+		global.GVA_LOG.Error("读取消息模板失败", zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	sendAt := s.quietUntil(message.UserID, now)
+	data := messageTemplateData{
+		Title:     message.Title,
+		Content:   message.Content,
+		Username:  user.Username,
+		RealName:  user.RealName,
+		Type:      message.Type,
+		CreatedAt: message.CreatedAt.Format("2006-01-02 15:04"),
+	}
+
+	var deliveries []model.NotificationDelivery
+	for _, tpl := range templates {
+		if !enabled[tpl.Channel] {
+			continue
+		}
+		d := model.NotificationDelivery{
+			MessageID:     message.ID,
+			UserID:        message.UserID,
+			MessageType:   message.Type,
+			Channel:       tpl.Channel,
+			Status:        model.DeliveryStatusPending,
+			NextAttemptAt: sendAt,
+		}
+		if tpl.Channel == model.ChannelEmail {
+			d.Recipient = user.Email
+		} else {
+			d.Recipient = user.Phone
+		}
+
+		d.Subject, d.Body, err = renderMessageTemplate(tpl, data)
+		switch {
+		case err != nil:
+			d.Status, d.LastError = model.DeliveryStatusSkipped, err.Error()
+		case s.sender(tpl.Channel) == nil:
+			d.Status, d.LastError = model.DeliveryStatusSkipped, "渠道未配置"
+		case d.Recipient == "":
+			d.Status, d.LastError = model.DeliveryStatusSkipped, "用户未填写邮箱或手机号"
+		}
+		deliveries = append(deliveries, d)
+	}
+	if len(deliveries) == 0 {
+		return
+	}
+
+	if err := global.GVA_DB.Create(&deliveries).Error; err != nil {
+		global.GVA_LOG.Error("创建通知投递记录失败", zap.Uint("message_id", message.ID), zap.Error(err))
+		return
+	}
+	s.wake()
+}
+
+// ProcessDue 发送已到时间的投递（多实例部署时通过状态条件更新抢占，同一投递只会被一个实例发送）
+func (s *NotifyDispatchService) ProcessDue() error {
+	now := time.Now()
+	if err := global.GVA_DB.Model(&model.NotificationDelivery{}).
+		Where("status = ? AND updated_at < ?", model.DeliveryStatusSending, now.Add(-deliveryStuckTimeout)).
+		Update("status", model.DeliveryStatusPending).Error; err != nil {
+		return err
+	}
+
+	var due []model.NotificationDelivery
+	if err := global.GVA_DB.Where("status = ? AND next_attempt_at <= ?", model.DeliveryStatusPending, now).
+		Order("next_attempt_at ASC").Limit(deliveryBatchSize).Find(&due).Error; err != nil {
+		return err
+	}
+
+	for i := range due {
+		d := &due[i]
+		claimed := global.GVA_DB.Model(&model.NotificationDelivery{}).
+			Where("id = ? AND status = ?", d.ID, model.DeliveryStatusPending).
+			Updates(map[string]interface{}{
+				"status":   model.DeliveryStatusSending,
+				"attempts": gorm.Expr("attempts + 1"),
+			})
+		if claimed.Error != nil {
+			return claimed.Error
+		}
+		if claimed.RowsAffected == 0 {
+			continue
+		}
+		d.Attempts++
+		s.send(d)
+	}
+	return nil
+}
+
+// send 发送一条投递并记录结果
+func (s *NotifyDispatchService) send(d *model.NotificationDelivery) {
+	sender := s.sender(d.Channel)
+	if sender == nil {
+		global.GVA_DB.Model(d).Updates(map[string]interface{}{
+			"status":     model.DeliveryStatusSkipped,
+			"last_error": "渠道未配置",
+		})
+		return
+	}
+
+	providerID, err := sender.Send(d.Recipient, d.Subject, d.Body)
+	if err == nil {
+		now := time.Now()
+		global.GVA_DB.Model(d).Updates(map[string]interface{}{
+			"status":      model.DeliveryStatusSent,
+			"provider_id": providerID,
+			"sent_at":     now,
+			"last_error":  "",
+		})
+		return
+	}
+
+	lastError := err.Error()
+	if len([]rune(lastError)) > 500 {
+		lastError = string([]rune(lastError)[:500])
+	}
+	updates := map[string]interface{}{
+		"status":     model.DeliveryStatusFailed,
+		"last_error": lastError,
+	}
+	if d.Attempts < deliveryMaxAttempts {
+		backoff := deliveryBackoff[len(deliveryBackoff)-1]
+		if d.Attempts-1 < len(deliveryBackoff) {
+			backoff = deliveryBackoff[d.Attempts-1]
+		}
+		updates["status"] = model.DeliveryStatusPending
+		updates["next_attempt_at"] = s.quietUntil(d.UserID, time.Now().Add(backoff))
+	}
+	global.GVA_DB.Model(d).Updates(updates)
+
+	global.GVA_LOG.Warn("通知发送失败",
+		zap.Uint("delivery_id", d.ID),
+		zap.String("channel", string(d.Channel)),
+		zap.Int("attempts", d.Attempts),
+		zap.Error(err))
+}
+
+// RetryDelivery 重新发送失败的投递
+func (s *NotifyDispatchService) RetryDelivery(id uint) error {
+	result := global.GVA_DB.Model(&model.NotificationDelivery{}).
+		Where("id = ? AND status = ?", id, model.DeliveryStatusFailed).
+		Updates(map[string]interface{}{
+			"status":          model.DeliveryStatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("投递记录不存在或不是失败状态")
+	}
+	s.wake()
+	return nil
+}
+
+// ListDeliveries 查询投递记录，按创建时间倒序
+func (s *NotifyDispatchService) ListDeliveries(q DeliveryQuery) ([]model.NotificationDelivery, int64, error) {
+	db := global.GVA_DB.Model(&model.NotificationDelivery{})
+	if q.Status != "" {
+		db = db.Where("status = ?", q.Status)
+	}
+	if q.Channel != "" {
+		db = db.Where("channel = ?", q.Channel)
+	}
+	if q.UserID > 0 {
+		db = db.Where("user_id = ?", q.UserID)
+	}
+	if q.MessageID > 0 {
+		db = db.Where("message_id = ?", q.MessageID)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var list []model.NotificationDelivery
+	err := db.Order("id DESC").Limit(q.PageSize).Offset((q.Page - 1) * q.PageSize).Find(&list).Error
+	return list, total, err
+}
+
+// ListTemplates 全部消息模板
+func (s *NotifyDispatchService) ListTemplates() ([]model.MessageTemplate, error) {
+	var templates []model.MessageTemplate
+	err := global.GVA_DB.Order("message_type ASC, channel ASC").Find(&templates).Error
+	return templates, err
+}
+
+// UpdateTemplate 修改消息模板，保存前用示例数据渲染一次以检查语法
+func (s *NotifyDispatchService) UpdateTemplate(id uint, subject, body string, enabled bool, actor model.AuditActor) (*model.MessageTemplate, error) {
+	if strings.TrimSpace(body) == "" {
+		return nil, errors.New("正文模板不能为空")
+	}
+
+	var tpl model.MessageTemplate
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&tpl, id).Error; err != nil {
+			return errors.New("模板不存在")
+		}
+		before := tpl
+
+		tpl.Subject, tpl.Body, tpl.Enabled, tpl.UpdatedBy = subject, body, enabled, actor.UserID
+		if _, _, err := renderMessageTemplate(tpl, messageTemplateData{
+			Title: "示例标题", Content: "示例内容", Username: "reader", RealName: "张三",
+			Type: tpl.MessageType, CreatedAt: time.Now().Format("2006-01-02 15:04"),
+		}); err != nil {
+			return err
+		}
+
+		if err := tx.Save(&tpl).Error; err != nil {
+			return err
+		}
+		return GlobalAuditService.Record(tx, actor, model.AuditTemplateUpdate, "message_template", tpl.ID, before, tpl)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &tpl, nil
+}
+
+// GetPreferences 用户的通知偏好：每种消息类型的邮件、短信开关（未设置的按默认值）和免打扰时段
+func (s *NotifyDispatchService) GetPreferences(userID uint) (*model.NotifyPreferences, error) {
+	var user model.User
+	if err := global.GVA_DB.First(&user, userID).Error; err != nil {
+		return nil, errors.New("用户不存在")
+	}
+
+	prefs := &model.NotifyPreferences{Email: user.Email, Phone: user.Phone}
+	for _, t := range model.MessageTypes {
+		enabled, err := s.enabledChannels(userID, t)
+		if err != nil {
+			return nil, err
+		}
+		for _, ch := range model.ExternalChannels {
+			prefs.Items = append(prefs.Items, model.NotifyPreferenceItem{MessageType: t, Channel: ch, Enabled: enabled[ch]})
+		}
+	}
+
+	var setting model.NotifySetting
+	if err := global.GVA_DB.Where("user_id = ?", userID).Limit(1).Find(&setting).Error; err != nil {
+		return nil, err
+	}
+	prefs.QuietStart, prefs.QuietEnd = setting.QuietStart, setting.QuietEnd
+	return prefs, nil
+}
+
+// UpdatePreferences 修改通知偏好，与默认值相同的开关不保存；免打扰时段两个时间都为空表示关闭
+func (s *NotifyDispatchService) UpdatePreferences(userID uint, items []model.NotifyPreferenceItem, quietStart, quietEnd string) error {
+	if (quietStart == "") != (quietEnd == "") {
+		return errors.New("免打扰开始和结束时间需要同时设置")
+	}
+	if quietStart != "" {
+		if _, err := parseClock(quietStart); err != nil {
+			return err
+		}
+		if _, err := parseClock(quietEnd); err != nil {
+			return err
+		}
+	}
+	for _, item := range items {
+		if !isValidMessageType(item.MessageType) {
+			return fmt.Errorf("消息类型无效: %s", item.MessageType)
+		}
+		if item.Channel != model.ChannelEmail && item.Channel != model.ChannelSMS {
+			return fmt.Errorf("渠道无效: %s（站内消息不能关闭）", item.Channel)
+		}
+	}
+
+	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		for _, item := range items {
+			where := tx.Where("user_id = ? AND message_type = ? AND channel = ?", userID, item.MessageType, item.Channel)
+			if item.Enabled == model.DefaultChannelEnabled(item.MessageType, item.Channel) {
+				if err := where.Delete(&model.NotifyPreference{}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			pref := model.NotifyPreference{UserID: userID, MessageType: item.MessageType, Channel: item.Channel, Enabled: item.Enabled}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "message_type"}, {Name: "channel"}},
+				DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+			}).Create(&pref).Error; err != nil {
+				return err
+			}
+		}
+
+		setting := model.NotifySetting{UserID: userID, QuietStart: quietStart, QuietEnd: quietEnd}
+		return tx.Save(&setting).Error
+	})
+}
+
+// enabledChannels 用户对某类消息各外部渠道的开关
+func (s *NotifyDispatchService) enabledChannels(userID uint, t model.MessageType) (map[model.NotifyChannel]bool, error) {
+	enabled := make(map[model.NotifyChannel]bool, len(model.ExternalChannels))
+	for _, ch := range model.ExternalChannels {
+		enabled[ch] = model.DefaultChannelEnabled(t, ch)
+	}
+
+	var prefs []model.NotifyPreference
+	if err := global.GVA_DB.Where("user_id = ? AND message_type = ?", userID, t).Find(&prefs).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range prefs {
+		enabled[p.Channel] = p.Enabled
+	}
+	return enabled, nil
+}
+
+// quietUntil t 落在用户免打扰时段内时返回时段结束时间，否则返回 t
+func (s *NotifyDispatchService) quietUntil(userID uint, t time.Time) time.Time {
+	var setting model.NotifySetting
+	if err := global.GVA_DB.Where("user_id = ?", userID).Limit(1).Find(&setting).Error; err != nil || setting.QuietStart == "" {
+		return t
+	}
+	start, err1 := parseClock(setting.QuietStart)
+	end, err2 := parseClock(setting.QuietEnd)
+	if err1 != nil || err2 != nil || start == end {
+		return t
+	}
+
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	minute := t.Hour()*60 + t.Minute()
+	switch {
+	case start < end && minute >= start && minute < end:
+		return day.Add(time.Duration(end) * time.Minute)
+	case start > end && minute >= start: // 跨零点，当天晚上
+		return day.AddDate(0, 0, 1).Add(time.Duration(end) * time.Minute)
+	case start > end && minute < end: // 跨零点，次日凌晨
+		return day.Add(time.Duration(end) * time.Minute)
+	}
+	return t
+}
+
+// parseClock 解析 HH:MM，返回当天的分钟数
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("时间格式应为 HH:MM: %s", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// isValidMessageType 判断消息类型是否已定义
+func isValidMessageType(t model.MessageType) bool {
+	for _, mt := range model.MessageTypes {
+		if mt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// renderMessageTemplate 渲染邮件标题和正文
+func renderMessageTemplate(tpl model.MessageTemplate, data messageTemplateData) (string, string, error) {
+	render := func(name, text string) (string, error) {
+		t, err := template.New(name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return "", fmt.Errorf("模板语法错误: %w", err)
+		}
+		var sb strings.Builder
+		if err := t.Execute(&sb, data); err != nil {
+			return "", fmt.Errorf("模板渲染失败: %w", err)
+		}
+		return sb.String(), nil
+	}
+
+	var subject string
+	if tpl.Channel == model.ChannelEmail {
+		var err error
+		if subject, err = render("subject", tpl.Subject); err != nil {
+			return "", "", err
+		}
+	}
+	body, err := render("body", tpl.Body)
+	if err != nil {
+		return "", "", err
+	}
+	return subject, body, nil
+}
+
+// 全局通知分发服务实例
+var GlobalNotifyDispatchService = &NotifyDispatchService{}
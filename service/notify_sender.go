@@ -0,0 +1,166 @@
+package service
+
+import (
+	"bookadmin/config"
+	"bookadmin/global"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// notifySendTimeout 单次发送的超时时间
+const notifySendTimeout = 15 * time.Second
+
+// NotificationSender 外部通知渠道，返回服务商的消息ID
+type NotificationSender interface {
+	Send(recipient, subject, body string) (string, error)
+}
+
+// SmsProvider 短信服务商，接入新的服务商时实现此接口并在 newSmsProvider 中注册
+type SmsProvider interface {
+	SendSms(phone, content string) (string, error)
+}
+
+// smsSender 将短信服务商适配为通知渠道（短信没有标题）
+type smsSender struct {
+	provider SmsProvider
+}
+
+func (s *smsSender) Send(recipient, subject, body string) (string, error) {
+	return s.provider.SendSms(recipient, body)
+}
+
+// newSmsProvider 按配置创建短信服务商，未配置时返回nil
+func newSmsProvider(cfg config.SMS) SmsProvider {
+	switch cfg.Provider {
+	case "fake":
+		return &fakeSmsProvider{}
+	default:
+		return nil
+	}
+}
+
+// fakeSmsProvider 本地开发用的短信服务商：不真正发送，只记录日志
+// 手机号以 000 结尾时模拟发送失败，便于验证重试
+type fakeSmsProvider struct {
+	seq uint64
+}
+
+func (p *fakeSmsProvider) SendSms(phone, content string) (string, error) {
+	if strings.HasSuffix(phone, "000") {
+		return "", errors.New("模拟短信发送失败")
+	}
+	id := "fake-" + strconv.FormatUint(atomic.AddUint64(&p.seq, 1), 10)
+	global.GVA_LOG.Info("模拟发送短信", zap.String("phone", phone), zap.String("content", content), zap.String("id", id))
+	return id, nil
+}
+
+// smtpSender 通过SMTP发送纯文本邮件
+type smtpSender struct {
+	cfg  config.SMTP
+	from *mail.Address
+}
+
+func newSmtpSender(cfg config.SMTP) (*smtpSender, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("发件人格式错误: %w", err)
+	}
+	return &smtpSender{cfg: cfg, from: from}, nil
+}
+
+func (s *smtpSender) Send(recipient, subject, body string) (string, error) {
+	to, err := mail.ParseAddress(recipient)
+	if err != nil {
+		return "", fmt.Errorf("邮箱格式错误: %w", err)
+	}
+
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	dialer := &net.Dialer{Timeout: notifySendTimeout}
+	var conn net.Conn
+	if s.cfg.SSL {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: s.cfg.Host})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return "", err
+	}
+	_ = conn.SetDeadline(time.Now().Add(notifySendTimeout))
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return "", err
+	}
+	defer client.Close()
+
+	if !s.cfg.SSL {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: s.cfg.Host}); err != nil {
+				return "", err
+			}
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return "", err
+		}
+	}
+	if err := client.Mail(s.from.Address); err != nil {
+		return "", err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return "", err
+	}
+
+	messageID := fmt.Sprintf("<%d.%d@%s>", time.Now().UnixNano(), atomic.AddUint64(&smtpMessageSeq, 1), s.cfg.Host)
+	w, err := client.Data()
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(s.buildMessage(to, subject, body, messageID)); err != nil {
+		w.Close()
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	_ = client.Quit()
+	return messageID, nil
+}
+
+// smtpMessageSeq 生成邮件 Message-ID 的序号
+var smtpMessageSeq uint64
+
+// buildMessage 组装邮件：标题按RFC 2047编码，正文使用base64
+func (s *smtpSender) buildMessage(to *mail.Address, subject, body, messageID string) []byte {
+	var sb strings.Builder
+	sb.WriteString("From: " + s.from.String() + "\r\n")
+	sb.WriteString("To: " + to.String() + "\r\n")
+	sb.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", subject) + "\r\n")
+	sb.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	sb.WriteString("Message-ID: " + messageID + "\r\n")
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	sb.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		sb.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	sb.WriteString(encoded + "\r\n")
+	return []byte(sb.String())
+}
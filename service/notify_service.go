@@ -0,0 +1,150 @@
+package service
+
+import (
+	"bookadmin/constants"
+	"bookadmin/global"
+	"bookadmin/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// notifyBufferSize 每个连接待发送事件的缓冲数，客户端读取过慢时丢弃新事件
+const notifyBufferSize = 32
+
+// NotifyClient 一个在线连接
+type NotifyClient struct {
+	UserID uint
+	Events chan model.Notification
+}
+
+// NotifyService 实时通知服务
+// 业务代码调用 Publish 发布事件：Redis可用时发布到 notify:events 频道，由各实例转发给本实例上的连接；
+// Redis不可用时只推送给连接在本实例的用户
+type NotifyService struct {
+	mu      sync.RWMutex
+	clients map[uint]map[*NotifyClient]struct{}
+	seq     uint64
+	started bool
+	startMu sync.Mutex
+}
+
+// Start 订阅Redis通知频道（可重复调用，Redis恢复后由预热回调调用）
+func (s *NotifyService) Start() {
+	s.startMu.Lock()
+	defer s.startMu.Unlock()
+	if s.started || global.GVA_REDIS == nil {
+		return
+	}
+	s.started = true
+
+	// go-redis 断线后会自动重连并重新订阅
+	pubsub := global.GVA_REDIS.Subscribe(context.Background(), constants.ChannelNotify)
+	go func() {
+		for msg := range pubsub.Channel() {
+			var n model.Notification
+			if err := json.Unmarshal([]byte(msg.Payload), &n); err != nil {
+				global.GVA_LOG.Warn("解析通知失败", zap.Error(err))
+				continue
+			}
+			s.deliver(n)
+		}
+	}()
+	global.GVA_LOG.Info("已订阅实时通知频道", zap.String("channel", constants.ChannelNotify))
+}
+
+// Subscribe 注册一个在线连接，返回的函数用于连接断开时注销
+func (s *NotifyService) Subscribe(userID uint) (*NotifyClient, func()) {
+	client := &NotifyClient{UserID: userID, Events: make(chan model.Notification, notifyBufferSize)}
+
+	s.mu.Lock()
+	if s.clients == nil {
+		s.clients = make(map[uint]map[*NotifyClient]struct{})
+	}
+	if s.clients[userID] == nil {
+		s.clients[userID] = make(map[*NotifyClient]struct{})
+	}
+	s.clients[userID][client] = struct{}{}
+	s.mu.Unlock()
+
+	return client, func() {
+		s.mu.Lock()
+		delete(s.clients[userID], client)
+		if len(s.clients[userID]) == 0 {
+			delete(s.clients, userID)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// OnlineCount 本实例的在线用户数和连接数
+func (s *NotifyService) OnlineCount() (users, connections int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, clients := range s.clients {
+		connections += len(clients)
+	}
+	return len(s.clients), connections
+}
+
+// Publish 向用户推送事件，推送失败只记录日志，不影响业务
+func (s *NotifyService) Publish(userID uint, event model.NotificationEvent, data interface{}) {
+	if userID == 0 {
+		return
+	}
+	n := model.Notification{
+		ID:        fmt.Sprintf("%x-%x", time.Now().UnixNano(), atomic.AddUint64(&s.seq, 1)),
+		UserID:    userID,
+		Event:     event,
+		Data:      data,
+		CreatedAt: time.Now(),
+	}
+
+	if GlobalRedisHealth.Available() {
+		payload, err := json.Marshal(n)
+		if err != nil {
+			global.GVA_LOG.Error("序列化通知失败", zap.String("event", string(event)), zap.Error(err))
+			return
+		}
+		err = global.GVA_REDIS.Publish(context.Background(), constants.ChannelNotify, payload).Err()
+		if err == nil {
+			return
+		}
+		GlobalRedisHealth.MarkUnavailable(err)
+		global.GVA_LOG.Warn("发布通知失败，只推送给本实例的连接", zap.String("event", string(event)), zap.Error(err))
+	}
+	s.deliver(n)
+}
+
+// PublishUnreadCount 推送最新的未读消息数
+func (s *NotifyService) PublishUnreadCount(userID uint) {
+	count, err := (&MessageService{}).GetUnreadCount(userID)
+	if err != nil {
+		global.GVA_LOG.Error("获取未读消息数量失败", zap.Uint("user_id", userID), zap.Error(err))
+		return
+	}
+	s.Publish(userID, model.NotifyUnreadCount, map[string]interface{}{"count": count})
+}
+
+// deliver 推送给连接在本实例的用户
+func (s *NotifyService) deliver(n model.Notification) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for client := range s.clients[n.UserID] {
+		select {
+		case client.Events <- n:
+		default:
+			global.GVA_LOG.Warn("通知连接缓冲已满，丢弃事件",
+				zap.Uint("user_id", n.UserID),
+				zap.String("event", string(n.Event)))
+		}
+	}
+}
+
+// 全局实时通知服务实例
+var GlobalNotifyService = &NotifyService{}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 待执行事件参数
+const (
+	outboxMaxAttempts  = 8                  // 最多尝试次数
+	outboxBatchSize    = 100                // 每轮最多处理的事件数
+	outboxPollInterval = 5 * time.Second    // 检查待执行事件的间隔
+	outboxStuckTimeout = 5 * time.Minute    // 执行中超过此时间视为实例崩溃遗留
+	outboxRetention    = 7 * 24 * time.Hour // 已执行事件的保留时间
+)
+
+// outboxBackoff 第N次失败后的重试间隔
+var outboxBackoff = []time.Duration{10 * time.Second, 30 * time.Second, time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour}
+
+// errOutboxReclaimed 执行超时后事件已被其他实例重新领取，本次结果作废
+var errOutboxReclaimed = errors.New("事件已被重新领取")
+
+// OutboxHandler 执行一种事件的后续操作
+// 数据库操作使用 tx，与标记事件已执行在同一事务内提交；返回的 after 在提交后调用，用于实时推送等尽力而为的操作
+type OutboxHandler func(tx *gorm.DB, e *model.OutboxEvent) (after func(), err error)
+
+// OutboxQuery 待执行事件查询条件
+type OutboxQuery struct {
+	Status   model.OutboxStatus
+	Topic    model.OutboxTopic
+	Page     int
+	PageSize int
+}
+
+// OutboxService 事务性待执行事件（transactional outbox）
+// 借还书、预约等业务在自己的事务内写入后续操作（通知预约者、站内消息、榜单），事务回滚时事件一并回滚；
+// 提交后由后台循环至少执行一次，失败按退避间隔重试，同一事件由幂等键保证只生效一次。
+// 逾期罚款、赔偿罚款只涉及MySQL，直接在还书、登记丢失损坏的事务内创建并记账（见 ReturnBook、LossService.charge），
+// 与借阅记录一同提交或回滚，不经过待执行事件
+type OutboxService struct {
+	handlers map[model.OutboxTopic]OutboxHandler
+	kick     chan struct{}
+	once     sync.Once
+}
+
+// Enqueue 在业务事务内写入待执行事件，幂等键已存在时忽略
+// 调用方提交事务后调用 Wake 立即执行，否则等待下一轮轮询
+func (s *OutboxService) Enqueue(tx *gorm.DB, topic model.OutboxTopic, key string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.OutboxEvent{
+		Topic:          topic,
+		IdempotencyKey: key,
+		Payload:        string(data),
+		Status:         model.OutboxStatusPending,
+		NextAttemptAt:  time.Now(),
+	}).Error
+}
+
+// Start 注册事件处理函数并启动后台执行循环
+func (s *OutboxService) Start() {
+	s.once.Do(func() {
+		reservationService := NewReservationService()
+		messageService := &MessageService{}
+		s.handlers = map[model.OutboxTopic]OutboxHandler{
+			model.OutboxHoldNotify: reservationService.handleHoldEvent,
+			model.OutboxMessage:    messageService.handleMessageEvent,
+			model.OutboxRanking:    handleRankingEvent,
+		}
+		go func() {
+			ticker := time.NewTicker(outboxPollInterval)
+			defer ticker.Stop()
+			for {
+				if err := s.ProcessDue(); err != nil {
+					global.GVA_LOG.Error("执行待执行事件失败", zap.Error(err))
+				}
+				select {
+				case <-ticker.C:
+				case <-s.kick:
+				}
+			}
+		}()
+	})
+}
+
+// Wake 有新的待执行事件时立即唤醒执行循环
+// 执行循环启动前的唤醒保留在通道中，启动后立即执行一轮
+func (s *OutboxService) Wake() {
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+}
+
+// ProcessDue 执行已到时间的事件（多实例部署时通过状态条件更新抢占，同一事件同一时刻只被一个实例执行）
+func (s *OutboxService) ProcessDue() error {
+	now := time.Now()
+	if err := global.GVA_DB.Model(&model.OutboxEvent{}).
+		Where("status = ? AND updated_at < ?", model.OutboxStatusProcessing, now.Add(-outboxStuckTimeout)).
+		Update("status", model.OutboxStatusPending).Error; err != nil {
+		return err
+	}
+
+	var due []model.OutboxEvent
+	if err := global.GVA_DB.Where("status = ? AND next_attempt_at <= ?", model.OutboxStatusPending, now).
+		Order("id ASC").Limit(outboxBatchSize).Find(&due).Error; err != nil {
+		return err
+	}
+
+	for i := range due {
+		e := &due[i]
+		claimed := global.GVA_DB.Model(&model.OutboxEvent{}).
+			Where("id = ? AND status = ?", e.ID, model.OutboxStatusPending).
+			Updates(map[string]interface{}{
+				"status":   model.OutboxStatusProcessing,
+				"attempts": gorm.Expr("attempts + 1"),
+			})
+		if claimed.Error != nil {
+			return claimed.Error
+		}
+		if claimed.RowsAffected == 0 {
+			continue
+		}
+		e.Attempts++
+		s.execute(e)
+	}
+	return nil
+}
+
+// execute 执行一个事件并记录结果
+func (s *OutboxService) execute(e *model.OutboxEvent) {
+	handler, ok := s.handlers[e.Topic]
+	if !ok {
+		s.fail(e, fmt.Errorf("未知的事件类型: %s", e.Topic), true)
+		return
+	}
+
+	var after func()
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if after, err = handler(tx, e); err != nil {
+			return err
+		}
+		now := time.Now()
+		result := tx.Model(&model.OutboxEvent{}).
+			Where("id = ? AND status = ?", e.ID, model.OutboxStatusProcessing).
+			Updates(map[string]interface{}{
+				"status":       model.OutboxStatusDone,
+				"processed_at": now,
+				"last_error":   "",
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errOutboxReclaimed
+		}
+		return nil
+	})
+	if errors.Is(err, errOutboxReclaimed) {
+		return
+	}
+	if err != nil {
+		s.fail(e, err, false)
+		return
+	}
+	if after != nil {
+		after()
+	}
+}
+
+// fail 记录失败原因，未超过重试次数时按退避间隔重试
+func (s *OutboxService) fail(e *model.OutboxEvent, err error, permanent bool) {
+	lastError := err.Error()
+	if len([]rune(lastError)) > 500 {
+		lastError = string([]rune(lastError)[:500])
+	}
+	updates := map[string]interface{}{
+		"status":     model.OutboxStatusFailed,
+		"last_error": lastError,
+	}
+	if !permanent && e.Attempts < outboxMaxAttempts {
+		backoff := outboxBackoff[len(outboxBackoff)-1]
+		if e.Attempts-1 < len(outboxBackoff) {
+			backoff = outboxBackoff[e.Attempts-1]
+		}
+		updates["status"] = model.OutboxStatusPending
+		updates["next_attempt_at"] = time.Now().Add(backoff)
+	}
+	global.GVA_DB.Model(&model.OutboxEvent{}).
+		Where("id = ? AND status = ?", e.ID, model.OutboxStatusProcessing).
+		Updates(updates)
+
+	global.GVA_LOG.Warn("待执行事件执行失败",
+		zap.Uint("event_id", e.ID),
+		zap.String("topic", string(e.Topic)),
+		zap.Int("attempts", e.Attempts),
+		zap.Error(err))
+}
+
+// RetryEvent 重新执行失败的事件
+func (s *OutboxService) RetryEvent(id uint, actor model.AuditActor) error {
+	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.OutboxEvent{}).
+			Where("id = ? AND status = ?", id, model.OutboxStatusFailed).
+			Updates(map[string]interface{}{
+				"status":          model.OutboxStatusPending,
+				"attempts":        0,
+				"next_attempt_at": time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("事件不存在或不是失败状态")
+		}
+		return GlobalAuditService.Record(tx, actor, model.AuditOutboxRetry, "outbox_event", id, nil, nil)
+	})
+}
+
+// ListEvents 待执行事件列表
+func (s *OutboxService) ListEvents(q OutboxQuery) ([]model.OutboxEvent, int64, error) {
+	db := global.GVA_DB.Model(&model.OutboxEvent{})
+	if q.Status != "" {
+		db = db.Where("status = ?", q.Status)
+	}
+	if q.Topic != "" {
+		db = db.Where("topic = ?", q.Topic)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var list []model.OutboxEvent
+	err := db.Order("id DESC").Limit(q.PageSize).Offset((q.Page - 1) * q.PageSize).Find(&list).Error
+	return list, total, err
+}
+
+// Stats 各状态的事件数
+func (s *OutboxService) Stats() (map[model.OutboxStatus]int64, error) {
+	var rows []struct {
+		Status model.OutboxStatus
+		Count  int64
+	}
+	if err := global.GVA_DB.Model(&model.OutboxEvent{}).
+		Select("status, COUNT(*) AS count").Group("status").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	stats := map[model.OutboxStatus]int64{
+		model.OutboxStatusPending:    0,
+		model.OutboxStatusProcessing: 0,
+		model.OutboxStatusDone:       0,
+		model.OutboxStatusFailed:     0,
+	}
+	for _, r := range rows {
+		stats[r.Status] = r.Count
+	}
+	return stats, nil
+}
+
+// CleanupDone 删除超过保留时间的已执行事件
+func (s *OutboxService) CleanupDone() (int64, error) {
+	result := global.GVA_DB.Where("status = ? AND processed_at < ?", model.OutboxStatusDone, time.Now().Add(-outboxRetention)).
+		Delete(&model.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// handleRankingEvent 借阅、预约计入榜单
+// Redis 不可用时返回错误按退避间隔重试；同一事件通过Redis中的幂等标记只计入一次
+func handleRankingEvent(_ *gorm.DB, e *model.OutboxEvent) (func(), error) {
+	var p model.RankingPayload
+	if err := json.Unmarshal([]byte(e.Payload), &p); err != nil {
+		return nil, err
+	}
+	if !GlobalRedisHealth.Available() {
+		return nil, errors.New("Redis不可用")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := updateRankingBoardsOnce(ctx, p.Type, p.BookID, 1, e.CreatedAt, e.IdempotencyKey); err != nil {
+		GlobalRedisHealth.MarkUnavailable(err)
+		return nil, fmt.Errorf("更新榜单失败: %w", err)
+	}
+	return nil, nil
+}
+
+// 全局待执行事件服务实例
+var GlobalOutboxService = &OutboxService{kick: make(chan struct{}, 1)}
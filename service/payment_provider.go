@@ -0,0 +1,234 @@
+package service
+
+import (
+	"bookadmin/config"
+	"bookadmin/model"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TradeStatus 支付渠道侧的交易状态
+type TradeStatus string
+
+const (
+	TradeStatusPending TradeStatus = "pending" // 未支付
+	TradeStatusPaid    TradeStatus = "paid"    // 已支付
+	TradeStatusClosed  TradeStatus = "closed"  // 已关闭
+)
+
+// ProviderOrder 支付渠道创建的交易
+type ProviderOrder struct {
+	TradeNo string // 支付渠道交易号
+	PayURL  string // 读者打开此地址完成支付
+}
+
+// PaymentNotice 支付结果（回调通知或主动查询）
+type PaymentNotice struct {
+	EventID string      `json:"event_id"` // 通知ID，同一通知重复推送时不变
+	OrderNo string      `json:"order_no"`
+	TradeNo string      `json:"trade_no"`
+	Status  TradeStatus `json:"status"`
+	Amount  float64     `json:"amount"`
+	PaidAt  time.Time   `json:"paid_at"`
+}
+
+// PaymentProvider 支付渠道，接入新的渠道时实现此接口并在 newPaymentProvider 中注册
+type PaymentProvider interface {
+	Name() string
+	// CreateOrder 在支付渠道创建交易
+	CreateOrder(order *model.PaymentOrder, notifyURL string) (*ProviderOrder, error)
+	// VerifyCallback 校验回调签名并解析支付结果，签名错误时返回错误
+	VerifyCallback(header http.Header, body []byte) (*PaymentNotice, error)
+	// Query 主动查询交易状态，用于回调丢失时补单
+	Query(order *model.PaymentOrder) (*PaymentNotice, error)
+	// Refund 退款，同一退款单号重复调用只退一次，返回渠道退款单号
+	Refund(order *model.PaymentOrder, refundNo string, amount float64) (string, error)
+}
+
+// newPaymentProvider 按配置创建支付渠道，未配置时返回nil
+func newPaymentProvider(cfg config.Payment) PaymentProvider {
+	switch cfg.Provider {
+	case "mock":
+		return &mockPaymentProvider{secret: []byte(cfg.Mock.Secret), trades: make(map[string]*mockTrade)}
+	default:
+		return nil
+	}
+}
+
+// 模拟渠道回调的签名请求头
+const (
+	mockHeaderTimestamp = "X-Mock-Timestamp"
+	mockHeaderSignature = "X-Mock-Signature"
+	mockCallbackMaxSkew = 5 * time.Minute // 回调时间戳与本机时间的最大偏差，防止重放旧通知
+)
+
+// mockTrade 模拟渠道保存的交易
+type mockTrade struct {
+	orderNo  string
+	tradeNo  string
+	amount   float64
+	status   TradeStatus
+	paidAt   time.Time
+	refunded map[string]float64 // 退款单号 -> 金额
+}
+
+// mockPaymentProvider 本地测试用的支付渠道：交易保存在内存中，
+// 调用 Pay 模拟读者完成支付，并像真实渠道一样向回调地址推送带签名的通知
+type mockPaymentProvider struct {
+	secret []byte
+	mu     sync.Mutex
+	trades map[string]*mockTrade
+	seq    uint64
+}
+
+func (p *mockPaymentProvider) Name() string {
+	return "mock"
+}
+
+func (p *mockPaymentProvider) CreateOrder(order *model.PaymentOrder, notifyURL string) (*ProviderOrder, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	trade := &mockTrade{
+		orderNo:  order.OrderNo,
+		tradeNo:  fmt.Sprintf("MOCK%d%04d", time.Now().Unix(), atomic.AddUint64(&p.seq, 1)%10000),
+		amount:   order.Amount,
+		status:   TradeStatusPending,
+		refunded: make(map[string]float64),
+	}
+	p.trades[order.OrderNo] = trade
+	return &ProviderOrder{
+		TradeNo: trade.tradeNo,
+		PayURL:  "/api/payment/mock/pay?order_no=" + url.QueryEscape(order.OrderNo),
+	}, nil
+}
+
+func (p *mockPaymentProvider) VerifyCallback(header http.Header, body []byte) (*PaymentNotice, error) {
+	ts, err := strconv.ParseInt(header.Get(mockHeaderTimestamp), 10, 64)
+	if err != nil {
+		return nil, errors.New("缺少签名时间戳")
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > mockCallbackMaxSkew || skew < -mockCallbackMaxSkew {
+		return nil, errors.New("签名已过期")
+	}
+	expected := p.sign(ts, body)
+	if !hmac.Equal([]byte(expected), []byte(header.Get(mockHeaderSignature))) {
+		return nil, errors.New("签名错误")
+	}
+
+	var notice PaymentNotice
+	if err := json.Unmarshal(body, &notice); err != nil {
+		return nil, fmt.Errorf("通知格式错误: %w", err)
+	}
+	return &notice, nil
+}
+
+func (p *mockPaymentProvider) Query(order *model.PaymentOrder) (*PaymentNotice, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	trade, ok := p.trades[order.OrderNo]
+	if !ok {
+		// 服务重启后内存中的交易丢失，视为未支付
+		return &PaymentNotice{OrderNo: order.OrderNo, Status: TradeStatusPending}, nil
+	}
+	return trade.notice(), nil
+}
+
+func (p *mockPaymentProvider) Refund(order *model.PaymentOrder, refundNo string, amount float64) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	trade, ok := p.trades[order.OrderNo]
+	if !ok || trade.status != TradeStatusPaid {
+		return "", errors.New("交易不存在或未支付")
+	}
+	if _, done := trade.refunded[refundNo]; !done {
+		var refunded float64
+		for _, v := range trade.refunded {
+			refunded += v
+		}
+		if toCents(refunded+amount) > toCents(trade.amount) {
+			return "", errors.New("退款金额超过支付金额")
+		}
+		trade.refunded[refundNo] = amount
+	}
+	return "MOCKREFUND-" + refundNo, nil
+}
+
+// Pay 模拟读者完成支付：标记交易已支付，向回调地址推送签名通知；
+// 未配置回调地址时返回通知由调用方直接处理
+func (p *mockPaymentProvider) Pay(orderNo, notifyURL string) (http.Header, []byte, error) {
+	p.mu.Lock()
+	trade, ok := p.trades[orderNo]
+	if !ok {
+		p.mu.Unlock()
+		return nil, nil, errors.New("交易不存在")
+	}
+	if trade.status == TradeStatusPending {
+		trade.status = TradeStatusPaid
+		trade.paidAt = time.Now()
+	}
+	notice := trade.notice()
+	p.mu.Unlock()
+
+	body, err := json.Marshal(notice)
+	if err != nil {
+		return nil, nil, err
+	}
+	ts := time.Now().Unix()
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(mockHeaderTimestamp, strconv.FormatInt(ts, 10))
+	header.Set(mockHeaderSignature, p.sign(ts, body))
+	if notifyURL == "" {
+		return header, body, nil
+	}
+
+	req, err := http.NewRequest(http.MethodPost, notifyURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header = header
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("推送支付通知失败: %w", err)
+	}
+	defer resp.Body.Close()
+	ack, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if resp.StatusCode != http.StatusOK || string(ack) != PaymentCallbackAck {
+		return nil, nil, fmt.Errorf("回调处理失败: %d %s", resp.StatusCode, ack)
+	}
+	return nil, nil, nil
+}
+
+// sign HMAC-SHA256(时间戳 + "\n" + 通知原文)
+func (p *mockPaymentProvider) sign(ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(strconv.FormatInt(ts, 10) + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (t *mockTrade) notice() *PaymentNotice {
+	return &PaymentNotice{
+		EventID: t.tradeNo + "-" + string(t.status),
+		OrderNo: t.orderNo,
+		TradeNo: t.tradeNo,
+		Status:  t.status,
+		Amount:  t.amount,
+		PaidAt:  t.paidAt,
+	}
+}
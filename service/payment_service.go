@@ -0,0 +1,591 @@
+package service
+
+import (
+	"bookadmin/config"
+	"bookadmin/global"
+	"bookadmin/model"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PaymentCallbackAck 回调处理成功时返回给支付渠道的内容，渠道收到后停止重复推送
+const PaymentCallbackAck = "success"
+
+// PaymentQuery 支付订单查询条件
+type PaymentQuery struct {
+	ReaderID uint
+	Status   model.PaymentStatus
+	OrderNo  string
+	Page     int
+	PageSize int
+}
+
+// PaymentService 罚款在线支付
+// 读者为未结清的罚款下单，在支付渠道完成支付后由回调（或超时前的主动查询）结清罚款；
+// 同一订单的重复通知只处理一次，下单后罚款已被其他方式结清的部分自动退回
+type PaymentService struct {
+	mu       sync.RWMutex
+	cfg      config.Payment
+	provider PaymentProvider
+}
+
+// Configure 按配置创建支付渠道
+func (s *PaymentService) Configure(cfg config.Payment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+	s.provider = newPaymentProvider(cfg)
+}
+
+// current 当前支付渠道和配置，未开放在线支付时渠道为nil
+func (s *PaymentService) current() (PaymentProvider, config.Payment) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.provider, s.cfg
+}
+
+// providerFor 订单所属的支付渠道（切换渠道后旧渠道的订单无法再查询和退款）
+func (s *PaymentService) providerFor(order *model.PaymentOrder) (PaymentProvider, error) {
+	provider, _ := s.current()
+	if provider == nil || provider.Name() != order.Provider {
+		return nil, fmt.Errorf("支付渠道 %s 未启用", order.Provider)
+	}
+	return provider, nil
+}
+
+// CreateOrder 读者为罚款下单，fineIDs 为空时支付全部未结清的罚款
+// 同一罚款之前未支付的订单会被关闭
+func (s *PaymentService) CreateOrder(userID uint, fineIDs []uint) (*model.PaymentOrder, error) {
+	provider, cfg := s.current()
+	if provider == nil {
+		return nil, errors.New("未开放在线支付")
+	}
+
+	var reader model.Reader
+	if err := global.GVA_DB.Where("user_id = ?", userID).First(&reader).Error; err != nil {
+		return nil, errors.New("读者信息不存在")
+	}
+
+	db := global.GVA_DB.Where("reader_id = ? AND status = ?", reader.ID, model.FineStatusUnpaid)
+	if len(fineIDs) > 0 {
+		db = db.Where("id IN ?", fineIDs)
+	}
+	var fines []model.FineRecord
+	if err := db.Order("id ASC").Find(&fines).Error; err != nil {
+		return nil, err
+	}
+	if len(fineIDs) > 0 && len(fines) != len(uniqueUints(fineIDs)) {
+		return nil, errors.New("罚款记录不存在或已结清")
+	}
+
+	order := model.PaymentOrder{
+		OrderNo:  newPaymentOrderNo(),
+		ReaderID: reader.ID,
+		UserID:   userID,
+		Provider: provider.Name(),
+		Status:   model.PaymentStatusPending,
+		ExpireAt: time.Now().Add(cfg.OrderExpire),
+	}
+	var amount int64
+	var ids []uint
+	for _, fine := range fines {
+		remaining := toCents(fine.Amount) - toCents(fine.PaidAmount)
+		if remaining <= 0 {
+			continue
+		}
+		amount += remaining
+		ids = append(ids, fine.ID)
+		order.Items = append(order.Items, model.PaymentOrderItem{FineID: fine.ID, Amount: fromCents(remaining)})
+	}
+	if amount == 0 {
+		return nil, errors.New("没有需要支付的罚款")
+	}
+	order.Amount = fromCents(amount)
+	order.Subject = fmt.Sprintf("图书馆罚款（%d笔）", len(order.Items))
+
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.PaymentOrder{}).
+			Where("status = ? AND id IN (?)", model.PaymentStatusPending,
+				tx.Model(&model.PaymentOrderItem{}).Select("order_id").Where("fine_id IN ?", ids)).
+			Update("status", model.PaymentStatusClosed).Error; err != nil {
+			return err
+		}
+		return tx.Create(&order).Error
+	})
+	if err != nil {
+		global.GVA_LOG.Error("创建支付订单失败", zap.Error(err))
+		return nil, errors.New("创建支付订单失败")
+	}
+
+	trade, err := provider.CreateOrder(&order, cfg.NotifyURL)
+	if err != nil {
+		global.GVA_LOG.Error("支付渠道下单失败", zap.String("order_no", order.OrderNo), zap.Error(err))
+		global.GVA_DB.Model(&order).Update("status", model.PaymentStatusClosed)
+		return nil, errors.New("支付渠道下单失败，请稍后重试")
+	}
+	order.TradeNo, order.PayURL = trade.TradeNo, trade.PayURL
+	if err := global.GVA_DB.Model(&order).Updates(map[string]interface{}{
+		"trade_no": order.TradeNo,
+		"pay_url":  order.PayURL,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	global.GVA_LOG.Info("创建支付订单", zap.String("order_no", order.OrderNo), zap.Float64("amount", order.Amount))
+	return &order, nil
+}
+
+// GetReaderOrder 读者查看自己的订单，待支付的订单先向支付渠道查询最新状态
+func (s *PaymentService) GetReaderOrder(userID uint, orderNo string) (*model.PaymentOrder, error) {
+	var order model.PaymentOrder
+	if err := global.GVA_DB.Where("order_no = ? AND user_id = ?", orderNo, userID).First(&order).Error; err != nil {
+		return nil, errors.New("订单不存在")
+	}
+	if order.Status == model.PaymentStatusPending {
+		if err := s.sync(&order); err != nil {
+			global.GVA_LOG.Warn("查询支付状态失败", zap.String("order_no", orderNo), zap.Error(err))
+		}
+	}
+
+	if err := global.GVA_DB.Preload("Items").Preload("Items.Fine").First(&order, order.ID).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// ListOrders 查询支付订单，按创建时间倒序
+func (s *PaymentService) ListOrders(q PaymentQuery) ([]model.PaymentOrder, int64, error) {
+	db := global.GVA_DB.Model(&model.PaymentOrder{})
+	if q.ReaderID > 0 {
+		db = db.Where("reader_id = ?", q.ReaderID)
+	}
+	if q.Status != "" {
+		db = db.Where("status = ?", q.Status)
+	}
+	if q.OrderNo != "" {
+		db = db.Where("order_no = ?", q.OrderNo)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var orders []model.PaymentOrder
+	err := db.Preload("Items").Order("id DESC").Limit(q.PageSize).Offset((q.Page - 1) * q.PageSize).Find(&orders).Error
+	return orders, total, err
+}
+
+// HandleCallback 处理支付渠道的回调通知，返回nil时应答渠道处理成功
+func (s *PaymentService) HandleCallback(providerName string, header http.Header, body []byte) error {
+	provider, _ := s.current()
+	if provider == nil || provider.Name() != providerName {
+		return fmt.Errorf("支付渠道 %s 未启用", providerName)
+	}
+
+	notice, err := provider.VerifyCallback(header, body)
+	if err != nil {
+		global.GVA_LOG.Warn("支付回调校验失败", zap.String("provider", providerName), zap.Error(err))
+		return err
+	}
+
+	switch notice.Status {
+	case TradeStatusPaid:
+		return s.settle(notice, &model.PaymentCallback{
+			Provider: providerName,
+			EventID:  notice.EventID,
+			OrderNo:  notice.OrderNo,
+			Payload:  string(body),
+		})
+	case TradeStatusClosed:
+		return s.close(notice.OrderNo)
+	}
+	return nil
+}
+
+// MockPay 模拟读者在支付页面完成支付（仅 mock 渠道）
+func (s *PaymentService) MockPay(orderNo string) error {
+	provider, cfg := s.current()
+	mock, ok := provider.(*mockPaymentProvider)
+	if !ok {
+		return errors.New("当前支付渠道不支持模拟支付")
+	}
+
+	var order model.PaymentOrder
+	if err := global.GVA_DB.Where("order_no = ?", orderNo).First(&order).Error; err != nil {
+		return errors.New("订单不存在")
+	}
+	if order.Status != model.PaymentStatusPending {
+		return errors.New("订单不是待支付状态")
+	}
+
+	header, body, err := mock.Pay(orderNo, cfg.NotifyURL)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		return s.HandleCallback(mock.Name(), header, body)
+	}
+	return nil
+}
+
+// RefundOrder 全额退款，订单结清的罚款恢复为未支付
+func (s *PaymentService) RefundOrder(orderNo, reason string, actor model.AuditActor) error {
+	var order model.PaymentOrder
+	if err := global.GVA_DB.Where("order_no = ?", orderNo).First(&order).Error; err != nil {
+		return errors.New("订单不存在")
+	}
+	if order.Status != model.PaymentStatusPaid {
+		return errors.New("只能对已支付的订单退款")
+	}
+	amount := fromCents(toCents(order.Amount) - toCents(order.RefundedAmount))
+	if amount <= 0 {
+		return errors.New("订单已全部退款")
+	}
+
+	provider, err := s.providerFor(&order)
+	if err != nil {
+		return err
+	}
+	refundNo := "R" + order.OrderNo
+	providerID, err := provider.Refund(&order, refundNo, amount)
+	if err != nil {
+		global.GVA_LOG.Error("支付渠道退款失败", zap.String("order_no", orderNo), zap.Error(err))
+		return fmt.Errorf("退款失败: %w", err)
+	}
+
+	var reopened float64
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, order.ID).Error; err != nil {
+			return err
+		}
+		if order.Status != model.PaymentStatusPaid {
+			return nil // 并发的退款已完成
+		}
+		if err := GlobalLedgerService.LockReader(tx, order.ReaderID); err != nil {
+			return err
+		}
+		before := map[string]interface{}{"status": order.Status, "refunded_amount": order.RefundedAmount}
+
+		var items []model.PaymentOrderItem
+		if err := tx.Where("order_id = ? AND applied_amount > 0", order.ID).Find(&items).Error; err != nil {
+			return err
+		}
+		var cents int64
+		var reopen []FineAllocation
+		for _, item := range items {
+			var fine model.FineRecord
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&fine, item.FineID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					continue
+				}
+				return err
+			}
+			if fine.Status == model.FineStatusWaived {
+				continue
+			}
+			fine.PaidAmount = fromCents(toCents(fine.PaidAmount) - toCents(item.AppliedAmount))
+			fine.Status = model.FineStatusUnpaid
+			fine.PaidDate = nil
+			if err := tx.Save(&fine).Error; err != nil {
+				return err
+			}
+			cents += toCents(item.AppliedAmount)
+			reopen = append(reopen, FineAllocation{FineID: item.FineID, Amount: item.AppliedAmount})
+		}
+		reopened = fromCents(cents)
+
+		if err := s.recordRefund(tx, &order, refundNo, amount, reopen, reason, providerID, actor.UserID); err != nil {
+			return err
+		}
+		order.Status = model.PaymentStatusRefunded
+		if err := tx.Model(&order).Update("status", order.Status).Error; err != nil {
+			return err
+		}
+
+		return GlobalAuditService.Record(tx, actor, model.AuditPaymentRefund, "payment_order", order.ID, before, map[string]interface{}{
+			"status":          order.Status,
+			"refunded_amount": order.RefundedAmount,
+			"reopened_fine":   reopened,
+			"reason":          reason,
+		})
+	})
+	if err != nil {
+		// 渠道已退款，本地状态下次重试时补齐（退款单号不变，渠道不会重复退款）
+		global.GVA_LOG.Error("保存退款结果失败", zap.String("order_no", orderNo), zap.Error(err))
+		return errors.New("保存退款结果失败，请重试")
+	}
+
+	global.GVA_LOG.Info("支付订单退款", zap.String("order_no", orderNo), zap.Float64("amount", amount))
+	return nil
+}
+
+// CloseExpiredOrders 处理超过支付有效期的订单：向支付渠道确认，已支付的补单，未支付的关闭
+// 定时任务调用
+func (s *PaymentService) CloseExpiredOrders() error {
+	var orders []model.PaymentOrder
+	if err := global.GVA_DB.Where("status = ? AND expire_at < ?", model.PaymentStatusPending, time.Now()).
+		Limit(200).Find(&orders).Error; err != nil {
+		return err
+	}
+
+	for i := range orders {
+		if err := s.sync(&orders[i]); err != nil {
+			global.GVA_LOG.Warn("查询支付状态失败", zap.String("order_no", orders[i].OrderNo), zap.Error(err))
+			continue
+		}
+		if err := s.close(orders[i].OrderNo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sync 向支付渠道查询订单状态，已支付的按回调同样的方式结清
+func (s *PaymentService) sync(order *model.PaymentOrder) error {
+	provider, err := s.providerFor(order)
+	if err != nil {
+		return err
+	}
+	notice, err := provider.Query(order)
+	if err != nil {
+		return err
+	}
+	switch notice.Status {
+	case TradeStatusPaid:
+		return s.settle(notice, nil)
+	case TradeStatusClosed:
+		return s.close(order.OrderNo)
+	}
+	return nil
+}
+
+// close 关闭待支付的订单
+func (s *PaymentService) close(orderNo string) error {
+	return global.GVA_DB.Model(&model.PaymentOrder{}).
+		Where("order_no = ? AND status = ?", orderNo, model.PaymentStatusPending).
+		Update("status", model.PaymentStatusClosed).Error
+}
+
+// settle 支付成功后结清订单中的罚款，并更新读者的未支付罚款
+// callback 不为nil时同一事务内记录回调，重复的通知直接返回；已支付的订单不会重复结清
+// 订单已关闭（超时或被新订单取代）后才到账的同样结清，罚款已被其他方式结清的部分退回
+func (s *PaymentService) settle(notice *PaymentNotice, callback *model.PaymentCallback) error {
+	var order model.PaymentOrder
+	var excess float64
+	settled := false
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if callback != nil {
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(callback)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return nil // 重复通知
+			}
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_no = ?", notice.OrderNo).First(&order).Error; err != nil {
+			return fmt.Errorf("订单不存在: %s", notice.OrderNo)
+		}
+		if order.Status == model.PaymentStatusPaid || order.Status == model.PaymentStatusRefunded {
+			return nil
+		}
+		if toCents(notice.Amount) != toCents(order.Amount) {
+			return fmt.Errorf("支付金额 %.2f 与订单金额 %.2f 不一致", notice.Amount, order.Amount)
+		}
+		// 先锁读者再锁罚款
+		if err := GlobalLedgerService.LockReader(tx, order.ReaderID); err != nil {
+			return err
+		}
+
+		paidAt := notice.PaidAt
+		if paidAt.IsZero() {
+			paidAt = time.Now()
+		}
+
+		var items []model.PaymentOrderItem
+		if err := tx.Where("order_id = ?", order.ID).Find(&items).Error; err != nil {
+			return err
+		}
+		var applied int64
+		var allocations []FineAllocation
+		for i := range items {
+			var fine model.FineRecord
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&fine, items[i].FineID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					continue
+				}
+				return err
+			}
+			if fine.Status != model.FineStatusUnpaid {
+				continue
+			}
+			apply := toCents(fine.Amount) - toCents(fine.PaidAmount)
+			if itemCents := toCents(items[i].Amount); apply > itemCents {
+				apply = itemCents
+			}
+			if apply <= 0 {
+				continue
+			}
+
+			fine.PaidAmount = fromCents(toCents(fine.PaidAmount) + apply)
+			if toCents(fine.PaidAmount) >= toCents(fine.Amount) {
+				fine.Status = model.FineStatusPaid
+				fine.PaidDate = &paidAt
+			}
+			if err := tx.Save(&fine).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&items[i]).Update("applied_amount", fromCents(apply)).Error; err != nil {
+				return err
+			}
+			applied += apply
+			allocations = append(allocations, FineAllocation{FineID: fine.ID, Amount: fromCents(apply)})
+		}
+
+		// 按实收金额记账，未能分配到罚款的部分先计入预存余额，退还多付金额时再扣除
+		if err := GlobalLedgerService.Payment(tx, LedgerPayment{
+			ReaderID:       order.ReaderID,
+			Source:         model.AccountGateway,
+			Amount:         order.Amount,
+			Allocations:    allocations,
+			PaymentOrderID: &order.ID,
+			Key:            "payment:order:" + order.OrderNo,
+			Memo:           "在线支付罚款",
+		}); err != nil {
+			return err
+		}
+
+		order.Status = model.PaymentStatusPaid
+		order.PaidAt = &paidAt
+		order.TradeNo = notice.TradeNo
+		order.AppliedAmount = fromCents(applied)
+		excess = fromCents(toCents(order.Amount) - applied)
+		settled = true
+		if err := tx.Save(&order).Error; err != nil {
+			return err
+		}
+
+		// 支付成功消息随结清一起提交
+		relatedID := order.ID
+		return (&MessageService{}).EnqueueMessage(tx, "message:payment:"+order.OrderNo, model.MessagePayload{
+			UserID:      order.UserID,
+			Type:        model.MessageTypeFine,
+			Title:       "罚款支付成功",
+			Content:     fmt.Sprintf("您已在线支付罚款 %.2f 元（订单号 %s）。", order.AppliedAmount, order.OrderNo),
+			RelatedID:   &relatedID,
+			RelatedType: "payment",
+		})
+	})
+	if err != nil {
+		global.GVA_LOG.Error("结清支付订单失败", zap.String("order_no", notice.OrderNo), zap.Error(err))
+		return err
+	}
+	if !settled {
+		return nil
+	}
+	GlobalOutboxService.Wake()
+
+	global.GVA_LOG.Info("支付订单已结清", zap.String("order_no", order.OrderNo), zap.Float64("applied", order.AppliedAmount))
+
+	if excess > 0 {
+		s.refundExcess(&order, excess)
+	}
+	return nil
+}
+
+// refundExcess 退回订单中罚款已被其他方式结清的金额
+func (s *PaymentService) refundExcess(order *model.PaymentOrder, amount float64) {
+	refundNo := "E" + order.OrderNo
+	provider, err := s.providerFor(order)
+	if err == nil {
+		var providerID string
+		if providerID, err = provider.Refund(order, refundNo, amount); err == nil {
+			err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+				return s.recordRefund(tx, order, refundNo, amount, nil, "罚款已结清，退还多付金额", providerID, 0)
+			})
+		}
+	}
+	if err != nil {
+		global.GVA_LOG.Error("退还多付金额失败，需要人工处理",
+			zap.String("order_no", order.OrderNo),
+			zap.Float64("amount", amount),
+			zap.Error(err))
+	}
+}
+
+// recordRefund 保存退款记录、记账并累加订单的退款金额，同一退款单号只记录一次；
+// reopen 为退款后恢复为待付的罚款金额，其余部分从读者预存余额中扣除
+func (s *PaymentService) recordRefund(tx *gorm.DB, order *model.PaymentOrder, refundNo string, amount float64, reopen []FineAllocation, reason, providerID string, operatorID uint) error {
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.PaymentRefund{
+		RefundNo:   refundNo,
+		OrderID:    order.ID,
+		Amount:     amount,
+		Reason:     reason,
+		ProviderID: providerID,
+		OperatorID: operatorID,
+	})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	if err := GlobalLedgerService.Refund(tx, LedgerRefund{
+		ReaderID:       order.ReaderID,
+		Destination:    model.AccountGateway,
+		Amount:         amount,
+		Reopen:         reopen,
+		PaymentOrderID: &order.ID,
+		Key:            "refund:" + refundNo,
+		Memo:           reason,
+		OperatorID:     operatorID,
+	}); err != nil {
+		return err
+	}
+	order.RefundedAmount = fromCents(toCents(order.RefundedAmount) + toCents(amount))
+	return tx.Model(order).UpdateColumn("refunded_amount", gorm.Expr("refunded_amount + ?", amount)).Error
+}
+
+// newPaymentOrderNo 生成订单号：PF + 时间 + 6位随机数
+func newPaymentOrderNo() string {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		n = big.NewInt(time.Now().UnixNano() % 1000000)
+	}
+	return fmt.Sprintf("PF%s%06d", time.Now().Format("20060102150405"), n.Int64())
+}
+
+// toCents 金额转换为分，避免浮点误差
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// fromCents 分转换为金额
+func fromCents(cents int64) float64 {
+	return float64(cents) / 100
+}
+
+// uniqueUints 去重
+func uniqueUints(ids []uint) []uint {
+	seen := make(map[uint]struct{}, len(ids))
+	var result []uint
+	for _, id := range ids {
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// 全局罚款支付服务实例
+var GlobalPaymentService = &PaymentService{}
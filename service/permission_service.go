@@ -0,0 +1,151 @@
+package service
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"errors"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// PermissionService 角色权限服务，权限映射缓存在内存中
+type PermissionService struct {
+	mu    sync.RWMutex
+	cache map[model.UserRole]map[model.Permission]struct{}
+}
+
+// HasPermission 判断角色是否拥有全部指定权限
+func (s *PermissionService) HasPermission(role model.UserRole, perms ...model.Permission) bool {
+	// 管理员拥有全部权限
+	if role == model.RoleAdmin {
+		return true
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	granted := s.cache[role]
+	for _, p := range perms {
+		if _, ok := granted[p]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// GetRolePermissions 获取角色拥有的权限列表
+func (s *PermissionService) GetRolePermissions(role model.UserRole) []model.Permission {
+	if role == model.RoleAdmin {
+		perms := make([]model.Permission, 0, len(model.AllPermissions))
+		for _, info := range model.AllPermissions {
+			perms = append(perms, info.Code)
+		}
+		sort.Slice(perms, func(i, j int) bool { return perms[i] < perms[j] })
+		return perms
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	perms := make([]model.Permission, 0, len(s.cache[role]))
+	for p := range s.cache[role] {
+		perms = append(perms, p)
+	}
+	sort.Slice(perms, func(i, j int) bool { return perms[i] < perms[j] })
+	return perms
+}
+
+// SetRolePermissions 覆盖设置角色的权限
+func (s *PermissionService) SetRolePermissions(role model.UserRole, perms []model.Permission, actor model.AuditActor) error {
+	switch role {
+	case model.RoleLibrarian, model.RoleReader:
+	case model.RoleAdmin:
+		return errors.New("管理员拥有全部权限，不可修改")
+	default:
+		return errors.New("角色不存在")
+	}
+
+	unique := make(map[model.Permission]struct{}, len(perms))
+	for _, p := range perms {
+		if !model.IsValidPermission(p) {
+			return errors.New("未定义的权限：" + string(p))
+		}
+		unique[p] = struct{}{}
+	}
+
+	tx := global.GVA_DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Unscoped().Where("role = ?", role).Delete(&model.RolePermission{}).Error; err != nil {
+		tx.Rollback()
+		return errors.New("更新权限失败")
+	}
+
+	after := make([]model.Permission, 0, len(unique))
+	if len(unique) > 0 {
+		records := make([]model.RolePermission, 0, len(unique))
+		for p := range unique {
+			records = append(records, model.RolePermission{Role: role, Permission: p})
+			after = append(after, p)
+		}
+		sort.Slice(after, func(i, j int) bool { return after[i] < after[j] })
+		if err := tx.Create(&records).Error; err != nil {
+			tx.Rollback()
+			return errors.New("更新权限失败")
+		}
+	}
+
+	if err := GlobalAuditService.Record(tx, actor, model.AuditPermissionUpdate, "role", 0,
+		map[string]interface{}{"role": role, "permissions": s.GetRolePermissions(role)},
+		map[string]interface{}{"role": role, "permissions": after},
+	); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.New("更新权限失败")
+	}
+
+	s.mu.Lock()
+	if s.cache == nil {
+		s.cache = make(map[model.UserRole]map[model.Permission]struct{})
+	}
+	s.cache[role] = unique
+	s.mu.Unlock()
+
+	global.GVA_LOG.Info("角色权限已更新", zap.String("role", string(role)), zap.Int("count", len(unique)))
+	return nil
+}
+
+// RefreshCache 从数据库重新加载角色权限
+func (s *PermissionService) RefreshCache() error {
+	var records []model.RolePermission
+	if err := global.GVA_DB.Find(&records).Error; err != nil {
+		return err
+	}
+
+	cache := make(map[model.UserRole]map[model.Permission]struct{})
+	for _, r := range records {
+		if cache[r.Role] == nil {
+			cache[r.Role] = make(map[model.Permission]struct{})
+		}
+		cache[r.Role][r.Permission] = struct{}{}
+	}
+
+	s.mu.Lock()
+	s.cache = cache
+	s.mu.Unlock()
+
+	global.GVA_LOG.Info("权限缓存已刷新", zap.Int("count", len(records)))
+	return nil
+}
+
+// 全局权限服务实例
+var GlobalPermissionService = &PermissionService{}
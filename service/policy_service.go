@@ -0,0 +1,307 @@
+package service
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"errors"
+	"sync"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// fallbackLoanPolicy 借阅规则表为空或字段缺失时使用的兜底值
+var fallbackLoanPolicy = model.EffectiveLoanPolicy{
+	MaxBorrow:       5,
+	BorrowDays:      30,
+	MaxRenew:        2,
+	RenewDays:       15,
+	MaxReservations: 3,
+	PickupDays:      3,
+	FinePerDay:      0.5,
+	MaxFineRate:     0.5,
+}
+
+// PolicyService 借阅规则服务
+// 借阅数量、借期、续借、预约配额和罚款标准统一由读者类型决定，可按图书分类覆盖，规则缓存在内存中
+type PolicyService struct {
+	mu        sync.RWMutex
+	base      map[model.PatronCategory]model.LoanPolicy
+	overrides map[model.PatronCategory]map[uint]model.LoanPolicy
+}
+
+// Resolve 计算某类读者借阅属于指定分类的图书时生效的规则
+// 覆盖规则优先于基础规则；图书属于多个分类且都设置了覆盖时，取最严格的值（期限、次数取小，罚款取大）
+func (s *PolicyService) Resolve(category model.PatronCategory, bookCategoryIDs []uint) model.EffectiveLoanPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	base, ok := s.base[category]
+	if !ok {
+		base, ok = s.base[model.DefaultPatronCategory]
+		if !ok {
+			global.GVA_LOG.Warn("未找到读者类型的借阅规则，使用默认值", zap.String("patron_category", string(category)))
+		}
+	}
+
+	eff := fallbackLoanPolicy
+	eff.PatronCategory = category
+	applyInt(&eff.MaxBorrow, base.MaxBorrow)
+	applyInt(&eff.BorrowDays, base.BorrowDays)
+	applyInt(&eff.MaxRenew, base.MaxRenew)
+	applyInt(&eff.RenewDays, base.RenewDays)
+	applyInt(&eff.MaxReservations, base.MaxReservations)
+	applyInt(&eff.PickupDays, base.PickupDays)
+	applyFloat(&eff.FinePerDay, base.FinePerDay)
+	applyFloat(&eff.MaxFineRate, base.MaxFineRate)
+
+	var borrowDays, maxRenew, renewDays *int
+	var finePerDay, maxFineRate *float64
+	for _, id := range bookCategoryIDs {
+		o, ok := s.overrides[category][id]
+		if !ok {
+			continue
+		}
+		borrowDays = minInt(borrowDays, o.BorrowDays)
+		maxRenew = minInt(maxRenew, o.MaxRenew)
+		renewDays = minInt(renewDays, o.RenewDays)
+		finePerDay = maxFloat(finePerDay, o.FinePerDay)
+		maxFineRate = maxFloat(maxFineRate, o.MaxFineRate)
+		if o.MaxBorrow != nil {
+			if eff.CategoryLimits == nil {
+				eff.CategoryLimits = make(map[uint]int)
+			}
+			eff.CategoryLimits[id] = *o.MaxBorrow
+		}
+	}
+	applyInt(&eff.BorrowDays, borrowDays)
+	applyInt(&eff.MaxRenew, maxRenew)
+	applyInt(&eff.RenewDays, renewDays)
+	applyFloat(&eff.FinePerDay, finePerDay)
+	applyFloat(&eff.MaxFineRate, maxFineRate)
+
+	return eff
+}
+
+// ResolveForReader 获取读者的基础规则（不涉及具体图书，用于借阅数量、预约配额等）
+func (s *PolicyService) ResolveForReader(reader *model.Reader) model.EffectiveLoanPolicy {
+	return s.Resolve(reader.PatronCategory, nil)
+}
+
+// ResolveForBook 获取读者借阅指定图书时生效的规则，db 可以传入事务
+func (s *PolicyService) ResolveForBook(db *gorm.DB, reader *model.Reader, bookID uint) (model.EffectiveLoanPolicy, error) {
+	var categoryIDs []uint
+	if err := db.Model(&model.BookCategory{}).Where("book_id = ?", bookID).
+		Pluck("category_id", &categoryIDs).Error; err != nil {
+		return model.EffectiveLoanPolicy{}, err
+	}
+	return s.Resolve(reader.PatronCategory, categoryIDs), nil
+}
+
+// GetPolicies 获取借阅规则列表，patronCategory为空时返回全部
+func (s *PolicyService) GetPolicies(patronCategory model.PatronCategory) ([]model.LoanPolicy, error) {
+	var policies []model.LoanPolicy
+	db := global.GVA_DB.Preload("Category")
+	if patronCategory != "" {
+		db = db.Where("patron_category = ?", patronCategory)
+	}
+	if err := db.Order("patron_category ASC, category_id ASC").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// SavePolicy 新增或覆盖一条借阅规则（按读者类型和图书分类唯一）
+func (s *PolicyService) SavePolicy(policy *model.LoanPolicy, actor model.AuditActor) error {
+	if err := validateLoanPolicy(policy); err != nil {
+		return err
+	}
+
+	tx := global.GVA_DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if policy.CategoryID > 0 {
+		var count int64
+		tx.Model(&model.Category{}).Where("id = ?", policy.CategoryID).Count(&count)
+		if count == 0 {
+			tx.Rollback()
+			return errors.New("图书分类不存在")
+		}
+	}
+
+	var existing model.LoanPolicy
+	var before interface{}
+	err := tx.Where("patron_category = ? AND category_id = ?", policy.PatronCategory, policy.CategoryID).
+		First(&existing).Error
+	switch {
+	case err == nil:
+		before = existing
+		policy.ID = existing.ID
+		policy.CreatedAt = existing.CreatedAt
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if policy.CategoryID == 0 {
+			tx.Rollback()
+			return errors.New("读者类型的基础规则不存在")
+		}
+		policy.ID = 0
+	default:
+		tx.Rollback()
+		return errors.New("查询借阅规则失败")
+	}
+
+	if err := tx.Omit("Category").Save(policy).Error; err != nil {
+		tx.Rollback()
+		global.GVA_LOG.Error("保存借阅规则失败", zap.Error(err))
+		return errors.New("保存借阅规则失败")
+	}
+
+	if err := GlobalAuditService.Record(tx, actor, model.AuditPolicyUpdate, "loan_policy", policy.ID, before, policy); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.New("保存借阅规则失败")
+	}
+
+	return s.RefreshCache()
+}
+
+// DeletePolicy 删除图书分类覆盖规则，基础规则不可删除
+func (s *PolicyService) DeletePolicy(id uint, actor model.AuditActor) error {
+	tx := global.GVA_DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var policy model.LoanPolicy
+	if err := tx.First(&policy, id).Error; err != nil {
+		tx.Rollback()
+		return errors.New("借阅规则不存在")
+	}
+	if policy.CategoryID == 0 {
+		tx.Rollback()
+		return errors.New("读者类型的基础规则不可删除")
+	}
+
+	// 物理删除，避免与同一读者类型和分类的唯一索引冲突
+	if err := tx.Unscoped().Delete(&policy).Error; err != nil {
+		tx.Rollback()
+		return errors.New("删除借阅规则失败")
+	}
+
+	if err := GlobalAuditService.Record(tx, actor, model.AuditPolicyUpdate, "loan_policy", policy.ID, policy, nil); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return errors.New("删除借阅规则失败")
+	}
+
+	return s.RefreshCache()
+}
+
+// RefreshCache 从数据库重新加载借阅规则
+func (s *PolicyService) RefreshCache() error {
+	var policies []model.LoanPolicy
+	if err := global.GVA_DB.Find(&policies).Error; err != nil {
+		return err
+	}
+
+	base := make(map[model.PatronCategory]model.LoanPolicy)
+	overrides := make(map[model.PatronCategory]map[uint]model.LoanPolicy)
+	for _, p := range policies {
+		if p.CategoryID == 0 {
+			base[p.PatronCategory] = p
+			continue
+		}
+		if overrides[p.PatronCategory] == nil {
+			overrides[p.PatronCategory] = make(map[uint]model.LoanPolicy)
+		}
+		overrides[p.PatronCategory][p.CategoryID] = p
+	}
+
+	s.mu.Lock()
+	s.base = base
+	s.overrides = overrides
+	s.mu.Unlock()
+
+	global.GVA_LOG.Info("借阅规则缓存已刷新", zap.Int("count", len(policies)))
+	return nil
+}
+
+// validateLoanPolicy 校验借阅规则的取值
+func validateLoanPolicy(p *model.LoanPolicy) error {
+	if !model.IsValidPatronCategory(p.PatronCategory) {
+		return errors.New("读者类型无效")
+	}
+
+	ints := []*int{p.MaxBorrow, p.BorrowDays, p.MaxRenew, p.RenewDays, p.MaxReservations, p.PickupDays}
+	floats := []*float64{p.FinePerDay, p.MaxFineRate}
+
+	if p.CategoryID == 0 {
+		for _, v := range ints {
+			if v == nil {
+				return errors.New("基础规则的所有字段都必须填写")
+			}
+		}
+		for _, v := range floats {
+			if v == nil {
+				return errors.New("基础规则的所有字段都必须填写")
+			}
+		}
+	} else if p.MaxReservations != nil || p.PickupDays != nil {
+		return errors.New("预约数量和取书期限只能在基础规则中设置")
+	}
+
+	for _, v := range ints {
+		if v != nil && *v < 0 {
+			return errors.New("规则取值不能为负数")
+		}
+	}
+	for _, v := range floats {
+		if v != nil && *v < 0 {
+			return errors.New("规则取值不能为负数")
+		}
+	}
+	if p.BorrowDays != nil && *p.BorrowDays > 365 {
+		return errors.New("借阅天数不能超过365天")
+	}
+	return nil
+}
+
+func applyInt(dst *int, v *int) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+func applyFloat(dst *float64, v *float64) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+func minInt(cur, v *int) *int {
+	if v == nil || (cur != nil && *cur <= *v) {
+		return cur
+	}
+	return v
+}
+
+func maxFloat(cur, v *float64) *float64 {
+	if v == nil || (cur != nil && *cur >= *v) {
+		return cur
+	}
+	return v
+}
+
+// 全局借阅规则服务实例
+var GlobalPolicyService = &PolicyService{}
@@ -6,11 +6,59 @@ import (
 	"bookadmin/model"
 	"bookadmin/utils"
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"time"
 
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// rankingKeyNames 榜单类型对应的Redis Key名称
+var rankingKeyNames = map[model.RankingType]string{
+	model.RankingTypeLike:     "likes",
+	model.RankingTypeFavorite: "favorites",
+	model.RankingTypeBorrow:   "borrows",
+	model.RankingTypeReserve:  "reservations",
+	model.RankingTypeTrending: "trending",
+	model.RankingTypeRating:   "ratings",
+}
+
+// rankingTypes 全部榜单类型
+var rankingTypes = []model.RankingType{
+	model.RankingTypeLike,
+	model.RankingTypeFavorite,
+	model.RankingTypeBorrow,
+	model.RankingTypeReserve,
+	model.RankingTypeTrending,
+	model.RankingTypeRating,
+}
+
+// rankingPeriods 全部榜单周期
+var rankingPeriods = []model.RankingPeriod{
+	model.RankingPeriodWeek,
+	model.RankingPeriodMonth,
+	model.RankingPeriodYear,
+}
+
+// trendingWeights 各类行为计入热度榜的权重
+var trendingWeights = map[model.RankingType]float64{
+	model.RankingTypeLike:     1,
+	model.RankingTypeFavorite: 2,
+	model.RankingTypeReserve:  3,
+	model.RankingTypeBorrow:   4,
+}
+
+// trendingHalfLife 热度半衰期：一次行为的热度每3天减半
+const trendingHalfLife = 72 * time.Hour
+
+// 评分榜使用贝叶斯平均：在实际评分之外加入3条3星的虚拟评分，避免只有一两条高分评价的图书排在前面
+const (
+	ratingPriorCount = 3
+	ratingPriorScore = 3
 )
 
 // RankingService 榜单服务
@@ -25,219 +73,297 @@ func NewRankingService() *RankingService {
 	}
 }
 
-// GetRanking 获取榜单
-func (s *RankingService) GetRanking(ctx context.Context, rankingType model.RankingType, period model.RankingPeriod, limit int) (*model.RankingResponse, error) {
+// rankCount 榜单中一本书的分数
+type rankCount struct {
+	BookID uint
+	Score  float64
+}
+
+// ErrRankingPeriodKey 周期标识无效
+var ErrRankingPeriodKey = errors.New("周期标识格式错误或周期尚未开始")
+
+// GetRanking 获取榜单，periodKey 为空时取当前周期，categoryID 为0时返回全部分类的榜单
+// 当前周期从Redis实时榜单读取，已结束的周期读取MySQL快照；每项附带与上一周期相比的排名变化
+func (s *RankingService) GetRanking(ctx context.Context, rankingType model.RankingType, period model.RankingPeriod, periodKey string, categoryID uint, limit int) (*model.RankingResponse, error) {
 	if limit <= 0 || limit > 100 {
 		limit = 100 // 默认返回Top100
 	}
 
-	// 1. 获取当前周期
-	var periodKey string
-	if period == model.RankingPeriodWeek {
-		periodKey = utils.GetCurrentWeekPeriod()
-	} else {
-		periodKey = utils.GetCurrentMonthPeriod()
+	// 1. 确定周期，统一为标准格式（如 2025-W5 → 2025-W05）
+	now := time.Now()
+	if periodKey == "" {
+		periodKey = rankingPeriodKey(period, now)
 	}
+	startTime, endTime, err := rankingPeriodRange(period, periodKey)
+	if err != nil || startTime.After(now) {
+		return nil, ErrRankingPeriodKey
+	}
+	periodKey = rankingPeriodKey(period, startTime)
 
-	// 2. 构建Redis Key
-	var redisKey string
-	if rankingType == model.RankingTypeLike {
-		if period == model.RankingPeriodWeek {
-			redisKey = constants.KeyRankLikesWeek(periodKey)
-		} else {
-			redisKey = constants.KeyRankLikesMonth(periodKey)
-		}
+	// 2. 已结束的周期读取快照，当前周期读取实时榜单
+	var ranking *model.RankingResponse
+	if endTime.Before(now) {
+		ranking, err = s.getRankingFromSnapshot(rankingType, period, periodKey, categoryID, limit)
 	} else {
-		if period == model.RankingPeriodWeek {
-			redisKey = constants.KeyRankFavoritesWeek(periodKey)
-		} else {
-			redisKey = constants.KeyRankFavoritesMonth(periodKey)
-		}
+		ranking, err = s.getCurrentRanking(ctx, rankingType, period, periodKey, categoryID, limit)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 与上一周期的快照对比排名
+	s.fillRankChanges(ranking)
+	return ranking, nil
+}
+
+// getCurrentRanking 从Redis读取当前周期的实时榜单
+func (s *RankingService) getCurrentRanking(ctx context.Context, rankingType model.RankingType, period model.RankingPeriod, periodKey string, categoryID uint, limit int) (*model.RankingResponse, error) {
+	// Redis不可用时直接从MySQL统计
+	if !GlobalRedisHealth.Available() {
+		return s.getRankingFromDB(rankingType, period, periodKey, categoryID, limit)
+	}
+
+	// 1. 构建Redis Key
+	redisKey := rankingKey(rankingType, period, periodKey, categoryID)
 
-	// 3. 从Redis获取榜单数据
+	// 2. 从Redis获取榜单数据
 	rankData, err := s.redis.GetRankingTop(ctx, redisKey, limit)
 	if err != nil {
-		global.GVA_LOG.Error("获取榜单失败",
+		global.GVA_LOG.Error("获取榜单失败，改为从MySQL统计",
 			zap.String("type", string(rankingType)),
 			zap.String("period", string(period)),
 			zap.Error(err))
-		return nil, err
+		GlobalRedisHealth.MarkUnavailable(err)
+		return s.getRankingFromDB(rankingType, period, periodKey, categoryID, limit)
 	}
 
-	// 4. 如果Redis没有数据，从MySQL重建榜单
+	// 3. 如果Redis没有数据，从MySQL重建榜单
 	if len(rankData) == 0 {
 		global.GVA_LOG.Warn("榜单数据为空，尝试从MySQL重建",
 			zap.String("type", string(rankingType)),
-			zap.String("period", string(period)))
+			zap.String("period", string(period)),
+			zap.Uint("categoryID", categoryID))
 
-		if err := s.rebuildRanking(ctx, rankingType, period, periodKey); err != nil {
+		if err := s.rebuildRanking(ctx, rankingType, period, periodKey, categoryID); err != nil {
 			global.GVA_LOG.Error("重建榜单失败", zap.Error(err))
-			return &model.RankingResponse{
-				Type:      rankingType,
-				Period:    period,
-				PeriodKey: periodKey,
-				Items:     []model.RankingItem{},
-				Total:     0,
-				UpdatedAt: time.Now().Format("2006-01-02 15:04:05"),
-			}, nil
+			return s.buildRankingResponse(rankingType, period, periodKey, categoryID, nil), nil
 		}
 
 		// 重新获取
 		rankData, _ = s.redis.GetRankingTop(ctx, redisKey, limit)
 	}
 
-	// 5. 构建榜单响应
-	items := make([]model.RankingItem, 0, len(rankData))
-	for i, data := range rankData {
-		bookID, _ := strconv.ParseUint(data.Member.(string), 10, 32)
+	// 4. 构建榜单响应
+	counts := make([]rankCount, 0, len(rankData))
+	for _, data := range rankData {
+		bookID, err := strconv.ParseUint(data.Member.(string), 10, 32)
+		if err != nil {
+			continue
+		}
+		counts = append(counts, rankCount{
+			BookID: uint(bookID),
+			Score:  s.displayScore(rankingType, period, periodKey, data.Score),
+		})
+	}
+
+	return s.buildRankingResponse(rankingType, period, periodKey, categoryID, counts), nil
+}
+
+// getRankingFromDB Redis不可用时直接从MySQL统计榜单
+func (s *RankingService) getRankingFromDB(rankingType model.RankingType, period model.RankingPeriod, periodKey string, categoryID uint, limit int) (*model.RankingResponse, error) {
+	counts, err := s.queryRankingFromDB(rankingType, period, periodKey, categoryID, limit)
+	if err != nil {
+		global.GVA_LOG.Error("从MySQL统计榜单失败", zap.Error(err))
+		return nil, err
+	}
+	for i := range counts {
+		counts[i].Score = s.displayScore(rankingType, period, periodKey, counts[i].Score)
+	}
+
+	return s.buildRankingResponse(rankingType, period, periodKey, categoryID, counts), nil
+}
 
-		// 查询图书详情
+// buildRankingResponse 查询图书详情并构建榜单响应，已删除的图书不计排名
+func (s *RankingService) buildRankingResponse(rankingType model.RankingType, period model.RankingPeriod, periodKey string, categoryID uint, counts []rankCount) *model.RankingResponse {
+	items := make([]model.RankingItem, 0, len(counts))
+	for _, c := range counts {
 		var book model.Book
-		if err := global.GVA_DB.First(&book, bookID).Error; err == nil {
+		if err := global.GVA_DB.First(&book, c.BookID).Error; err == nil {
 			items = append(items, model.RankingItem{
-				Rank:   i + 1,
-				BookID: uint(bookID),
+				Rank:   len(items) + 1,
+				BookID: c.BookID,
 				Book:   &book,
-				Score:  int64(data.Score),
+				Score:  c.Score,
 			})
 		}
 	}
 
 	return &model.RankingResponse{
-		Type:      rankingType,
-		Period:    period,
-		PeriodKey: periodKey,
-		Items:     items,
-		Total:     len(items),
-		UpdatedAt: time.Now().Format("2006-01-02 15:04:05"),
-	}, nil
+		Type:       rankingType,
+		Period:     period,
+		PeriodKey:  periodKey,
+		CategoryID: categoryID,
+		Items:      items,
+		Total:      len(items),
+		UpdatedAt:  time.Now().Format("2006-01-02 15:04:05"),
+	}
 }
 
-// rebuildRanking 重建榜单（从MySQL统计数据）
-func (s *RankingService) rebuildRanking(ctx context.Context, rankingType model.RankingType, period model.RankingPeriod, periodKey string) error {
-	global.GVA_LOG.Info("开始重建榜单",
-		zap.String("type", string(rankingType)),
-		zap.String("period", string(period)),
-		zap.String("periodKey", periodKey))
+// displayScore 热度榜存储的是按周期起点放大后的分数，展示时换算为当前时刻的热度
+func (s *RankingService) displayScore(rankingType model.RankingType, period model.RankingPeriod, periodKey string, score float64) float64 {
+	if rankingType == model.RankingTypeRating {
+		return math.Round(score*100) / 100
+	}
+	if rankingType != model.RankingTypeTrending {
+		return score
+	}
+	startTime, endTime, err := rankingPeriodRange(period, periodKey)
+	if err != nil {
+		return score
+	}
+	at := time.Now()
+	if at.After(endTime) {
+		at = endTime
+	}
+	return math.Round(score/trendingFactor(startTime, at)*100) / 100
+}
 
+// queryRankingFromDB 从MySQL统计周期内的榜单数据
+func (s *RankingService) queryRankingFromDB(rankingType model.RankingType, period model.RankingPeriod, periodKey string, categoryID uint, limit int) ([]rankCount, error) {
 	// 1. 获取时间范围
-	var startTime, endTime time.Time
-	var err error
+	startTime, endTime, err := rankingPeriodRange(period, periodKey)
+	if err != nil {
+		return nil, err
+	}
 
-	if period == model.RankingPeriodWeek {
-		startTime, endTime, err = utils.GetWeekStartEnd(periodKey)
+	// 2. 组合行为明细：普通榜单每条记录计1分，热度榜按权重和发生时间前向衰减
+	var events string
+	var args []interface{}
+	scoreExpr := "SUM(e.w)"
+	if rankingType == model.RankingTypeRating {
+		// 周期内新增或修改后仍公开的评价
+		scoreExpr = "(SUM(e.w) + ?) / (COUNT(*) + ?)"
+		events = "SELECT book_id, updated_at AS t, rating AS w FROM book_reviews WHERE status = 'approved' AND updated_at BETWEEN ? AND ?"
+		args = append(args, ratingPriorCount*ratingPriorScore, ratingPriorCount, startTime, endTime)
+	} else if rankingType == model.RankingTypeTrending {
+		scoreExpr = "SUM(e.w * POW(2, TIMESTAMPDIFF(SECOND, ?, e.t) / ?))"
+		args = append(args, startTime, trendingHalfLife.Seconds())
+		for i, t := range []model.RankingType{model.RankingTypeLike, model.RankingTypeFavorite, model.RankingTypeReserve, model.RankingTypeBorrow} {
+			if i > 0 {
+				events += " UNION ALL "
+			}
+			events += rankingEventSQL(t)
+			args = append(args, trendingWeights[t], startTime, endTime)
+		}
 	} else {
-		startTime, endTime, err = utils.GetMonthStartEnd(periodKey)
+		events = rankingEventSQL(rankingType)
+		if events == "" {
+			return nil, fmt.Errorf("不支持的榜单类型: %s", rankingType)
+		}
+		args = append(args, 1, startTime, endTime)
 	}
 
-	if err != nil {
-		return err
+	// 已删除的图书不上榜
+	query := "SELECT e.book_id, " + scoreExpr + " AS score FROM (" + events + ") e" +
+		" JOIN books b ON b.id = e.book_id AND b.deleted_at IS NULL"
+	if categoryID > 0 {
+		query += " JOIN book_categories bc ON bc.book_id = e.book_id AND bc.category_id = ?"
+		args = append(args, categoryID)
 	}
+	query += " GROUP BY e.book_id ORDER BY score DESC LIMIT ?"
+	args = append(args, limit)
 
-	// 2. 从MySQL统计数据
-	type RankItem struct {
-		BookID uint
-		Count  int64
+	// 3. 从MySQL统计数据
+	var items []rankCount
+	if err := global.GVA_DB.Raw(query, args...).Scan(&items).Error; err != nil {
+		return nil, err
 	}
 
-	var items []RankItem
+	return items, nil
+}
 
-	if rankingType == model.RankingTypeLike {
-		// 统计点赞数
-		if err := global.GVA_DB.Model(&model.BookLike{}).
-			Select("book_id, COUNT(*) as count").
-			Where("created_at BETWEEN ? AND ?", startTime, endTime).
-			Group("book_id").
-			Order("count DESC").
-			Limit(100).
-			Scan(&items).Error; err != nil {
-			return err
-		}
-	} else {
-		// 统计收藏数
-		if err := global.GVA_DB.Model(&model.BookFavorite{}).
-			Select("book_id, COUNT(*) as count").
-			Where("created_at BETWEEN ? AND ?", startTime, endTime).
-			Group("book_id").
-			Order("count DESC").
-			Limit(100).
-			Scan(&items).Error; err != nil {
-			return err
-		}
+// rankingEventSQL 各类榜单的行为明细查询，返回 book_id、发生时间 t 和权重 w
+func rankingEventSQL(rankingType model.RankingType) string {
+	switch rankingType {
+	case model.RankingTypeLike:
+		return "SELECT book_id, created_at AS t, ? AS w FROM book_likes WHERE created_at BETWEEN ? AND ?"
+	case model.RankingTypeFavorite:
+		return "SELECT book_id, created_at AS t, ? AS w FROM book_favorites WHERE created_at BETWEEN ? AND ?"
+	case model.RankingTypeBorrow:
+		// 待审批和被拒绝的申请不算借阅
+		return "SELECT book_id, borrow_date AS t, ? AS w FROM borrow_records WHERE deleted_at IS NULL AND status NOT IN ('pending', 'rejected') AND borrow_date BETWEEN ? AND ?"
+	case model.RankingTypeReserve:
+		return "SELECT book_id, reserve_date AS t, ? AS w FROM reservations WHERE deleted_at IS NULL AND reserve_date BETWEEN ? AND ?"
 	}
+	return ""
+}
 
-	// 3. 写入Redis
-	var redisKey string
-	if rankingType == model.RankingTypeLike {
-		if period == model.RankingPeriodWeek {
-			redisKey = constants.KeyRankLikesWeek(periodKey)
-		} else {
-			redisKey = constants.KeyRankLikesMonth(periodKey)
-		}
-	} else {
-		if period == model.RankingPeriodWeek {
-			redisKey = constants.KeyRankFavoritesWeek(periodKey)
-		} else {
-			redisKey = constants.KeyRankFavoritesMonth(periodKey)
-		}
-	}
+// rebuildRanking 重建榜单（从MySQL统计数据）
+func (s *RankingService) rebuildRanking(ctx context.Context, rankingType model.RankingType, period model.RankingPeriod, periodKey string, categoryID uint) error {
+	global.GVA_LOG.Info("开始重建榜单",
+		zap.String("type", string(rankingType)),
+		zap.String("period", string(period)),
+		zap.String("periodKey", periodKey),
+		zap.Uint("categoryID", categoryID))
 
-	// 清空旧数据
-	global.GVA_REDIS.Del(ctx, redisKey)
+	items, err := s.queryRankingFromDB(rankingType, period, periodKey, categoryID, 100)
+	if err != nil {
+		return err
+	}
 
-	// 批量写入
+	// 清空旧数据后一次性写入并设置过期时间
+	redisKey := rankingKey(rankingType, period, periodKey, categoryID)
+	members := make([]redis.Z, 0, len(items))
 	for _, item := range items {
-		if err := s.redis.IncrRankingScore(ctx, redisKey, item.BookID, float64(item.Count)); err != nil {
-			global.GVA_LOG.Warn("写入榜单数据失败",
-				zap.Uint("bookID", item.BookID),
-				zap.Error(err))
-		}
+		members = append(members, redis.Z{Score: item.Score, Member: strconv.FormatUint(uint64(item.BookID), 10)})
 	}
-
-	// 设置过期时间
-	var expireTime time.Duration
-	if period == model.RankingPeriodWeek {
-		expireTime = time.Duration(constants.ExpireWeekRank) * time.Second
-	} else {
-		expireTime = time.Duration(constants.ExpireMonthRank) * time.Second
+	_, err = global.GVA_REDIS.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, redisKey)
+		if len(members) > 0 {
+			pipe.ZAdd(ctx, redisKey, members...)
+			pipe.Expire(ctx, redisKey, rankingExpire(period))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	global.GVA_REDIS.Expire(ctx, redisKey, expireTime)
 
 	global.GVA_LOG.Info("榜单重建完成",
 		zap.String("type", string(rankingType)),
 		zap.String("period", string(period)),
+		zap.Uint("categoryID", categoryID),
 		zap.Int("count", len(items)))
 
 	return nil
 }
 
-// RebuildAllRankings 重建所有榜单（定时任务调用）
+// RebuildAllRankings 重建当前周期的所有榜单，包括各分类榜单（定时任务调用）
 func (s *RankingService) RebuildAllRankings(ctx context.Context) error {
+	if global.GVA_REDIS == nil {
+		return errors.New("Redis未连接")
+	}
 	global.GVA_LOG.Info("开始重建所有榜单")
 
-	// 当前周期
-	weekPeriod := utils.GetCurrentWeekPeriod()
-	monthPeriod := utils.GetCurrentMonthPeriod()
-
-	// 重建4个榜单
-	rankings := []struct {
-		rankingType model.RankingType
-		period      model.RankingPeriod
-		periodKey   string
-	}{
-		{model.RankingTypeLike, model.RankingPeriodWeek, weekPeriod},
-		{model.RankingTypeLike, model.RankingPeriodMonth, monthPeriod},
-		{model.RankingTypeFavorite, model.RankingPeriodWeek, weekPeriod},
-		{model.RankingTypeFavorite, model.RankingPeriodMonth, monthPeriod},
-	}
-
-	for _, r := range rankings {
-		if err := s.rebuildRanking(ctx, r.rankingType, r.period, r.periodKey); err != nil {
-			global.GVA_LOG.Error("重建榜单失败",
-				zap.String("type", string(r.rankingType)),
-				zap.String("period", string(r.period)),
-				zap.Error(err))
+	var categoryIDs []uint
+	if err := global.GVA_DB.Model(&model.Category{}).Pluck("id", &categoryIDs).Error; err != nil {
+		return err
+	}
+	scopes := append([]uint{0}, categoryIDs...)
+
+	now := time.Now()
+	for _, period := range rankingPeriods {
+		periodKey := rankingPeriodKey(period, now)
+		for _, rankingType := range rankingTypes {
+			for _, categoryID := range scopes {
+				if err := s.rebuildRanking(ctx, rankingType, period, periodKey, categoryID); err != nil {
+					global.GVA_LOG.Error("重建榜单失败",
+						zap.String("type", string(rankingType)),
+						zap.String("period", string(period)),
+						zap.Uint("categoryID", categoryID),
+						zap.Error(err))
+				}
+			}
 		}
 	}
 
@@ -267,3 +393,183 @@ func (s *RankingService) SyncBookStatsToRedis(ctx context.Context) error {
 	global.GVA_LOG.Info(fmt.Sprintf("图书统计数据同步完成，共 %d 本书", len(books)))
 	return nil
 }
+
+// rankingIncrement 一个榜单的分数增量
+type rankingIncrement struct {
+	key    string
+	delta  float64
+	expire time.Duration
+}
+
+// rankingIncrements 一次行为需要更新的榜单：一本书所在的周/月/年榜及其各分类榜，新增行为同时计入热度榜
+func rankingIncrements(rankingType model.RankingType, bookID uint, delta float64, now time.Time) ([]rankingIncrement, error) {
+	var categoryIDs []uint
+	if err := global.GVA_DB.Model(&model.BookCategory{}).Where("book_id = ?", bookID).Pluck("category_id", &categoryIDs).Error; err != nil {
+		return nil, err
+	}
+	scopes := append([]uint{0}, categoryIDs...)
+
+	var increments []rankingIncrement
+	for _, period := range rankingPeriods {
+		periodKey := rankingPeriodKey(period, now)
+		expire := rankingExpire(period)
+
+		// 热度按周期起点前向衰减：越晚发生的行为放大越多，读取时再统一换算到当前时刻
+		var trendingDelta float64
+		if delta > 0 {
+			if startTime, _, err := rankingPeriodRange(period, periodKey); err == nil {
+				trendingDelta = trendingWeights[rankingType] * delta * trendingFactor(startTime, now)
+			}
+		}
+
+		for _, categoryID := range scopes {
+			increments = append(increments, rankingIncrement{rankingKey(rankingType, period, periodKey, categoryID), delta, expire})
+			if trendingDelta > 0 {
+				increments = append(increments, rankingIncrement{rankingKey(model.RankingTypeTrending, period, periodKey, categoryID), trendingDelta, expire})
+			}
+		}
+	}
+	return increments, nil
+}
+
+// updateRankingBoards 更新一本书所在的周/月/年榜及其各分类榜，新增行为同时计入热度榜
+func updateRankingBoards(ctx context.Context, rankingType model.RankingType, bookID uint, delta float64) error {
+	increments, err := rankingIncrements(rankingType, bookID, delta, time.Now())
+	if err != nil {
+		return err
+	}
+	member := strconv.FormatUint(uint64(bookID), 10)
+
+	_, err = global.GVA_REDIS.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, inc := range increments {
+			pipe.ZIncrBy(ctx, inc.key, inc.delta, member)
+			pipe.Expire(ctx, inc.key, inc.expire)
+		}
+		return nil
+	})
+	return err
+}
+
+// rankingOnceScript 幂等键不存在时写入幂等键并更新全部榜单，已存在时不做任何修改
+// KEYS[1] 为幂等键，KEYS[2..] 为榜单；ARGV[1] 为幂等键过期秒数，ARGV[2] 为图书ID，
+// 之后每个榜单依次为增量和过期秒数
+var rankingOnceScript = redis.NewScript(`
+if not redis.call('SET', KEYS[1], '1', 'NX', 'EX', ARGV[1]) then
+	return 0
+end
+for i = 2, #KEYS do
+	redis.call('ZINCRBY', KEYS[i], ARGV[2 * i - 1], ARGV[2])
+	redis.call('EXPIRE', KEYS[i], ARGV[2 * i])
+end
+return 1
+`)
+
+// updateRankingBoardsOnce 与 updateRankingBoards 相同，但同一幂等键只计入一次，用于会重复投递的事件
+// at 为行为发生时间，延迟执行时仍计入发生时所在的周期
+func updateRankingBoardsOnce(ctx context.Context, rankingType model.RankingType, bookID uint, delta float64, at time.Time, idempotencyKey string) error {
+	increments, err := rankingIncrements(rankingType, bookID, delta, at)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(increments)+1)
+	args := make([]interface{}, 0, 2*len(increments)+2)
+	keys = append(keys, constants.KeyOutboxDone(idempotencyKey))
+	args = append(args, constants.ExpireOutboxDone, bookID)
+	for _, inc := range increments {
+		keys = append(keys, inc.key)
+		args = append(args, inc.delta, int64(inc.expire/time.Second))
+	}
+	return rankingOnceScript.Run(ctx, global.GVA_REDIS, keys, args...).Err()
+}
+
+// invalidateRankingBoards 删除一本书所在的当前周期榜单（含分类榜单），下次查询时从MySQL重建
+// 用于无法增量更新的评分榜
+func invalidateRankingBoards(rankingType model.RankingType, bookID uint) {
+	if !GlobalRedisHealth.Available() {
+		return
+	}
+	var categoryIDs []uint
+	if err := global.GVA_DB.Model(&model.BookCategory{}).Where("book_id = ?", bookID).Pluck("category_id", &categoryIDs).Error; err != nil {
+		global.GVA_LOG.Warn("查询图书分类失败", zap.Uint("bookID", bookID), zap.Error(err))
+	}
+	scopes := append([]uint{0}, categoryIDs...)
+
+	now := time.Now()
+	keys := make([]string, 0, len(rankingPeriods)*len(scopes))
+	for _, period := range rankingPeriods {
+		periodKey := rankingPeriodKey(period, now)
+		for _, categoryID := range scopes {
+			keys = append(keys, rankingKey(rankingType, period, periodKey, categoryID))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := global.GVA_REDIS.Del(ctx, keys...).Err(); err != nil {
+		GlobalRedisHealth.MarkUnavailable(err)
+		global.GVA_LOG.Warn("清除榜单失败",
+			zap.String("type", string(rankingType)),
+			zap.Uint("bookID", bookID),
+			zap.Error(err))
+	}
+}
+
+// enqueueRankingEvent 在借阅、预约事务内写入榜单事件，提交后由待执行事件循环计入榜单
+func enqueueRankingEvent(tx *gorm.DB, rankingType model.RankingType, bookID uint, key string) error {
+	return GlobalOutboxService.Enqueue(tx, model.OutboxRanking, key, model.RankingPayload{
+		Type:   rankingType,
+		BookID: bookID,
+	})
+}
+
+// rankingKey 榜单的Redis Key，categoryID 不为0时为分类榜单
+func rankingKey(rankingType model.RankingType, period model.RankingPeriod, periodKey string, categoryID uint) string {
+	name := rankingKeyNames[rankingType]
+	if categoryID > 0 {
+		return constants.KeyRankCategory(name, string(period), periodKey, categoryID)
+	}
+	return constants.KeyRank(name, string(period), periodKey)
+}
+
+// rankingPeriodKey 指定时间所在周期的标识
+func rankingPeriodKey(period model.RankingPeriod, t time.Time) string {
+	switch period {
+	case model.RankingPeriodWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case model.RankingPeriodYear:
+		return fmt.Sprintf("%d", t.Year())
+	default:
+		return fmt.Sprintf("%d-%02d", t.Year(), t.Month())
+	}
+}
+
+// rankingPeriodRange 周期的开始和结束时间
+func rankingPeriodRange(period model.RankingPeriod, periodKey string) (time.Time, time.Time, error) {
+	switch period {
+	case model.RankingPeriodWeek:
+		return utils.GetWeekStartEnd(periodKey)
+	case model.RankingPeriodYear:
+		return utils.GetYearStartEnd(periodKey)
+	default:
+		return utils.GetMonthStartEnd(periodKey)
+	}
+}
+
+// rankingExpire 榜单过期时间
+func rankingExpire(period model.RankingPeriod) time.Duration {
+	switch period {
+	case model.RankingPeriodWeek:
+		return time.Duration(constants.ExpireWeekRank) * time.Second
+	case model.RankingPeriodYear:
+		return time.Duration(constants.ExpireYearRank) * time.Second
+	default:
+		return time.Duration(constants.ExpireMonthRank) * time.Second
+	}
+}
+
+// trendingFactor 热度前向衰减系数：距周期起点每过一个半衰期翻倍
+func trendingFactor(startTime, at time.Time) float64 {
+	return math.Pow(2, at.Sub(startTime).Seconds()/trendingHalfLife.Seconds())
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// getRankingFromSnapshot 读取已结束周期的榜单快照，快照不存在时先从MySQL生成
+func (s *RankingService) getRankingFromSnapshot(rankingType model.RankingType, period model.RankingPeriod, periodKey string, categoryID uint, limit int) (*model.RankingResponse, error) {
+	snapshot, items, err := s.ensureSnapshot(rankingType, period, periodKey, categoryID)
+	if err != nil {
+		global.GVA_LOG.Error("获取榜单快照失败",
+			zap.String("type", string(rankingType)),
+			zap.String("period", string(period)),
+			zap.String("periodKey", periodKey),
+			zap.Error(err))
+		return nil, err
+	}
+
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	counts := make([]rankCount, 0, len(items))
+	for _, item := range items {
+		counts = append(counts, rankCount{BookID: item.BookID, Score: item.Score})
+	}
+
+	ranking := s.buildRankingResponse(rankingType, period, periodKey, categoryID, counts)
+	ranking.Snapshot = true
+	ranking.UpdatedAt = snapshot.CreatedAt.Format("2006-01-02 15:04:05")
+	return ranking, nil
+}
+
+// fillRankChanges 对比上一周期快照，填写每项的上期排名和排名变化
+func (s *RankingService) fillRankChanges(ranking *model.RankingResponse) {
+	prevKey, err := previousPeriodKey(ranking.Period, ranking.PeriodKey)
+	if err != nil {
+		return
+	}
+	ranking.PrevPeriodKey = prevKey
+
+	_, prevItems, err := s.ensureSnapshot(ranking.Type, ranking.Period, prevKey, ranking.CategoryID)
+	if err != nil {
+		global.GVA_LOG.Warn("获取上一周期榜单快照失败",
+			zap.String("type", string(ranking.Type)),
+			zap.String("periodKey", prevKey),
+			zap.Error(err))
+		return
+	}
+
+	prevRanks := make(map[uint]int, len(prevItems))
+	for _, item := range prevItems {
+		prevRanks[item.BookID] = item.Rank
+	}
+
+	for i := range ranking.Items {
+		item := &ranking.Items[i]
+		prevRank, ok := prevRanks[item.BookID]
+		if !ok {
+			item.Trend = "new"
+			continue
+		}
+		item.PrevRank = prevRank
+		item.RankChange = prevRank - item.Rank
+		switch {
+		case item.RankChange > 0:
+			item.Trend = "up"
+		case item.RankChange < 0:
+			item.Trend = "down"
+		default:
+			item.Trend = "same"
+		}
+	}
+}
+
+// ensureSnapshot 获取已结束周期的榜单快照，不存在时从MySQL明细表统计Top100并保存
+func (s *RankingService) ensureSnapshot(rankingType model.RankingType, period model.RankingPeriod, periodKey string, categoryID uint) (*model.RankingSnapshot, []model.RankingSnapshotItem, error) {
+	snapshot, items, err := s.loadSnapshot(rankingType, period, periodKey, categoryID)
+	if err == nil {
+		return snapshot, items, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+
+	// 周期未结束时不生成快照
+	_, endTime, err := rankingPeriodRange(period, periodKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !endTime.Before(time.Now()) {
+		return nil, nil, errors.New("周期尚未结束")
+	}
+
+	counts, err := s.queryRankingFromDB(rankingType, period, periodKey, categoryID, 100)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	snapshot = &model.RankingSnapshot{
+		Type:       rankingType,
+		Period:     period,
+		PeriodKey:  periodKey,
+		CategoryID: categoryID,
+		ItemCount:  len(counts),
+	}
+	items = make([]model.RankingSnapshotItem, 0, len(counts))
+	for i, c := range counts {
+		items = append(items, model.RankingSnapshotItem{
+			Rank:   i + 1,
+			BookID: c.BookID,
+			Score:  s.displayScore(rankingType, period, periodKey, c.Score),
+		})
+	}
+
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(snapshot).Error; err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].SnapshotID = snapshot.ID
+		}
+		if len(items) > 0 {
+			return tx.Create(&items).Error
+		}
+		return nil
+	})
+	if err != nil {
+		// 其他请求或定时任务已同时生成快照（唯一索引冲突），改为读取
+		if snapshot, items, loadErr := s.loadSnapshot(rankingType, period, periodKey, categoryID); loadErr == nil {
+			return snapshot, items, nil
+		}
+		return nil, nil, err
+	}
+
+	global.GVA_LOG.Info("生成榜单快照",
+		zap.String("type", string(rankingType)),
+		zap.String("period", string(period)),
+		zap.String("periodKey", periodKey),
+		zap.Uint("categoryID", categoryID),
+		zap.Int("count", len(items)))
+	return snapshot, items, nil
+}
+
+// loadSnapshot 读取快照及其明细，按排名排序
+func (s *RankingService) loadSnapshot(rankingType model.RankingType, period model.RankingPeriod, periodKey string, categoryID uint) (*model.RankingSnapshot, []model.RankingSnapshotItem, error) {
+	var snapshot model.RankingSnapshot
+	if err := global.GVA_DB.Where("type = ? AND period = ? AND period_key = ? AND category_id = ?",
+		rankingType, period, periodKey, categoryID).First(&snapshot).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var items []model.RankingSnapshotItem
+	if err := global.GVA_DB.Where("snapshot_id = ?", snapshot.ID).Order("`rank` ASC").Find(&items).Error; err != nil {
+		return nil, nil, err
+	}
+	return &snapshot, items, nil
+}
+
+// SnapshotClosedPeriods 为刚结束的周榜、月榜、年榜生成快照，包括各分类榜单（定时任务调用，已存在的快照不会重复生成）
+func (s *RankingService) SnapshotClosedPeriods() error {
+	var categoryIDs []uint
+	if err := global.GVA_DB.Model(&model.Category{}).Pluck("id", &categoryIDs).Error; err != nil {
+		return err
+	}
+	scopes := append([]uint{0}, categoryIDs...)
+
+	now := time.Now()
+	var failed int
+	for _, period := range rankingPeriods {
+		prevKey, err := previousPeriodKey(period, rankingPeriodKey(period, now))
+		if err != nil {
+			return err
+		}
+		for _, rankingType := range rankingTypes {
+			for _, categoryID := range scopes {
+				if _, _, err := s.ensureSnapshot(rankingType, period, prevKey, categoryID); err != nil {
+					failed++
+					global.GVA_LOG.Error("生成榜单快照失败",
+						zap.String("type", string(rankingType)),
+						zap.String("periodKey", prevKey),
+						zap.Uint("categoryID", categoryID),
+						zap.Error(err))
+				}
+			}
+		}
+	}
+
+	if failed > 0 {
+		return errors.New("部分榜单快照生成失败")
+	}
+	return nil
+}
+
+// GetSnapshotPeriods 获取已有快照的周期标识，按时间倒序
+func (s *RankingService) GetSnapshotPeriods(rankingType model.RankingType, period model.RankingPeriod) ([]string, error) {
+	var periodKeys []string
+	err := global.GVA_DB.Model(&model.RankingSnapshot{}).
+		Where("type = ? AND period = ? AND category_id = 0", rankingType, period).
+		Order("period_key DESC").
+		Pluck("period_key", &periodKeys).Error
+	return periodKeys, err
+}
+
+// previousPeriodKey 上一周期的标识
+func previousPeriodKey(period model.RankingPeriod, periodKey string) (string, error) {
+	startTime, _, err := rankingPeriodRange(period, periodKey)
+	if err != nil {
+		return "", err
+	}
+	return rankingPeriodKey(period, startTime.AddDate(0, 0, -1)), nil
+}
@@ -0,0 +1,612 @@
+package service
+
+import (
+	"bookadmin/constants"
+	"bookadmin/global"
+	"bookadmin/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 推荐计算参数
+const (
+	recommendAlsoBorrowedSize = 20  // 每本书保留的相关图书数
+	recommendForYouSize       = 30  // 每位用户保留的推荐图书数
+	recommendCategorySize     = 5   // 每位用户保留的偏好分类数
+	recommendCategoryBooks    = 50  // 每个分类参与推荐的热门图书数
+	recommendMaxUserBooks     = 200 // 计算共同借阅时每位读者最多取最近借阅的图书数
+	recommendPopularDays      = 90  // 冷启动时统计近期热门图书的天数
+)
+
+// 行为权重：借阅 > 收藏 > 点赞，同一本书取最高的一种
+const (
+	recommendWeightBorrow   = 3
+	recommendWeightFavorite = 2
+	recommendWeightLike     = 1
+)
+
+// RecommendService 图书推荐服务
+// 每天离线计算一次，结果写入 recommendations 表并缓存到Redis；查询时优先读Redis，缺失时从MySQL回填
+type RecommendService struct {
+	mu sync.Mutex
+}
+
+// recommendData 计算推荐所需的行为数据
+type recommendData struct {
+	borrows    map[uint][]uint           // 用户 → 借过的图书，最近借阅的在前
+	weights    map[uint]map[uint]float64 // 用户 → 图书 → 行为权重
+	categories map[uint][]uint           // 图书 → 分类
+	popularity map[uint]int              // 图书 → 借阅人数
+}
+
+// recommendCache Redis中缓存的推荐结果
+type recommendCache struct {
+	ComputedAt time.Time                `json:"computed_at"`
+	Books      []model.RecommendItem    `json:"books,omitempty"`
+	Categories []model.CategoryAffinity `json:"categories,omitempty"`
+}
+
+// Start 在后台重新计算推荐，已有计算任务时返回错误
+func (s *RecommendService) Start() error {
+	if !s.mu.TryLock() {
+		return errors.New("推荐计算正在进行中")
+	}
+	go func() {
+		defer s.mu.Unlock()
+		if err := s.compute(); err != nil {
+			global.GVA_LOG.Error("计算推荐失败", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// Compute 重新计算全部推荐（定时任务调用）
+func (s *RecommendService) Compute() error {
+	if !s.mu.TryLock() {
+		return errors.New("推荐计算正在进行中")
+	}
+	defer s.mu.Unlock()
+	return s.compute()
+}
+
+// compute 计算共同借阅、分类偏好和为你推荐，整体替换MySQL中的结果并刷新Redis缓存
+func (s *RecommendService) compute() error {
+	startedAt := time.Now()
+
+	data, err := s.loadBehaviors()
+	if err != nil {
+		return err
+	}
+
+	also := s.computeAlsoBorrowed(data)
+	affinity := s.computeCategoryAffinity(data)
+	forYou := s.computeForYou(data, also, affinity)
+
+	// 1. 整体替换MySQL中的结果
+	now := time.Now()
+	var rows []model.Recommendation
+	for bookID, items := range also {
+		for i, item := range items {
+			rows = append(rows, model.Recommendation{Kind: model.RecommendAlsoBorrowed, SubjectID: bookID, ItemID: item.BookID,
+				Rank: i + 1, Score: item.Score, ComputedAt: now})
+		}
+	}
+	for userID, items := range forYou {
+		for i, item := range items {
+			rows = append(rows, model.Recommendation{Kind: model.RecommendForYou, SubjectID: userID, ItemID: item.BookID,
+				Rank: i + 1, Score: item.Score, Reason: item.Reason, ComputedAt: now})
+		}
+	}
+	for userID, cats := range affinity {
+		for i, cat := range cats {
+			rows = append(rows, model.Recommendation{Kind: model.RecommendCategory, SubjectID: userID, ItemID: cat.CategoryID,
+				Rank: i + 1, Score: cat.Score, ComputedAt: now})
+		}
+	}
+
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&model.Recommendation{}).Error; err != nil {
+			return err
+		}
+		if len(rows) > 0 {
+			return tx.CreateInBatches(&rows, 500).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 2. 刷新Redis缓存：先清除旧结果，期间的查询从MySQL回填
+	if GlobalRedisHealth.Available() {
+		if err := s.refreshCache(now, also, forYou, affinity); err != nil {
+			GlobalRedisHealth.MarkUnavailable(err)
+			global.GVA_LOG.Warn("刷新推荐缓存失败，查询将从MySQL读取", zap.Error(err))
+		}
+	}
+
+	global.GVA_LOG.Info("推荐计算完成",
+		zap.Int("books", len(also)),
+		zap.Int("users", len(forYou)),
+		zap.Int("rows", len(rows)),
+		zap.Duration("elapsed", time.Since(startedAt)))
+	return nil
+}
+
+// loadBehaviors 读取借阅、收藏、点赞和图书分类，已删除的图书不参与计算
+func (s *RecommendService) loadBehaviors() (*recommendData, error) {
+	data := &recommendData{
+		borrows:    make(map[uint][]uint),
+		weights:    make(map[uint]map[uint]float64),
+		categories: make(map[uint][]uint),
+		popularity: make(map[uint]int),
+	}
+	addWeight := func(userID, bookID uint, w float64) {
+		if data.weights[userID] == nil {
+			data.weights[userID] = make(map[uint]float64)
+		}
+		if w > data.weights[userID][bookID] {
+			data.weights[userID][bookID] = w
+		}
+	}
+
+	// 借阅：待审批和被拒绝的申请不算
+	var borrowRows []struct {
+		UserID uint
+		BookID uint
+	}
+	if err := global.GVA_DB.Raw(`SELECT r.user_id, br.book_id, MAX(br.borrow_date) AS last_borrow
+		FROM borrow_records br
+		JOIN readers r ON r.id = br.reader_id
+		JOIN books b ON b.id = br.book_id AND b.deleted_at IS NULL
+		WHERE br.deleted_at IS NULL AND br.status NOT IN ('pending', 'rejected')
+		GROUP BY r.user_id, br.book_id
+		ORDER BY r.user_id, last_borrow DESC`).Scan(&borrowRows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range borrowRows {
+		data.borrows[row.UserID] = append(data.borrows[row.UserID], row.BookID)
+		data.popularity[row.BookID]++
+		addWeight(row.UserID, row.BookID, recommendWeightBorrow)
+	}
+
+	for table, w := range map[string]float64{"book_favorites": recommendWeightFavorite, "book_likes": recommendWeightLike} {
+		var rows []struct {
+			UserID uint
+			BookID uint
+		}
+		if err := global.GVA_DB.Raw("SELECT t.user_id, t.book_id FROM " + table + " t " +
+			"JOIN books b ON b.id = t.book_id AND b.deleted_at IS NULL").Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			addWeight(row.UserID, row.BookID, w)
+		}
+	}
+
+	var categoryRows []struct {
+		BookID     uint
+		CategoryID uint
+	}
+	if err := global.GVA_DB.Raw(`SELECT bc.book_id, bc.category_id FROM book_categories bc
+		JOIN books b ON b.id = bc.book_id AND b.deleted_at IS NULL`).Scan(&categoryRows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range categoryRows {
+		data.categories[row.BookID] = append(data.categories[row.BookID], row.CategoryID)
+	}
+
+	return data, nil
+}
+
+// computeAlsoBorrowed 借过这本书的读者还借过：按共同借阅人数的余弦相似度排序
+func (s *RecommendService) computeAlsoBorrowed(data *recommendData) map[uint][]model.RecommendItem {
+	co := make(map[uint]map[uint]float64)
+	for _, books := range data.borrows {
+		if len(books) > recommendMaxUserBooks {
+			books = books[:recommendMaxUserBooks]
+		}
+		for _, a := range books {
+			for _, b := range books {
+				if a == b {
+					continue
+				}
+				if co[a] == nil {
+					co[a] = make(map[uint]float64)
+				}
+				co[a][b]++
+			}
+		}
+	}
+
+	result := make(map[uint][]model.RecommendItem, len(co))
+	for a, related := range co {
+		items := make([]model.RecommendItem, 0, len(related))
+		for b, n := range related {
+			sim := n / math.Sqrt(float64(data.popularity[a]*data.popularity[b]))
+			items = append(items, model.RecommendItem{BookID: b, Score: roundScore(sim)})
+		}
+		result[a] = topRecommendItems(items, recommendAlsoBorrowedSize)
+	}
+	return result
+}
+
+// computeCategoryAffinity 用户的分类偏好：借阅、收藏、点赞按权重累加到图书所属分类，换算为占比
+func (s *RecommendService) computeCategoryAffinity(data *recommendData) map[uint][]model.CategoryAffinity {
+	result := make(map[uint][]model.CategoryAffinity, len(data.weights))
+	for userID, books := range data.weights {
+		scores := make(map[uint]float64)
+		var total float64
+		for bookID, w := range books {
+			for _, categoryID := range data.categories[bookID] {
+				scores[categoryID] += w
+				total += w
+			}
+		}
+		if total == 0 {
+			continue
+		}
+
+		cats := make([]model.CategoryAffinity, 0, len(scores))
+		for categoryID, score := range scores {
+			cats = append(cats, model.CategoryAffinity{CategoryID: categoryID, Score: roundScore(score / total)})
+		}
+		sort.Slice(cats, func(i, j int) bool {
+			if cats[i].Score != cats[j].Score {
+				return cats[i].Score > cats[j].Score
+			}
+			return cats[i].CategoryID < cats[j].CategoryID
+		})
+		if len(cats) > recommendCategorySize {
+			cats = cats[:recommendCategorySize]
+		}
+		result[userID] = cats
+	}
+	return result
+}
+
+// computeForYou 为你推荐：与用户有过行为的图书的相关图书，加上偏好分类中的热门图书，排除已借过的书
+func (s *RecommendService) computeForYou(data *recommendData, also map[uint][]model.RecommendItem, affinity map[uint][]model.CategoryAffinity) map[uint][]model.RecommendItem {
+	// 每个分类按借阅人数排序的热门图书
+	categoryBooks := make(map[uint][]uint)
+	for bookID, categoryIDs := range data.categories {
+		if data.popularity[bookID] == 0 {
+			continue
+		}
+		for _, categoryID := range categoryIDs {
+			categoryBooks[categoryID] = append(categoryBooks[categoryID], bookID)
+		}
+	}
+	for categoryID, books := range categoryBooks {
+		sort.Slice(books, func(i, j int) bool {
+			if data.popularity[books[i]] != data.popularity[books[j]] {
+				return data.popularity[books[i]] > data.popularity[books[j]]
+			}
+			return books[i] < books[j]
+		})
+		if len(books) > recommendCategoryBooks {
+			categoryBooks[categoryID] = books[:recommendCategoryBooks]
+		}
+	}
+
+	result := make(map[uint][]model.RecommendItem, len(data.weights))
+	for userID, books := range data.weights {
+		borrowed := make(map[uint]bool, len(data.borrows[userID]))
+		for _, bookID := range data.borrows[userID] {
+			borrowed[bookID] = true
+		}
+
+		alsoScores := make(map[uint]float64)
+		for bookID, w := range books {
+			for _, rel := range also[bookID] {
+				if !borrowed[rel.BookID] {
+					alsoScores[rel.BookID] += w * rel.Score
+				}
+			}
+		}
+		categoryScores := make(map[uint]float64)
+		for _, cat := range affinity[userID] {
+			for rank, bookID := range categoryBooks[cat.CategoryID] {
+				if !borrowed[bookID] {
+					categoryScores[bookID] += cat.Score / float64(rank+1)
+				}
+			}
+		}
+
+		items := make([]model.RecommendItem, 0, len(alsoScores)+len(categoryScores))
+		for bookID, score := range alsoScores {
+			reason := model.RecommendReasonAlsoBorrowed
+			if categoryScores[bookID] > score {
+				reason = model.RecommendReasonCategory
+			}
+			items = append(items, model.RecommendItem{BookID: bookID, Score: roundScore(score + categoryScores[bookID]), Reason: reason})
+		}
+		for bookID, score := range categoryScores {
+			if _, ok := alsoScores[bookID]; !ok {
+				items = append(items, model.RecommendItem{BookID: bookID, Score: roundScore(score), Reason: model.RecommendReasonCategory})
+			}
+		}
+		if len(items) > 0 {
+			result[userID] = topRecommendItems(items, recommendForYouSize)
+		}
+	}
+	return result
+}
+
+// refreshCache 清除旧的推荐缓存并写入新结果
+func (s *RecommendService) refreshCache(computedAt time.Time, also, forYou map[uint][]model.RecommendItem, affinity map[uint][]model.CategoryAffinity) error {
+	ctx := context.Background()
+
+	// 清除旧结果（包括本次已没有推荐的图书和用户）
+	var cursor uint64
+	for {
+		keys, next, err := global.GVA_REDIS.Scan(ctx, cursor, "rec:*", 500).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := global.GVA_REDIS.Del(ctx, keys...).Err(); err != nil {
+				return err
+			}
+		}
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+
+	caches := make(map[string]recommendCache, len(also)+len(forYou)+len(affinity))
+	for bookID, items := range also {
+		caches[constants.KeyRecommend(string(model.RecommendAlsoBorrowed), bookID)] = recommendCache{ComputedAt: computedAt, Books: items}
+	}
+	for userID, items := range forYou {
+		caches[constants.KeyRecommend(string(model.RecommendForYou), userID)] = recommendCache{ComputedAt: computedAt, Books: items}
+	}
+	for userID, cats := range affinity {
+		caches[constants.KeyRecommend(string(model.RecommendCategory), userID)] = recommendCache{ComputedAt: computedAt, Categories: cats}
+	}
+
+	// 分批写入
+	pipe := global.GVA_REDIS.Pipeline()
+	for key, cache := range caches {
+		if err := s.setCache(ctx, pipe, key, cache); err != nil {
+			return err
+		}
+		if pipe.Len() >= 500 {
+			if _, err := pipe.Exec(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// setCache 写入一条推荐缓存
+func (s *RecommendService) setCache(ctx context.Context, cmd redis.Cmdable, key string, cache recommendCache) error {
+	value, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	return cmd.Set(ctx, key, value, time.Duration(constants.ExpireRecommend)*time.Second).Err()
+}
+
+// load 读取推荐结果：优先Redis，缺失或Redis不可用时从MySQL读取并回填缓存
+func (s *RecommendService) load(ctx context.Context, kind model.RecommendKind, subjectID uint) (*recommendCache, string, error) {
+	key := constants.KeyRecommend(string(kind), subjectID)
+	useRedis := GlobalRedisHealth.Available()
+
+	if useRedis {
+		value, err := global.GVA_REDIS.Get(ctx, key).Result()
+		if err == nil {
+			var cache recommendCache
+			if err := json.Unmarshal([]byte(value), &cache); err == nil {
+				return &cache, "redis", nil
+			}
+		} else if err != redis.Nil {
+			GlobalRedisHealth.MarkUnavailable(err)
+			useRedis = false
+		}
+	}
+
+	var rows []model.Recommendation
+	if err := global.GVA_DB.Where("kind = ? AND subject_id = ?", kind, subjectID).
+		Order("`rank` ASC").Find(&rows).Error; err != nil {
+		return nil, "", err
+	}
+	cache := &recommendCache{}
+	for _, row := range rows {
+		cache.ComputedAt = row.ComputedAt
+		if kind == model.RecommendCategory {
+			cache.Categories = append(cache.Categories, model.CategoryAffinity{CategoryID: row.ItemID, Score: row.Score})
+		} else {
+			cache.Books = append(cache.Books, model.RecommendItem{BookID: row.ItemID, Score: row.Score, Reason: row.Reason})
+		}
+	}
+
+	if useRedis && len(rows) > 0 {
+		if err := s.setCache(ctx, global.GVA_REDIS, key, *cache); err != nil {
+			global.GVA_LOG.Warn("回填推荐缓存失败", zap.String("key", key), zap.Error(err))
+		}
+	}
+	return cache, "mysql", nil
+}
+
+// GetAlsoBorrowed 借过这本书的读者还借过
+func (s *RecommendService) GetAlsoBorrowed(ctx context.Context, bookID uint, limit int) (*model.RecommendResponse, error) {
+	cache, source, err := s.load(ctx, model.RecommendAlsoBorrowed, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &model.RecommendResponse{
+		Items:  s.fillBooks(cache.Books, nil, limit),
+		Source: source,
+	}
+	if !cache.ComputedAt.IsZero() {
+		resp.ComputedAt = &cache.ComputedAt
+	}
+	return resp, nil
+}
+
+// GetForYou 为你推荐，排除已借过（包括正在申请）的图书；没有推荐结果时推荐近期热门图书
+func (s *RecommendService) GetForYou(ctx context.Context, userID uint, limit int) (*model.RecommendResponse, error) {
+	cache, source, err := s.load(ctx, model.RecommendForYou, userID)
+	if err != nil {
+		return nil, err
+	}
+	categories, _, err := s.load(ctx, model.RecommendCategory, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 离线计算之后新借的书也要排除
+	var borrowedIDs []uint
+	if err := global.GVA_DB.Model(&model.BorrowRecord{}).
+		Joins("JOIN readers ON readers.id = borrow_records.reader_id").
+		Where("readers.user_id = ? AND borrow_records.status <> ?", userID, model.BorrowStatusRejected).
+		Distinct().Pluck("borrow_records.book_id", &borrowedIDs).Error; err != nil {
+		return nil, err
+	}
+	exclude := make(map[uint]bool, len(borrowedIDs))
+	for _, id := range borrowedIDs {
+		exclude[id] = true
+	}
+
+	resp := &model.RecommendResponse{
+		Items:      s.fillBooks(cache.Books, exclude, limit),
+		Categories: s.fillCategoryNames(categories.Categories),
+		Source:     source,
+	}
+	if !cache.ComputedAt.IsZero() {
+		resp.ComputedAt = &cache.ComputedAt
+	}
+
+	if len(resp.Items) == 0 {
+		popular, err := s.popularBooks(borrowedIDs, limit)
+		if err != nil {
+			return nil, err
+		}
+		resp.Items = s.fillBooks(popular, exclude, limit)
+		resp.Source = "popular"
+	}
+	return resp, nil
+}
+
+// popularBooks 近期借阅人数最多的图书，用于没有推荐结果的新用户
+func (s *RecommendService) popularBooks(exclude []uint, limit int) ([]model.RecommendItem, error) {
+	db := global.GVA_DB.Table("borrow_records").
+		Select("borrow_records.book_id, COUNT(DISTINCT borrow_records.reader_id) AS score").
+		Joins("JOIN books ON books.id = borrow_records.book_id AND books.deleted_at IS NULL").
+		Where("borrow_records.deleted_at IS NULL AND borrow_records.status NOT IN ? AND borrow_records.borrow_date >= ?",
+			[]model.BorrowStatus{model.BorrowStatusPending, model.BorrowStatusRejected},
+			time.Now().AddDate(0, 0, -recommendPopularDays))
+	if len(exclude) > 0 {
+		db = db.Where("borrow_records.book_id NOT IN ?", exclude)
+	}
+
+	var rows []struct {
+		BookID uint
+		Score  float64
+	}
+	if err := db.Group("borrow_records.book_id").Order("score DESC, borrow_records.book_id ASC").
+		Limit(limit).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	items := make([]model.RecommendItem, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, model.RecommendItem{BookID: row.BookID, Score: row.Score, Reason: model.RecommendReasonPopular})
+	}
+	return items, nil
+}
+
+// fillBooks 按推荐顺序填充图书详情，跳过已删除和需要排除的图书
+func (s *RecommendService) fillBooks(items []model.RecommendItem, exclude map[uint]bool, limit int) []model.RecommendItem {
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		if !exclude[item.BookID] {
+			ids = append(ids, item.BookID)
+		}
+	}
+	result := make([]model.RecommendItem, 0, limit)
+	if len(ids) == 0 {
+		return result
+	}
+
+	var books []model.Book
+	global.GVA_DB.Where("id IN ?", ids).Find(&books)
+	byID := make(map[uint]*model.Book, len(books))
+	for i := range books {
+		byID[books[i].ID] = &books[i]
+	}
+
+	for _, item := range items {
+		if len(result) >= limit {
+			break
+		}
+		book, ok := byID[item.BookID]
+		if !ok || exclude[item.BookID] {
+			continue
+		}
+		item.Book = book
+		result = append(result, item)
+	}
+	return result
+}
+
+// fillCategoryNames 填充分类名称
+func (s *RecommendService) fillCategoryNames(cats []model.CategoryAffinity) []model.CategoryAffinity {
+	if len(cats) == 0 {
+		return cats
+	}
+	ids := make([]uint, 0, len(cats))
+	for _, cat := range cats {
+		ids = append(ids, cat.CategoryID)
+	}
+	var categories []model.Category
+	global.GVA_DB.Where("id IN ?", ids).Find(&categories)
+	names := make(map[uint]string, len(categories))
+	for _, c := range categories {
+		names[c.ID] = c.Name
+	}
+
+	result := make([]model.CategoryAffinity, 0, len(cats))
+	for _, cat := range cats {
+		if name, ok := names[cat.CategoryID]; ok {
+			cat.Name = name
+			result = append(result, cat)
+		}
+	}
+	return result
+}
+
+// topRecommendItems 按分数从高到低取前n项，分数相同时按图书ID排序保证结果稳定
+func topRecommendItems(items []model.RecommendItem, n int) []model.RecommendItem {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Score != items[j].Score {
+			return items[i].Score > items[j].Score
+		}
+		return items[i].BookID < items[j].BookID
+	})
+	if len(items) > n {
+		items = items[:n]
+	}
+	return items
+}
+
+// roundScore 分数保留4位小数
+func roundScore(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
+
+// 全局图书推荐服务实例
+var GlobalRecommendService = &RecommendService{}
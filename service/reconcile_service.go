@@ -0,0 +1,519 @@
+package service
+
+import (
+	"bookadmin/constants"
+	"bookadmin/global"
+	"bookadmin/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	reconcileBatchSize  = 500              // 每批检查的图书数
+	reconcileMaxDetails = 200              // 最多保存的差异明细条数
+	reconcileLockTTL    = 30 * time.Minute // 校对任务锁的有效期
+)
+
+// reconcileTarget 一类操作（点赞或收藏）的校对参数
+type reconcileTarget struct {
+	countField string            // books表和Redis图书统计中的计数字段
+	setField   string            // 差异明细中用户集合的名称
+	table      string            // 明细表
+	userKey    func(uint) string // Redis用户集合的key
+	keyPattern string            // 扫描Redis用户集合的模式
+}
+
+var reconcileTargets = []reconcileTarget{
+	{countField: "like_count", setField: "likes", table: "book_likes", userKey: constants.KeyUserLikes, keyPattern: "user:likes:*"},
+	{countField: "favorite_count", setField: "favorites", table: "book_favorites", userKey: constants.KeyUserFavorites, keyPattern: "user:favorites:*"},
+}
+
+// ReconcileService 点赞/收藏 Redis 与 MySQL 校对服务
+// 以 book_likes、book_favorites 明细表为准：
+// 1. books 表的 like_count、favorite_count
+// 2. Redis 图书统计 book:stats:{id}
+// 3. Redis 用户集合 user:likes:{id}、user:favorites:{id}
+// 同步队列还有未处理的消息时 Redis 可能合理地领先于 MySQL，此时只校对 books 表
+type ReconcileService struct {
+	mu sync.Mutex // 同一进程内只允许一个校对任务
+}
+
+// reconcileState 一次校对的中间状态
+type reconcileState struct {
+	run     *model.ReconcileRun
+	counts  map[string]map[uint]int64 // 计数字段 -> 图书ID -> 明细表中的数量
+	bookIDs []uint
+	details []model.ReconcileDiff
+}
+
+// addDiff 记录差异，明细只保留前 reconcileMaxDetails 条
+func (st *reconcileState) addDiff(diff model.ReconcileDiff) {
+	switch diff.Kind {
+	case model.ReconcileDiffBookCounter:
+		st.run.BookCounterDiffs++
+	case model.ReconcileDiffRedisCounter:
+		st.run.RedisCounterDiffs++
+	case model.ReconcileDiffUserSet:
+		st.run.UserSetDiffs++
+	}
+	if len(st.details) < reconcileMaxDetails {
+		st.details = append(st.details, diff)
+	}
+}
+
+// Run 同步执行一次校对（定时任务使用）
+func (s *ReconcileService) Run(trigger string, dryRun bool, actor model.AuditActor) (*model.ReconcileRun, error) {
+	run, err := s.begin(trigger, dryRun, actor)
+	if err != nil {
+		return nil, err
+	}
+	s.execute(run)
+	return run, nil
+}
+
+// Start 在后台执行一次校对，立即返回校对记录（管理接口使用）
+func (s *ReconcileService) Start(dryRun bool, actor model.AuditActor) (*model.ReconcileRun, error) {
+	run, err := s.begin("manual", dryRun, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := GlobalAuditService.Record(global.GVA_DB, actor, model.AuditSyncReconcile, "reconcile_run", run.ID, nil, map[string]interface{}{
+		"dry_run": dryRun,
+	}); err != nil {
+		global.GVA_LOG.Error("记录校对审计失败", zap.Error(err))
+	}
+
+	go s.execute(run)
+	return run, nil
+}
+
+// begin 获取校对锁并创建校对记录
+func (s *ReconcileService) begin(trigger string, dryRun bool, actor model.AuditActor) (*model.ReconcileRun, error) {
+	if !s.mu.TryLock() {
+		return nil, errors.New("校对任务正在执行")
+	}
+
+	// 多实例部署时通过Redis锁互斥，Redis不可用时只做进程内互斥
+	if global.GVA_REDIS != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		locked, err := global.GVA_REDIS.SetNX(ctx, constants.KeyReconcileLock, "1", reconcileLockTTL).Result()
+		cancel()
+		if err == nil && !locked {
+			s.mu.Unlock()
+			return nil, errors.New("其他实例正在执行校对任务")
+		}
+	}
+
+	now := time.Now()
+	run := model.ReconcileRun{
+		Trigger:      trigger,
+		DryRun:       dryRun,
+		Status:       model.ReconcileStatusRunning,
+		OperatorID:   actor.UserID,
+		OperatorName: actor.Username,
+		StartedAt:    &now,
+	}
+	if err := global.GVA_DB.Create(&run).Error; err != nil {
+		s.release()
+		return nil, errors.New("创建校对记录失败")
+	}
+	return &run, nil
+}
+
+// release 释放校对锁
+func (s *ReconcileService) release() {
+	if global.GVA_REDIS != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		global.GVA_REDIS.Del(ctx, constants.KeyReconcileLock)
+		cancel()
+	}
+	s.mu.Unlock()
+}
+
+// execute 执行校对并保存结果
+func (s *ReconcileService) execute(run *model.ReconcileRun) {
+	defer s.release()
+
+	st := &reconcileState{run: run, details: []model.ReconcileDiff{}}
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			global.GVA_LOG.Error("校对任务异常", zap.Uint("run_id", run.ID), zap.Any("panic", r))
+			err = fmt.Errorf("校对任务异常: %v", r)
+		}
+		s.finish(st, err)
+	}()
+
+	ctx := context.Background()
+	if err = s.loadCounts(st); err != nil {
+		return
+	}
+	if err = s.reconcileBookCounters(st); err != nil {
+		return
+	}
+
+	if reason := s.redisSkipReason(ctx); reason != "" {
+		run.RedisSkipped = true
+		run.RedisSkipReason = reason
+		return
+	}
+	if err = s.reconcileRedisCounters(ctx, st); err != nil {
+		return
+	}
+	err = s.reconcileUserSets(ctx, st)
+}
+
+// finish 保存校对结果
+func (s *ReconcileService) finish(st *reconcileState, err error) {
+	run := st.run
+	now := time.Now()
+	run.FinishedAt = &now
+	run.Status = model.ReconcileStatusCompleted
+	if err != nil {
+		run.Status = model.ReconcileStatusFailed
+		run.ErrorMessage = err.Error()
+	}
+	details, _ := json.Marshal(st.details)
+	run.Details = string(details)
+
+	if dbErr := global.GVA_DB.Model(run).Updates(map[string]interface{}{
+		"status":              run.Status,
+		"books_checked":       run.BooksChecked,
+		"users_checked":       run.UsersChecked,
+		"book_counter_diffs":  run.BookCounterDiffs,
+		"redis_counter_diffs": run.RedisCounterDiffs,
+		"user_set_diffs":      run.UserSetDiffs,
+		"redis_skipped":       run.RedisSkipped,
+		"redis_skip_reason":   run.RedisSkipReason,
+		"details":             run.Details,
+		"error_message":       run.ErrorMessage,
+		"finished_at":         now,
+	}).Error; dbErr != nil {
+		global.GVA_LOG.Error("保存校对结果失败", zap.Uint("run_id", run.ID), zap.Error(dbErr))
+	}
+
+	global.GVA_LOG.Info("点赞收藏校对结束",
+		zap.Uint("run_id", run.ID),
+		zap.String("status", string(run.Status)),
+		zap.Bool("dry_run", run.DryRun),
+		zap.Int("book_counter_diffs", run.BookCounterDiffs),
+		zap.Int("redis_counter_diffs", run.RedisCounterDiffs),
+		zap.Int("user_set_diffs", run.UserSetDiffs),
+		zap.Bool("redis_skipped", run.RedisSkipped))
+}
+
+// loadCounts 从明细表统计每本书的点赞数和收藏数
+func (s *ReconcileService) loadCounts(st *reconcileState) error {
+	st.counts = make(map[string]map[uint]int64, len(reconcileTargets))
+	for _, t := range reconcileTargets {
+		var rows []struct {
+			BookID uint
+			Total  int64
+		}
+		if err := global.GVA_DB.Table(t.table).Select("book_id, COUNT(*) AS total").Group("book_id").Scan(&rows).Error; err != nil {
+			return errors.New("统计" + t.table + "失败")
+		}
+		counts := make(map[uint]int64, len(rows))
+		for _, row := range rows {
+			counts[row.BookID] = row.Total
+		}
+		st.counts[t.countField] = counts
+	}
+	return nil
+}
+
+// reconcileBookCounters 校对 books 表中的计数
+func (s *ReconcileService) reconcileBookCounters(st *reconcileState) error {
+	var books []model.Book
+	result := global.GVA_DB.Model(&model.Book{}).Select("id", "like_count", "favorite_count").
+		FindInBatches(&books, reconcileBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, book := range books {
+				st.bookIDs = append(st.bookIDs, book.ID)
+				st.run.BooksChecked++
+
+				for _, t := range reconcileTargets {
+					actual := int64(book.LikeCount)
+					if t.countField == "favorite_count" {
+						actual = int64(book.FavoriteCount)
+					}
+					expected := st.counts[t.countField][book.ID]
+					if actual == expected {
+						continue
+					}
+
+					st.addDiff(model.ReconcileDiff{
+						Kind:     model.ReconcileDiffBookCounter,
+						BookID:   book.ID,
+						Field:    t.countField,
+						Expected: expected,
+						Actual:   actual,
+					})
+					if st.run.DryRun {
+						continue
+					}
+					// 修复时重新计数，避免覆盖校对期间Worker写入的变化
+					if err := global.GVA_DB.Model(&model.Book{}).Where("id = ?", book.ID).
+						UpdateColumn(t.countField, gorm.Expr("(SELECT COUNT(*) FROM "+t.table+" WHERE book_id = ?)", book.ID)).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+	if result.Error != nil {
+		global.GVA_LOG.Error("校对图书计数失败", zap.Error(result.Error))
+		return errors.New("校对图书计数失败")
+	}
+	return nil
+}
+
+// redisSkipReason 判断是否需要跳过Redis校对，返回跳过原因
+func (s *ReconcileService) redisSkipReason(ctx context.Context) string {
+	if global.GVA_REDIS == nil {
+		return "Redis未连接"
+	}
+	if GlobalRedisHealth.Status().Mode == model.RedisModeDegraded {
+		return "Redis不可用，当前为MySQL降级模式"
+	}
+
+	for _, stream := range syncStreams {
+		info, err := global.GVA_REDIS.XInfoStream(ctx, stream).Result()
+		if err != nil {
+			if strings.Contains(err.Error(), "no such key") {
+				continue
+			}
+			return "读取同步队列状态失败: " + err.Error()
+		}
+		groups, err := global.GVA_REDIS.XInfoGroups(ctx, stream).Result()
+		if err != nil {
+			return "读取同步队列状态失败: " + err.Error()
+		}
+		for _, g := range groups {
+			if g.Name != constants.StreamSyncGroup {
+				continue
+			}
+			if g.Pending > 0 || g.LastDeliveredID != info.LastGeneratedID {
+				return "同步队列 " + stream + " 还有未处理的消息"
+			}
+		}
+	}
+	return ""
+}
+
+// reconcileRedisCounters 校对 Redis 图书统计
+// 统计key不存在时读取结果为0，因此明细表数量大于0时也需要写入
+func (s *ReconcileService) reconcileRedisCounters(ctx context.Context, st *reconcileState) error {
+	for start := 0; start < len(st.bookIDs); start += reconcileBatchSize {
+		end := start + reconcileBatchSize
+		if end > len(st.bookIDs) {
+			end = len(st.bookIDs)
+		}
+		ids := st.bookIDs[start:end]
+
+		pipe := global.GVA_REDIS.Pipeline()
+		cmds := make([]*redis.SliceCmd, len(ids))
+		for i, id := range ids {
+			cmds[i] = pipe.HMGet(ctx, constants.KeyBookStats(id), "like_count", "favorite_count")
+		}
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return errors.New("读取Redis图书统计失败")
+		}
+
+		fix := global.GVA_REDIS.Pipeline()
+		for i, id := range ids {
+			values := cmds[i].Val()
+			for j, t := range reconcileTargets {
+				var actual int64
+				if j < len(values) && values[j] != nil {
+					actual, _ = strconv.ParseInt(fmt.Sprint(values[j]), 10, 64)
+				}
+				expected := st.counts[t.countField][id]
+				if actual == expected {
+					continue
+				}
+
+				st.addDiff(model.ReconcileDiff{
+					Kind:     model.ReconcileDiffRedisCounter,
+					BookID:   id,
+					Field:    t.countField,
+					Expected: expected,
+					Actual:   actual,
+				})
+				// 与 HIncrBy 写入的统计一致，不设置过期时间
+				fix.HSet(ctx, constants.KeyBookStats(id), t.countField, expected)
+			}
+		}
+		if !st.run.DryRun && fix.Len() > 0 {
+			if _, err := fix.Exec(ctx); err != nil {
+				return errors.New("修复Redis图书统计失败")
+			}
+		}
+	}
+	return nil
+}
+
+// reconcileUserSets 校对 Redis 用户点赞/收藏集合
+func (s *ReconcileService) reconcileUserSets(ctx context.Context, st *reconcileState) error {
+	checked := make(map[uint]bool)
+	for _, t := range reconcileTargets {
+		expected, err := s.loadUserBooks(t.table)
+		if err != nil {
+			return err
+		}
+
+		// 需要检查的用户：明细表中有记录的用户 + Redis中已有集合的用户
+		users := make(map[uint]bool, len(expected))
+		for userID := range expected {
+			users[userID] = true
+		}
+		prefix := strings.TrimSuffix(t.keyPattern, "*")
+		iter := global.GVA_REDIS.Scan(ctx, 0, t.keyPattern, 1000).Iterator()
+		for iter.Next(ctx) {
+			if userID, err := strconv.ParseUint(strings.TrimPrefix(iter.Val(), prefix), 10, 32); err == nil {
+				users[uint(userID)] = true
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return errors.New("扫描Redis用户集合失败")
+		}
+
+		userIDs := make([]uint, 0, len(users))
+		for userID := range users {
+			userIDs = append(userIDs, userID)
+		}
+		sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+
+		for _, userID := range userIDs {
+			checked[userID] = true
+			key := t.userKey(userID)
+			members, err := global.GVA_REDIS.SMembers(ctx, key).Result()
+			if err != nil {
+				return errors.New("读取Redis用户集合失败")
+			}
+
+			actual := make(map[uint]bool, len(members))
+			var extra []uint
+			var remove []interface{}
+			for _, m := range members {
+				bookID, err := strconv.ParseUint(m, 10, 32)
+				if err != nil || !expected[userID][uint(bookID)] {
+					// 无法解析的成员也视为多余，按原值删除
+					remove = append(remove, m)
+					if err == nil {
+						extra = append(extra, uint(bookID))
+					}
+					continue
+				}
+				actual[uint(bookID)] = true
+			}
+			var missing []uint
+			for bookID := range expected[userID] {
+				if !actual[bookID] {
+					missing = append(missing, bookID)
+				}
+			}
+			if len(remove) == 0 && len(missing) == 0 {
+				continue
+			}
+			sort.Slice(extra, func(i, j int) bool { return extra[i] < extra[j] })
+			sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+
+			st.addDiff(model.ReconcileDiff{
+				Kind:     model.ReconcileDiffUserSet,
+				UserID:   userID,
+				Field:    t.setField,
+				Expected: int64(len(expected[userID])),
+				Actual:   int64(len(members)),
+				Missing:  missing,
+				Extra:    extra,
+			})
+			if st.run.DryRun {
+				continue
+			}
+
+			pipe := global.GVA_REDIS.Pipeline()
+			if len(remove) > 0 {
+				pipe.SRem(ctx, key, remove...)
+			}
+			for _, bookID := range missing {
+				pipe.SAdd(ctx, key, bookID)
+			}
+			if _, err := pipe.Exec(ctx); err != nil {
+				return errors.New("修复Redis用户集合失败")
+			}
+		}
+	}
+	st.run.UsersChecked = len(checked)
+	return nil
+}
+
+// loadUserBooks 加载明细表中每个用户的图书集合
+func (s *ReconcileService) loadUserBooks(table string) (map[uint]map[uint]bool, error) {
+	result := make(map[uint]map[uint]bool)
+	var rows []struct {
+		ID     uint
+		UserID uint
+		BookID uint
+	}
+	err := global.GVA_DB.Table(table).Select("id, user_id, book_id").
+		FindInBatches(&rows, 5000, func(tx *gorm.DB, batch int) error {
+			for _, row := range rows {
+				if result[row.UserID] == nil {
+					result[row.UserID] = make(map[uint]bool)
+				}
+				result[row.UserID][row.BookID] = true
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, errors.New("读取" + table + "失败")
+	}
+	return result, nil
+}
+
+// GetRuns 分页获取校对记录（不含差异明细）
+func (s *ReconcileService) GetRuns(page, pageSize int) ([]model.ReconcileRun, int64, error) {
+	var runs []model.ReconcileRun
+	var total int64
+	db := global.GVA_DB.Model(&model.ReconcileRun{})
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Omit("details").Order("id DESC").Limit(pageSize).Offset((page - 1) * pageSize).Find(&runs).Error; err != nil {
+		return nil, 0, err
+	}
+	return runs, total, nil
+}
+
+// GetRun 获取校对记录和差异明细，id 为0时返回最近一次
+func (s *ReconcileService) GetRun(id uint) (*model.ReconcileRun, []model.ReconcileDiff, error) {
+	var run model.ReconcileRun
+	db := global.GVA_DB.Model(&model.ReconcileRun{})
+	if id > 0 {
+		db = db.Where("id = ?", id)
+	}
+	if err := db.Order("id DESC").First(&run).Error; err != nil {
+		return nil, nil, errors.New("校对记录不存在")
+	}
+
+	var details []model.ReconcileDiff
+	if run.Details != "" {
+		_ = json.Unmarshal([]byte(run.Details), &details)
+	}
+	return &run, details, nil
+}
+
+// 全局校对服务实例
+var GlobalReconcileService = &ReconcileService{}
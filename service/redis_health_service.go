@@ -0,0 +1,161 @@
+package service
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// redisHealthInterval Redis健康检查间隔
+const redisHealthInterval = 5 * time.Second
+
+// RedisHealthService Redis健康检查与降级开关
+// Redis不可用时点赞/收藏/榜单改为只使用MySQL；恢复后先执行预热回调（启动Worker、重建榜单、校对缓存），
+// 预热完成后再切回Redis模式
+type RedisHealthService struct {
+	mu        sync.RWMutex
+	client    *redis.Client
+	mode      model.RedisMode
+	since     time.Time
+	lastCheck *time.Time
+	lastError string
+	onRecover []func()
+}
+
+// Watch 开始定期检查Redis连接，connected 为启动时是否已连通
+func (s *RedisHealthService) Watch(client *redis.Client, connected bool, err error) {
+	s.mu.Lock()
+	s.client = client
+	s.mode = model.RedisModeNormal
+	if !connected {
+		s.mode = model.RedisModeDegraded
+		if err != nil {
+			s.lastError = err.Error()
+		}
+	}
+	s.since = time.Now()
+	s.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(redisHealthInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.check()
+		}
+	}()
+}
+
+// OnRecover 注册Redis恢复后的预热回调，按注册顺序执行
+func (s *RedisHealthService) OnRecover(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onRecover = append(s.onRecover, fn)
+}
+
+// Available 点赞/收藏/榜单是否可以使用Redis
+func (s *RedisHealthService) Available() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.client != nil && s.mode == model.RedisModeNormal
+}
+
+// Status 当前健康状态
+func (s *RedisHealthService) Status() model.RedisHealth {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	mode := s.mode
+	if s.client == nil {
+		mode = model.RedisModeDegraded
+	}
+	return model.RedisHealth{
+		Mode:      mode,
+		Available: s.client != nil && mode == model.RedisModeNormal,
+		Since:     s.since,
+		LastCheck: s.lastCheck,
+		LastError: s.lastError,
+	}
+}
+
+// MarkUnavailable 业务调用Redis失败时立即切换为MySQL模式，不必等到下一次健康检查
+func (s *RedisHealthService) MarkUnavailable(err error) {
+	if err == nil || err == redis.Nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastError = err.Error()
+	if s.mode == model.RedisModeNormal {
+		s.setMode(model.RedisModeDegraded)
+	}
+}
+
+// check 执行一次健康检查
+func (s *RedisHealthService) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	err := s.client.Ping(ctx).Err()
+	cancel()
+
+	now := time.Now()
+	s.mu.Lock()
+	s.lastCheck = &now
+	if err != nil {
+		s.lastError = err.Error()
+		if s.mode != model.RedisModeDegraded {
+			s.setMode(model.RedisModeDegraded)
+		}
+		s.mu.Unlock()
+		return
+	}
+	if s.mode != model.RedisModeDegraded {
+		s.mu.Unlock()
+		return
+	}
+
+	// Redis恢复：启动时未连通的客户端在此时才注册为全局客户端
+	s.setMode(model.RedisModeRecovering)
+	if global.GVA_REDIS == nil {
+		global.GVA_REDIS = s.client
+	}
+	hooks := append([]func(){}, s.onRecover...)
+	s.mu.Unlock()
+
+	for _, fn := range hooks {
+		s.runHook(fn)
+	}
+
+	s.mu.Lock()
+	// 预热期间Redis再次断开时保持MySQL模式
+	if s.mode == model.RedisModeRecovering {
+		s.setMode(model.RedisModeNormal)
+		s.lastError = ""
+	}
+	s.mu.Unlock()
+}
+
+// runHook 执行预热回调，回调异常不影响切回
+func (s *RedisHealthService) runHook(fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			global.GVA_LOG.Error("Redis恢复预热异常", zap.Any("panic", r))
+		}
+	}()
+	fn()
+}
+
+// setMode 切换模式，调用方需持有锁
+func (s *RedisHealthService) setMode(mode model.RedisMode) {
+	global.GVA_LOG.Warn("点赞/收藏运行模式切换",
+		zap.String("from", string(s.mode)),
+		zap.String("to", string(mode)),
+		zap.String("last_error", s.lastError))
+	s.mode = mode
+	s.since = time.Now()
+}
+
+// 全局Redis健康检查服务实例
+var GlobalRedisHealth = &RedisHealthService{}
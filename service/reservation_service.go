@@ -3,11 +3,14 @@ package service
 import (
 	"bookadmin/global"
 	"bookadmin/model"
+	"encoding/json"
 	"errors"
+	"strconv"
 	"time"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ReservationService struct {
@@ -32,8 +35,9 @@ func (s *ReservationService) CreateReservation(readerID, bookID uint) (*model.Re
 	}()
 
 	// 1. 检查读者是否存在且状态正常
+	// 锁定读者行，同一读者同时预约时依次检查预约数量和重复预约
 	var reader model.Reader
-	if err := tx.First(&reader, readerID).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reader, readerID).Error; err != nil {
 		tx.Rollback()
 		return nil, errors.New("读者不存在")
 	}
@@ -58,7 +62,7 @@ func (s *ReservationService) CreateReservation(readerID, bookID uint) (*model.Re
 		}).
 		Count(&reservationCount)
 
-	maxReservations := GlobalConfigService.GetIntConfig(model.ConfigMaxReservations, reader.MaxReservations)
+	maxReservations := GlobalPolicyService.ResolveForReader(&reader).MaxReservations
 	if int(reservationCount) >= maxReservations {
 		tx.Rollback()
 		return nil, errors.New("已达到最大预约数量")
@@ -84,18 +88,21 @@ func (s *ReservationService) CreateReservation(readerID, bookID uint) (*model.Re
 		return nil, errors.New("您已借阅该书，无需预约")
 	}
 
-	// 6. 检查图书是否存在
+	// 6. 检查图书是否存在（锁定图书行，同一本书的预约依次排队）
 	var book model.Book
-	if err := tx.First(&book, bookID).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&book, bookID).Error; err != nil {
 		tx.Rollback()
 		return nil, errors.New("图书不存在")
 	}
 
-	// 7. 计算队列位置
+	// 7. 计算队列位置（加锁统计，看到其他事务刚提交的预约，队列位置不会重复）
 	var queuePosition int64
-	tx.Model(&model.Reservation{}).
+	if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).Model(&model.Reservation{}).
 		Where("book_id = ? AND status = ?", bookID, model.ReservationStatusPending).
-		Count(&queuePosition)
+		Count(&queuePosition).Error; err != nil {
+		tx.Rollback()
+		return nil, errors.New("预约失败")
+	}
 
 	// 8. 创建预约记录
 	now := time.Now()
@@ -113,10 +120,17 @@ func (s *ReservationService) CreateReservation(readerID, bookID uint) (*model.Re
 		return nil, errors.New("预约失败")
 	}
 
+	// 9. 计入预约榜单
+	if err := enqueueRankingEvent(tx, model.RankingTypeReserve, bookID, "ranking:reserve:"+strconv.FormatUint(uint64(reservation.ID), 10)); err != nil {
+		tx.Rollback()
+		return nil, errors.New("预约失败")
+	}
+
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		return nil, errors.New("预约失败")
 	}
+	GlobalOutboxService.Wake()
 
 	global.GVA_LOG.Info("预约成功", zap.Uint("reader_id", readerID), zap.Uint("book_id", bookID))
 	return &reservation, nil
@@ -138,6 +152,17 @@ func (s *ReservationService) CancelReservation(reservationID, readerID uint) err
 		return errors.New("预约记录不存在")
 	}
 
+	// 先锁定图书行再加锁重新读取，与排队、到书分配依次执行，队列位置按最新数据调整
+	var book model.Book
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&book, reservation.BookID).Error; err != nil {
+		tx.Rollback()
+		return errors.New("图书不存在")
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reservation, reservationID).Error; err != nil {
+		tx.Rollback()
+		return errors.New("预约记录不存在")
+	}
+
 	// 验证是否是本人的预约
 	if reservation.ReaderID != readerID {
 		tx.Rollback()
@@ -162,74 +187,144 @@ func (s *ReservationService) CancelReservation(reservationID, readerID uint) err
 
 	// 更新后续预约的队列位置
 	if reservation.Status == model.ReservationStatusPending {
-		tx.Model(&model.Reservation{}).
+		if err := tx.Model(&model.Reservation{}).
 			Where("book_id = ? AND status = ? AND queue_position > ?",
 				reservation.BookID, model.ReservationStatusPending, reservation.QueuePosition).
-			UpdateColumn("queue_position", gorm.Expr("queue_position - 1"))
+			UpdateColumn("queue_position", gorm.Expr("queue_position - 1")).Error; err != nil {
+			tx.Rollback()
+			return errors.New("取消预约失败")
+		}
+	}
+
+	// 放弃已保留的图书时轮到下一位预约者
+	if reservation.Status == model.ReservationStatusAvailable {
+		if err := s.EnqueueHoldNotify(tx, reservation.BookID, "hold:cancel:"+strconv.FormatUint(uint64(reservation.ID), 10)); err != nil {
+			tx.Rollback()
+			return errors.New("取消预约失败")
+		}
 	}
 
 	if err := tx.Commit().Error; err != nil {
 		return errors.New("取消预约失败")
 	}
+	GlobalOutboxService.Wake()
 
 	global.GVA_LOG.Info("取消预约成功", zap.Uint("reservation_id", reservationID))
 	return nil
 }
 
-// CheckAndNotifyAvailableReservations 检查并通知可取书的预约
-// 当图书归还时调用
-func (s *ReservationService) CheckAndNotifyAvailableReservations(bookID uint) error {
-	// 查找该书的第一个pending预约，同时加载关联的Reader和Book信息
-	var reservation model.Reservation
-	if err := global.GVA_DB.Preload("Reader").Preload("Book").
-		Where("book_id = ? AND status = ?", bookID, model.ReservationStatusPending).
-		Order("queue_position ASC").
-		First(&reservation).Error; err != nil {
-		// 没有预约，正常情况
-		return nil
+// EnqueueHoldNotify 在归还、上架等增加可借库存的事务内写入通知预约者事件
+// 事务回滚时事件一并回滚，不会通知读者实际未归还的库存；key 为幂等键
+func (s *ReservationService) EnqueueHoldNotify(tx *gorm.DB, bookID uint, key string) error {
+	return GlobalOutboxService.Enqueue(tx, model.OutboxHoldNotify, key, model.HoldNotifyPayload{BookID: bookID})
+}
+
+// handleHoldEvent 执行通知预约者事件
+func (s *ReservationService) handleHoldEvent(tx *gorm.DB, e *model.OutboxEvent) (func(), error) {
+	var p model.HoldNotifyPayload
+	if err := json.Unmarshal([]byte(e.Payload), &p); err != nil {
+		return nil, err
 	}
+	notified, err := s.assignAvailableHolds(tx, p.BookID)
+	if err != nil {
+		return nil, err
+	}
+	if notified == 0 {
+		return nil, nil
+	}
+	// 可取书消息已写入同一事务，提交后立即发送
+	return GlobalOutboxService.Wake, nil
+}
 
-	// 检查图书是否有可用库存
+// assignAvailableHolds 按队列顺序将预约改为可取书并写入通知消息，返回本次通知的预约数
+// 可分配数量为可借库存减去已保留给其他预约者的数量，因此同一次归还重复执行也不会多通知
+func (s *ReservationService) assignAvailableHolds(tx *gorm.DB, bookID uint) (int, error) {
 	var book model.Book
-	if err := global.GVA_DB.First(&book, bookID).Error; err != nil {
-		return err
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&book, bookID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, err
 	}
 
-	if book.AvailableStock <= 0 {
-		// 没有可用库存，无法通知
-		return nil
+	var held int64
+	if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).Model(&model.Reservation{}).
+		Where("book_id = ? AND status = ?", bookID, model.ReservationStatusAvailable).
+		Count(&held).Error; err != nil {
+		return 0, err
+	}
+	free := book.AvailableStock - int(held)
+	if free <= 0 {
+		return 0, nil
+	}
+
+	var reservations []model.Reservation
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Reader").
+		Where("book_id = ? AND status = ?", bookID, model.ReservationStatusPending).
+		Order("queue_position ASC, id ASC").
+		Limit(free).
+		Find(&reservations).Error; err != nil {
+		return 0, err
 	}
 
-	// 更新预约状态为可取书
+	// 按队列位置依次分配，成功分配的预约离开队列
+	notified := 0
 	now := time.Now()
-	pickupDays := GlobalConfigService.GetIntConfig(model.ConfigReservationPickupDays, 3)
-	pickupDeadline := now.AddDate(0, 0, pickupDays)
+	for _, reservation := range reservations {
+		pickupDays := GlobalPolicyService.ResolveForReader(&reservation.Reader).PickupDays
+		result := tx.Model(&model.Reservation{}).
+			Where("id = ? AND status = ?", reservation.ID, model.ReservationStatusPending).
+			Updates(map[string]interface{}{
+				"status":          model.ReservationStatusAvailable,
+				"available_date":  now,
+				"pickup_deadline": GlobalCalendarService.DueDate(now, pickupDays),
+			})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
 
-	if err := global.GVA_DB.Model(&reservation).Updates(map[string]interface{}{
-		"status":          model.ReservationStatusAvailable,
-		"available_date":  now,
-		"pickup_deadline": pickupDeadline,
-	}).Error; err != nil {
-		global.GVA_LOG.Error("更新预约状态失败", zap.Error(err))
-		return err
+		// 站内消息随状态变更一起提交
+		if reservation.Reader.UserID > 0 {
+			if err := s.messageService.SendReservationAvailableMessage(tx, reservation.Reader.UserID, book.Title, reservation.ID, pickupDays); err != nil {
+				return 0, err
+			}
+		}
+		notified++
+
+		global.GVA_LOG.Info("预约图书已可取", zap.Uint("reservation_id", reservation.ID), zap.Uint("reader_id", reservation.ReaderID))
 	}
 
-	// 发送站内消息给读者
-	if reservation.Reader.UserID > 0 {
-		bookTitle := reservation.Book.Title
-		if err := s.messageService.SendReservationAvailableMessage(
-			reservation.Reader.UserID,
-			bookTitle,
-			reservation.ID,
-			pickupDays,
-		); err != nil {
-			global.GVA_LOG.Error("发送预约通知消息失败", zap.Error(err))
-			// 不影响主流程，继续执行
+	// 离开队列后重新编号，队列位置保持从1开始连续
+	if notified > 0 {
+		if err := s.renumberQueue(tx, bookID); err != nil {
+			return 0, err
 		}
 	}
 
-	global.GVA_LOG.Info("预约图书已可取", zap.Uint("reservation_id", reservation.ID), zap.Uint("reader_id", reservation.ReaderID))
+	return notified, nil
+}
 
+// renumberQueue 按原队列顺序把排队中的预约重新编号为 1..n（需在锁定图书后调用）
+func (s *ReservationService) renumberQueue(tx *gorm.DB, bookID uint) error {
+	var pending []model.Reservation
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "queue_position").
+		Where("book_id = ? AND status = ?", bookID, model.ReservationStatusPending).
+		Order("queue_position ASC, id ASC").
+		Find(&pending).Error; err != nil {
+		return err
+	}
+	for i, reservation := range pending {
+		if reservation.QueuePosition == i+1 {
+			continue
+		}
+		if err := tx.Model(&model.Reservation{}).Where("id = ?", reservation.ID).
+			UpdateColumn("queue_position", i+1).Error; err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -249,33 +344,35 @@ func (s *ReservationService) CheckExpiredReservations() error {
 		return nil
 	}
 
-	// 批量更新为expired
-	reservationIDs := make([]uint, len(expiredReservations))
-	for i, r := range expiredReservations {
-		reservationIDs[i] = r.ID
-	}
-
-	if err := global.GVA_DB.Model(&model.Reservation{}).
-		Where("id IN ?", reservationIDs).
-		Updates(map[string]interface{}{
-			"status":         model.ReservationStatusExpired,
-			"fulfilled_date": now,
-		}).Error; err != nil {
+	// 逐条改为expired，并在同一事务内为每本书写入通知下一个预约者的事件
+	expired := 0
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		for _, r := range expiredReservations {
+			result := tx.Model(&model.Reservation{}).
+				Where("id = ? AND status = ?", r.ID, model.ReservationStatusAvailable).
+				Updates(map[string]interface{}{
+					"status":         model.ReservationStatusExpired,
+					"fulfilled_date": now,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+			if err := s.EnqueueHoldNotify(tx, r.BookID, "hold:expire:"+strconv.FormatUint(uint64(r.ID), 10)); err != nil {
+				return err
+			}
+			expired++
+		}
+		return nil
+	})
+	if err != nil {
 		return err
 	}
+	GlobalOutboxService.Wake()
 
-	global.GVA_LOG.Info("处理过期预约", zap.Int("count", len(expiredReservations)))
-
-	// 对于每本书，通知下一个预约者
-	bookIDs := make(map[uint]bool)
-	for _, r := range expiredReservations {
-		bookIDs[r.BookID] = true
-	}
-
-	for bookID := range bookIDs {
-		s.CheckAndNotifyAvailableReservations(bookID)
-	}
-
+	global.GVA_LOG.Info("处理过期预约", zap.Int("count", expired))
 	return nil
 }
 
@@ -0,0 +1,360 @@
+package service
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// maxReviewLength 评价内容最大字数
+const maxReviewLength = 2000
+
+// ReviewService 图书评价服务
+type ReviewService struct{}
+
+// ReviewQuery 评价列表查询条件
+type ReviewQuery struct {
+	BookID   uint
+	UserID   uint
+	Status   model.ReviewStatus
+	Reported bool // 只看有未处理举报的评价
+	Sort     model.ReviewSort
+	Page     int
+	PageSize int
+}
+
+// SubmitReview 发表或修改评价，只有归还过该书的读者才能评价
+func (s *ReviewService) SubmitReview(userID, bookID uint, rating int, content string) (*model.BookReview, error) {
+	if rating < 1 || rating > 5 {
+		return nil, errors.New("评分必须为1-5星")
+	}
+	content = strings.TrimSpace(content)
+	if utf8.RuneCountInString(content) > maxReviewLength {
+		return nil, errors.New("评价内容不能超过2000字")
+	}
+
+	// 1. 检查图书是否存在
+	var book model.Book
+	if err := global.GVA_DB.First(&book, bookID).Error; err != nil {
+		return nil, errors.New("图书不存在")
+	}
+
+	// 2. 检查是否归还过该书
+	var borrowed int64
+	global.GVA_DB.Model(&model.BorrowRecord{}).
+		Joins("JOIN readers ON readers.id = borrow_records.reader_id").
+		Where("readers.user_id = ? AND borrow_records.book_id = ? AND borrow_records.status = ?",
+			userID, bookID, model.BorrowStatusReturned).
+		Count(&borrowed)
+	if borrowed == 0 {
+		return nil, errors.New("归还该书后才能评价")
+	}
+
+	status := model.ReviewStatusApproved
+	if GlobalConfigService.GetBoolConfig(model.ConfigReviewRequireApproval, false) {
+		status = model.ReviewStatusPending
+	}
+
+	// 3. 新建或修改评价，并重新计算图书评分
+	var review model.BookReview
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("book_id = ? AND user_id = ?", bookID, userID).First(&review).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			review = model.BookReview{
+				BookID:  bookID,
+				UserID:  userID,
+				Rating:  rating,
+				Content: content,
+				Status:  status,
+			}
+			if err := tx.Create(&review).Error; err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		} else {
+			// 被隐藏的评价修改后仍需审核
+			if review.Status == model.ReviewStatusHidden {
+				status = model.ReviewStatusPending
+			}
+			if err := tx.Model(&review).Updates(map[string]interface{}{
+				"rating":  rating,
+				"content": content,
+				"status":  status,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		return s.refreshBookRating(tx, bookID)
+	})
+	if err != nil {
+		global.GVA_LOG.Error("保存评价失败", zap.Uint("user_id", userID), zap.Uint("book_id", bookID), zap.Error(err))
+		return nil, errors.New("保存评价失败")
+	}
+
+	invalidateRankingBoards(model.RankingTypeRating, bookID)
+	return s.GetReview(review.ID)
+}
+
+// DeleteReview 删除自己的评价
+func (s *ReviewService) DeleteReview(userID, reviewID uint) error {
+	var review model.BookReview
+	if err := global.GVA_DB.First(&review, reviewID).Error; err != nil {
+		return errors.New("评价不存在")
+	}
+	if review.UserID != userID {
+		return errors.New("只能删除自己的评价")
+	}
+
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("review_id = ?", review.ID).Delete(&model.BookReviewReport{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&review).Error; err != nil {
+			return err
+		}
+		return s.refreshBookRating(tx, review.BookID)
+	})
+	if err != nil {
+		global.GVA_LOG.Error("删除评价失败", zap.Uint("review_id", reviewID), zap.Error(err))
+		return errors.New("删除评价失败")
+	}
+
+	invalidateRankingBoards(model.RankingTypeRating, review.BookID)
+	return nil
+}
+
+// ReportReview 举报评价，举报次数达到阈值后转为待审核，暂不公开
+func (s *ReviewService) ReportReview(userID, reviewID uint, reason string) error {
+	var review model.BookReview
+	if err := global.GVA_DB.First(&review, reviewID).Error; err != nil {
+		return errors.New("评价不存在")
+	}
+	if review.Status != model.ReviewStatusApproved {
+		return errors.New("评价不存在")
+	}
+	if review.UserID == userID {
+		return errors.New("不能举报自己的评价")
+	}
+
+	threshold := GlobalConfigService.GetIntConfig(model.ConfigReviewReportThreshold, 3)
+	var suspended bool
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		var exists int64
+		tx.Model(&model.BookReviewReport{}).Where("review_id = ? AND user_id = ?", reviewID, userID).Count(&exists)
+		if exists > 0 {
+			return errors.New("您已举报过该评价")
+		}
+
+		report := model.BookReviewReport{
+			ReviewID: reviewID,
+			UserID:   userID,
+			Reason:   strings.TrimSpace(reason),
+		}
+		if err := tx.Create(&report).Error; err != nil {
+			return err
+		}
+
+		// 条件更新，避免并发举报时重复计数或覆盖审核结果
+		if err := tx.Model(&model.BookReview{}).Where("id = ?", reviewID).
+			Update("report_count", gorm.Expr("report_count + 1")).Error; err != nil {
+			return err
+		}
+		if threshold > 0 {
+			result := tx.Model(&model.BookReview{}).
+				Where("id = ? AND status = ? AND report_count >= ?", reviewID, model.ReviewStatusApproved, threshold).
+				Update("status", model.ReviewStatusPending)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				suspended = true
+				return s.refreshBookRating(tx, review.BookID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if err.Error() == "您已举报过该评价" {
+			return err
+		}
+		global.GVA_LOG.Error("举报评价失败", zap.Uint("review_id", reviewID), zap.Error(err))
+		return errors.New("举报失败")
+	}
+
+	if suspended {
+		global.GVA_LOG.Info("评价举报次数达到阈值，转为待审核", zap.Uint("review_id", reviewID))
+		invalidateRankingBoards(model.RankingTypeRating, review.BookID)
+	}
+	return nil
+}
+
+// ModerateReview 审核评价：approve 公开并清除举报，hide 隐藏
+func (s *ReviewService) ModerateReview(reviewID uint, action, note string, actor model.AuditActor) (*model.BookReview, error) {
+	var status model.ReviewStatus
+	switch action {
+	case "approve":
+		status = model.ReviewStatusApproved
+	case "hide":
+		status = model.ReviewStatusHidden
+	default:
+		return nil, errors.New("不支持的审核操作")
+	}
+
+	var review model.BookReview
+	if err := global.GVA_DB.First(&review, reviewID).Error; err != nil {
+		return nil, errors.New("评价不存在")
+	}
+	before := map[string]interface{}{
+		"status":       review.Status,
+		"report_count": review.ReportCount,
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":          status,
+		"moderator_id":    actor.UserID,
+		"moderated_at":    &now,
+		"moderation_note": strings.TrimSpace(note),
+	}
+	if status == model.ReviewStatusApproved {
+		updates["report_count"] = 0
+	}
+
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&review).Updates(updates).Error; err != nil {
+			return err
+		}
+		// 审核后举报视为已处理
+		if err := tx.Model(&model.BookReviewReport{}).Where("review_id = ? AND handled = ?", reviewID, false).
+			Update("handled", true).Error; err != nil {
+			return err
+		}
+		if err := s.refreshBookRating(tx, review.BookID); err != nil {
+			return err
+		}
+		return GlobalAuditService.Record(tx, actor, model.AuditReviewModerate, "book_review", review.ID, before, map[string]interface{}{
+			"action": action,
+			"status": status,
+			"note":   updates["moderation_note"],
+		})
+	})
+	if err != nil {
+		global.GVA_LOG.Error("审核评价失败", zap.Uint("review_id", reviewID), zap.Error(err))
+		return nil, errors.New("审核评价失败")
+	}
+
+	invalidateRankingBoards(model.RankingTypeRating, review.BookID)
+	return s.GetReview(review.ID)
+}
+
+// GetReview 获取评价详情
+func (s *ReviewService) GetReview(reviewID uint) (*model.BookReview, error) {
+	var review model.BookReview
+	if err := s.reviewQuery(global.GVA_DB).Where("book_reviews.id = ?", reviewID).First(&review).Error; err != nil {
+		return nil, errors.New("评价不存在")
+	}
+	return &review, nil
+}
+
+// ListReviews 分页查询评价
+func (s *ReviewService) ListReviews(q ReviewQuery) ([]model.BookReview, int64, error) {
+	db := global.GVA_DB.Model(&model.BookReview{})
+	if q.BookID > 0 {
+		db = db.Where("book_reviews.book_id = ?", q.BookID)
+	}
+	if q.UserID > 0 {
+		db = db.Where("book_reviews.user_id = ?", q.UserID)
+	}
+	if q.Status != "" {
+		db = db.Where("book_reviews.status = ?", q.Status)
+	}
+	if q.Reported {
+		db = db.Where("book_reviews.report_count > 0")
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var reviews []model.BookReview
+	err := s.reviewQuery(db).
+		Order(reviewOrder(q.Sort)).
+		Offset((q.Page - 1) * q.PageSize).
+		Limit(q.PageSize).
+		Find(&reviews).Error
+	return reviews, total, err
+}
+
+// GetRatingSummary 图书评分汇总及各星级人数（只统计已公开的评价）
+func (s *ReviewService) GetRatingSummary(bookID uint) (*model.RatingSummary, error) {
+	var book model.Book
+	if err := global.GVA_DB.Select("id, rating_avg, rating_count").First(&book, bookID).Error; err != nil {
+		return nil, errors.New("图书不存在")
+	}
+
+	var rows []struct {
+		Rating int
+		Count  int
+	}
+	if err := global.GVA_DB.Model(&model.BookReview{}).
+		Select("rating, COUNT(*) AS count").
+		Where("book_id = ? AND status = ?", bookID, model.ReviewStatusApproved).
+		Group("rating").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	summary := &model.RatingSummary{
+		BookID:       bookID,
+		RatingAvg:    book.RatingAvg,
+		RatingCount:  book.RatingCount,
+		Distribution: map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0},
+	}
+	for _, row := range rows {
+		summary.Distribution[row.Rating] = row.Count
+	}
+	return summary, nil
+}
+
+// refreshBookRating 按已公开的评价重新计算图书的平均评分和评分人数
+func (s *ReviewService) refreshBookRating(tx *gorm.DB, bookID uint) error {
+	return tx.Exec(`UPDATE books SET
+		rating_count = (SELECT COUNT(*) FROM book_reviews WHERE book_id = ? AND status = ?),
+		rating_avg = (SELECT COALESCE(ROUND(AVG(rating), 2), 0) FROM book_reviews WHERE book_id = ? AND status = ?)
+		WHERE id = ?`,
+		bookID, model.ReviewStatusApproved, bookID, model.ReviewStatusApproved, bookID).Error
+}
+
+// reviewQuery 关联用户表取评价人用户名
+func (s *ReviewService) reviewQuery(db *gorm.DB) *gorm.DB {
+	return db.Select("book_reviews.*, users.username").
+		Joins("LEFT JOIN users ON users.id = book_reviews.user_id")
+}
+
+// reviewOrder 评价列表排序
+func reviewOrder(sort model.ReviewSort) string {
+	switch sort {
+	case model.ReviewSortOldest:
+		return "book_reviews.created_at ASC, book_reviews.id ASC"
+	case model.ReviewSortRatingDesc:
+		return "book_reviews.rating DESC, book_reviews.created_at DESC"
+	case model.ReviewSortRatingAsc:
+		return "book_reviews.rating ASC, book_reviews.created_at DESC"
+	case model.ReviewSortReports:
+		return "book_reviews.report_count DESC, book_reviews.created_at DESC"
+	default:
+		return "book_reviews.created_at DESC, book_reviews.id DESC"
+	}
+}
+
+// 全局图书评价服务实例
+var GlobalReviewService = &ReviewService{}
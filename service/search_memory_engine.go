@@ -0,0 +1,446 @@
+package service
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// 内置索引各字段的权重
+var memorySearchFieldWeights = []struct {
+	field  func(b *model.Book) string
+	weight float64
+}{
+	{func(b *model.Book) string { return b.Title }, 3},
+	{func(b *model.Book) string { return b.Author }, 2},
+	{func(b *model.Book) string { return b.Publisher }, 1},
+	{func(b *model.Book) string { return b.Description }, 1},
+}
+
+// memorySearchDoc 内置索引中的一本书
+// 可借库存和评分变化频繁，不进索引，搜索时从MySQL读取
+type memorySearchDoc struct {
+	ID         uint
+	ISBN       string
+	Publisher  string
+	Year       int // 出版年份，无法识别时为0
+	Price      float64
+	CreatedAt  time.Time
+	Categories []uint
+	Terms      map[string]float64 // 检索词 → 命中字段的权重之和
+}
+
+// memorySearchLive 搜索时读取的实时字段
+type memorySearchLive struct {
+	ID             uint
+	AvailableStock int
+	RatingAvg      float64
+	RatingCount    int
+}
+
+// memorySearchEngine 进程内倒排索引，不依赖MySQL全文索引，适合数万册以内的馆藏
+// 多实例部署时每个实例各自维护索引，其他实例的修改在定时重建后生效
+type memorySearchEngine struct {
+	mu       sync.RWMutex
+	docs     map[uint]*memorySearchDoc
+	postings map[string]map[uint]float64 // 检索词 → 图书ID → 命中字段的权重之和
+}
+
+func newMemorySearchEngine() *memorySearchEngine {
+	return &memorySearchEngine{
+		docs:     make(map[uint]*memorySearchDoc),
+		postings: make(map[string]map[uint]float64),
+	}
+}
+
+func (e *memorySearchEngine) Name() string {
+	return "memory"
+}
+
+// Init 加载全部图书建立索引
+func (e *memorySearchEngine) Init() error {
+	return e.Rebuild()
+}
+
+// Rebuild 重新加载全部图书，建好后整体替换
+func (e *memorySearchEngine) Rebuild() error {
+	docs := make(map[uint]*memorySearchDoc)
+	postings := make(map[string]map[uint]float64)
+
+	var books []model.Book
+	err := global.GVA_DB.Preload("Categories").FindInBatches(&books, 500, func(tx *gorm.DB, batch int) error {
+		for i := range books {
+			doc := newMemorySearchDoc(&books[i])
+			docs[doc.ID] = doc
+			addPostings(postings, doc)
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.docs = docs
+	e.postings = postings
+	e.mu.Unlock()
+	return nil
+}
+
+// Index 重新读取图书并更新索引，已删除的图书从索引中移除
+func (e *memorySearchEngine) Index(bookIDs ...uint) error {
+	var books []model.Book
+	if err := global.GVA_DB.Preload("Categories").Where("id IN ?", bookIDs).Find(&books).Error; err != nil {
+		return err
+	}
+	found := make(map[uint]bool, len(books))
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i := range books {
+		found[books[i].ID] = true
+		e.remove(books[i].ID)
+		doc := newMemorySearchDoc(&books[i])
+		e.docs[doc.ID] = doc
+		addPostings(e.postings, doc)
+	}
+	for _, id := range bookIDs {
+		if !found[id] {
+			e.remove(id)
+		}
+	}
+	return nil
+}
+
+// Remove 从索引中移除图书
+func (e *memorySearchEngine) Remove(bookIDs ...uint) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, id := range bookIDs {
+		e.remove(id)
+	}
+	return nil
+}
+
+// remove 移除一本书的倒排记录，调用方需持有写锁
+func (e *memorySearchEngine) remove(id uint) {
+	doc, ok := e.docs[id]
+	if !ok {
+		return
+	}
+	for term := range doc.Terms {
+		delete(e.postings[term], id)
+		if len(e.postings[term]) == 0 {
+			delete(e.postings, term)
+		}
+	}
+	delete(e.docs, id)
+}
+
+// Search 匹配、筛选、排序后返回当前页
+func (e *memorySearchEngine) Search(q model.SearchQuery) ([]searchMatch, int64, error) {
+	live, err := e.loadLive()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var matches []searchMatch
+	for id, score := range e.match(q.Keyword) {
+		doc := e.docs[id]
+		l, ok := live[id]
+		if !ok || !doc.passes(q, l, "") {
+			continue
+		}
+		matches = append(matches, searchMatch{BookID: id, Score: score})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		da, db := e.docs[a.BookID], e.docs[b.BookID]
+		switch q.Sort {
+		case model.SearchSortRelevance:
+			if a.Score != b.Score {
+				return a.Score > b.Score
+			}
+		case model.SearchSortPriceAsc:
+			if da.Price != db.Price {
+				return da.Price < db.Price
+			}
+			return a.BookID < b.BookID
+		case model.SearchSortPriceDesc:
+			if da.Price != db.Price {
+				return da.Price > db.Price
+			}
+		case model.SearchSortRating:
+			la, lb := live[a.BookID], live[b.BookID]
+			if la.RatingAvg != lb.RatingAvg {
+				return la.RatingAvg > lb.RatingAvg
+			}
+			if la.RatingCount != lb.RatingCount {
+				return la.RatingCount > lb.RatingCount
+			}
+		default:
+			if !da.CreatedAt.Equal(db.CreatedAt) {
+				return da.CreatedAt.After(db.CreatedAt)
+			}
+		}
+		return a.BookID > b.BookID
+	})
+
+	total := int64(len(matches))
+	start := (q.Page - 1) * q.PageSize
+	if start >= len(matches) {
+		return nil, total, nil
+	}
+	end := start + q.PageSize
+	if end > len(matches) {
+		end = len(matches)
+	}
+
+	page := matches[start:end]
+	for i := range page {
+		page[i].Score = math.Round(page[i].Score*10000) / 10000
+	}
+	return page, total, nil
+}
+
+// Facets 分面统计
+func (e *memorySearchEngine) Facets(q model.SearchQuery) (*model.SearchFacets, error) {
+	live, err := e.loadLive()
+	if err != nil {
+		return nil, err
+	}
+
+	categoryCounts := make(map[uint]int64)
+	publisherCounts := make(map[string]int64)
+	yearCounts := make(map[int]int64)
+	var available, unavailable int64
+
+	e.mu.RLock()
+	for id := range e.match(q.Keyword) {
+		doc := e.docs[id]
+		l, ok := live[id]
+		if !ok {
+			continue
+		}
+		if doc.passes(q, l, facetCategory) {
+			for _, c := range doc.Categories {
+				categoryCounts[c]++
+			}
+		}
+		if doc.Publisher != "" && doc.passes(q, l, facetPublisher) {
+			publisherCounts[doc.Publisher]++
+		}
+		if doc.Year > 0 && doc.passes(q, l, facetYear) {
+			yearCounts[doc.Year]++
+		}
+		if doc.passes(q, l, facetAvailability) {
+			if l.AvailableStock > 0 {
+				available++
+			} else {
+				unavailable++
+			}
+		}
+	}
+	e.mu.RUnlock()
+
+	facets := &model.SearchFacets{
+		Categories:   []model.FacetCount{},
+		Publishers:   []model.FacetCount{},
+		Years:        []model.FacetCount{},
+		Availability: availabilityFacets(available, unavailable),
+	}
+
+	if len(categoryCounts) > 0 {
+		ids := make([]uint, 0, len(categoryCounts))
+		for id := range categoryCounts {
+			ids = append(ids, id)
+		}
+		var categories []model.Category
+		if err := global.GVA_DB.Where("id IN ?", ids).Find(&categories).Error; err != nil {
+			return nil, err
+		}
+		for _, c := range categories {
+			facets.Categories = append(facets.Categories, model.FacetCount{
+				Value: strconv.FormatUint(uint64(c.ID), 10), Label: c.Name, Count: categoryCounts[c.ID]})
+		}
+		sortFacetsByCount(facets.Categories)
+	}
+
+	for publisher, count := range publisherCounts {
+		facets.Publishers = append(facets.Publishers, model.FacetCount{Value: publisher, Label: publisher, Count: count})
+	}
+	sortFacetsByCount(facets.Publishers)
+
+	for year, count := range yearCounts {
+		value := strconv.Itoa(year)
+		facets.Years = append(facets.Years, model.FacetCount{Value: value, Label: value, Count: count})
+	}
+	sort.Slice(facets.Years, func(i, j int) bool { return facets.Years[i].Value > facets.Years[j].Value })
+
+	facets.Categories = limitFacets(facets.Categories)
+	facets.Publishers = limitFacets(facets.Publishers)
+	facets.Years = limitFacets(facets.Years)
+	return facets, nil
+}
+
+// match 按关键词匹配：各检索词按 字段权重 × IDF 累加，任一检索词命中即可；ISBN完全一致时排在最前，前缀一致的也算命中
+// 没有关键词时返回全部图书，调用方需持有读锁
+func (e *memorySearchEngine) match(keyword string) map[uint]float64 {
+	scores := make(map[uint]float64)
+	if keyword == "" {
+		for id := range e.docs {
+			scores[id] = 0
+		}
+		return scores
+	}
+
+	n := float64(len(e.docs))
+	for _, term := range searchTerms(keyword) {
+		posting := e.postings[term]
+		if len(posting) == 0 {
+			continue
+		}
+		idf := math.Log(1 + n/float64(len(posting)))
+		for id, tf := range posting {
+			scores[id] += tf * idf
+		}
+	}
+
+	isbn := normalizeSearchISBN(keyword)
+	for id, doc := range e.docs {
+		switch {
+		case doc.ISBN == "":
+		case doc.ISBN == isbn:
+			scores[id] += 100
+		case strings.HasPrefix(doc.ISBN, isbn):
+			scores[id] += 0
+		}
+	}
+	return scores
+}
+
+// loadLive 读取全部未删除图书的可借库存和评分
+func (e *memorySearchEngine) loadLive() (map[uint]memorySearchLive, error) {
+	var rows []memorySearchLive
+	if err := global.GVA_DB.Model(&model.Book{}).
+		Select("id, available_stock, rating_avg, rating_count").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	live := make(map[uint]memorySearchLive, len(rows))
+	for _, row := range rows {
+		live[row.ID] = row
+	}
+	return live, nil
+}
+
+// passes 判断图书是否满足筛选条件，skip 为统计分面时忽略的维度
+func (d *memorySearchDoc) passes(q model.SearchQuery, live memorySearchLive, skip string) bool {
+	if q.CategoryID > 0 && skip != facetCategory {
+		found := false
+		for _, c := range d.Categories {
+			if c == q.CategoryID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.Publisher != "" && skip != facetPublisher && d.Publisher != q.Publisher {
+		return false
+	}
+	if (q.YearFrom > 0 || q.YearTo > 0) && skip != facetYear {
+		if d.Year == 0 || (q.YearFrom > 0 && d.Year < q.YearFrom) || (q.YearTo > 0 && d.Year > q.YearTo) {
+			return false
+		}
+	}
+	if q.Available != nil && skip != facetAvailability && (live.AvailableStock > 0) != *q.Available {
+		return false
+	}
+	if q.PriceMin != nil && d.Price < *q.PriceMin {
+		return false
+	}
+	if q.PriceMax != nil && d.Price > *q.PriceMax {
+		return false
+	}
+	return true
+}
+
+// newMemorySearchDoc 切分图书的各字段生成索引文档
+func newMemorySearchDoc(book *model.Book) *memorySearchDoc {
+	doc := &memorySearchDoc{
+		ID:        book.ID,
+		ISBN:      normalizeSearchISBN(book.ISBN),
+		Publisher: book.Publisher,
+		Price:     book.Price,
+		CreatedAt: book.CreatedAt,
+		Terms:     make(map[string]float64),
+	}
+	if len(book.PublishDate) >= 4 {
+		if year, err := strconv.Atoi(book.PublishDate[:4]); err == nil && year > 0 {
+			doc.Year = year
+		}
+	}
+	for _, c := range book.Categories {
+		doc.Categories = append(doc.Categories, c.ID)
+	}
+	for _, f := range memorySearchFieldWeights {
+		text := f.field(book)
+		terms := make(map[string]bool)
+		for _, term := range searchTerms(text) {
+			terms[term] = true
+		}
+		// 单个汉字也建索引，使单字关键词可以命中
+		for _, r := range strings.ToLower(text) {
+			if unicode.Is(unicode.Han, r) {
+				terms[string(r)] = true
+			}
+		}
+		for term := range terms {
+			doc.Terms[term] += f.weight
+		}
+	}
+	return doc
+}
+
+// addPostings 将文档的检索词写入倒排表
+func addPostings(postings map[string]map[uint]float64, doc *memorySearchDoc) {
+	for term, tf := range doc.Terms {
+		if postings[term] == nil {
+			postings[term] = make(map[uint]float64)
+		}
+		postings[term][doc.ID] = tf
+	}
+}
+
+// sortFacetsByCount 按数量从多到少排序
+func sortFacetsByCount(items []model.FacetCount) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Value < items[j].Value
+	})
+}
+
+// limitFacets 每个维度最多返回 searchFacetLimit 项
+func limitFacets(items []model.FacetCount) []model.FacetCount {
+	if len(items) > searchFacetLimit {
+		return items[:searchFacetLimit]
+	}
+	return items
+}
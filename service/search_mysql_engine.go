@@ -0,0 +1,210 @@
+package service
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// searchFulltextIndex 图书全文索引名称
+const searchFulltextIndex = "idx_books_fulltext"
+
+// searchMatchExpr 全文匹配表达式，列必须与全文索引一致
+const searchMatchExpr = "MATCH(books.title, books.author, books.publisher, books.description) AGAINST (? IN NATURAL LANGUAGE MODE)"
+
+// mysqlSearchEngine 基于MySQL FULLTEXT（ngram分词）的搜索引擎，索引由MySQL随写入自动维护
+type mysqlSearchEngine struct{}
+
+func (e *mysqlSearchEngine) Name() string {
+	return "mysql"
+}
+
+// Init 创建全文索引（ngram分词，支持中文）
+func (e *mysqlSearchEngine) Init() error {
+	var count int64
+	if err := global.GVA_DB.Raw(`SELECT COUNT(*) FROM information_schema.statistics
+		WHERE table_schema = DATABASE() AND table_name = 'books' AND index_name = ?`, searchFulltextIndex).
+		Scan(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	global.GVA_LOG.Info("创建图书全文索引，数据量较大时需要一些时间")
+	return global.GVA_DB.Exec("CREATE FULLTEXT INDEX " + searchFulltextIndex +
+		" ON books (title, author, publisher, description) WITH PARSER ngram").Error
+}
+
+// Search 按条件查询当前页
+func (e *mysqlSearchEngine) Search(q model.SearchQuery) ([]searchMatch, int64, error) {
+	var total int64
+	if err := e.filtered(q, "").Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return nil, 0, nil
+	}
+
+	scoreExpr, scoreArgs := e.score(q.Keyword)
+	var order string
+	switch q.Sort {
+	case model.SearchSortRelevance:
+		order = "score DESC, books.id DESC"
+	case model.SearchSortPriceAsc:
+		order = "books.price ASC, books.id ASC"
+	case model.SearchSortPriceDesc:
+		order = "books.price DESC, books.id DESC"
+	case model.SearchSortRating:
+		order = "books.rating_avg DESC, books.rating_count DESC, books.id DESC"
+	default:
+		order = "books.created_at DESC, books.id DESC"
+	}
+
+	var matches []searchMatch
+	err := e.filtered(q, "").
+		Select("books.id AS book_id, "+scoreExpr+" AS score", scoreArgs...).
+		Order(order).
+		Limit(q.PageSize).Offset((q.Page - 1) * q.PageSize).
+		Scan(&matches).Error
+	return matches, total, err
+}
+
+// Facets 分面统计
+func (e *mysqlSearchEngine) Facets(q model.SearchQuery) (*model.SearchFacets, error) {
+	facets := &model.SearchFacets{}
+
+	if err := e.filtered(q, facetCategory).
+		Joins("JOIN book_categories fc ON fc.book_id = books.id").
+		Joins("JOIN categories c ON c.id = fc.category_id AND c.deleted_at IS NULL").
+		Select("c.id AS value, c.name AS label, COUNT(*) AS count").
+		Group("c.id, c.name").
+		Order("count DESC, c.id ASC").
+		Limit(searchFacetLimit).
+		Scan(&facets.Categories).Error; err != nil {
+		return nil, err
+	}
+
+	if err := e.filtered(q, facetPublisher).
+		Where("books.publisher <> ''").
+		Select("books.publisher AS value, books.publisher AS label, COUNT(*) AS count").
+		Group("books.publisher").
+		Order("count DESC, books.publisher ASC").
+		Limit(searchFacetLimit).
+		Scan(&facets.Publishers).Error; err != nil {
+		return nil, err
+	}
+
+	if err := e.filtered(q, facetYear).
+		Where("books.publish_date REGEXP '^[0-9]{4}'").
+		Select("LEFT(books.publish_date, 4) AS value, LEFT(books.publish_date, 4) AS label, COUNT(*) AS count").
+		Group("LEFT(books.publish_date, 4)").
+		Order("value DESC").
+		Limit(searchFacetLimit).
+		Scan(&facets.Years).Error; err != nil {
+		return nil, err
+	}
+
+	var availability struct {
+		Available   int64
+		Unavailable int64
+	}
+	if err := e.filtered(q, facetAvailability).
+		Select("COALESCE(SUM(books.available_stock > 0), 0) AS available, COALESCE(SUM(books.available_stock <= 0), 0) AS unavailable").
+		Scan(&availability).Error; err != nil {
+		return nil, err
+	}
+	facets.Availability = availabilityFacets(availability.Available, availability.Unavailable)
+
+	// 没有结果的维度返回空数组
+	for _, items := range []*[]model.FacetCount{&facets.Categories, &facets.Publishers, &facets.Years} {
+		if *items == nil {
+			*items = []model.FacetCount{}
+		}
+	}
+	return facets, nil
+}
+
+// Index MySQL全文索引随写入自动维护
+func (e *mysqlSearchEngine) Index(bookIDs ...uint) error {
+	return nil
+}
+
+// Remove MySQL全文索引随删除自动维护
+func (e *mysqlSearchEngine) Remove(bookIDs ...uint) error {
+	return nil
+}
+
+// Rebuild 确认全文索引存在
+func (e *mysqlSearchEngine) Rebuild() error {
+	return e.Init()
+}
+
+// filtered 按关键词和筛选条件生成查询，skip 为统计分面时忽略的维度
+func (e *mysqlSearchEngine) filtered(q model.SearchQuery, skip string) *gorm.DB {
+	db := global.GVA_DB.Model(&model.Book{})
+
+	if q.Keyword != "" {
+		if utf8.RuneCountInString(q.Keyword) < 2 {
+			// 短于ngram分词长度的关键词无法使用全文索引
+			like := "%" + q.Keyword + "%"
+			db = db.Where("(books.title LIKE ? OR books.author LIKE ? OR books.publisher LIKE ?)", like, like, like)
+		} else {
+			db = db.Where("("+searchMatchExpr+" OR books.isbn LIKE ?)", q.Keyword, normalizeSearchISBN(q.Keyword)+"%")
+		}
+	}
+	if q.CategoryID > 0 && skip != facetCategory {
+		db = db.Where("EXISTS (SELECT 1 FROM book_categories bc WHERE bc.book_id = books.id AND bc.category_id = ?)", q.CategoryID)
+	}
+	if q.Publisher != "" && skip != facetPublisher {
+		db = db.Where("books.publisher = ?", q.Publisher)
+	}
+	if (q.YearFrom > 0 || q.YearTo > 0) && skip != facetYear {
+		db = db.Where("books.publish_date REGEXP '^[0-9]{4}'")
+		if q.YearFrom > 0 {
+			db = db.Where("LEFT(books.publish_date, 4) >= ?", fmt.Sprintf("%04d", q.YearFrom))
+		}
+		if q.YearTo > 0 {
+			db = db.Where("LEFT(books.publish_date, 4) <= ?", fmt.Sprintf("%04d", q.YearTo))
+		}
+	}
+	if q.Available != nil && skip != facetAvailability {
+		if *q.Available {
+			db = db.Where("books.available_stock > 0")
+		} else {
+			db = db.Where("books.available_stock <= 0")
+		}
+	}
+	if q.PriceMin != nil {
+		db = db.Where("books.price >= ?", *q.PriceMin)
+	}
+	if q.PriceMax != nil {
+		db = db.Where("books.price <= ?", *q.PriceMax)
+	}
+	return db
+}
+
+// score 相关度表达式：全文匹配得分，ISBN完全一致时排在最前，ISBN前缀匹配的只按全文得分
+func (e *mysqlSearchEngine) score(keyword string) (string, []interface{}) {
+	switch {
+	case keyword == "":
+		return "0", nil
+	case utf8.RuneCountInString(keyword) < 2:
+		like := "%" + keyword + "%"
+		return "(books.title LIKE ?) * 3 + (books.author LIKE ?) * 2 + (books.publisher LIKE ?)", []interface{}{like, like, like}
+	default:
+		return searchMatchExpr + " + (books.isbn = ?) * 100", []interface{}{keyword, normalizeSearchISBN(keyword)}
+	}
+}
+
+// availabilityFacets 可借状态分面
+func availabilityFacets(available, unavailable int64) []model.FacetCount {
+	return []model.FacetCount{
+		{Value: strconv.FormatBool(true), Label: "可借", Count: available},
+		{Value: strconv.FormatBool(false), Label: "已借完", Count: unavailable},
+	}
+}
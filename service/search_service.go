@@ -0,0 +1,349 @@
+package service
+
+import (
+	"bookadmin/global"
+	"bookadmin/model"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+	"unicode"
+
+	"go.uber.org/zap"
+)
+
+// 搜索参数
+const (
+	searchMaxPageSize = 100 // 每页最多返回的条数
+	searchFacetLimit  = 20  // 每个分面维度最多返回的项数
+	searchSnippetLen  = 120 // 简介高亮片段的长度（字符数）
+)
+
+// 分面维度，统计某个维度时忽略该维度自身的筛选条件
+const (
+	facetCategory     = "category"
+	facetPublisher    = "publisher"
+	facetYear         = "year"
+	facetAvailability = "availability"
+)
+
+// ErrSearchQuery 搜索条件无效
+var ErrSearchQuery = errors.New("搜索条件无效")
+
+// SearchEngine 图书搜索引擎
+// 引擎只负责匹配、筛选、排序和分面统计，返回图书ID；图书详情和高亮由 SearchService 统一处理
+type SearchEngine interface {
+	// Name 引擎名称
+	Name() string
+	// Init 启动时调用，建立索引
+	Init() error
+	// Search 返回当前页的图书ID和相关度，以及符合条件的总数
+	Search(q model.SearchQuery) ([]searchMatch, int64, error)
+	// Facets 分面统计
+	Facets(q model.SearchQuery) (*model.SearchFacets, error)
+	// Index 图书新建或修改后更新索引
+	Index(bookIDs ...uint) error
+	// Remove 图书删除后移除索引
+	Remove(bookIDs ...uint) error
+	// Rebuild 重建全部索引
+	Rebuild() error
+}
+
+// searchMatch 引擎返回的一条匹配结果
+type searchMatch struct {
+	BookID uint
+	Score  float64
+}
+
+// SearchService 图书搜索服务
+type SearchService struct {
+	mu     sync.RWMutex
+	engine SearchEngine
+}
+
+// Use 切换搜索引擎并建立索引：mysql 使用 FULLTEXT ngram 索引，memory 使用进程内的倒排索引
+func (s *SearchService) Use(name string) error {
+	var engine SearchEngine
+	switch name {
+	case "mysql":
+		engine = &mysqlSearchEngine{}
+	case "memory":
+		engine = newMemorySearchEngine()
+	default:
+		return fmt.Errorf("不支持的搜索引擎: %s", name)
+	}
+
+	if err := engine.Init(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.engine = engine
+	s.mu.Unlock()
+	return nil
+}
+
+// current 当前使用的搜索引擎，未初始化时使用MySQL
+func (s *SearchService) current() SearchEngine {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.engine == nil {
+		return &mysqlSearchEngine{}
+	}
+	return s.engine
+}
+
+// Search 搜索图书：按相关度或指定方式排序，返回图书详情、命中字段高亮和分面统计
+func (s *SearchService) Search(q model.SearchQuery) (*model.SearchResult, error) {
+	if err := normalizeSearchQuery(&q); err != nil {
+		return nil, err
+	}
+
+	engine := s.current()
+	matches, total, err := engine.Search(q)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.SearchResult{
+		List:     make([]model.SearchHit, 0, len(matches)),
+		Total:    total,
+		Page:     q.Page,
+		PageSize: q.PageSize,
+		Engine:   engine.Name(),
+	}
+
+	// 按引擎返回的顺序填充图书详情
+	if len(matches) > 0 {
+		ids := make([]uint, 0, len(matches))
+		for _, m := range matches {
+			ids = append(ids, m.BookID)
+		}
+		var books []model.Book
+		if err := global.GVA_DB.Preload("Categories").Where("id IN ?", ids).Find(&books).Error; err != nil {
+			return nil, err
+		}
+		byID := make(map[uint]model.Book, len(books))
+		for _, b := range books {
+			byID[b.ID] = b
+		}
+
+		terms := searchTerms(q.Keyword)
+		for _, m := range matches {
+			book, ok := byID[m.BookID]
+			if !ok {
+				continue
+			}
+			result.List = append(result.List, model.SearchHit{
+				Book:       book,
+				Score:      m.Score,
+				Highlights: highlightBook(book, terms),
+			})
+		}
+	}
+
+	if q.Facets {
+		facets, err := engine.Facets(q)
+		if err != nil {
+			return nil, err
+		}
+		result.Facets = facets
+	}
+	return result, nil
+}
+
+// IndexBooks 图书新建或修改后更新索引，失败只记录日志，定时重建时修正
+func (s *SearchService) IndexBooks(bookIDs ...uint) {
+	if err := s.current().Index(bookIDs...); err != nil {
+		global.GVA_LOG.Error("更新搜索索引失败", zap.Uints("bookIDs", bookIDs), zap.Error(err))
+	}
+}
+
+// RemoveBooks 图书删除后移除索引
+func (s *SearchService) RemoveBooks(bookIDs ...uint) {
+	if err := s.current().Remove(bookIDs...); err != nil {
+		global.GVA_LOG.Error("移除搜索索引失败", zap.Uints("bookIDs", bookIDs), zap.Error(err))
+	}
+}
+
+// Rebuild 重建全部索引
+func (s *SearchService) Rebuild() error {
+	return s.current().Rebuild()
+}
+
+// normalizeSearchQuery 校验搜索条件并填充默认值
+func normalizeSearchQuery(q *model.SearchQuery) error {
+	q.Keyword = strings.TrimSpace(q.Keyword)
+	q.Publisher = strings.TrimSpace(q.Publisher)
+
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = 10
+	}
+	if q.PageSize > searchMaxPageSize {
+		q.PageSize = searchMaxPageSize
+	}
+
+	switch q.Sort {
+	case "":
+		q.Sort = model.SearchSortNewest
+		if q.Keyword != "" {
+			q.Sort = model.SearchSortRelevance
+		}
+	case model.SearchSortRelevance:
+		// 没有关键词时相关度都为0，按最新入库排序
+		if q.Keyword == "" {
+			q.Sort = model.SearchSortNewest
+		}
+	case model.SearchSortNewest, model.SearchSortPriceAsc, model.SearchSortPriceDesc, model.SearchSortRating:
+	default:
+		return fmt.Errorf("%w: 不支持的排序方式 %s", ErrSearchQuery, q.Sort)
+	}
+
+	if q.YearFrom < 0 || q.YearTo < 0 || (q.YearFrom > 0 && q.YearTo > 0 && q.YearFrom > q.YearTo) {
+		return fmt.Errorf("%w: 出版年份范围错误", ErrSearchQuery)
+	}
+	if q.PriceMin != nil && q.PriceMax != nil && *q.PriceMin > *q.PriceMax {
+		return fmt.Errorf("%w: 价格范围错误", ErrSearchQuery)
+	}
+	return nil
+}
+
+// normalizeSearchISBN 去掉关键词中的连字符和空格，用于ISBN精确匹配
+func normalizeSearchISBN(keyword string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(keyword))
+}
+
+// searchTerms 切分检索词：汉字按相邻两字切分（与MySQL ngram_token_size=2一致），单个汉字保留为一个词；
+// 字母和数字按连续片段切分，统一转为小写
+func searchTerms(text string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	add := func(term string) {
+		if term != "" && !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+
+	var han, word []rune
+	flush := func() {
+		if len(han) == 1 {
+			add(string(han))
+		}
+		for i := 0; i+1 < len(han); i++ {
+			add(string(han[i : i+2]))
+		}
+		add(string(word))
+		han, word = han[:0], word[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			if len(word) > 0 {
+				flush()
+			}
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if len(han) > 0 {
+				flush()
+			}
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return terms
+}
+
+// highlightBook 为书名、作者、出版社和简介生成高亮，简介只截取第一个命中位置附近的片段
+func highlightBook(book model.Book, terms []string) map[string]string {
+	if len(terms) == 0 {
+		return nil
+	}
+	highlights := make(map[string]string)
+	for field, text := range map[string]string{"title": book.Title, "author": book.Author, "publisher": book.Publisher} {
+		if h, ok := highlightText(text, terms, 0); ok {
+			highlights[field] = h
+		}
+	}
+	if h, ok := highlightText(book.Description, terms, searchSnippetLen); ok {
+		highlights["description"] = h
+	}
+	if len(highlights) == 0 {
+		return nil
+	}
+	return highlights
+}
+
+// highlightText 用 <em></em> 包裹命中的检索词，其余内容做HTML转义；snippet 大于0时只返回命中位置附近的片段
+func highlightText(text string, terms []string, snippet int) (string, bool) {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		tr := []rune(term)
+		for i := 0; i+len(tr) <= len(lower); i++ {
+			if string(lower[i:i+len(tr)]) != term {
+				continue
+			}
+			for j := i; j < i+len(tr); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+
+	start, end := 0, len(runes)
+	if snippet > 0 && len(runes) > snippet {
+		start = first - snippet/4
+		if start < 0 {
+			start = 0
+		}
+		end = start + snippet
+		if end > len(runes) {
+			end = len(runes)
+			start = end - snippet
+		}
+	}
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		segment := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			sb.WriteString("<em>" + segment + "</em>")
+		} else {
+			sb.WriteString(segment)
+		}
+		i = j
+	}
+	if end < len(runes) {
+		sb.WriteString("…")
+	}
+	return sb.String(), true
+}
+
+// 全局图书搜索服务实例
+var GlobalSearchService = &SearchService{}
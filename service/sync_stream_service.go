@@ -0,0 +1,224 @@
+package service
+
+import (
+	"bookadmin/constants"
+	"bookadmin/global"
+	"bookadmin/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// SyncStreamService 点赞/收藏同步队列管理
+// 同步Worker把多次投递仍失败或格式错误的消息转入死信Stream，管理员在这里查看、重放或丢弃
+type SyncStreamService struct{}
+
+// syncStreams 同步Worker消费的Stream
+var syncStreams = []string{constants.StreamLikeActions, constants.StreamFavoriteActions}
+
+// isSyncStream 判断是否为同步Worker消费的Stream
+func isSyncStream(stream string) bool {
+	for _, s := range syncStreams {
+		if s == stream {
+			return true
+		}
+	}
+	return false
+}
+
+// GetStats 获取各同步Stream的消费状态和死信数量
+func (s *SyncStreamService) GetStats(ctx context.Context) ([]model.SyncStreamStats, int64, error) {
+	if global.GVA_REDIS == nil {
+		return nil, 0, errors.New("Redis未连接")
+	}
+
+	stats := make([]model.SyncStreamStats, 0, len(syncStreams))
+	for _, stream := range syncStreams {
+		item := model.SyncStreamStats{Stream: stream, Consumers: map[string]int64{}}
+
+		length, err := global.GVA_REDIS.XLen(ctx, stream).Result()
+		if err != nil {
+			return nil, 0, err
+		}
+		item.Length = length
+
+		pending, err := global.GVA_REDIS.XPending(ctx, stream, constants.StreamSyncGroup).Result()
+		if err != nil && err != redis.Nil {
+			return nil, 0, err
+		}
+		if pending != nil {
+			item.Pending = pending.Count
+			item.Consumers = pending.Consumers
+		}
+		stats = append(stats, item)
+	}
+
+	deadLetters, err := global.GVA_REDIS.XLen(ctx, constants.StreamSyncDeadLetter).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	return stats, deadLetters, nil
+}
+
+// GetPending 获取Stream待确认列表中的消息
+func (s *SyncStreamService) GetPending(ctx context.Context, stream string, count int64) ([]model.SyncPendingMessage, error) {
+	if global.GVA_REDIS == nil {
+		return nil, errors.New("Redis未连接")
+	}
+	if !isSyncStream(stream) {
+		return nil, errors.New("未知的Stream")
+	}
+
+	pending, err := global.GVA_REDIS.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  constants.StreamSyncGroup,
+		Start:  "-",
+		End:    "+",
+		Count:  count,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]model.SyncPendingMessage, 0, len(pending))
+	for _, p := range pending {
+		list = append(list, model.SyncPendingMessage{
+			ID:          p.ID,
+			Consumer:    p.Consumer,
+			IdleSeconds: int64(p.Idle / time.Second),
+			Deliveries:  p.RetryCount,
+		})
+	}
+	return list, nil
+}
+
+// GetDeadLetters 按时间倒序获取死信消息，before 为上一页最后一条的ID
+func (s *SyncStreamService) GetDeadLetters(ctx context.Context, before string, count int64) ([]model.SyncDeadLetter, int64, error) {
+	if global.GVA_REDIS == nil {
+		return nil, 0, errors.New("Redis未连接")
+	}
+
+	end := "+"
+	if before != "" {
+		end = "(" + before
+	}
+	messages, err := global.GVA_REDIS.XRevRangeN(ctx, constants.StreamSyncDeadLetter, end, "-", count).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := global.GVA_REDIS.XLen(ctx, constants.StreamSyncDeadLetter).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	list := make([]model.SyncDeadLetter, 0, len(messages))
+	for _, msg := range messages {
+		list = append(list, parseDeadLetter(msg))
+	}
+	return list, total, nil
+}
+
+// ReplayDeadLetters 将死信消息重新投递到来源Stream，成功后从死信Stream删除
+// 同步Worker的写入是幂等的，重放已经落库的消息不会重复计数
+func (s *SyncStreamService) ReplayDeadLetters(ctx context.Context, ids []string, actor model.AuditActor) (int, error) {
+	if global.GVA_REDIS == nil {
+		return 0, errors.New("Redis未连接")
+	}
+
+	replayed := make([]string, 0, len(ids))
+	for _, id := range ids {
+		messages, err := global.GVA_REDIS.XRange(ctx, constants.StreamSyncDeadLetter, id, id).Result()
+		if err != nil {
+			return len(replayed), err
+		}
+		if len(messages) == 0 {
+			continue
+		}
+
+		letter := parseDeadLetter(messages[0])
+		if !isSyncStream(letter.Stream) {
+			return len(replayed), errors.New("死信消息来源无效: " + id)
+		}
+		values := make(map[string]interface{}, len(letter.Payload))
+		for k, v := range letter.Payload {
+			values[k] = v
+		}
+
+		if err := global.GVA_REDIS.XAdd(ctx, &redis.XAddArgs{Stream: letter.Stream, Values: values}).Err(); err != nil {
+			return len(replayed), err
+		}
+		if err := global.GVA_REDIS.XDel(ctx, constants.StreamSyncDeadLetter, id).Err(); err != nil {
+			global.GVA_LOG.Warn("删除已重放的死信消息失败", zap.String("id", id), zap.Error(err))
+		}
+		replayed = append(replayed, id)
+	}
+
+	if len(replayed) > 0 {
+		if err := GlobalAuditService.Record(global.GVA_DB, actor, model.AuditSyncReplay, "sync_dead_letter", 0, nil, map[string]interface{}{
+			"ids": replayed,
+		}); err != nil {
+			global.GVA_LOG.Error("记录死信重放审计失败", zap.Error(err))
+		}
+	}
+	return len(replayed), nil
+}
+
+// DiscardDeadLetters 丢弃死信消息
+func (s *SyncStreamService) DiscardDeadLetters(ctx context.Context, ids []string, actor model.AuditActor) (int64, error) {
+	if global.GVA_REDIS == nil {
+		return 0, errors.New("Redis未连接")
+	}
+
+	// 先取出内容写入审计日志，丢弃后无法恢复
+	discarded := make([]model.SyncDeadLetter, 0, len(ids))
+	for _, id := range ids {
+		messages, err := global.GVA_REDIS.XRange(ctx, constants.StreamSyncDeadLetter, id, id).Result()
+		if err != nil {
+			return 0, err
+		}
+		if len(messages) > 0 {
+			discarded = append(discarded, parseDeadLetter(messages[0]))
+		}
+	}
+	if len(discarded) == 0 {
+		return 0, nil
+	}
+
+	if err := GlobalAuditService.Record(global.GVA_DB, actor, model.AuditSyncDiscard, "sync_dead_letter", 0, discarded, nil); err != nil {
+		return 0, err
+	}
+
+	deleteIDs := make([]string, 0, len(discarded))
+	for _, letter := range discarded {
+		deleteIDs = append(deleteIDs, letter.ID)
+	}
+	return global.GVA_REDIS.XDel(ctx, constants.StreamSyncDeadLetter, deleteIDs...).Result()
+}
+
+// parseDeadLetter 解析死信Stream中的消息
+func parseDeadLetter(msg redis.XMessage) model.SyncDeadLetter {
+	letter := model.SyncDeadLetter{ID: msg.ID}
+	letter.Stream, _ = msg.Values["stream"].(string)
+	letter.MessageID, _ = msg.Values["message_id"].(string)
+	letter.Reason, _ = msg.Values["reason"].(string)
+	if v, ok := msg.Values["deliveries"].(string); ok {
+		letter.Deliveries, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if v, ok := msg.Values["failed_at"].(string); ok {
+		if ts, err := strconv.ParseInt(v, 10, 64); err == nil {
+			letter.FailedAt = time.Unix(ts, 0)
+		}
+	}
+	if v, ok := msg.Values["payload"].(string); ok {
+		_ = json.Unmarshal([]byte(v), &letter.Payload)
+	}
+	return letter
+}
+
+// 全局同步队列管理服务实例
+var GlobalSyncStreamService = &SyncStreamService{}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"bookadmin/constants"
+	"bookadmin/global"
+	"bookadmin/model"
+	"bookadmin/utils"
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// TokenPair 登录/刷新后返回的令牌对
+type TokenPair struct {
+	AccessToken      string    `json:"token"`
+	AccessExpiresAt  time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// TokenService 令牌服务：签发、轮换和吊销
+// 吊销状态保存在Redis中；Redis不可用时退化为按数据库中的用户状态和角色校验
+type TokenService struct{}
+
+// NewTokenService 创建令牌服务实例
+func NewTokenService() *TokenService {
+	return &TokenService{}
+}
+
+// IssueTokens 为用户签发访问令牌和刷新令牌
+func (s *TokenService) IssueTokens(ctx context.Context, user *model.User) (*TokenPair, error) {
+	// 版本未知时不签发，避免签发的令牌与吊销状态不一致
+	version, err := s.getUserVersion(ctx, user.ID)
+	if err != nil {
+		return nil, errors.New("签发令牌失败")
+	}
+
+	accessToken, accessClaims, err := utils.GenerateToken(user.ID, user.Username, user.Role, version)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, refreshClaims, err := utils.GenerateRefreshToken(user.ID, user.Username, user.Role, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// 登记刷新令牌，刷新时删除以实现轮换
+	if global.GVA_REDIS != nil {
+		ttl := time.Until(refreshClaims.ExpiresAt.Time)
+		if err := global.GVA_REDIS.Set(ctx, constants.KeyRefreshToken(refreshClaims.ID), user.ID, ttl).Err(); err != nil {
+			global.GVA_LOG.Error("登记刷新令牌失败", zap.Uint("user_id", user.ID), zap.Error(err))
+			return nil, errors.New("签发令牌失败")
+		}
+	}
+
+	return &TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessClaims.ExpiresAt.Time,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshClaims.ExpiresAt.Time,
+	}, nil
+}
+
+// Refresh 使用刷新令牌换取新的令牌对，旧刷新令牌随即失效
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	claims, err := utils.ParseToken(refreshToken)
+	if err != nil || claims.TokenType != utils.TokenTypeRefresh {
+		return nil, errors.New("刷新令牌无效")
+	}
+
+	if global.GVA_REDIS != nil {
+		// GETDEL 保证同一个刷新令牌只能使用一次
+		_, err := global.GVA_REDIS.GetDel(ctx, constants.KeyRefreshToken(claims.ID)).Result()
+		if err == redis.Nil {
+			// 已使用过的刷新令牌再次出现，可能已泄露，吊销该用户全部令牌
+			global.GVA_LOG.Warn("刷新令牌重复使用，吊销用户全部令牌", zap.Uint("user_id", claims.UserID))
+			_ = s.RevokeUser(ctx, claims.UserID)
+			return nil, errors.New("刷新令牌已失效，请重新登录")
+		}
+		if err != nil {
+			global.GVA_LOG.Error("校验刷新令牌失败", zap.Error(err))
+			return nil, errors.New("刷新令牌失败")
+		}
+	}
+
+	if version, err := s.getUserVersion(ctx, claims.UserID); err != nil {
+		// 查询版本失败时按数据库中的用户状态和角色校验
+		if err := s.validateAgainstDB(claims); err != nil {
+			return nil, errors.New("刷新令牌已失效，请重新登录")
+		}
+	} else if version != claims.Version {
+		return nil, errors.New("刷新令牌已失效，请重新登录")
+	}
+
+	// 重新读取用户，以最新的角色签发令牌
+	var user model.User
+	if err := global.GVA_DB.First(&user, claims.UserID).Error; err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	if user.Status != "active" {
+		return nil, errors.New("用户已被禁用")
+	}
+
+	return s.IssueTokens(ctx, &user)
+}
+
+// Logout 注销：吊销当前访问令牌以及对应的刷新令牌
+func (s *TokenService) Logout(ctx context.Context, accessClaims *utils.Claims, refreshToken string) error {
+	if global.GVA_REDIS == nil {
+		return nil
+	}
+
+	if err := s.revokeToken(ctx, accessClaims); err != nil {
+		return err
+	}
+
+	if refreshToken != "" {
+		// 只允许注销属于自己的刷新令牌
+		if claims, err := utils.ParseToken(refreshToken); err == nil &&
+			claims.TokenType == utils.TokenTypeRefresh && claims.UserID == accessClaims.UserID {
+			if err := global.GVA_REDIS.Del(ctx, constants.KeyRefreshToken(claims.ID)).Err(); err != nil {
+				global.GVA_LOG.Error("删除刷新令牌失败", zap.Error(err))
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RevokeUser 吊销用户已签发的全部令牌（角色或状态变更、删除用户时调用）
+func (s *TokenService) RevokeUser(ctx context.Context, userID uint) error {
+	if global.GVA_REDIS == nil {
+		return nil
+	}
+	if err := global.GVA_REDIS.Incr(ctx, constants.KeyUserTokenVersion(userID)).Err(); err != nil {
+		global.GVA_LOG.Error("吊销用户令牌失败", zap.Uint("user_id", userID), zap.Error(err))
+		return err
+	}
+	global.GVA_LOG.Info("已吊销用户全部令牌", zap.Uint("user_id", userID))
+	return nil
+}
+
+// ValidateAccess 校验访问令牌是否仍然有效
+func (s *TokenService) ValidateAccess(ctx context.Context, claims *utils.Claims) error {
+	if claims.TokenType != utils.TokenTypeAccess {
+		return errors.New("令牌类型错误")
+	}
+
+	if global.GVA_REDIS == nil {
+		return s.validateAgainstDB(claims)
+	}
+
+	values, err := global.GVA_REDIS.MGet(ctx,
+		constants.KeyRevokedToken(claims.ID),
+		constants.KeyUserTokenVersion(claims.UserID),
+	).Result()
+	if err != nil {
+		global.GVA_LOG.Warn("查询令牌吊销状态失败，改为数据库校验", zap.Error(err))
+		return s.validateAgainstDB(claims)
+	}
+
+	if values[0] != nil {
+		return errors.New("令牌已注销")
+	}
+	if parseVersion(values[1]) != claims.Version {
+		return errors.New("令牌已失效")
+	}
+	return nil
+}
+
+// revokeToken 吊销单个令牌，保留到令牌过期为止
+func (s *TokenService) revokeToken(ctx context.Context, claims *utils.Claims) error {
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+	if err := global.GVA_REDIS.Set(ctx, constants.KeyRevokedToken(claims.ID), 1, ttl).Err(); err != nil {
+		global.GVA_LOG.Error("吊销令牌失败", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// validateAgainstDB Redis不可用时，按数据库中的用户状态和角色校验
+func (s *TokenService) validateAgainstDB(claims *utils.Claims) error {
+	var user model.User
+	if err := global.GVA_DB.Select("id", "role", "status").First(&user, claims.UserID).Error; err != nil {
+		return errors.New("用户不存在")
+	}
+	if user.Status != "active" || user.Role != claims.Role {
+		return errors.New("令牌已失效")
+	}
+	return nil
+}
+
+// getUserVersion 获取用户当前的令牌版本，未配置Redis或从未吊销过时为0
+// 查询Redis出错时返回错误，由调用方改为数据库校验，不能当作版本0
+func (s *TokenService) getUserVersion(ctx context.Context, userID uint) (int64, error) {
+	if global.GVA_REDIS == nil {
+		return 0, nil
+	}
+	value, err := global.GVA_REDIS.Get(ctx, constants.KeyUserTokenVersion(userID)).Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		global.GVA_LOG.Warn("获取令牌版本失败", zap.Uint("user_id", userID), zap.Error(err))
+		return 0, err
+	}
+	return parseVersion(value), nil
+}
+
+func parseVersion(value interface{}) int64 {
+	str, ok := value.(string)
+	if !ok {
+		return 0
+	}
+	version, _ := strconv.ParseInt(str, 10, 64)
+	return version
+}
@@ -0,0 +1,372 @@
+#!/bin/bash
+
+echo "=================================="
+echo "🧪 库存与预约队列并发测试脚本"
+echo "=================================="
+echo ""
+
+# 可通过环境变量覆盖，例如 ADMIN_PASS=xxx MYSQL="mysql -h127.0.0.1 -uroot -proot bookadmin" ./test-concurrency.sh
+BASE_URL=${BASE_URL:-"http://localhost:8888/api"}
+ADMIN_USER=${ADMIN_USER:-"admin"}
+ADMIN_PASS=${ADMIN_PASS:-"admin123"}
+MYSQL=${MYSQL:-"docker exec -i bookadmin-mysql mysql -uroot -proot bookadmin"}
+CONCURRENCY=${CONCURRENCY:-20} # 并发读者数
+TOKEN=""
+FAILED=0
+
+# 颜色定义
+GREEN='\033[0;32m'
+RED='\033[0;31m'
+YELLOW='\033[1;33m'
+NC='\033[0m' # No Color
+
+TMP_DIR=$(mktemp -d)
+trap 'rm -rf "$TMP_DIR"' EXIT
+
+# 取响应中第一个数字字段的值
+json_number() {
+    echo "$1" | grep -o "\"$2\":[0-9-]*" | head -1 | cut -d':' -f2
+}
+
+# 取响应中第一个字符串字段的值
+json_string() {
+    echo "$1" | grep -o "\"$2\":\"[^\"]*" | head -1 | cut -d'"' -f4
+}
+
+# 断言相等，不相等时记为失败
+assert_eq() {
+    if [ "$1" == "$2" ]; then
+        echo -e "  ${GREEN}✅ $3（$1）${NC}"
+    else
+        echo -e "  ${RED}❌ $3：期望 $2，实际 $1${NC}"
+        FAILED=$((FAILED + 1))
+    fi
+}
+
+# 统计并发请求中成功（code 为 200）的响应数
+count_success() {
+    grep -l '"code":200' "$TMP_DIR"/$1.* 2>/dev/null | wc -l | tr -d ' '
+}
+
+# 执行SQL，输出不带表头，列之间以制表符分隔
+db_query() {
+    $MYSQL -N -B -e "$1" 2>/dev/null
+}
+
+# 从数据库检查图书库存：books 表的库存与副本状态一致，借出的副本数与在借记录数一致
+check_stock() {
+    local book_id=$1
+    local row=$(db_query "SELECT b.available_stock, b.total_stock,
+        (SELECT COUNT(*) FROM book_copies c WHERE c.book_id = b.id AND c.deleted_at IS NULL AND c.status = 'available'),
+        (SELECT COUNT(*) FROM book_copies c WHERE c.book_id = b.id AND c.deleted_at IS NULL AND c.status NOT IN ('lost', 'retired')),
+        (SELECT COUNT(*) FROM book_copies c WHERE c.book_id = b.id AND c.deleted_at IS NULL AND c.status = 'borrowed'),
+        (SELECT COUNT(*) FROM borrow_records r WHERE r.book_id = b.id AND r.deleted_at IS NULL AND r.status IN ('borrowed', 'overdue', 'renewed')),
+        (SELECT COUNT(*) FROM reservations v WHERE v.book_id = b.id AND v.deleted_at IS NULL AND v.status = 'available')
+        FROM books b WHERE b.id = $book_id")
+    local avail total copy_avail copy_total copy_borrowed loans held
+    read avail total copy_avail copy_total copy_borrowed loans held <<< "$row"
+    assert_eq "$avail" "$copy_avail" "[DB] available_stock 与在架副本数一致"
+    assert_eq "$total" "$copy_total" "[DB] total_stock 与有效副本数一致"
+    assert_eq "$copy_borrowed" "$loans" "[DB] 借出副本数与在借记录数一致"
+    if [ -n "$held" ] && [ -n "$avail" ] && [ "$held" -le "$avail" ]; then
+        echo -e "  ${GREEN}✅ [DB] 待取书预约数（$held）不超过可借库存（$avail）${NC}"
+    else
+        echo -e "  ${RED}❌ [DB] 待取书预约数 $held 超过可借库存 $avail${NC}"
+        FAILED=$((FAILED + 1))
+    fi
+}
+
+# 从数据库检查预约队列：排队中的预约位置为 1..n 且不重复
+check_queue() {
+    local book_id=$1
+    local expected=$2
+    local row=$(db_query "SELECT COUNT(*), COUNT(DISTINCT queue_position), IFNULL(MIN(queue_position), 0), IFNULL(MAX(queue_position), 0)
+        FROM reservations WHERE book_id = $book_id AND deleted_at IS NULL AND status = 'pending'")
+    local total distinct min max
+    read total distinct min max <<< "$row"
+    assert_eq "$total" "$expected" "[DB] 排队中的预约数"
+    assert_eq "$distinct" "$expected" "[DB] 队列位置不重复"
+    if [ "$expected" -gt 0 ]; then
+        assert_eq "$min-$max" "1-$expected" "[DB] 队列位置从1开始连续"
+    fi
+}
+
+# 创建只有1本副本的测试图书，输出图书ID
+create_book() {
+    local title=$1
+    local isbn=$2
+    curl -s -X POST "$BASE_URL/book/createBook" \
+      -H "Authorization: Bearer $TOKEN" \
+      -H "Content-Type: application/json" \
+      -d "{
+        \"title\": \"$title\",
+        \"author\": \"并发测试\",
+        \"isbn\": \"$isbn\",
+        \"price\": 30,
+        \"total_stock\": 1
+      }" > /dev/null
+
+    # ISBN完全一致的图书排在搜索结果第一位
+    local resp=$(curl -s -G "$BASE_URL/book/getBookList" --data-urlencode "keyword=$isbn")
+    echo "$resp" | grep -o '"list":\[{"id":[0-9]*' | grep -o '[0-9]*$'
+}
+
+# 修改图书信息（不涉及库存），用于与借还书同时执行
+update_book() {
+    curl -s -X PUT "$BASE_URL/book/updateBook" \
+      -H "Authorization: Bearer $TOKEN" \
+      -H "Content-Type: application/json" \
+      -d "{
+        \"id\": $1,
+        \"title\": \"$2\",
+        \"author\": \"并发测试\",
+        \"isbn\": \"$3\",
+        \"price\": 30,
+        \"description\": \"并发修改 $4\"
+      }"
+}
+
+# 0. 检查数据库连接
+echo "📝 步骤0: 检查数据库连接..."
+if [ "$(db_query 'SELECT 1')" != "1" ]; then
+    echo -e "${RED}❌ 无法执行SQL，请通过 MYSQL 环境变量指定连接命令${NC}"
+    echo "  当前: $MYSQL"
+    exit 1
+fi
+echo -e "${GREEN}✅ 数据库连接正常${NC}"
+echo ""
+
+# 1. 管理员登录
+echo "📝 步骤1: 管理员登录..."
+LOGIN_RESPONSE=$(curl -s -X POST "$BASE_URL/auth/login" \
+  -H "Content-Type: application/json" \
+  -d "{
+    \"username\": \"$ADMIN_USER\",
+    \"password\": \"$ADMIN_PASS\"
+  }")
+
+TOKEN=$(echo $LOGIN_RESPONSE | grep -o '"token":"[^"]*' | cut -d'"' -f4)
+
+if [ -z "$TOKEN" ]; then
+    echo -e "${RED}❌ 登录失败，请通过 ADMIN_USER、ADMIN_PASS 环境变量指定管理员账号${NC}"
+    echo "响应: $LOGIN_RESPONSE"
+    exit 1
+fi
+echo -e "${GREEN}✅ 登录成功${NC}"
+echo ""
+
+# 2. 创建只有1本副本的测试图书
+SUFFIX=$(date +%s)
+TITLE="并发测试图书$SUFFIX"
+ISBN="99$SUFFIX"
+echo "📝 步骤2: 创建测试图书《$TITLE》（1本副本）..."
+BOOK_ID=$(create_book "$TITLE" "$ISBN")
+if [ -z "$BOOK_ID" ]; then
+    echo -e "${RED}❌ 创建图书失败${NC}"
+    exit 1
+fi
+echo -e "${GREEN}✅ 图书ID: $BOOK_ID${NC}"
+check_stock $BOOK_ID
+echo ""
+
+# 3. 注册并审核通过测试读者
+echo "📝 步骤3: 注册 $CONCURRENCY 个测试读者..."
+for i in $(seq 1 $CONCURRENCY); do
+    USERNAME="cc${SUFFIX}_$i"
+    curl -s -X POST "$BASE_URL/auth/register" \
+      -H "Content-Type: application/json" \
+      -d "{
+        \"username\": \"$USERNAME\",
+        \"password\": \"test123\",
+        \"real_name\": \"并发读者$i\",
+        \"id_card\": \"CC$SUFFIX$i\"
+      }" > /dev/null
+
+    READER_RESPONSE=$(curl -s -G "$BASE_URL/reader/getReaderList" \
+      -H "Authorization: Bearer $TOKEN" \
+      --data-urlencode "keyword=$USERNAME")
+    READER_ID=$(echo "$READER_RESPONSE" | grep -o '"list":\[{"ID":[0-9]*' | grep -o '[0-9]*$')
+    READER_NOS[$i]=$(json_string "$READER_RESPONSE" "reader_no")
+    curl -s -X PUT "$BASE_URL/reader/updateReaderStatus" \
+      -H "Authorization: Bearer $TOKEN" \
+      -H "Content-Type: application/json" \
+      -d "{\"id\": $READER_ID, \"status\": \"active\"}" > /dev/null
+
+    USER_LOGIN=$(curl -s -X POST "$BASE_URL/auth/login" \
+      -H "Content-Type: application/json" \
+      -d "{\"username\": \"$USERNAME\", \"password\": \"test123\"}")
+    USER_IDS[$i]=$(json_number "$USER_LOGIN" "user_id")
+    USER_TOKENS[$i]=$(echo $USER_LOGIN | grep -o '"token":"[^"]*' | cut -d'"' -f4)
+    if [ -z "${USER_TOKENS[$i]}" ]; then
+        echo -e "${RED}❌ 读者 $USERNAME 登录失败${NC}"
+        echo "响应: $USER_LOGIN"
+        exit 1
+    fi
+done
+echo -e "${GREEN}✅ 读者准备完成${NC}"
+echo ""
+
+# 4. 并发借出最后一本
+echo "📝 步骤4: $CONCURRENCY 个借书请求同时借最后一本..."
+for i in $(seq 1 $CONCURRENCY); do
+    curl -s -X POST "$BASE_URL/borrow/borrowBook" \
+      -H "Authorization: Bearer $TOKEN" \
+      -H "Content-Type: application/json" \
+      -d "{\"reader_id\": ${USER_IDS[$i]}, \"book_id\": $BOOK_ID}" > "$TMP_DIR/borrow.$i" &
+done
+wait
+
+assert_eq "$(count_success borrow)" "1" "只有一个借书请求成功"
+assert_eq "$(db_query "SELECT available_stock FROM books WHERE id = $BOOK_ID")" "0" "[DB] 可借库存为0，没有变成负数"
+check_stock $BOOK_ID
+
+WINNER=""
+for i in $(seq 1 $CONCURRENCY); do
+    if grep -q '"code":200' "$TMP_DIR/borrow.$i"; then
+        WINNER=$i
+        RECORD_ID=$(json_number "$(cat $TMP_DIR/borrow.$i)" "id")
+    fi
+done
+if [ -z "$WINNER" ]; then
+    echo -e "${RED}❌ 没有借书成功的请求，无法继续${NC}"
+    exit 1
+fi
+echo "  借到的读者: cc${SUFFIX}_$WINNER，借阅记录ID: $RECORD_ID"
+echo ""
+
+# 5. 其余读者同时预约
+WAITING=$((CONCURRENCY - 1))
+echo "📝 步骤5: 其余 $WAITING 个读者同时预约..."
+for i in $(seq 1 $CONCURRENCY); do
+    if [ "$i" == "$WINNER" ]; then
+        continue
+    fi
+    curl -s -X POST "$BASE_URL/reservation/create" \
+      -H "Authorization: Bearer ${USER_TOKENS[$i]}" \
+      -H "Content-Type: application/json" \
+      -d "{\"book_id\": $BOOK_ID}" > "$TMP_DIR/reserve.$i" &
+done
+wait
+
+assert_eq "$(count_success reserve)" "$WAITING" "全部预约成功"
+check_queue $BOOK_ID $WAITING
+echo ""
+
+# 6. 同一条借阅记录同时还书，同时修改图书信息
+echo "📝 步骤6: 同一条借阅记录同时发起5次还书，同时5次修改图书信息..."
+for i in $(seq 1 5); do
+    curl -s -X POST "$BASE_URL/borrow/returnBook" \
+      -H "Authorization: Bearer $TOKEN" \
+      -H "Content-Type: application/json" \
+      -d "{\"record_id\": $RECORD_ID}" > "$TMP_DIR/return.$i" &
+    update_book $BOOK_ID "$TITLE" "$ISBN" $i > "$TMP_DIR/update.$i" &
+done
+wait
+
+assert_eq "$(count_success return)" "1" "只有一次还书成功"
+assert_eq "$(count_success update)" "5" "修改图书信息全部成功"
+check_stock $BOOK_ID
+echo ""
+
+# 7. 到书分配（由待执行事件循环异步执行）
+echo "📝 步骤7: 等待到书通知分配给队首读者..."
+AVAILABLE=0
+for n in $(seq 1 10); do
+    sleep 1
+    AVAILABLE=$(db_query "SELECT COUNT(*) FROM reservations WHERE book_id = $BOOK_ID AND deleted_at IS NULL AND status = 'available'")
+    if [ "$AVAILABLE" != "0" ]; then
+        break
+    fi
+done
+assert_eq "$AVAILABLE" "1" "[DB] 只有一个预约改为可取书"
+check_queue $BOOK_ID $((WAITING - 1))
+check_stock $BOOK_ID
+echo ""
+
+# 8. 同一本书的两个借阅申请同时被多次批准
+TITLE2="并发审批图书$SUFFIX"
+ISBN2="98$SUFFIX"
+echo "📝 步骤8: 创建《$TITLE2》（1本副本），两个借阅申请同时各批准3次..."
+BOOK2_ID=$(create_book "$TITLE2" "$ISBN2")
+if [ -z "$BOOK2_ID" ]; then
+    echo -e "${RED}❌ 创建图书失败${NC}"
+    exit 1
+fi
+for i in 1 2; do
+    REQ=$(curl -s -X POST "$BASE_URL/borrow/borrowBook" \
+      -H "Authorization: Bearer ${USER_TOKENS[$i]}" \
+      -H "Content-Type: application/json" \
+      -d "{\"book_id\": $BOOK2_ID}")
+    REQUEST_IDS[$i]=$(json_number "$REQ" "id")
+    if [ -z "${REQUEST_IDS[$i]}" ]; then
+        echo -e "${RED}❌ 提交借阅申请失败${NC}"
+        echo "响应: $REQ"
+        exit 1
+    fi
+done
+assert_eq "$(db_query "SELECT COUNT(*) FROM borrow_records WHERE book_id = $BOOK2_ID AND deleted_at IS NULL AND status = 'pending'")" "2" "[DB] 两个借阅申请待审批"
+
+for n in 1 2 3; do
+    for i in 1 2; do
+        curl -s -X POST "$BASE_URL/borrow/approve" \
+          -H "Authorization: Bearer $TOKEN" \
+          -H "Content-Type: application/json" \
+          -d "{\"record_id\": ${REQUEST_IDS[$i]}, \"approved\": true}" > "$TMP_DIR/approve.$i$n" &
+    done
+    update_book $BOOK2_ID "$TITLE2" "$ISBN2" $n > /dev/null &
+done
+wait
+
+assert_eq "$(count_success approve)" "1" "只有一次批准成功"
+assert_eq "$(db_query "SELECT available_stock FROM books WHERE id = $BOOK2_ID")" "0" "[DB] 可借库存为0，没有变成负数"
+check_stock $BOOK2_ID
+echo ""
+
+# 9. 流通台同时扫描同一本副本借给不同读者
+TITLE3="并发流通台图书$SUFFIX"
+ISBN3="97$SUFFIX"
+echo "📝 步骤9: 创建《$TITLE3》（1本副本），$CONCURRENCY 个读者同时在流通台借同一条码..."
+BOOK3_ID=$(create_book "$TITLE3" "$ISBN3")
+if [ -z "$BOOK3_ID" ]; then
+    echo -e "${RED}❌ 创建图书失败${NC}"
+    exit 1
+fi
+COPY_RESPONSE=$(curl -s -G "$BASE_URL/copy/getCopyList" \
+  -H "Authorization: Bearer $TOKEN" \
+  --data-urlencode "book_id=$BOOK3_ID")
+BARCODE=$(json_string "$COPY_RESPONSE" "barcode")
+if [ -z "$BARCODE" ]; then
+    echo -e "${RED}❌ 查询副本条码失败${NC}"
+    echo "响应: $COPY_RESPONSE"
+    exit 1
+fi
+
+for i in $(seq 1 $CONCURRENCY); do
+    curl -s -X POST "$BASE_URL/desk/checkout" \
+      -H "Authorization: Bearer $TOKEN" \
+      -H "Content-Type: application/json" \
+      -d "{\"reader_no\": \"${READER_NOS[$i]}\", \"barcode\": \"$BARCODE\"}" > "$TMP_DIR/checkout.$i" &
+done
+wait
+
+assert_eq "$(count_success checkout)" "1" "只有一次扫码借书成功"
+assert_eq "$(db_query "SELECT COUNT(*) FROM borrow_records r JOIN book_copies c ON c.id = r.copy_id
+    WHERE c.barcode = '$BARCODE' AND r.deleted_at IS NULL AND r.status IN ('borrowed', 'overdue', 'renewed')")" "1" "[DB] 该副本只有一条在借记录"
+check_stock $BOOK3_ID
+echo ""
+
+echo "=================================="
+if [ "$FAILED" -eq 0 ]; then
+    echo -e "${GREEN}✅ 并发测试全部通过！${NC}"
+else
+    echo -e "${RED}❌ $FAILED 项检查失败${NC}"
+fi
+echo "=================================="
+echo ""
+echo "💡 提示："
+echo "  - 可通过 CONCURRENCY 环境变量调整并发数，如 CONCURRENCY=50 ./test-concurrency.sh"
+echo "  - BASE_URL、ADMIN_USER、ADMIN_PASS 指定服务地址和管理员账号，MYSQL 指定执行SQL的命令（默认通过 docker exec 连接 bookadmin-mysql 容器）"
+echo "  - 测试数据（图书《$TITLE》《$TITLE2》《$TITLE3》、读者 cc${SUFFIX}_*）不会自动删除"
+echo ""
+
+exit $FAILED
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// NormalizeISBN 校验ISBN并统一转换为不带分隔符的13位ISBN
+// 支持带连字符或空格的 ISBN-10 和 ISBN-13，ISBN-10 会转换为 978 前缀的 ISBN-13
+func NormalizeISBN(isbn string) (string, error) {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(strings.TrimSpace(isbn)) {
+		switch {
+		case r >= '0' && r <= '9', r == 'X':
+			b.WriteRune(r)
+		case r == '-' || r == ' ':
+		default:
+			return "", errors.New("ISBN包含非法字符")
+		}
+	}
+	digits := b.String()
+
+	switch len(digits) {
+	case 10:
+		if !validISBN10(digits) {
+			return "", errors.New("ISBN-10校验位错误")
+		}
+		return ISBN10To13(digits), nil
+	case 13:
+		if !validISBN13(digits) {
+			return "", errors.New("ISBN-13校验位错误")
+		}
+		return digits, nil
+	default:
+		return "", errors.New("ISBN长度应为10位或13位")
+	}
+}
+
+// ISBN10To13 将合法的ISBN-10转换为ISBN-13
+func ISBN10To13(isbn10 string) string {
+	body := "978" + isbn10[:9]
+	return body + string(isbn13CheckDigit(body))
+}
+
+func validISBN10(s string) bool {
+	sum := 0
+	for i := 0; i < 10; i++ {
+		c := s[i]
+		var v int
+		switch {
+		case c == 'X' && i == 9:
+			v = 10
+		case c >= '0' && c <= '9':
+			v = int(c - '0')
+		default:
+			return false
+		}
+		sum += v * (10 - i)
+	}
+	return sum%11 == 0
+}
+
+func validISBN13(s string) bool {
+	if strings.ContainsRune(s, 'X') {
+		return false
+	}
+	return isbn13CheckDigit(s[:12]) == s[12]
+}
+
+// isbn13CheckDigit 计算ISBN-13的校验位，body为前12位数字
+func isbn13CheckDigit(body string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		v := int(body[i] - '0')
+		if i%2 == 1 {
+			v *= 3
+		}
+		sum += v
+	}
+	return byte('0' + (10-sum%10)%10)
+}
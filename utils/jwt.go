@@ -1,8 +1,11 @@
 package utils
 
 import (
+	"bookadmin/config"
 	"bookadmin/global"
 	"bookadmin/model"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -10,41 +13,91 @@ import (
 	"go.uber.org/zap"
 )
 
-var jwtSecret = []byte("bookadmin-secret-key-change-in-production")
+var (
+	jwtSecret             = []byte("bookadmin-secret-key-change-in-production")
+	jwtExpiresTime        = 30 * time.Minute
+	jwtRefreshExpiresTime = 7 * 24 * time.Hour
+	jwtIssuer             = "bookadmin"
+)
+
+// 令牌类型
+const (
+	TokenTypeAccess  = "access"  // 访问令牌，短期有效
+	TokenTypeRefresh = "refresh" // 刷新令牌，只能用于换取新令牌
+)
+
+// InitJWT 使用配置初始化JWT签名参数
+func InitJWT(cfg config.JWT) {
+	jwtSecret = []byte(cfg.Secret)
+	jwtExpiresTime = cfg.ExpiresTime
+	jwtRefreshExpiresTime = cfg.RefreshExpiresTime
+	jwtIssuer = cfg.Issuer
+}
 
 type Claims struct {
-	UserID   uint           `json:"user_id"`
-	Username string         `json:"username"`
-	Role     model.UserRole `json:"role"`
+	UserID    uint           `json:"user_id"`
+	Username  string         `json:"username"`
+	Role      model.UserRole `json:"role"`
+	TokenType string         `json:"token_type"`
+	Version   int64          `json:"ver"` // 用户令牌版本，版本号递增后旧令牌全部失效
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成JWT token
-func GenerateToken(userID uint, username string, role model.UserRole) (string, error) {
+// GenerateToken 生成访问令牌
+func GenerateToken(userID uint, username string, role model.UserRole, version int64) (string, *Claims, error) {
+	return generateToken(userID, username, role, version, TokenTypeAccess, jwtExpiresTime)
+}
+
+// GenerateRefreshToken 生成刷新令牌
+func GenerateRefreshToken(userID uint, username string, role model.UserRole, version int64) (string, *Claims, error) {
+	return generateToken(userID, username, role, version, TokenTypeRefresh, jwtRefreshExpiresTime)
+}
+
+func generateToken(userID uint, username string, role model.UserRole, version int64, tokenType string, ttl time.Duration) (string, *Claims, error) {
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", nil, err
+	}
+
 	nowTime := time.Now()
-	expireTime := nowTime.Add(24 * time.Hour) // 24小时过期
+	expireTime := nowTime.Add(ttl)
 
-	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
+	claims := &Claims{
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		TokenType: tokenType,
+		Version:   version,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
 			ExpiresAt: jwt.NewNumericDate(expireTime),
 			IssuedAt:  jwt.NewNumericDate(nowTime),
-			Issuer:    "bookadmin",
+			Issuer:    jwtIssuer,
 		},
 	}
 
 	tokenClaims := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	token, err := tokenClaims.SignedString(jwtSecret)
-	return token, err
+	if err != nil {
+		return "", nil, err
+	}
+	return token, claims, nil
+}
+
+// newTokenID 生成随机令牌ID（jti）
+func newTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
 }
 
 // ParseToken 解析JWT token
 func ParseToken(token string) (*Claims, error) {
 	tokenClaims, err := jwt.ParseWithClaims(token, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return jwtSecret, nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if tokenClaims != nil {
 		if claims, ok := tokenClaims.Claims.(*Claims); ok && tokenClaims.Valid {
@@ -52,22 +105,12 @@ func ParseToken(token string) (*Claims, error) {
 		}
 	}
 
+	if err == nil {
+		err = errors.New("token无效")
+	}
 	return nil, err
 }
 
-// HashPassword 密码加密
-func HashPassword(password string) (string, error) {
-	// 使用bcrypt加密，需要添加golang.org/x/crypto/bcrypt
-	// 这里简化处理，实际应该使用bcrypt
-	return password, nil
-}
-
-// CheckPassword 验证密码
-func CheckPassword(hashedPassword, password string) bool {
-	// 简化处理，实际应该使用bcrypt验证
-	return hashedPassword == password
-}
-
 // GetUserByID 根据ID获取用户
 func GetUserByID(userID uint) (*model.User, error) {
 	var user model.User
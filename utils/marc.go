@@ -0,0 +1,313 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MARC21 记录的读写，支持 ISO 2709 二进制格式（UTF-8 编码）和 MARCXML
+
+const (
+	marcSubfieldDelimiter = 0x1F
+	marcFieldTerminator   = 0x1E
+	marcRecordTerminator  = 0x1D
+	marcXMLNamespace      = "http://www.loc.gov/MARC21/slim"
+)
+
+// MarcSubfield 子字段
+type MarcSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// MarcField 字段，控制字段（00X）只有 Value，数据字段有指示符和子字段
+type MarcField struct {
+	Tag       string
+	Ind1      string
+	Ind2      string
+	Value     string
+	Subfields []MarcSubfield
+}
+
+// IsControl 是否为控制字段
+func (f MarcField) IsControl() bool {
+	return strings.HasPrefix(f.Tag, "00")
+}
+
+// MarcRecord 一条MARC记录
+type MarcRecord struct {
+	Leader string
+	Fields []MarcField
+}
+
+// SubfieldValues 返回指定字段和子字段的全部值
+func (r *MarcRecord) SubfieldValues(tag, code string) []string {
+	var values []string
+	for _, f := range r.Fields {
+		if f.Tag != tag {
+			continue
+		}
+		for _, sf := range f.Subfields {
+			if sf.Code == code {
+				values = append(values, sf.Value)
+			}
+		}
+	}
+	return values
+}
+
+// FirstSubfield 返回指定字段和子字段的第一个值
+func (r *MarcRecord) FirstSubfield(tag, code string) string {
+	if values := r.SubfieldValues(tag, code); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// AddDataField 追加数据字段，值为空的子字段会被忽略
+func (r *MarcRecord) AddDataField(tag, ind1, ind2 string, subfields ...MarcSubfield) {
+	field := MarcField{Tag: tag, Ind1: ind1, Ind2: ind2}
+	for _, sf := range subfields {
+		if sf.Value != "" {
+			field.Subfields = append(field.Subfields, sf)
+		}
+	}
+	if len(field.Subfields) > 0 {
+		r.Fields = append(r.Fields, field)
+	}
+}
+
+// ReadMARC21 读取 ISO 2709 格式的MARC记录
+func ReadMARC21(r io.Reader) ([]MarcRecord, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, marcRecordTerminator); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && len(bytes.TrimSpace(data)) > 0 {
+			return len(data), data, nil
+		}
+		if atEOF {
+			return len(data), nil, nil
+		}
+		return 0, nil, nil
+	})
+
+	var records []MarcRecord
+	for scanner.Scan() {
+		raw := bytes.TrimLeft(scanner.Bytes(), "\r\n ")
+		if len(raw) == 0 {
+			continue
+		}
+		record, err := parseMARC21Record(raw)
+		if err != nil {
+			return records, fmt.Errorf("第%d条记录: %w", len(records)+1, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return records, err
+	}
+	return records, nil
+}
+
+func parseMARC21Record(raw []byte) (MarcRecord, error) {
+	if len(raw) < 25 {
+		return MarcRecord{}, errors.New("记录长度不足")
+	}
+	leader := string(raw[:24])
+	base, err := strconv.Atoi(strings.TrimSpace(leader[12:17]))
+	if err != nil || base < 25 || base > len(raw) {
+		return MarcRecord{}, errors.New("头标区数据起始地址无效")
+	}
+
+	record := MarcRecord{Leader: leader}
+	directory := raw[24 : base-1]
+	if len(directory)%12 != 0 {
+		return MarcRecord{}, errors.New("目次区长度无效")
+	}
+
+	for i := 0; i+12 <= len(directory); i += 12 {
+		entry := directory[i : i+12]
+		tag := string(entry[:3])
+		length, err1 := strconv.Atoi(string(entry[3:7]))
+		start, err2 := strconv.Atoi(string(entry[7:12]))
+		if err1 != nil || err2 != nil || base+start+length > len(raw) || length == 0 {
+			return MarcRecord{}, fmt.Errorf("字段%s的目次项无效", tag)
+		}
+		data := raw[base+start : base+start+length-1] // 去掉字段结束符
+
+		field := MarcField{Tag: tag}
+		if field.IsControl() {
+			field.Value = string(data)
+		} else {
+			if len(data) >= 2 {
+				field.Ind1, field.Ind2 = string(data[0]), string(data[1])
+				data = data[2:]
+			}
+			for _, part := range bytes.Split(data, []byte{marcSubfieldDelimiter}) {
+				if len(part) == 0 {
+					continue
+				}
+				field.Subfields = append(field.Subfields, MarcSubfield{
+					Code:  string(part[0]),
+					Value: string(part[1:]),
+				})
+			}
+		}
+		record.Fields = append(record.Fields, field)
+	}
+	return record, nil
+}
+
+// WriteMARC21 以 ISO 2709 格式写出一条MARC记录（UTF-8 编码）
+func WriteMARC21(w io.Writer, record MarcRecord) error {
+	var directory, data bytes.Buffer
+	for _, f := range record.Fields {
+		start := data.Len()
+		if f.IsControl() {
+			data.WriteString(f.Value)
+		} else {
+			data.WriteString(marcIndicator(f.Ind1))
+			data.WriteString(marcIndicator(f.Ind2))
+			for _, sf := range f.Subfields {
+				data.WriteByte(marcSubfieldDelimiter)
+				data.WriteString(sf.Code)
+				data.WriteString(sf.Value)
+			}
+		}
+		data.WriteByte(marcFieldTerminator)
+		fmt.Fprintf(&directory, "%3s%04d%05d", f.Tag, data.Len()-start, start)
+	}
+	directory.WriteByte(marcFieldTerminator)
+
+	base := 24 + directory.Len()
+	total := base + data.Len() + 1
+	if total > 99999 {
+		return errors.New("MARC记录超过99999字节")
+	}
+
+	leader := []byte(record.Leader)
+	if len(leader) != 24 {
+		leader = []byte("00000nam a2200000   4500")
+	}
+	copy(leader[0:5], fmt.Sprintf("%05d", total))
+	leader[9] = 'a' // UTF-8
+	copy(leader[10:12], "22")
+	copy(leader[12:17], fmt.Sprintf("%05d", base))
+	copy(leader[20:24], "4500")
+
+	var buf bytes.Buffer
+	buf.Write(leader)
+	buf.Write(directory.Bytes())
+	buf.Write(data.Bytes())
+	buf.WriteByte(marcRecordTerminator)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func marcIndicator(ind string) string {
+	if ind == "" {
+		return " "
+	}
+	return ind[:1]
+}
+
+type marcXMLRecord struct {
+	Leader        string `xml:"leader"`
+	ControlFields []struct {
+		Tag   string `xml:"tag,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"controlfield"`
+	DataFields []struct {
+		Tag       string         `xml:"tag,attr"`
+		Ind1      string         `xml:"ind1,attr"`
+		Ind2      string         `xml:"ind2,attr"`
+		Subfields []MarcSubfield `xml:"subfield"`
+	} `xml:"datafield"`
+}
+
+// ReadMARCXML 读取MARCXML格式的记录（根元素可以是 collection 或 record）
+func ReadMARCXML(r io.Reader) ([]MarcRecord, error) {
+	decoder := xml.NewDecoder(r)
+	var records []MarcRecord
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return records, errors.New("解析MARCXML失败: " + err.Error())
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "record" {
+			continue
+		}
+
+		var raw marcXMLRecord
+		if err := decoder.DecodeElement(&raw, &start); err != nil {
+			return records, fmt.Errorf("第%d条记录: %w", len(records)+1, err)
+		}
+
+		// MARCXML 中控制字段总是位于数据字段之前
+		record := MarcRecord{Leader: raw.Leader}
+		for _, cf := range raw.ControlFields {
+			record.Fields = append(record.Fields, MarcField{Tag: cf.Tag, Value: cf.Value})
+		}
+		for _, df := range raw.DataFields {
+			record.Fields = append(record.Fields, MarcField{Tag: df.Tag, Ind1: df.Ind1, Ind2: df.Ind2, Subfields: df.Subfields})
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// MarcXMLWriter 流式写出MARCXML
+type MarcXMLWriter struct {
+	w io.Writer
+}
+
+// NewMarcXMLWriter 写出XML声明和 collection 开始标签
+func NewMarcXMLWriter(w io.Writer) (*MarcXMLWriter, error) {
+	if _, err := io.WriteString(w, xml.Header+`<collection xmlns="`+marcXMLNamespace+`">`+"\n"); err != nil {
+		return nil, err
+	}
+	return &MarcXMLWriter{w: w}, nil
+}
+
+// Write 写出一条记录
+func (m *MarcXMLWriter) Write(record MarcRecord) error {
+	var b strings.Builder
+	b.WriteString("  <record>\n")
+	if record.Leader != "" {
+		b.WriteString("    <leader>" + xmlEscape(record.Leader) + "</leader>\n")
+	}
+	for _, f := range record.Fields {
+		if f.IsControl() {
+			b.WriteString(`    <controlfield tag="` + xmlEscape(f.Tag) + `">` + xmlEscape(f.Value) + "</controlfield>\n")
+			continue
+		}
+		b.WriteString(`    <datafield tag="` + xmlEscape(f.Tag) + `" ind1="` + xmlEscape(marcIndicator(f.Ind1)) +
+			`" ind2="` + xmlEscape(marcIndicator(f.Ind2)) + `">` + "\n")
+		for _, sf := range f.Subfields {
+			b.WriteString(`      <subfield code="` + xmlEscape(sf.Code) + `">` + xmlEscape(sf.Value) + "</subfield>\n")
+		}
+		b.WriteString("    </datafield>\n")
+	}
+	b.WriteString("  </record>\n")
+	_, err := io.WriteString(m.w, b.String())
+	return err
+}
+
+// Close 写出 collection 结束标签
+func (m *MarcXMLWriter) Close() error {
+	_, err := io.WriteString(m.w, "</collection>\n")
+	return err
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HashPassword 使用bcrypt加密密码
+func HashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// CheckPassword 验证密码
+// 兼容旧版明文存储的密码，调用方应在验证通过后用 IsPasswordHashed 判断是否需要升级
+func CheckPassword(hashedPassword, password string) bool {
+	if IsPasswordHashed(hashedPassword) {
+		return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password)) == nil
+	}
+	return subtle.ConstantTimeCompare([]byte(hashedPassword), []byte(password)) == 1
+}
+
+// IsPasswordHashed 判断存储的密码是否已经是bcrypt哈希
+func IsPasswordHashed(storedPassword string) bool {
+	return strings.HasPrefix(storedPassword, "$2a$") ||
+		strings.HasPrefix(storedPassword, "$2b$") ||
+		strings.HasPrefix(storedPassword, "$2y$")
+}
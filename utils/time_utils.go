@@ -18,6 +18,11 @@ func GetCurrentMonthPeriod() string {
 	return fmt.Sprintf("%d-%02d", now.Year(), now.Month())
 }
 
+// GetCurrentYearPeriod 获取当前年份标识（2025）
+func GetCurrentYearPeriod() string {
+	return fmt.Sprintf("%d", time.Now().Year())
+}
+
 // GetWeekStartEnd 获取指定周期的开始和结束时间
 func GetWeekStartEnd(period string) (time.Time, time.Time, error) {
 	var year, week int
@@ -26,17 +31,11 @@ func GetWeekStartEnd(period string) (time.Time, time.Time, error) {
 		return time.Time{}, time.Time{}, err
 	}
 
-	// 计算该周的第一天（周一）
-	jan1 := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
-	weekday := int(jan1.Weekday())
-	if weekday == 0 {
-		weekday = 7
-	}
-	
-	// ISO周从周一开始
-	daysToMonday := (8 - weekday) % 7
-	firstMonday := jan1.AddDate(0, 0, daysToMonday)
-	
+	// ISO周：第1周是包含1月4日的那一周，从周一开始
+	jan4 := time.Date(year, 1, 4, 0, 0, 0, 0, time.Local)
+	daysSinceMonday := (int(jan4.Weekday()) + 6) % 7
+	firstMonday := jan4.AddDate(0, 0, -daysSinceMonday)
+
 	// 加上周数
 	weekStart := firstMonday.AddDate(0, 0, (week-1)*7)
 	weekEnd := weekStart.AddDate(0, 0, 7).Add(-time.Second)
@@ -58,6 +57,20 @@ func GetMonthStartEnd(period string) (time.Time, time.Time, error) {
 	return monthStart, monthEnd, nil
 }
 
+// GetYearStartEnd 获取指定年份的开始和结束时间
+func GetYearStartEnd(period string) (time.Time, time.Time, error) {
+	var year int
+	_, err := fmt.Sscanf(period, "%d", &year)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	yearStart := time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
+	yearEnd := yearStart.AddDate(1, 0, 0).Add(-time.Second)
+
+	return yearStart, yearEnd, nil
+}
+
 // GetLastWeekPeriod 获取上周周期
 func GetLastWeekPeriod() string {
 	now := time.Now().AddDate(0, 0, -7)
@@ -71,3 +84,7 @@ func GetLastMonthPeriod() string {
 	return fmt.Sprintf("%d-%02d", now.Year(), now.Month())
 }
 
+// GetLastYearPeriod 获取去年周期
+func GetLastYearPeriod() string {
+	return fmt.Sprintf("%d", time.Now().Year()-1)
+}
@@ -0,0 +1,210 @@
+package worker
+
+import (
+	"bookadmin/constants"
+	"bookadmin/global"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// reclaimLoop 定期回收待确认列表（PEL）中空闲过久的消息
+// Worker在XACK之前崩溃时，已读取的消息会一直留在待确认列表中，需要由其他Worker接管
+func (w *SyncWorker) reclaimLoop() {
+	ticker := time.NewTicker(w.reclaimInterval)
+	defer ticker.Stop()
+
+	for {
+		w.reclaimStream(constants.StreamLikeActions)
+		w.reclaimStream(constants.StreamFavoriteActions)
+
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reclaimStream 使用XAUTOCLAIM接管空闲消息并重新处理
+// 每次接管都会使消息的投递次数加1，超过 maxDeliveries 的消息转入死信Stream
+func (w *SyncWorker) reclaimStream(streamName string) {
+	start := "0-0"
+	for w.ctx.Err() == nil {
+		ctx, cancel := context.WithTimeout(w.ctx, 5*time.Second)
+		messages, next, err := global.GVA_REDIS.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   streamName,
+			Group:    w.consumerGroup,
+			Consumer: w.workerID,
+			MinIdle:  w.reclaimMinIdle,
+			Start:    start,
+			Count:    int64(w.batchSize),
+		}).Result()
+		cancel()
+		if err != nil {
+			global.GVA_LOG.Warn("回收待确认消息失败",
+				zap.String("worker", w.workerID),
+				zap.String("stream", streamName),
+				zap.Error(err))
+			return
+		}
+
+		if len(messages) > 0 {
+			w.handleReclaimed(streamName, messages)
+		}
+
+		// 游标回到0-0表示待确认列表已扫描完
+		if next == "" || next == "0-0" {
+			return
+		}
+		start = next
+	}
+}
+
+// handleReclaimed 处理接管的消息
+func (w *SyncWorker) handleReclaimed(streamName string, messages []redis.XMessage) {
+	counts := w.deliveryCounts(streamName, messages)
+
+	retry := make([]redis.XMessage, 0, len(messages))
+	deadCount := 0
+	for _, msg := range messages {
+		// 消息已从Stream中删除，只需从待确认列表中移除
+		if len(msg.Values) == 0 {
+			w.ackMessages(streamName, []string{msg.ID})
+			continue
+		}
+		if counts[msg.ID] > w.maxDeliveries {
+			w.deadLetter(streamName, msg, fmt.Sprintf("超过最大投递次数(%d)", w.maxDeliveries))
+			deadCount++
+			continue
+		}
+		retry = append(retry, msg)
+	}
+
+	global.GVA_LOG.Info("接管待确认消息",
+		zap.String("worker", w.workerID),
+		zap.String("stream", streamName),
+		zap.Int("retry", len(retry)),
+		zap.Int("dead", deadCount))
+
+	if len(retry) == 0 {
+		return
+	}
+	switch streamName {
+	case constants.StreamLikeActions:
+		w.processLikeMessages(streamName, retry)
+	case constants.StreamFavoriteActions:
+		w.processFavoriteMessages(streamName, retry)
+	}
+}
+
+// deliveryCounts 查询消息的投递次数（即重试计数），由Redis在待确认列表中维护
+func (w *SyncWorker) deliveryCounts(streamName string, messages []redis.XMessage) map[string]int64 {
+	ctx, cancel := context.WithTimeout(w.ctx, 2*time.Second)
+	defer cancel()
+
+	// 该消费者名下还可能有正在处理的消息，多取一批避免遗漏
+	pending, err := global.GVA_REDIS.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream:   streamName,
+		Group:    w.consumerGroup,
+		Start:    messages[0].ID,
+		End:      messages[len(messages)-1].ID,
+		Count:    int64(len(messages) + w.batchSize),
+		Consumer: w.workerID,
+	}).Result()
+	if err != nil {
+		global.GVA_LOG.Warn("查询消息投递次数失败",
+			zap.String("worker", w.workerID),
+			zap.String("stream", streamName),
+			zap.Error(err))
+		return map[string]int64{}
+	}
+
+	counts := make(map[string]int64, len(pending))
+	for _, p := range pending {
+		counts[p.ID] = p.RetryCount
+	}
+	return counts
+}
+
+// deadLetter 将无法处理的消息转入死信Stream并确认原消息
+// 写入死信Stream失败时不确认，消息留在待确认列表中等待下次回收
+func (w *SyncWorker) deadLetter(streamName string, msg redis.XMessage, reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var deliveries int64
+	pending, err := global.GVA_REDIS.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: streamName,
+		Group:  w.consumerGroup,
+		Start:  msg.ID,
+		End:    msg.ID,
+		Count:  1,
+	}).Result()
+	if err == nil && len(pending) > 0 {
+		deliveries = pending[0].RetryCount
+	}
+
+	payload, _ := json.Marshal(msg.Values)
+	err = global.GVA_REDIS.XAdd(ctx, &redis.XAddArgs{
+		Stream: constants.StreamSyncDeadLetter,
+		Values: map[string]interface{}{
+			"stream":     streamName,
+			"message_id": msg.ID,
+			"deliveries": deliveries,
+			"reason":     reason,
+			"failed_at":  time.Now().Unix(),
+			"payload":    string(payload),
+		},
+	}).Err()
+	if err != nil {
+		global.GVA_LOG.Error("写入死信Stream失败",
+			zap.String("worker", w.workerID),
+			zap.String("stream", streamName),
+			zap.String("message_id", msg.ID),
+			zap.Error(err))
+		return
+	}
+
+	w.ackMessages(streamName, []string{msg.ID})
+	global.GVA_LOG.Warn("消息转入死信Stream",
+		zap.String("worker", w.workerID),
+		zap.String("stream", streamName),
+		zap.String("message_id", msg.ID),
+		zap.Int64("deliveries", deliveries),
+		zap.String("reason", reason))
+}
+
+// parseSyncMessage 解析点赞/收藏消息，addAction、removeAction 为该Stream允许的两种操作
+func parseSyncMessage(msg redis.XMessage, addAction, removeAction string) (userID, bookID uint, action string, err error) {
+	if userID, err = parseUintValue(msg.Values, "user_id"); err != nil {
+		return
+	}
+	if bookID, err = parseUintValue(msg.Values, "book_id"); err != nil {
+		return
+	}
+	action, _ = msg.Values["action"].(string)
+	if action != addAction && action != removeAction {
+		err = fmt.Errorf("未知的操作: %q", action)
+	}
+	return
+}
+
+// parseUintValue 解析消息中的正整数字段
+func parseUintValue(values map[string]interface{}, key string) (uint, error) {
+	s, ok := values[key].(string)
+	if !ok {
+		return 0, errors.New("缺少字段 " + key)
+	}
+	v, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || v == 0 {
+		return 0, errors.New("字段格式错误 " + key)
+	}
+	return uint(v), nil
+}
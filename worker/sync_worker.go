@@ -6,7 +6,7 @@ import (
 	"bookadmin/model"
 	"context"
 	"fmt"
-	"strconv"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -16,24 +16,30 @@ import (
 
 // SyncWorker 同步Worker，负责消费Stream消息并批量写入MySQL
 type SyncWorker struct {
-	workerID      string
-	consumerGroup string
-	batchSize     int           // 批量处理大小
-	batchTimeout  time.Duration // 批量超时时间
-	ctx           context.Context
-	cancel        context.CancelFunc
+	workerID        string
+	consumerGroup   string
+	batchSize       int           // 批量处理大小
+	batchTimeout    time.Duration // 批量超时时间
+	reclaimInterval time.Duration // 回收待确认消息的间隔
+	reclaimMinIdle  time.Duration // 待确认消息空闲多久后被回收
+	maxDeliveries   int64         // 最大投递次数，超过后转入死信Stream
+	ctx             context.Context
+	cancel          context.CancelFunc
 }
 
 // NewSyncWorker 创建同步Worker实例
 func NewSyncWorker(workerID string) *SyncWorker {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &SyncWorker{
-		workerID:      workerID,
-		consumerGroup: "sync-group",
-		batchSize:     100,             // 每批处理100条
-		batchTimeout:  5 * time.Second, // 5秒超时
-		ctx:           ctx,
-		cancel:        cancel,
+		workerID:        workerID,
+		consumerGroup:   constants.StreamSyncGroup,
+		batchSize:       100,              // 每批处理100条
+		batchTimeout:    5 * time.Second,  // 5秒超时
+		reclaimInterval: 30 * time.Second, // 每30秒回收一次
+		reclaimMinIdle:  time.Minute,      // 空闲1分钟视为消费者已崩溃
+		maxDeliveries:   5,                // 最多投递5次
+		ctx:             ctx,
+		cancel:          cancel,
 	}
 }
 
@@ -44,6 +50,9 @@ func (w *SyncWorker) Start() {
 	// 启动两个goroutine分别处理点赞和收藏
 	go w.consumeLikeStream()
 	go w.consumeFavoriteStream()
+
+	// 回收其他Worker崩溃后遗留在待确认列表中的消息
+	go w.reclaimLoop()
 }
 
 // Stop 停止Worker
@@ -144,21 +153,23 @@ func (w *SyncWorker) processLikeMessages(streamName string, messages []redis.XMe
 	var unlikes []struct{ UserID, BookID uint }
 	messageIDs := make([]string, 0, len(messages))
 
-	// 解析消息
+	// 解析消息，格式错误的消息直接转入死信Stream
 	for _, msg := range messages {
-		userID, _ := strconv.ParseUint(msg.Values["user_id"].(string), 10, 32)
-		bookID, _ := strconv.ParseUint(msg.Values["book_id"].(string), 10, 32)
-		action := msg.Values["action"].(string)
+		userID, bookID, action, err := parseSyncMessage(msg, "like", "unlike")
+		if err != nil {
+			w.deadLetter(streamName, msg, err.Error())
+			continue
+		}
 
 		if action == "like" {
 			likes = append(likes, model.BookLike{
-				UserID: uint(userID),
-				BookID: uint(bookID),
+				UserID: userID,
+				BookID: bookID,
 			})
 		} else {
 			unlikes = append(unlikes, struct{ UserID, BookID uint }{
-				UserID: uint(userID),
-				BookID: uint(bookID),
+				UserID: userID,
+				BookID: bookID,
 			})
 		}
 
@@ -182,8 +193,10 @@ func (w *SyncWorker) processLikeMessages(streamName string, messages []redis.XMe
 
 		// 处理取消点赞
 		for _, unlike := range unlikes {
-			if err := tx.Where("user_id = ? AND book_id = ?", unlike.UserID, unlike.BookID).
-				Delete(&model.BookLike{}).Error; err != nil {
+			// 消息可能被重复投递，只有确实删除了记录才扣减统计
+			result := tx.Where("user_id = ? AND book_id = ?", unlike.UserID, unlike.BookID).
+				Delete(&model.BookLike{})
+			if result.Error != nil || result.RowsAffected == 0 {
 				continue
 			}
 			// 更新books表统计
@@ -218,21 +231,23 @@ func (w *SyncWorker) processFavoriteMessages(streamName string, messages []redis
 	var unfavorites []struct{ UserID, BookID uint }
 	messageIDs := make([]string, 0, len(messages))
 
-	// 解析消息
+	// 解析消息，格式错误的消息直接转入死信Stream
 	for _, msg := range messages {
-		userID, _ := strconv.ParseUint(msg.Values["user_id"].(string), 10, 32)
-		bookID, _ := strconv.ParseUint(msg.Values["book_id"].(string), 10, 32)
-		action := msg.Values["action"].(string)
+		userID, bookID, action, err := parseSyncMessage(msg, "favorite", "unfavorite")
+		if err != nil {
+			w.deadLetter(streamName, msg, err.Error())
+			continue
+		}
 
 		if action == "favorite" {
 			favorites = append(favorites, model.BookFavorite{
-				UserID: uint(userID),
-				BookID: uint(bookID),
+				UserID: userID,
+				BookID: bookID,
 			})
 		} else {
 			unfavorites = append(unfavorites, struct{ UserID, BookID uint }{
-				UserID: uint(userID),
-				BookID: uint(bookID),
+				UserID: userID,
+				BookID: bookID,
 			})
 		}
 
@@ -251,8 +266,9 @@ func (w *SyncWorker) processFavoriteMessages(streamName string, messages []redis
 		}
 
 		for _, unfavorite := range unfavorites {
-			if err := tx.Where("user_id = ? AND book_id = ?", unfavorite.UserID, unfavorite.BookID).
-				Delete(&model.BookFavorite{}).Error; err != nil {
+			result := tx.Where("user_id = ? AND book_id = ?", unfavorite.UserID, unfavorite.BookID).
+				Delete(&model.BookFavorite{})
+			if result.Error != nil || result.RowsAffected == 0 {
 				continue
 			}
 			tx.Model(&model.Book{}).
@@ -282,6 +298,10 @@ func (w *SyncWorker) processFavoriteMessages(streamName string, messages []redis
 
 // ackMessages 确认消息已处理
 func (w *SyncWorker) ackMessages(streamName string, messageIDs []string) {
+	if len(messageIDs) == 0 {
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
@@ -296,8 +316,10 @@ func (w *SyncWorker) ackMessages(streamName string, messageIDs []string) {
 
 // WorkerPool Worker池管理
 type WorkerPool struct {
+	mu      sync.Mutex
 	workers []*SyncWorker
 	size    int
+	started bool
 }
 
 // NewWorkerPool 创建Worker池
@@ -308,8 +330,15 @@ func NewWorkerPool(size int) *WorkerPool {
 	}
 }
 
-// Start 启动所有Worker
+// Start 启动所有Worker，重复调用时不会重复启动（Redis恢复后会再次调用）
 func (p *WorkerPool) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started {
+		return
+	}
+	p.started = true
+
 	for i := 0; i < p.size; i++ {
 		workerID := fmt.Sprintf("worker-%d", i+1)
 		worker := NewSyncWorker(workerID)
@@ -321,6 +350,11 @@ func (p *WorkerPool) Start() {
 
 // Stop 停止所有Worker
 func (p *WorkerPool) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.started {
+		return
+	}
 	for _, worker := range p.workers {
 		worker.Stop()
 	}